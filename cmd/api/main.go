@@ -3,9 +3,12 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
+	_ "time/tzdata" // profile time zones must resolve without system zoneinfo
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	apitoken "github.com/ishola-faazele/taskflow/internal/apitoken/http"
 	"github.com/ishola-faazele/taskflow/internal/project"
 	shared "github.com/ishola-faazele/taskflow/internal/shared"
 	"github.com/ishola-faazele/taskflow/internal/user"
@@ -22,6 +25,26 @@ func main() {
 	appState := shared.NewAppState()
 	defer appState.Clean()
 
+	// remove attachment files no task references any more
+	blobCollector := project.NewBlobCollector(appState.DB, appState.Blobs, time.Hour)
+	blobCollector.Start()
+	defer blobCollector.Stop()
+	trashPurger := project.NewTrashPurger(appState.DB, time.Hour)
+	trashPurger.Start()
+	defer trashPurger.Stop()
+	// build and drop custom field indexes without locking the task table
+	customFieldIndexer := project.NewCustomFieldIndexer(appState.DB, time.Minute)
+	customFieldIndexer.Start()
+	defer customFieldIndexer.Stop()
+	// email assignees on the morning their tasks are due
+	dueReminder := project.NewDueReminder(appState.DB, appState.AmqpConn, 15*time.Minute)
+	dueReminder.Start()
+	defer dueReminder.Stop()
+	// forget rate limit attempts, redeemed tokens and abandoned OIDC logins
+	authPruner := user.NewAuthPruner(appState.DB, time.Hour)
+	authPruner.Start()
+	defer authPruner.Stop()
+
 	// mount routes
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
@@ -35,13 +58,35 @@ func main() {
 		user.RegisterRoutes(r, appState)
 	})
 	apiRouter.Route("/workspace/{ws_id}/project", func(r chi.Router) {
-		project.RegisterProjectRoutes(r, appState.DB)
+		project.RegisterProjectRoutes(r, appState)
 	})
 	apiRouter.Route("/workspace/{ws_id}/task", func(r chi.Router) {
-		project.RegisterTaskRoutes(r, appState.DB)
+		project.RegisterTaskRoutes(r, appState)
+	})
+	apiRouter.Route("/workspace/{ws_id}/label", func(r chi.Router) {
+		project.RegisterLabelRoutes(r, appState)
+	})
+	apiRouter.Route("/workspace/{ws_id}/report", func(r chi.Router) {
+		project.RegisterReportRoutes(r, appState)
+	})
+	apiRouter.Route("/workspace/{ws_id}/template", func(r chi.Router) {
+		project.RegisterTemplateRoutes(r, appState)
+	})
+	apiRouter.Route("/workspace/{ws_id}/trash", func(r chi.Router) {
+		project.RegisterTrashRoutes(r, appState)
+	})
+	apiRouter.Route("/workspace/{ws_id}/notification", func(r chi.Router) {
+		project.RegisterNotificationRoutes(r, appState)
+	})
+	apiRouter.Route("/workspace/{ws_id}/service-account", func(r chi.Router) {
+		apitoken.RegisterServiceAccountRoutes(r, appState)
+	})
+	apiRouter.Route("/user/tokens", func(r chi.Router) {
+		apitoken.RegisterPersonalTokenRoutes(r, appState)
 	})
 
 	r.Mount("/api", apiRouter)
+	r.Get("/.well-known/jwks.json", appState.KeyRing.ServeJWKS)
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		_, err := w.Write([]byte("Hello World!"))
 		if err != nil {
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/ishola-faazele/taskflow/internal/oidc/mockidp"
+)
+
+// Runs the mock OpenID provider for local development. Point the API at it with
+// OIDC_PROVIDERS=mock and OIDC_MOCK_ISSUER, OIDC_MOCK_CLIENT_ID,
+// OIDC_MOCK_CLIENT_SECRET and OIDC_MOCK_REDIRECT_URL matching the flags below.
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9000", "address to listen on")
+	issuer := flag.String("issuer", "http://127.0.0.1:9000", "issuer URL the provider identifies itself with")
+	clientID := flag.String("client-id", "taskflow", "client id of the API")
+	clientSecret := flag.String("client-secret", "taskflow-secret", "client secret of the API")
+	redirectURI := flag.String("redirect-uri", "http://localhost:3000/api/user/oidc/mock/callback", "redirect URI registered for the API")
+	flag.Parse()
+
+	server, err := mockidp.New(*issuer)
+	if err != nil {
+		log.Fatalln("FAILED_TO_CREATE_MOCK_IDP:", err)
+	}
+	server.RegisterClient(mockidp.Client{
+		ID:           *clientID,
+		Secret:       *clientSecret,
+		RedirectURIs: []string{*redirectURI},
+	})
+	log.Println("Mock identity provider is running at", server.Issuer())
+	if err := http.ListenAndServe(*addr, server.Handler()); err != nil {
+		log.Fatalln(err)
+	}
+}
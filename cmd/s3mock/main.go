@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/ishola-faazele/taskflow/internal/utils/blobstore/s3mock"
+)
+
+// Runs the in-memory S3 stand-in for local development. Point the API at it with
+// BLOBSTORE_BACKEND=s3, BLOBSTORE_S3_PATH_STYLE=true and BLOBSTORE_S3_ENDPOINT,
+// _BUCKET, _REGION, _ACCESS_KEY and _SECRET_KEY matching the flags below.
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9100", "address to listen on")
+	bucket := flag.String("bucket", "taskflow", "bucket to create")
+	region := flag.String("region", "us-east-1", "region requests are signed for")
+	accessKey := flag.String("access-key", "taskflow", "access key of the API")
+	secretKey := flag.String("secret-key", "taskflow-secret", "secret key of the API")
+	flag.Parse()
+
+	server := s3mock.New(*accessKey, *secretKey, *region)
+	server.CreateBucket(*bucket)
+	log.Println("Mock S3 server is running at http://" + *addr)
+	if err := http.ListenAndServe(*addr, server.Handler()); err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,140 @@
+package apitoken
+
+import (
+	"strings"
+	"time"
+)
+
+// Kind tells personal access tokens apart from tokens of workspace service accounts
+type Kind string
+
+const (
+	KindPersonal       Kind = "personal"
+	KindServiceAccount Kind = "service_account"
+)
+
+// Secrets start with a prefix naming their kind so they are easy to recognise in
+// logs and secret scanners, and so the middleware can tell them apart from JWTs
+const (
+	PersonalTokenPrefix       = "tfp_"
+	ServiceAccountTokenPrefix = "tfs_"
+	// displayPrefixLength is how much of the secret is kept in clear to identify it
+	displayPrefixLength = 12
+)
+
+// Scope limits what a token may do. A write scope also grants the matching read scope.
+type Scope string
+
+const (
+	ScopeTasksRead       Scope = "tasks:read"
+	ScopeTasksWrite      Scope = "tasks:write"
+	ScopeProjectsRead    Scope = "projects:read"
+	ScopeProjectsWrite   Scope = "projects:write"
+	ScopeWorkspacesRead  Scope = "workspaces:read"
+	ScopeWorkspacesWrite Scope = "workspaces:write"
+	ScopeProfileRead     Scope = "profile:read"
+	ScopeProfileWrite    Scope = "profile:write"
+)
+
+// AllScopes lists every scope a token can be granted
+var AllScopes = []Scope{
+	ScopeTasksRead,
+	ScopeTasksWrite,
+	ScopeProjectsRead,
+	ScopeProjectsWrite,
+	ScopeWorkspacesRead,
+	ScopeWorkspacesWrite,
+	ScopeProfileRead,
+	ScopeProfileWrite,
+}
+
+// IsValid reports whether the scope is one of AllScopes
+func (s Scope) IsValid() bool {
+	for _, scope := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadScope and WriteScope build the scopes for a resource such as "tasks"
+func ReadScope(resource string) Scope  { return Scope(resource + ":read") }
+func WriteScope(resource string) Scope { return Scope(resource + ":write") }
+
+// Token is a personal access token or a service account token. The secret itself
+// is only returned once, when the token is created.
+type Token struct {
+	ID         string     `json:"id"`
+	Kind       Kind       `json:"kind"`
+	UserID     string     `json:"user_id"`
+	CreatedBy  string     `json:"created_by"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	TokenHash  string     `json:"-"`
+	Scopes     []Scope    `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// Email of the principal, filled in when a token is looked up for authentication
+	Email string `json:"-"`
+}
+
+// IsActive reports whether the token has neither been revoked nor expired
+func (t *Token) IsActive(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || t.ExpiresAt.After(now)
+}
+
+// HasScope reports whether the token grants scope, counting write as including read
+func (t *Token) HasScope(scope Scope) bool {
+	implied := scope
+	if resource, ok := strings.CutSuffix(string(scope), ":read"); ok {
+		implied = WriteScope(resource)
+	}
+	for _, granted := range t.Scopes {
+		if granted == scope || granted == implied {
+			return true
+		}
+	}
+	return false
+}
+
+// CreatedToken is returned once when a token is created and carries the secret
+type CreatedToken struct {
+	Token  *Token `json:"token"`
+	Secret string `json:"secret"`
+}
+
+// ServiceAccount is a non-human workspace member that automation authenticates as
+type ServiceAccount struct {
+	ID          string     `json:"id"`
+	WorkspaceID string     `json:"workspace_id"`
+	Name        string     `json:"name"`
+	Role        string     `json:"role"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"`
+}
+
+// ServiceAccountEmail is the placeholder address stored on a service account's auth
+// record. The .invalid domain can never receive mail or be verified by a provider.
+func ServiceAccountEmail(id string) string {
+	return id + "@service-account.invalid"
+}
+
+// LooksLikeToken reports whether a bearer credential is an API token rather than a JWT
+func LooksLikeToken(credential string) bool {
+	return strings.HasPrefix(credential, PersonalTokenPrefix) || strings.HasPrefix(credential, ServiceAccountTokenPrefix)
+}
+
+const (
+	// DefaultTokenLifetime applies when a token is created without an expiry
+	DefaultTokenLifetime = 90 * 24 * time.Hour
+	// MaxTokenLifetime is the longest expiry a token can be created with
+	MaxTokenLifetime = 366 * 24 * time.Hour
+)
@@ -0,0 +1,203 @@
+package apitoken
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	. "github.com/ishola-faazele/taskflow/internal/apitoken"
+	domain_middleware "github.com/ishola-faazele/taskflow/internal/middleware"
+	workspace_db "github.com/ishola-faazele/taskflow/internal/workspace/db"
+	workspace_entity "github.com/ishola-faazele/taskflow/internal/workspace/entity"
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+type APITokenHandler struct {
+	service   *APITokenService
+	responder *domain_errors.APIResponder
+}
+
+func NewAPITokenHandler(db *sql.DB) *APITokenHandler {
+	tokenRepo := NewPostgresTokenRepository(db)
+	accountRepo := NewPostgresServiceAccountRepository(db)
+	workspaceRepo := workspace_db.NewPostgresWorkspaceRepository(db)
+	service := NewAPITokenService(tokenRepo, accountRepo, workspaceRepo)
+	responder := domain_errors.NewAPIResponder()
+
+	return &APITokenHandler{
+		service:   service,
+		responder: responder,
+	}
+}
+
+type CreateTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []Scope    `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreateServiceAccountRequest struct {
+	Name string                `json:"name"`
+	Role workspace_entity.Role `json:"role"`
+}
+
+type ScopesResponse struct {
+	Scopes []Scope `json:"scopes"`
+}
+
+// requester returns the authenticated user, writing an error response when missing
+func (h *APITokenHandler) requester(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return "", false
+	}
+	return userID, true
+}
+
+// ListScopes lists the scopes tokens can be granted
+func (h *APITokenHandler) ListScopes(w http.ResponseWriter, r *http.Request) {
+	h.responder.Success(w, r, http.StatusOK, "Scopes retrieved successfully", ScopesResponse{Scopes: AllScopes})
+}
+
+// CreatePersonalToken handles personal access token creation
+func (h *APITokenHandler) CreatePersonalToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requester(w, r)
+	if !ok {
+		return
+	}
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	created, err := h.service.CreatePersonalToken(userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to create token", err)
+		return
+	}
+	h.responder.Created(w, r, "/api/user/tokens/"+created.Token.ID, created)
+}
+
+// ListPersonalTokens lists the requester's personal access tokens
+func (h *APITokenHandler) ListPersonalTokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requester(w, r)
+	if !ok {
+		return
+	}
+	tokens, err := h.service.ListPersonalTokens(userID)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to retrieve tokens", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Tokens retrieved successfully", tokens)
+}
+
+// RevokePersonalToken revokes one of the requester's personal access tokens
+func (h *APITokenHandler) RevokePersonalToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requester(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.RevokePersonalToken(userID, r.PathValue("id")); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to revoke token", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// CreateServiceAccount handles service account creation
+func (h *APITokenHandler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	requester, ok := h.requester(w, r)
+	if !ok {
+		return
+	}
+	var req CreateServiceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	wsID := r.PathValue("ws_id")
+	account, err := h.service.CreateServiceAccount(wsID, req.Name, req.Role, requester)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to create service account", err)
+		return
+	}
+	h.responder.Created(w, r, "/api/workspace/"+wsID+"/service-account/"+account.ID, account)
+}
+
+// ListServiceAccounts lists the workspace's service accounts
+func (h *APITokenHandler) ListServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	requester, ok := h.requester(w, r)
+	if !ok {
+		return
+	}
+	accounts, err := h.service.ListServiceAccounts(r.PathValue("ws_id"), requester)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to retrieve service accounts", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Service accounts retrieved successfully", accounts)
+}
+
+// DisableServiceAccount disables a service account and revokes its tokens
+func (h *APITokenHandler) DisableServiceAccount(w http.ResponseWriter, r *http.Request) {
+	requester, ok := h.requester(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.DisableServiceAccount(r.PathValue("ws_id"), r.PathValue("id"), requester); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to disable service account", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// CreateServiceAccountToken handles service account token creation
+func (h *APITokenHandler) CreateServiceAccountToken(w http.ResponseWriter, r *http.Request) {
+	requester, ok := h.requester(w, r)
+	if !ok {
+		return
+	}
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	wsID, accountID := r.PathValue("ws_id"), r.PathValue("id")
+	created, err := h.service.CreateServiceAccountToken(wsID, accountID, req.Name, req.Scopes, req.ExpiresAt, requester)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to create token", err)
+		return
+	}
+	h.responder.Created(w, r, "/api/workspace/"+wsID+"/service-account/"+accountID+"/token/"+created.Token.ID, created)
+}
+
+// ListServiceAccountTokens lists a service account's tokens
+func (h *APITokenHandler) ListServiceAccountTokens(w http.ResponseWriter, r *http.Request) {
+	requester, ok := h.requester(w, r)
+	if !ok {
+		return
+	}
+	tokens, err := h.service.ListServiceAccountTokens(r.PathValue("ws_id"), r.PathValue("id"), requester)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to retrieve tokens", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Tokens retrieved successfully", tokens)
+}
+
+// RevokeServiceAccountToken revokes one of a service account's tokens
+func (h *APITokenHandler) RevokeServiceAccountToken(w http.ResponseWriter, r *http.Request) {
+	requester, ok := h.requester(w, r)
+	if !ok {
+		return
+	}
+	err := h.service.RevokeServiceAccountToken(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("token_id"), requester)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to revoke token", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
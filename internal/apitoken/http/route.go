@@ -0,0 +1,36 @@
+package apitoken
+
+import (
+	"github.com/go-chi/chi/v5"
+	domain_middleware "github.com/ishola-faazele/taskflow/internal/middleware"
+	"github.com/ishola-faazele/taskflow/internal/shared"
+)
+
+// RegisterPersonalTokenRoutes mounts personal access token management. Tokens
+// cannot be used to manage tokens, so every route requires a session.
+func RegisterPersonalTokenRoutes(r chi.Router, as *shared.AppState) {
+	dm := domain_middleware.NewDomainMiddleware(as.DB)
+	handler := NewAPITokenHandler(as.DB)
+	r.Use(dm.Authenticate)
+	r.Use(dm.RequireSession)
+
+	r.Get("/scopes", handler.ListScopes)
+	r.Post("/", handler.CreatePersonalToken)
+	r.Get("/", handler.ListPersonalTokens)
+	r.Delete("/{id}", handler.RevokePersonalToken)
+}
+
+// RegisterServiceAccountRoutes mounts service account management for a workspace
+func RegisterServiceAccountRoutes(r chi.Router, as *shared.AppState) {
+	dm := domain_middleware.NewDomainMiddleware(as.DB)
+	handler := NewAPITokenHandler(as.DB)
+	r.Use(dm.Authenticate)
+	r.Use(dm.RequireSession)
+
+	r.Post("/", handler.CreateServiceAccount)
+	r.Get("/", handler.ListServiceAccounts)
+	r.Delete("/{id}", handler.DisableServiceAccount)
+	r.Post("/{id}/token", handler.CreateServiceAccountToken)
+	r.Get("/{id}/token", handler.ListServiceAccountTokens)
+	r.Delete("/{id}/token/{token_id}", handler.RevokeServiceAccountToken)
+}
@@ -0,0 +1,355 @@
+package apitoken
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+// PostgresTokenRepository handles API token persistence
+type PostgresTokenRepository struct {
+	db *sql.DB
+}
+
+// PostgresServiceAccountRepository handles service account persistence
+type PostgresServiceAccountRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresTokenRepository creates a new API token repository
+func NewPostgresTokenRepository(db *sql.DB) *PostgresTokenRepository {
+	return &PostgresTokenRepository{db: db}
+}
+
+// NewPostgresServiceAccountRepository creates a new service account repository
+func NewPostgresServiceAccountRepository(db *sql.DB) *PostgresServiceAccountRepository {
+	return &PostgresServiceAccountRepository{db: db}
+}
+
+// tokenColumns is the column list scanned by scanToken
+const tokenColumns = `id, kind, user_id, created_by, name, prefix, token_hash, scopes, expires_at, last_used_at, last_used_ip, created_at, revoked_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanToken(row rowScanner, token *Token, extra ...interface{}) error {
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	dest := []interface{}{
+		&token.ID,
+		&token.Kind,
+		&token.UserID,
+		&token.CreatedBy,
+		&token.Name,
+		&token.Prefix,
+		&token.TokenHash,
+		&scopes,
+		&expiresAt,
+		&lastUsedAt,
+		&token.LastUsedIP,
+		&token.CreatedAt,
+		&revokedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	token.Scopes = decodeScopes(scopes)
+	token.ExpiresAt = nullTimePtr(expiresAt)
+	token.LastUsedAt = nullTimePtr(lastUsedAt)
+	token.RevokedAt = nullTimePtr(revokedAt)
+	return nil
+}
+
+// scopes are stored space separated, the same way OAuth serialises them
+func encodeScopes(scopes []Scope) string {
+	parts := make([]string, len(scopes))
+	for i, scope := range scopes {
+		parts[i] = string(scope)
+	}
+	return strings.Join(parts, " ")
+}
+
+func decodeScopes(value string) []Scope {
+	fields := strings.Fields(value)
+	scopes := make([]Scope, len(fields))
+	for i, field := range fields {
+		scopes[i] = Scope(field)
+	}
+	return scopes
+}
+
+func nullTimePtr(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t := value.Time
+	return &t
+}
+
+// TokenRepository implementation
+
+func (r *PostgresTokenRepository) Create(token *Token) (*Token, domain_errors.DomainError) {
+	query := `
+		INSERT INTO api_token (id, kind, user_id, created_by, name, prefix, token_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + tokenColumns
+
+	row := r.db.QueryRow(
+		query,
+		token.ID,
+		token.Kind,
+		token.UserID,
+		token.CreatedBy,
+		token.Name,
+		token.Prefix,
+		token.TokenHash,
+		encodeScopes(token.Scopes),
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+
+	result := &Token{}
+	if err := scanToken(row, result); err != nil {
+		return nil, domain_errors.NewDatabaseError("API_TOKEN_CREATION", err)
+	}
+	return result, nil
+}
+
+func (r *PostgresTokenRepository) GetByID(id string) (*Token, domain_errors.DomainError) {
+	query := `SELECT ` + tokenColumns + ` FROM api_token WHERE id = $1`
+
+	result := &Token{}
+	if err := scanToken(r.db.QueryRow(query, id), result); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("API_TOKEN", id)
+		}
+		return nil, domain_errors.NewDatabaseError("API_TOKEN_QUERY", err)
+	}
+	return result, nil
+}
+
+func (r *PostgresTokenRepository) GetByHash(tokenHash string) (*Token, domain_errors.DomainError) {
+	query := `
+		SELECT t.id, t.kind, t.user_id, t.created_by, t.name, t.prefix, t.token_hash, t.scopes,
+			t.expires_at, t.last_used_at, t.last_used_ip, t.created_at, t.revoked_at, a.email
+		FROM api_token t
+		JOIN auth a ON a.id = t.user_id
+		WHERE t.token_hash = $1
+	`
+
+	result := &Token{}
+	if err := scanToken(r.db.QueryRow(query, tokenHash), result, &result.Email); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("API_TOKEN", "")
+		}
+		return nil, domain_errors.NewDatabaseError("API_TOKEN_QUERY", err)
+	}
+	return result, nil
+}
+
+func (r *PostgresTokenRepository) ListByUser(userID string) ([]*Token, domain_errors.DomainError) {
+	query := `
+		SELECT ` + tokenColumns + `
+		FROM api_token
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("API_TOKEN_LIST", err)
+	}
+	defer rows.Close()
+
+	tokens := []*Token{}
+	for rows.Next() {
+		token := &Token{}
+		if err := scanToken(rows, token); err != nil {
+			return nil, domain_errors.NewDatabaseError("API_TOKEN_SCAN", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("API_TOKEN_ITERATION", err)
+	}
+	return tokens, nil
+}
+
+func (r *PostgresTokenRepository) Revoke(id string) domain_errors.DomainError {
+	query := `
+		UPDATE api_token
+		SET revoked_at = COALESCE(revoked_at, $2)
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(query, id, time.Now().UTC())
+	if err != nil {
+		return domain_errors.NewDatabaseError("API_TOKEN_REVOCATION", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError("API_TOKEN_REVOCATION", err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError("API_TOKEN", id)
+	}
+	return nil
+}
+
+func (r *PostgresTokenRepository) TouchLastUsed(id, ipAddress string, at time.Time, interval time.Duration) domain_errors.DomainError {
+	query := `
+		UPDATE api_token
+		SET last_used_at = $2, last_used_ip = $3
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $4 OR last_used_ip <> $3)
+	`
+
+	if _, err := r.db.Exec(query, id, at, ipAddress, at.Add(-interval)); err != nil {
+		return domain_errors.NewDatabaseError("API_TOKEN_USAGE", err)
+	}
+	return nil
+}
+
+// ServiceAccountRepository implementation
+
+func (r *PostgresServiceAccountRepository) Create(account *ServiceAccount) (*ServiceAccount, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("START_OF_SERVICE_ACCOUNT_CREATION_TRANSACTION", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// service accounts cannot sign in interactively, so magic links stay disabled
+	authQuery := `
+		INSERT INTO auth (id, email, magic_link_enabled, created_at)
+		VALUES ($1, $2, FALSE, $3)
+	`
+	if _, err := tx.Exec(authQuery, account.ID, ServiceAccountEmail(account.ID), account.CreatedAt); err != nil {
+		return nil, domain_errors.NewDatabaseError("SERVICE_ACCOUNT_AUTH_CREATION", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO user_profile (id, name) VALUES ($1, $2)`, account.ID, account.Name); err != nil {
+		return nil, domain_errors.NewDatabaseError("SERVICE_ACCOUNT_PROFILE_CREATION", err)
+	}
+	membershipQuery := `
+		INSERT INTO membership (user_id, workspace_id, role, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := tx.Exec(membershipQuery, account.ID, account.WorkspaceID, account.Role, account.CreatedAt); err != nil {
+		return nil, domain_errors.NewDatabaseError("SERVICE_ACCOUNT_MEMBERSHIP_CREATION", err)
+	}
+	accountQuery := `
+		INSERT INTO service_account (id, workspace_id, name, role, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	if _, err := tx.Exec(accountQuery, account.ID, account.WorkspaceID, account.Name, account.Role, account.CreatedBy, account.CreatedAt); err != nil {
+		return nil, domain_errors.NewDatabaseError("SERVICE_ACCOUNT_CREATION", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("COMMIT_OF_SERVICE_ACCOUNT_CREATION_TRANSACTION", err)
+	}
+	return account, nil
+}
+
+func (r *PostgresServiceAccountRepository) GetByID(id string) (*ServiceAccount, domain_errors.DomainError) {
+	query := `
+		SELECT id, workspace_id, name, role, created_by, created_at, disabled_at
+		FROM service_account
+		WHERE id = $1
+	`
+
+	result := &ServiceAccount{}
+	var disabledAt sql.NullTime
+	err := r.db.QueryRow(query, id).Scan(
+		&result.ID,
+		&result.WorkspaceID,
+		&result.Name,
+		&result.Role,
+		&result.CreatedBy,
+		&result.CreatedAt,
+		&disabledAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("SERVICE_ACCOUNT", id)
+		}
+		return nil, domain_errors.NewDatabaseError("SERVICE_ACCOUNT_QUERY", err)
+	}
+	result.DisabledAt = nullTimePtr(disabledAt)
+	return result, nil
+}
+
+func (r *PostgresServiceAccountRepository) ListByWorkspace(workspaceID string) ([]*ServiceAccount, domain_errors.DomainError) {
+	query := `
+		SELECT id, workspace_id, name, role, created_by, created_at
+		FROM service_account
+		WHERE workspace_id = $1 AND disabled_at IS NULL
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(query, workspaceID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("SERVICE_ACCOUNT_LIST", err)
+	}
+	defer rows.Close()
+
+	accounts := []*ServiceAccount{}
+	for rows.Next() {
+		account := &ServiceAccount{}
+		err := rows.Scan(
+			&account.ID,
+			&account.WorkspaceID,
+			&account.Name,
+			&account.Role,
+			&account.CreatedBy,
+			&account.CreatedAt,
+		)
+		if err != nil {
+			return nil, domain_errors.NewDatabaseError("SERVICE_ACCOUNT_SCAN", err)
+		}
+		accounts = append(accounts, account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("SERVICE_ACCOUNT_ITERATION", err)
+	}
+	return accounts, nil
+}
+
+func (r *PostgresServiceAccountRepository) Disable(id string, at time.Time) domain_errors.DomainError {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return domain_errors.NewDatabaseError("START_OF_SERVICE_ACCOUNT_DISABLE_TRANSACTION", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := `
+		UPDATE service_account
+		SET disabled_at = $2
+		WHERE id = $1 AND disabled_at IS NULL
+		RETURNING workspace_id
+	`
+	var workspaceID string
+	if err := tx.QueryRow(query, id, at).Scan(&workspaceID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain_errors.NewNotFoundError("SERVICE_ACCOUNT", id)
+		}
+		return domain_errors.NewDatabaseError("SERVICE_ACCOUNT_DISABLE", err)
+	}
+	if _, err := tx.Exec(`UPDATE api_token SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL`, id, at); err != nil {
+		return domain_errors.NewDatabaseError("SERVICE_ACCOUNT_TOKEN_REVOCATION", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM membership WHERE user_id = $1 AND workspace_id = $2`, id, workspaceID); err != nil {
+		return domain_errors.NewDatabaseError("SERVICE_ACCOUNT_MEMBERSHIP_REMOVAL", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain_errors.NewDatabaseError("COMMIT_OF_SERVICE_ACCOUNT_DISABLE_TRANSACTION", err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package apitoken
+
+import (
+	"time"
+
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+type TokenRepository interface {
+	Create(token *Token) (*Token, domain_errors.DomainError)
+	GetByID(id string) (*Token, domain_errors.DomainError)
+	// GetByHash looks a token up by the hash of its secret and fills in the principal's email
+	GetByHash(tokenHash string) (*Token, domain_errors.DomainError)
+	ListByUser(userID string) ([]*Token, domain_errors.DomainError)
+	Revoke(id string) domain_errors.DomainError
+	// TouchLastUsed records use of a token, writing at most once per interval
+	TouchLastUsed(id, ipAddress string, at time.Time, interval time.Duration) domain_errors.DomainError
+}
+
+type ServiceAccountRepository interface {
+	// Create inserts the account's auth record, profile, workspace membership and
+	// service account row together
+	Create(account *ServiceAccount) (*ServiceAccount, domain_errors.DomainError)
+	GetByID(id string) (*ServiceAccount, domain_errors.DomainError)
+	ListByWorkspace(workspaceID string) ([]*ServiceAccount, domain_errors.DomainError)
+	// Disable revokes the account's tokens and removes it from its workspace. The
+	// auth record is kept so tasks and projects it created keep their creator.
+	Disable(id string, at time.Time) domain_errors.DomainError
+}
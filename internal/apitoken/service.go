@@ -0,0 +1,259 @@
+package apitoken
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	workspace_entity "github.com/ishola-faazele/taskflow/internal/workspace/entity"
+	workspace_repository "github.com/ishola-faazele/taskflow/internal/workspace/repository"
+	"github.com/ishola-faazele/taskflow/pkg/utils"
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+// lastUsedInterval limits how often authentication writes last-used information
+const lastUsedInterval = time.Minute
+
+type APITokenService struct {
+	tokenRepo     TokenRepository
+	accountRepo   ServiceAccountRepository
+	workspaceRepo workspace_repository.WorkspaceRepository
+}
+
+func NewAPITokenService(tokenRepo TokenRepository, accountRepo ServiceAccountRepository, workspaceRepo workspace_repository.WorkspaceRepository) *APITokenService {
+	return &APITokenService{
+		tokenRepo:     tokenRepo,
+		accountRepo:   accountRepo,
+		workspaceRepo: workspaceRepo,
+	}
+}
+
+// Authenticate resolves a bearer secret to its token and records the use. Unknown,
+// revoked and expired tokens all fail the same way.
+func (s *APITokenService) Authenticate(secret, ipAddress string) (*Token, domain_errors.DomainError) {
+	if !LooksLikeToken(secret) {
+		return nil, domain_errors.NewUnauthorizedError("INVALID_API_TOKEN")
+	}
+	token, err := s.tokenRepo.GetByHash(utils.HashToken(secret))
+	if err != nil {
+		if domain_errors.IsNotFound(err) {
+			return nil, domain_errors.NewUnauthorizedError("INVALID_API_TOKEN")
+		}
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if !token.IsActive(now) {
+		return nil, domain_errors.NewUnauthorizedError("INVALID_API_TOKEN")
+	}
+	if err := s.tokenRepo.TouchLastUsed(token.ID, ipAddress, now, lastUsedInterval); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// PERSONAL ACCESS TOKENS
+
+// Creates a personal access token acting as the user. The secret is only returned here.
+func (s *APITokenService) CreatePersonalToken(userID, name string, scopes []Scope, expiresAt *time.Time) (*CreatedToken, domain_errors.DomainError) {
+	return s.createToken(KindPersonal, userID, userID, name, scopes, expiresAt)
+}
+
+// Lists the user's personal access tokens that have not been revoked
+func (s *APITokenService) ListPersonalTokens(userID string) ([]*Token, domain_errors.DomainError) {
+	return s.tokenRepo.ListByUser(userID)
+}
+
+// Revokes one of the user's personal access tokens
+func (s *APITokenService) RevokePersonalToken(userID, tokenID string) domain_errors.DomainError {
+	token, err := s.tokenRepo.GetByID(tokenID)
+	if err != nil {
+		return err
+	}
+	// do not reveal tokens of other users
+	if token.UserID != userID || token.Kind != KindPersonal {
+		return domain_errors.NewNotFoundError("API_TOKEN", tokenID)
+	}
+	return s.tokenRepo.Revoke(tokenID)
+}
+
+// SERVICE ACCOUNTS
+
+// Creates a service account that joins the workspace with the given role
+func (s *APITokenService) CreateServiceAccount(workspaceID, name string, role workspace_entity.Role, requester string) (*ServiceAccount, domain_errors.DomainError) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, domain_errors.NewValidationErrorWithValue("name", name, "EMPTY_SERVICE_ACCOUNT_NAME")
+	}
+	if role == "" {
+		role = workspace_entity.RoleMember
+	}
+	if role != workspace_entity.RoleMember && role != workspace_entity.RoleAdmin {
+		return nil, domain_errors.NewValidationErrorWithValue("role", role, "SERVICE_ACCOUNT_ROLE_MUST_BE_MEMBER_OR_ADMIN")
+	}
+	if err := s.checkOwner(workspaceID, requester); err != nil {
+		return nil, err
+	}
+	account := &ServiceAccount{
+		ID:          uuid.NewString(),
+		WorkspaceID: workspaceID,
+		Name:        name,
+		Role:        string(role),
+		CreatedBy:   requester,
+		CreatedAt:   time.Now().UTC(),
+	}
+	return s.accountRepo.Create(account)
+}
+
+// Lists the workspace's service accounts
+func (s *APITokenService) ListServiceAccounts(workspaceID, requester string) ([]*ServiceAccount, domain_errors.DomainError) {
+	if err := s.checkOwner(workspaceID, requester); err != nil {
+		return nil, err
+	}
+	return s.accountRepo.ListByWorkspace(workspaceID)
+}
+
+// Disables a service account, revoking all of its tokens
+func (s *APITokenService) DisableServiceAccount(workspaceID, accountID, requester string) domain_errors.DomainError {
+	if _, err := s.getServiceAccount(workspaceID, accountID, requester); err != nil {
+		return err
+	}
+	return s.accountRepo.Disable(accountID, time.Now().UTC())
+}
+
+// Creates a token for a service account. The secret is only returned here.
+func (s *APITokenService) CreateServiceAccountToken(workspaceID, accountID, name string, scopes []Scope, expiresAt *time.Time, requester string) (*CreatedToken, domain_errors.DomainError) {
+	if _, err := s.getServiceAccount(workspaceID, accountID, requester); err != nil {
+		return nil, err
+	}
+	return s.createToken(KindServiceAccount, accountID, requester, name, scopes, expiresAt)
+}
+
+// Lists a service account's tokens that have not been revoked
+func (s *APITokenService) ListServiceAccountTokens(workspaceID, accountID, requester string) ([]*Token, domain_errors.DomainError) {
+	if _, err := s.getServiceAccount(workspaceID, accountID, requester); err != nil {
+		return nil, err
+	}
+	return s.tokenRepo.ListByUser(accountID)
+}
+
+// Revokes one of a service account's tokens
+func (s *APITokenService) RevokeServiceAccountToken(workspaceID, accountID, tokenID, requester string) domain_errors.DomainError {
+	if _, err := s.getServiceAccount(workspaceID, accountID, requester); err != nil {
+		return err
+	}
+	token, err := s.tokenRepo.GetByID(tokenID)
+	if err != nil {
+		return err
+	}
+	if token.UserID != accountID {
+		return domain_errors.NewNotFoundError("API_TOKEN", tokenID)
+	}
+	return s.tokenRepo.Revoke(tokenID)
+}
+
+// getServiceAccount loads an active service account of the workspace on behalf of its owner
+func (s *APITokenService) getServiceAccount(workspaceID, accountID, requester string) (*ServiceAccount, domain_errors.DomainError) {
+	if err := s.checkOwner(workspaceID, requester); err != nil {
+		return nil, err
+	}
+	account, err := s.accountRepo.GetByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.WorkspaceID != workspaceID || account.DisabledAt != nil {
+		return nil, domain_errors.NewNotFoundError("SERVICE_ACCOUNT", accountID)
+	}
+	return account, nil
+}
+
+// checkOwner allows only the workspace owner to manage its service accounts
+func (s *APITokenService) checkOwner(workspaceID, requester string) domain_errors.DomainError {
+	if err := uuid.Validate(workspaceID); err != nil {
+		return domain_errors.NewValidationErrorWithValue("workspace_id", workspaceID, "WORKSPACE_ID_IS_NOT_A_VALID_UUID")
+	}
+	ws, err := s.workspaceRepo.GetByID(workspaceID)
+	if err != nil {
+		return err
+	}
+	if ws.OwnerID != requester {
+		return domain_errors.NewUnauthorizedError("REQUESTER_IS_NOT_THE_OWNER_OF_WORKSPACE")
+	}
+	return nil
+}
+
+// createToken validates the request and stores the hash of a freshly generated secret
+func (s *APITokenService) createToken(kind Kind, userID, createdBy, name string, scopes []Scope, expiresAt *time.Time) (*CreatedToken, domain_errors.DomainError) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, domain_errors.NewValidationErrorWithValue("name", name, "EMPTY_TOKEN_NAME")
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if expiresAt == nil {
+		defaultExpiry := now.Add(DefaultTokenLifetime)
+		expiresAt = &defaultExpiry
+	}
+	if !expiresAt.After(now) || expiresAt.After(now.Add(MaxTokenLifetime)) {
+		return nil, domain_errors.NewValidationErrorWithValue("expires_at", expiresAt, "EXPIRY_MUST_BE_IN_THE_FUTURE_AND_WITHIN_A_YEAR")
+	}
+
+	prefix := PersonalTokenPrefix
+	if kind == KindServiceAccount {
+		prefix = ServiceAccountTokenPrefix
+	}
+	secret, secretErr := generateSecret(prefix)
+	if secretErr != nil {
+		return nil, domain_errors.NewInternalError("FAILED_TO_GENERATE_API_TOKEN", secretErr)
+	}
+	utcExpiry := expiresAt.UTC()
+	token := &Token{
+		ID:        uuid.NewString(),
+		Kind:      kind,
+		UserID:    userID,
+		CreatedBy: createdBy,
+		Name:      name,
+		Prefix:    secret[:displayPrefixLength],
+		TokenHash: utils.HashToken(secret),
+		Scopes:    scopes,
+		ExpiresAt: &utcExpiry,
+		CreatedAt: now,
+	}
+	created, err := s.tokenRepo.Create(token)
+	if err != nil {
+		return nil, err
+	}
+	return &CreatedToken{Token: created, Secret: secret}, nil
+}
+
+// normalizeScopes rejects unknown scopes and drops duplicates
+func normalizeScopes(scopes []Scope) ([]Scope, domain_errors.DomainError) {
+	if len(scopes) == 0 {
+		return nil, domain_errors.NewValidationError("scopes", "AT_LEAST_ONE_SCOPE_IS_REQUIRED")
+	}
+	seen := map[Scope]bool{}
+	normalized := make([]Scope, 0, len(scopes))
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return nil, domain_errors.NewValidationErrorWithValue("scopes", scope, "UNKNOWN_SCOPE")
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+// generateSecret returns the prefix followed by 32 random bytes in base64url
+func generateSecret(prefix string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -21,7 +21,7 @@ func (c *EmailConsumer) HandleEmailMessage(msg EmailMessage) error {
 		if err != nil {
 			return err
 		}
-		return c.emailService.SendMagicLink(payload.ToEmail, payload.Token, payload.VerifyURL)
+		return c.emailService.SendMagicLink(payload.ToEmail, payload.Token, payload.VerifyURL, payload.Locale)
 
 	case MessageTypeInvitation:
 		payload, err := msg.DecodeInvitation()
@@ -34,6 +34,7 @@ func (c *EmailConsumer) HandleEmailMessage(msg EmailMessage) error {
 			payload.Role,
 			payload.Token,
 			payload.InvitationURL,
+			payload.Locale,
 		)
 
 	case MessageTypePasswordReset:
@@ -41,7 +42,7 @@ func (c *EmailConsumer) HandleEmailMessage(msg EmailMessage) error {
 		if err != nil {
 			return err
 		}
-		return c.emailService.SendPasswordResetLink(payload.ToEmail, payload.Token, payload.ResetURL)
+		return c.emailService.SendPasswordResetLink(payload.ToEmail, payload.Token, payload.ResetURL, payload.Locale)
 
 	case MessageTypeCustom:
 		payload, err := msg.DecodeCustomEmail()
@@ -50,6 +51,27 @@ func (c *EmailConsumer) HandleEmailMessage(msg EmailMessage) error {
 		}
 		return c.emailService.SendCustomEmail(payload.ToEmail, payload.Template)
 
+	case MessageTypeMention:
+		payload, err := msg.DecodeMention()
+		if err != nil {
+			return err
+		}
+		return c.emailService.SendMention(payload.ToEmail, payload.ActorName, payload.TaskName, payload.TaskURL, payload.Locale)
+
+	case MessageTypeTaskCreated, MessageTypeTaskStatus, MessageTypeTaskAssigned, MessageTypeTaskComment, MessageTypeTaskMoved:
+		payload, err := msg.DecodeTaskActivity()
+		if err != nil {
+			return err
+		}
+		return c.emailService.SendTaskActivity(msg.Type, payload.ToEmail, payload.ActorName, payload.TaskName, payload.Detail, payload.TaskURL, payload.Locale)
+
+	case MessageTypeTaskDue:
+		payload, err := msg.DecodeTaskDue()
+		if err != nil {
+			return err
+		}
+		return c.emailService.SendTaskDue(payload.ToEmail, payload.TaskName, payload.DueAt, payload.TaskURL, payload.Locale)
+
 	default:
 		return fmt.Errorf("unknown message type: %s", msg.Type)
 	}
@@ -0,0 +1,428 @@
+package emailservice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultLocale is used when the recipient's locale has no translation
+const DefaultLocale = "en"
+
+// emailCopy is the translatable text of one kind of email. Invitation copy takes
+// the workspace name as %[1]s and the role as %[2]s, mention copy the name of
+// who mentioned the recipient as %[1]s and the task name as %[2]s. Task activity
+// copy takes the actor's name as %[1]s, the task name as %[2]s and the detail of
+// the activity as %[3]s. Due date reminders take the task name as %[1]s and when
+// it is due as %[2]s.
+type emailCopy struct {
+	Subject     string
+	Heading     string
+	Greeting    string
+	MainMessage string
+	ButtonText  string
+	FooterNote  string
+	ExpiryNote  string
+}
+
+// translations holds the copy of every email keyed by base language
+var translations = map[string]map[MessageType]emailCopy{
+	"en": {
+		MessageTypeMagicLink: {
+			Subject:     "Your Magic Link to Sign In",
+			Heading:     "Sign in to TaskFlow",
+			Greeting:    "Hello,",
+			MainMessage: "Click the button below to sign in to your account. This link will expire in 15 minutes for security reasons.",
+			ButtonText:  "Sign In Now",
+			FooterNote:  "If you didn't request this email, you can safely ignore it.",
+			ExpiryNote:  "This link will expire in 15 minutes.",
+		},
+		MessageTypeInvitation: {
+			Subject:     "You've been invited to join %[1]s",
+			Heading:     "Join %[1]s on TaskFlow",
+			Greeting:    "Hello,",
+			MainMessage: "You have been invited to join the %[1]s workspace as a %[2]s. Click the button below to accept the invitation and get started.",
+			ButtonText:  "Accept Invitation",
+			FooterNote:  "If you don't want to accept this invitation, you can safely ignore this email.",
+			ExpiryNote:  "This invitation will expire in 24 hours.",
+		},
+		MessageTypePasswordReset: {
+			Subject:     "Reset Your Password",
+			Heading:     "Password Reset Request",
+			Greeting:    "Hello,",
+			MainMessage: "We received a request to reset your password. Click the button below to create a new password.",
+			ButtonText:  "Reset Password",
+			FooterNote:  "If you didn't request a password reset, you can safely ignore this email. Your password will remain unchanged.",
+			ExpiryNote:  "This link will expire in 1 hour.",
+		},
+		MessageTypeMention: {
+			Subject:     "%[1]s mentioned you in %[2]s",
+			Heading:     "You were mentioned in %[2]s",
+			Greeting:    "Hello,",
+			MainMessage: "%[1]s mentioned you in the task %[2]s. Click the button below to see what they wrote.",
+			ButtonText:  "View Task",
+			FooterNote:  "You received this email because a member of your workspace mentioned you.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskCreated: {
+			Subject:     "%[1]s added %[2]s to %[3]s",
+			Heading:     "New task in %[3]s",
+			Greeting:    "Hello,",
+			MainMessage: "%[1]s added the task %[2]s to the project %[3]s.",
+			ButtonText:  "View Task",
+			FooterNote:  "You received this email because you watch this project.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskStatus: {
+			Subject:     "%[2]s is now %[3]s",
+			Heading:     "%[2]s is now %[3]s",
+			Greeting:    "Hello,",
+			MainMessage: "%[1]s changed the status of the task %[2]s to %[3]s.",
+			ButtonText:  "View Task",
+			FooterNote:  "You received this email because you watch this task or its project.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskAssigned: {
+			Subject:     "%[1]s assigned %[2]s",
+			Heading:     "%[2]s was assigned",
+			Greeting:    "Hello,",
+			MainMessage: "%[1]s assigned the task %[2]s to %[3]s.",
+			ButtonText:  "View Task",
+			FooterNote:  "You received this email because you watch this task or its project.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskMoved: {
+			Subject:     "%[1]s moved %[2]s",
+			Heading:     "%[2]s was moved",
+			Greeting:    "Hello,",
+			MainMessage: "%[1]s moved the task %[2]s to %[3]s.",
+			ButtonText:  "View Task",
+			FooterNote:  "You received this email because you watch this task or its project.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskComment: {
+			Subject:     "%[1]s commented on %[2]s",
+			Heading:     "New comment on %[2]s",
+			Greeting:    "Hello,",
+			MainMessage: "%[1]s commented on the task %[2]s: “%[3]s”",
+			ButtonText:  "View Comment",
+			FooterNote:  "You received this email because you watch this task or its project.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskDue: {
+			Subject:     "Reminder: %[1]s is due soon",
+			Heading:     "%[1]s is due soon",
+			Greeting:    "Hello,",
+			MainMessage: "The task %[1]s assigned to you is due on %[2]s.",
+			ButtonText:  "View Task",
+			FooterNote:  "You received this email because you are assigned to this task.",
+			ExpiryNote:  "",
+		},
+	},
+	"es": {
+		MessageTypeMagicLink: {
+			Subject:     "Tu enlace mágico para iniciar sesión",
+			Heading:     "Inicia sesión en TaskFlow",
+			Greeting:    "Hola,",
+			MainMessage: "Haz clic en el botón de abajo para iniciar sesión en tu cuenta. Por seguridad, este enlace caduca en 15 minutos.",
+			ButtonText:  "Iniciar sesión",
+			FooterNote:  "Si no solicitaste este correo, puedes ignorarlo.",
+			ExpiryNote:  "Este enlace caduca en 15 minutos.",
+		},
+		MessageTypeInvitation: {
+			Subject:     "Te han invitado a unirte a %[1]s",
+			Heading:     "Únete a %[1]s en TaskFlow",
+			Greeting:    "Hola,",
+			MainMessage: "Te han invitado a unirte al espacio de trabajo %[1]s como %[2]s. Haz clic en el botón de abajo para aceptar la invitación.",
+			ButtonText:  "Aceptar invitación",
+			FooterNote:  "Si no quieres aceptar esta invitación, puedes ignorar este correo.",
+			ExpiryNote:  "Esta invitación caduca en 24 horas.",
+		},
+		MessageTypePasswordReset: {
+			Subject:     "Restablece tu contraseña",
+			Heading:     "Solicitud de restablecimiento de contraseña",
+			Greeting:    "Hola,",
+			MainMessage: "Recibimos una solicitud para restablecer tu contraseña. Haz clic en el botón de abajo para crear una nueva.",
+			ButtonText:  "Restablecer contraseña",
+			FooterNote:  "Si no solicitaste el restablecimiento, puedes ignorar este correo. Tu contraseña no cambiará.",
+			ExpiryNote:  "Este enlace caduca en 1 hora.",
+		},
+		MessageTypeMention: {
+			Subject:     "%[1]s te mencionó en %[2]s",
+			Heading:     "Te mencionaron en %[2]s",
+			Greeting:    "Hola,",
+			MainMessage: "%[1]s te mencionó en la tarea %[2]s. Haz clic en el botón de abajo para ver lo que escribió.",
+			ButtonText:  "Ver tarea",
+			FooterNote:  "Recibiste este correo porque un miembro de tu espacio de trabajo te mencionó.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskCreated: {
+			Subject:     "%[1]s añadió %[2]s a %[3]s",
+			Heading:     "Nueva tarea en %[3]s",
+			Greeting:    "Hola,",
+			MainMessage: "%[1]s añadió la tarea %[2]s al proyecto %[3]s.",
+			ButtonText:  "Ver tarea",
+			FooterNote:  "Recibiste este correo porque sigues este proyecto.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskStatus: {
+			Subject:     "%[2]s ahora está en %[3]s",
+			Heading:     "%[2]s ahora está en %[3]s",
+			Greeting:    "Hola,",
+			MainMessage: "%[1]s cambió el estado de la tarea %[2]s a %[3]s.",
+			ButtonText:  "Ver tarea",
+			FooterNote:  "Recibiste este correo porque sigues esta tarea o su proyecto.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskAssigned: {
+			Subject:     "%[1]s asignó %[2]s",
+			Heading:     "Se asignó %[2]s",
+			Greeting:    "Hola,",
+			MainMessage: "%[1]s asignó la tarea %[2]s a %[3]s.",
+			ButtonText:  "Ver tarea",
+			FooterNote:  "Recibiste este correo porque sigues esta tarea o su proyecto.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskMoved: {
+			Subject:     "%[1]s movió %[2]s",
+			Heading:     "Se movió %[2]s",
+			Greeting:    "Hola,",
+			MainMessage: "%[1]s movió la tarea %[2]s a %[3]s.",
+			ButtonText:  "Ver tarea",
+			FooterNote:  "Recibiste este correo porque sigues esta tarea o su proyecto.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskComment: {
+			Subject:     "%[1]s comentó en %[2]s",
+			Heading:     "Nuevo comentario en %[2]s",
+			Greeting:    "Hola,",
+			MainMessage: "%[1]s comentó en la tarea %[2]s: “%[3]s”",
+			ButtonText:  "Ver comentario",
+			FooterNote:  "Recibiste este correo porque sigues esta tarea o su proyecto.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskDue: {
+			Subject:     "Recordatorio: %[1]s vence pronto",
+			Heading:     "%[1]s vence pronto",
+			Greeting:    "Hola,",
+			MainMessage: "La tarea %[1]s que tienes asignada vence el %[2]s.",
+			ButtonText:  "Ver tarea",
+			FooterNote:  "Recibiste este correo porque tienes asignada esta tarea.",
+			ExpiryNote:  "",
+		},
+	},
+	"fr": {
+		MessageTypeMagicLink: {
+			Subject:     "Votre lien magique de connexion",
+			Heading:     "Connectez-vous à TaskFlow",
+			Greeting:    "Bonjour,",
+			MainMessage: "Cliquez sur le bouton ci-dessous pour vous connecter à votre compte. Pour des raisons de sécurité, ce lien expire dans 15 minutes.",
+			ButtonText:  "Se connecter",
+			FooterNote:  "Si vous n'êtes pas à l'origine de cette demande, vous pouvez ignorer cet e-mail.",
+			ExpiryNote:  "Ce lien expire dans 15 minutes.",
+		},
+		MessageTypeInvitation: {
+			Subject:     "Vous êtes invité à rejoindre %[1]s",
+			Heading:     "Rejoignez %[1]s sur TaskFlow",
+			Greeting:    "Bonjour,",
+			MainMessage: "Vous êtes invité à rejoindre l'espace de travail %[1]s en tant que %[2]s. Cliquez sur le bouton ci-dessous pour accepter l'invitation.",
+			ButtonText:  "Accepter l'invitation",
+			FooterNote:  "Si vous ne souhaitez pas accepter cette invitation, vous pouvez ignorer cet e-mail.",
+			ExpiryNote:  "Cette invitation expire dans 24 heures.",
+		},
+		MessageTypePasswordReset: {
+			Subject:     "Réinitialisez votre mot de passe",
+			Heading:     "Demande de réinitialisation du mot de passe",
+			Greeting:    "Bonjour,",
+			MainMessage: "Nous avons reçu une demande de réinitialisation de votre mot de passe. Cliquez sur le bouton ci-dessous pour en choisir un nouveau.",
+			ButtonText:  "Réinitialiser le mot de passe",
+			FooterNote:  "Si vous n'avez pas demandé de réinitialisation, vous pouvez ignorer cet e-mail. Votre mot de passe reste inchangé.",
+			ExpiryNote:  "Ce lien expire dans 1 heure.",
+		},
+		MessageTypeMention: {
+			Subject:     "%[1]s vous a mentionné dans %[2]s",
+			Heading:     "Vous avez été mentionné dans %[2]s",
+			Greeting:    "Bonjour,",
+			MainMessage: "%[1]s vous a mentionné dans la tâche %[2]s. Cliquez sur le bouton ci-dessous pour voir ce qui a été écrit.",
+			ButtonText:  "Voir la tâche",
+			FooterNote:  "Vous recevez cet e-mail car un membre de votre espace de travail vous a mentionné.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskCreated: {
+			Subject:     "%[1]s a ajouté %[2]s à %[3]s",
+			Heading:     "Nouvelle tâche dans %[3]s",
+			Greeting:    "Bonjour,",
+			MainMessage: "%[1]s a ajouté la tâche %[2]s au projet %[3]s.",
+			ButtonText:  "Voir la tâche",
+			FooterNote:  "Vous recevez cet e-mail car vous suivez ce projet.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskStatus: {
+			Subject:     "%[2]s est maintenant %[3]s",
+			Heading:     "%[2]s est maintenant %[3]s",
+			Greeting:    "Bonjour,",
+			MainMessage: "%[1]s a changé le statut de la tâche %[2]s en %[3]s.",
+			ButtonText:  "Voir la tâche",
+			FooterNote:  "Vous recevez cet e-mail car vous suivez cette tâche ou son projet.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskAssigned: {
+			Subject:     "%[1]s a assigné %[2]s",
+			Heading:     "%[2]s a été assignée",
+			Greeting:    "Bonjour,",
+			MainMessage: "%[1]s a assigné la tâche %[2]s à %[3]s.",
+			ButtonText:  "Voir la tâche",
+			FooterNote:  "Vous recevez cet e-mail car vous suivez cette tâche ou son projet.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskMoved: {
+			Subject:     "%[1]s a déplacé %[2]s",
+			Heading:     "%[2]s a été déplacée",
+			Greeting:    "Bonjour,",
+			MainMessage: "%[1]s a déplacé la tâche %[2]s vers %[3]s.",
+			ButtonText:  "Voir la tâche",
+			FooterNote:  "Vous recevez cet e-mail car vous suivez cette tâche ou son projet.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskComment: {
+			Subject:     "%[1]s a commenté %[2]s",
+			Heading:     "Nouveau commentaire sur %[2]s",
+			Greeting:    "Bonjour,",
+			MainMessage: "%[1]s a commenté la tâche %[2]s : « %[3]s »",
+			ButtonText:  "Voir le commentaire",
+			FooterNote:  "Vous recevez cet e-mail car vous suivez cette tâche ou son projet.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskDue: {
+			Subject:     "Rappel : %[1]s arrive à échéance",
+			Heading:     "%[1]s arrive à échéance",
+			Greeting:    "Bonjour,",
+			MainMessage: "La tâche %[1]s qui vous est assignée est à terminer pour le %[2]s.",
+			ButtonText:  "Voir la tâche",
+			FooterNote:  "Vous recevez cet e-mail car cette tâche vous est assignée.",
+			ExpiryNote:  "",
+		},
+	},
+	"de": {
+		MessageTypeMagicLink: {
+			Subject:     "Dein Magic Link zur Anmeldung",
+			Heading:     "Bei TaskFlow anmelden",
+			Greeting:    "Hallo,",
+			MainMessage: "Klicke auf die Schaltfläche unten, um dich anzumelden. Aus Sicherheitsgründen läuft dieser Link in 15 Minuten ab.",
+			ButtonText:  "Jetzt anmelden",
+			FooterNote:  "Wenn du diese E-Mail nicht angefordert hast, kannst du sie ignorieren.",
+			ExpiryNote:  "Dieser Link läuft in 15 Minuten ab.",
+		},
+		MessageTypeInvitation: {
+			Subject:     "Du wurdest zu %[1]s eingeladen",
+			Heading:     "Tritt %[1]s auf TaskFlow bei",
+			Greeting:    "Hallo,",
+			MainMessage: "Du wurdest eingeladen, dem Workspace %[1]s als %[2]s beizutreten. Klicke auf die Schaltfläche unten, um die Einladung anzunehmen.",
+			ButtonText:  "Einladung annehmen",
+			FooterNote:  "Wenn du die Einladung nicht annehmen möchtest, kannst du diese E-Mail ignorieren.",
+			ExpiryNote:  "Diese Einladung läuft in 24 Stunden ab.",
+		},
+		MessageTypePasswordReset: {
+			Subject:     "Passwort zurücksetzen",
+			Heading:     "Anfrage zum Zurücksetzen des Passworts",
+			Greeting:    "Hallo,",
+			MainMessage: "Wir haben eine Anfrage zum Zurücksetzen deines Passworts erhalten. Klicke auf die Schaltfläche unten, um ein neues Passwort festzulegen.",
+			ButtonText:  "Passwort zurücksetzen",
+			FooterNote:  "Wenn du das nicht angefordert hast, kannst du diese E-Mail ignorieren. Dein Passwort bleibt unverändert.",
+			ExpiryNote:  "Dieser Link läuft in 1 Stunde ab.",
+		},
+		MessageTypeMention: {
+			Subject:     "%[1]s hat dich in %[2]s erwähnt",
+			Heading:     "Du wurdest in %[2]s erwähnt",
+			Greeting:    "Hallo,",
+			MainMessage: "%[1]s hat dich in der Aufgabe %[2]s erwähnt. Klicke auf die Schaltfläche unten, um den Beitrag zu sehen.",
+			ButtonText:  "Aufgabe ansehen",
+			FooterNote:  "Du erhältst diese E-Mail, weil dich ein Mitglied deines Workspaces erwähnt hat.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskCreated: {
+			Subject:     "%[1]s hat %[2]s zu %[3]s hinzugefügt",
+			Heading:     "Neue Aufgabe in %[3]s",
+			Greeting:    "Hallo,",
+			MainMessage: "%[1]s hat die Aufgabe %[2]s zum Projekt %[3]s hinzugefügt.",
+			ButtonText:  "Aufgabe ansehen",
+			FooterNote:  "Du erhältst diese E-Mail, weil du diesem Projekt folgst.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskStatus: {
+			Subject:     "%[2]s ist jetzt %[3]s",
+			Heading:     "%[2]s ist jetzt %[3]s",
+			Greeting:    "Hallo,",
+			MainMessage: "%[1]s hat den Status der Aufgabe %[2]s auf %[3]s geändert.",
+			ButtonText:  "Aufgabe ansehen",
+			FooterNote:  "Du erhältst diese E-Mail, weil du dieser Aufgabe oder ihrem Projekt folgst.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskAssigned: {
+			Subject:     "%[1]s hat %[2]s zugewiesen",
+			Heading:     "%[2]s wurde zugewiesen",
+			Greeting:    "Hallo,",
+			MainMessage: "%[1]s hat die Aufgabe %[2]s %[3]s zugewiesen.",
+			ButtonText:  "Aufgabe ansehen",
+			FooterNote:  "Du erhältst diese E-Mail, weil du dieser Aufgabe oder ihrem Projekt folgst.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskMoved: {
+			Subject:     "%[1]s hat %[2]s verschoben",
+			Heading:     "%[2]s wurde verschoben",
+			Greeting:    "Hallo,",
+			MainMessage: "%[1]s hat die Aufgabe %[2]s nach %[3]s verschoben.",
+			ButtonText:  "Aufgabe ansehen",
+			FooterNote:  "Du erhältst diese E-Mail, weil du dieser Aufgabe oder ihrem Projekt folgst.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskComment: {
+			Subject:     "%[1]s hat %[2]s kommentiert",
+			Heading:     "Neuer Kommentar zu %[2]s",
+			Greeting:    "Hallo,",
+			MainMessage: "%[1]s hat die Aufgabe %[2]s kommentiert: „%[3]s“",
+			ButtonText:  "Kommentar ansehen",
+			FooterNote:  "Du erhältst diese E-Mail, weil du dieser Aufgabe oder ihrem Projekt folgst.",
+			ExpiryNote:  "",
+		},
+		MessageTypeTaskDue: {
+			Subject:     "Erinnerung: %[1]s ist bald fällig",
+			Heading:     "%[1]s ist bald fällig",
+			Greeting:    "Hallo,",
+			MainMessage: "Die dir zugewiesene Aufgabe %[1]s ist am %[2]s fällig.",
+			ButtonText:  "Aufgabe ansehen",
+			FooterNote:  "Du erhältst diese E-Mail, weil dir diese Aufgabe zugewiesen ist.",
+			ExpiryNote:  "",
+		},
+	},
+}
+
+// localizedTemplate builds the template of an email in the recipient's locale,
+// falling back to DefaultLocale. A locale such as "pt-BR" matches "pt".
+func localizedTemplate(locale string, messageType MessageType, buttonURL string, args ...any) EmailTemplate {
+	copies, ok := translations[baseLanguage(locale)]
+	if !ok {
+		copies = translations[DefaultLocale]
+	}
+	text := copies[messageType]
+	format := func(s string) string {
+		if len(args) == 0 {
+			return s
+		}
+		return fmt.Sprintf(s, args...)
+	}
+	return EmailTemplate{
+		Subject:     format(text.Subject),
+		Heading:     format(text.Heading),
+		Greeting:    text.Greeting,
+		MainMessage: format(text.MainMessage),
+		ButtonText:  text.ButtonText,
+		ButtonURL:   buttonURL,
+		FooterNote:  text.FooterNote,
+		ExpiryNote:  text.ExpiryNote,
+	}
+}
+
+func baseLanguage(locale string) string {
+	base, _, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	return strings.ToLower(base)
+}
@@ -14,6 +14,14 @@ const (
 	MessageTypeInvitation    MessageType = "email.invitation"
 	MessageTypePasswordReset MessageType = "email.password_reset"
 	MessageTypeCustom        MessageType = "email.custom"
+	MessageTypeMention       MessageType = "email.mention"
+	// Task activity sent to watchers, all with a TaskActivityPayload
+	MessageTypeTaskCreated  MessageType = "email.task_created"
+	MessageTypeTaskStatus   MessageType = "email.task_status"
+	MessageTypeTaskAssigned MessageType = "email.task_assigned"
+	MessageTypeTaskComment  MessageType = "email.task_comment"
+	MessageTypeTaskMoved    MessageType = "email.task_moved"
+	MessageTypeTaskDue      MessageType = "email.task_due"
 )
 
 // EmailMessage is the unified message type for all email queue messages
@@ -27,6 +35,7 @@ type MagicLinkPayload struct {
 	ToEmail   string `json:"to_email"`
 	Token     string `json:"token"`
 	VerifyURL string `json:"verify_url"`
+	Locale    string `json:"locale,omitempty"`
 }
 
 type InvitationPayload struct {
@@ -35,12 +44,14 @@ type InvitationPayload struct {
 	Role          string `json:"role"`
 	Token         string `json:"token"`
 	InvitationURL string `json:"invitation_url"`
+	Locale        string `json:"locale,omitempty"`
 }
 
 type PasswordResetPayload struct {
 	ToEmail  string `json:"to_email"`
 	Token    string `json:"token"`
 	ResetURL string `json:"reset_url"`
+	Locale   string `json:"locale,omitempty"`
 }
 
 type CustomEmailPayload struct {
@@ -48,6 +59,36 @@ type CustomEmailPayload struct {
 	Template EmailTemplate `json:"template"`
 }
 
+// MentionPayload tells a member they were mentioned in a task's description or comment
+type MentionPayload struct {
+	ToEmail   string `json:"to_email"`
+	ActorName string `json:"actor_name"`
+	TaskName  string `json:"task_name"`
+	TaskURL   string `json:"task_url"`
+	Locale    string `json:"locale,omitempty"`
+}
+
+// TaskActivityPayload tells a watcher about activity on a task. Detail is the
+// project of a created task, the new status, the assignees or the comment.
+type TaskActivityPayload struct {
+	ToEmail   string `json:"to_email"`
+	ActorName string `json:"actor_name"`
+	TaskName  string `json:"task_name"`
+	Detail    string `json:"detail"`
+	TaskURL   string `json:"task_url"`
+	Locale    string `json:"locale,omitempty"`
+}
+
+// TaskDuePayload reminds an assignee of a task coming due. DueAt is already
+// written in the assignee's time zone.
+type TaskDuePayload struct {
+	ToEmail  string `json:"to_email"`
+	TaskName string `json:"task_name"`
+	DueAt    string `json:"due_at"`
+	TaskURL  string `json:"task_url"`
+	Locale   string `json:"locale,omitempty"`
+}
+
 
 // Decode methods to extract specific payloads
 func (m *EmailMessage) DecodeMagicLink() (*MagicLinkPayload, error) {
@@ -101,3 +142,44 @@ func (m *EmailMessage) DecodeCustomEmail() (*CustomEmailPayload, error) {
 
 	return &payload, nil
 }
+
+func (m *EmailMessage) DecodeMention() (*MentionPayload, error) {
+	if m.Type != MessageTypeMention {
+		return nil, fmt.Errorf("expected message type %s, got %s", MessageTypeMention, m.Type)
+	}
+
+	var payload MentionPayload
+	if err := json.Unmarshal(m.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mention payload: %w", err)
+	}
+
+	return &payload, nil
+}
+
+func (m *EmailMessage) DecodeTaskActivity() (*TaskActivityPayload, error) {
+	switch m.Type {
+	case MessageTypeTaskCreated, MessageTypeTaskStatus, MessageTypeTaskAssigned, MessageTypeTaskComment, MessageTypeTaskMoved:
+	default:
+		return nil, fmt.Errorf("expected a task activity message type, got %s", m.Type)
+	}
+
+	var payload TaskActivityPayload
+	if err := json.Unmarshal(m.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task activity payload: %w", err)
+	}
+
+	return &payload, nil
+}
+
+func (m *EmailMessage) DecodeTaskDue() (*TaskDuePayload, error) {
+	if m.Type != MessageTypeTaskDue {
+		return nil, fmt.Errorf("expected message type %s, got %s", MessageTypeTaskDue, m.Type)
+	}
+
+	var payload TaskDuePayload
+	if err := json.Unmarshal(m.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task due payload: %w", err)
+	}
+
+	return &payload, nil
+}
\ No newline at end of file
@@ -2,6 +2,8 @@ package emailservice
 
 import (
 	"fmt"
+	"html"
+	"strings"
 )
 
 type EmailService struct {
@@ -14,66 +16,72 @@ func NewEmailService(config EmailConfig) *EmailService {
 	}
 }
 
-// SendMagicLink sends a magic link email to the user
-func (e *EmailService) SendMagicLink(toEmail, token, verifyURL string) error {
+// SendMagicLink sends a magic link email to the user in their locale
+func (e *EmailService) SendMagicLink(toEmail, token, verifyURL, locale string) error {
 	// Construct the verification URL
 	fullURL := fmt.Sprintf("%s%s%s", e.config.FrontendURL, verifyURL, token)
 
-	template := EmailTemplate{
-		Subject:     "Your Magic Link to Sign In",
-		Heading:     "Sign in to TaskFlow",
-		Greeting:    "Hello,",
-		MainMessage: "Click the button below to sign in to your account. This link will expire in 15 minutes for security reasons.",
-		ButtonText:  "Sign In Now",
-		ButtonURL:   fullURL,
-		FooterNote:  "If you didn't request this email, you can safely ignore it.",
-		ExpiryNote:  "This link will expire in 15 minutes.",
-	}
-
+	template := localizedTemplate(locale, MessageTypeMagicLink, fullURL)
 	body := e.createEmailHTML(template)
 	return e.sendEmail(toEmail, template.Subject, body)
 }
 
 // SendInvitationLink sends an invitation email to join a workspace
-func (e *EmailService) SendInvitationLink(toEmail, workspaceName, role, token, invitationURL string) error {
+func (e *EmailService) SendInvitationLink(toEmail, workspaceName, role, token, invitationURL, locale string) error {
 	// Construct the invitation URL
 	fullURL := fmt.Sprintf("%s%s%s", e.config.FrontendURL, invitationURL, token)
 
-	template := EmailTemplate{
-		Subject:     fmt.Sprintf("You've been invited to join %s", workspaceName),
-		Heading:     fmt.Sprintf("Join %s on TaskFlow", workspaceName),
-		Greeting:    "Hello,",
-		MainMessage: fmt.Sprintf("You have been invited to join the %s workspace as a %s. Click the button below to accept the invitation and get started.", workspaceName, role),
-		ButtonText:  "Accept Invitation",
-		ButtonURL:   fullURL,
-		FooterNote:  "If you don't want to accept this invitation, you can safely ignore this email.",
-		ExpiryNote:  "This invitation will expire in 24 hours.",
-	}
-
+	template := localizedTemplate(locale, MessageTypeInvitation, fullURL, workspaceName, role)
 	body := e.createEmailHTML(template)
 	return e.sendEmail(toEmail, template.Subject, body)
 }
 
 // SendPasswordResetLink sends a password reset email
-func (e *EmailService) SendPasswordResetLink(toEmail, token, resetURL string) error {
+func (e *EmailService) SendPasswordResetLink(toEmail, token, resetURL, locale string) error {
 	// Construct the reset URL
 	fullURL := fmt.Sprintf("%s%s%s", e.config.FrontendURL, resetURL, token)
 
-	template := EmailTemplate{
-		Subject:     "Reset Your Password",
-		Heading:     "Password Reset Request",
-		Greeting:    "Hello,",
-		MainMessage: "We received a request to reset your password. Click the button below to create a new password.",
-		ButtonText:  "Reset Password",
-		ButtonURL:   fullURL,
-		FooterNote:  "If you didn't request a password reset, you can safely ignore this email. Your password will remain unchanged.",
-		ExpiryNote:  "This link will expire in 1 hour.",
+	template := localizedTemplate(locale, MessageTypePasswordReset, fullURL)
+	body := e.createEmailHTML(template)
+
+	return e.sendEmail(toEmail, template.Subject, body)
+}
+
+// SendMention tells a member someone mentioned them in a task. The names are
+// written by users, so they are kept to one line and escaped. Task URLs are
+// absolute: the service that sends the message knows where the task is served.
+func (e *EmailService) SendMention(toEmail, actorName, taskName, taskURL, locale string) error {
+	template := localizedTemplate(locale, MessageTypeMention, taskURL, plainText(actorName), plainText(taskName))
+	body := e.createEmailHTML(template)
+	return e.sendEmail(toEmail, template.Subject, body)
+}
+
+// SendTaskActivity tells a watcher about activity on a task, in the copy of its
+// message type. Long details, such as comments, are shortened.
+func (e *EmailService) SendTaskActivity(messageType MessageType, toEmail, actorName, taskName, detail, taskURL, locale string) error {
+	if runes := []rune(detail); len(runes) > maxActivityDetail {
+		detail = string(runes[:maxActivityDetail]) + "…"
 	}
+	template := localizedTemplate(locale, messageType, taskURL, plainText(actorName), plainText(taskName), plainText(detail))
 	body := e.createEmailHTML(template)
+	return e.sendEmail(toEmail, template.Subject, body)
+}
 
+// SendTaskDue reminds an assignee that a task is coming due
+func (e *EmailService) SendTaskDue(toEmail, taskName, dueAt, taskURL, locale string) error {
+	template := localizedTemplate(locale, MessageTypeTaskDue, taskURL, plainText(taskName), dueAt)
+	body := e.createEmailHTML(template)
 	return e.sendEmail(toEmail, template.Subject, body)
 }
 
+// maxActivityDetail bounds the characters of a detail quoted in an email
+const maxActivityDetail = 300
+
+// plainText collapses whitespace, line breaks included, and escapes HTML
+func plainText(s string) string {
+	return html.EscapeString(strings.Join(strings.Fields(s), " "))
+}
+
 // SendCustomEmail sends an email with custom template
 func (e *EmailService) SendCustomEmail(toEmail string, template EmailTemplate) error {
 	body := e.createEmailHTML(template)
@@ -4,13 +4,20 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/ishola-faazele/taskflow/internal/apitoken"
 	"github.com/ishola-faazele/taskflow/internal/utils/jwt"
+	"github.com/ishola-faazele/taskflow/pkg/utils"
 )
 
 type contextKey string
 
 const UserIDKey contextKey = "UserID"
 const UserEmailKey contextKey = "UserEmail"
+const SessionIDKey contextKey = "SessionID"
+
+// APITokenKey holds the *apitoken.Token when a request authenticated with a
+// personal access token or service account token instead of a session JWT
+const APITokenKey contextKey = "APIToken"
 
 func (dm *DomainMiddleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -25,6 +32,10 @@ func (dm *DomainMiddleware) Authenticate(next http.Handler) http.Handler {
 		if len(token) > 7 && token[:7] == "Bearer " {
 			token = token[7:]
 		}
+		if apitoken.LooksLikeToken(token) {
+			dm.authenticateAPIToken(w, r, next, token)
+			return
+		}
 
 		// parse token
 		claims, err := dm.jwt.ParseUserToken(token)
@@ -43,11 +54,40 @@ func (dm *DomainMiddleware) Authenticate(next http.Handler) http.Handler {
 			dm.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: Invalid token purpose", nil)
 			return
 		}
+		// check the session the token was issued for has not been revoked
+		if claims.SessionID == "" {
+			dm.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: Token is not bound to a session", nil)
+			return
+		}
+		active, sessionErr := dm.sessions.IsActive(claims.SessionID)
+		if sessionErr != nil {
+			dm.responder.Error(w, r, http.StatusInternalServerError, "Failed to validate session", sessionErr)
+			return
+		}
+		if !active {
+			dm.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: Session revoked or expired", nil)
+			return
+		}
 		// set user ID in context
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 		ctx = context.WithValue(ctx, UserEmailKey, claims.Email)
+		ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
 		r = r.WithContext(ctx)
 		// proceed to next handler
 		next.ServeHTTP(w, r)
 	})
 }
+
+// authenticateAPIToken authenticates a request made with an API token. The token's
+// scopes are enforced by RequireScope and RequireResourceScope.
+func (dm *DomainMiddleware) authenticateAPIToken(w http.ResponseWriter, r *http.Request, next http.Handler, secret string) {
+	token, err := dm.apiTokens.Authenticate(secret, utils.ClientIP(r))
+	if err != nil {
+		dm.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: Invalid API token", err)
+		return
+	}
+	ctx := context.WithValue(r.Context(), UserIDKey, token.UserID)
+	ctx = context.WithValue(ctx, UserEmailKey, token.Email)
+	ctx = context.WithValue(ctx, APITokenKey, token)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
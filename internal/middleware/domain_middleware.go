@@ -1,7 +1,12 @@
 package middleware
 
 import (
+	"database/sql"
+
+	"github.com/ishola-faazele/taskflow/internal/apitoken"
+	"github.com/ishola-faazele/taskflow/internal/session"
 	"github.com/ishola-faazele/taskflow/internal/utils/jwt"
+	workspace_db "github.com/ishola-faazele/taskflow/internal/workspace/db"
 	workspace "github.com/ishola-faazele/taskflow/internal/workspace/service"
 	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
 )
@@ -9,18 +14,26 @@ import (
 type DomainMiddleware struct {
 	jwt              *jwt.JWTUtils
 	responder        *domain_errors.APIResponder
+	sessions         session.SessionRepository
+	apiTokens        *apitoken.APITokenService
 	WorkspaceService *workspace.WorkspaceService
 }
 
-func NewDomainMiddleware() *DomainMiddleware {
+func NewDomainMiddleware(db *sql.DB) *DomainMiddleware {
 	responder := domain_errors.NewAPIResponder()
 	return &DomainMiddleware{
 		jwt:       jwt.NewJWTUtils(jwt.DefaultTokenConfig()),
 		responder: responder,
+		sessions:  session.NewPostgresSessionRepository(db),
+		apiTokens: apitoken.NewAPITokenService(
+			apitoken.NewPostgresTokenRepository(db),
+			apitoken.NewPostgresServiceAccountRepository(db),
+			workspace_db.NewPostgresWorkspaceRepository(db),
+		),
 	}
 }
-func NewDomainMiddlewareWithWorkspace(service *workspace.WorkspaceService) *DomainMiddleware {
-	mid := NewDomainMiddleware()
+func NewDomainMiddlewareWithWorkspace(db *sql.DB, service *workspace.WorkspaceService) *DomainMiddleware {
+	mid := NewDomainMiddleware(db)
 	mid.WorkspaceService = service
 	return mid
 }
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ishola-faazele/taskflow/internal/apitoken"
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+// RequireScope rejects API token requests whose token lacks scope. Requests
+// authenticated with a session JWT act with the user's full permissions.
+func (dm *DomainMiddleware) RequireScope(scope apitoken.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := r.Context().Value(APITokenKey).(*apitoken.Token)
+			if ok && !token.HasScope(scope) {
+				dm.responder.Error(w, r, http.StatusForbidden, "Forbidden: API token is missing scope "+string(scope), domain_errors.NewForbiddenError(string(scope), r.Method))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireResourceScope requires the read scope of resource for safe methods and
+// the write scope for everything else, e.g. tasks:read for GET and tasks:write for PUT
+func (dm *DomainMiddleware) RequireResourceScope(resource string) func(http.Handler) http.Handler {
+	readScope := dm.RequireScope(apitoken.ReadScope(resource))
+	writeScope := dm.RequireScope(apitoken.WriteScope(resource))
+	return func(next http.Handler) http.Handler {
+		read, write := readScope(next), writeScope(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				read.ServeHTTP(w, r)
+			default:
+				write.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// RequireSession rejects requests authenticated with an API token, for routes that
+// manage credentials and must not be reachable by automation
+func (dm *DomainMiddleware) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(APITokenKey).(*apitoken.Token); ok {
+			dm.responder.Error(w, r, http.StatusForbidden, "Forbidden: This route requires an interactive session", domain_errors.NewForbiddenError("credentials", r.Method))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,48 @@
+package oidc
+
+import (
+	"os"
+	"strings"
+)
+
+// ProviderConfig is the relying-party registration for one OpenID provider
+type ProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// DefaultScopes are requested when a provider does not configure its own
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+// LoadProvidersFromEnv reads provider registrations from the environment.
+// OIDC_PROVIDERS lists provider names separated by commas; each name is then
+// configured through OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_REDIRECT_URL and the optional
+// space separated OIDC_<NAME>_SCOPES. Incomplete registrations are skipped.
+func LoadProvidersFromEnv() []ProviderConfig {
+	var configs []ProviderConfig
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		config := ProviderConfig{
+			Name:         name,
+			Issuer:       strings.TrimRight(os.Getenv(prefix+"ISSUER"), "/"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			continue
+		}
+		configs = append(configs, config)
+	}
+	return configs
+}
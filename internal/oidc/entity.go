@@ -0,0 +1,27 @@
+package oidc
+
+import "time"
+
+// LoginState is kept between redirecting a user to the provider and handling the
+// callback. The state value is the lookup key; the verifier and nonce never leave
+// the server.
+type LoginState struct {
+	State        string    `json:"state"`
+	Provider     string    `json:"provider"`
+	CodeVerifier string    `json:"-"`
+	Nonce        string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Identity is the subset of ID token claims used to sign a user in
+type Identity struct {
+	Provider      string `json:"provider"`
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name,omitempty"`
+}
+
+// DefaultStateDuration is how long a user has to finish signing in at the provider
+const DefaultStateDuration = 10 * time.Minute
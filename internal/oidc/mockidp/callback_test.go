@@ -0,0 +1,219 @@
+package mockidp_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ishola-faazele/taskflow/internal/oidc"
+	"github.com/ishola-faazele/taskflow/internal/oidc/mockidp"
+	"github.com/ishola-faazele/taskflow/internal/session"
+	"github.com/ishola-faazele/taskflow/internal/user"
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+// memoryStates keeps login states in memory with the same single-use semantics
+// as the Postgres repository
+type memoryStates struct {
+	mu     sync.Mutex
+	states map[string]oidc.LoginState
+}
+
+func (m *memoryStates) Save(state *oidc.LoginState) domain_errors.DomainError {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states[state.State] = *state
+	return nil
+}
+
+func (m *memoryStates) Consume(state string, now time.Time) (*oidc.LoginState, domain_errors.DomainError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	saved, ok := m.states[state]
+	delete(m.states, state)
+	if !ok || !now.Before(saved.ExpiresAt) {
+		return nil, domain_errors.NewNotFoundError("OIDC_STATE", state)
+	}
+	return &saved, nil
+}
+
+func (m *memoryStates) DeleteExpired(now time.Time) (int, domain_errors.DomainError) {
+	return 0, nil
+}
+
+// memoryAuths implements the account lookups the callback needs
+type memoryAuths struct {
+	user.AuthRepository
+	mu       sync.Mutex
+	accounts []*user.Auth
+}
+
+func (m *memoryAuths) GetByEmail(email string) (*user.Auth, domain_errors.DomainError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, account := range m.accounts {
+		if strings.EqualFold(account.Email, email) {
+			return account, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryAuths) Create(auth *user.Auth) (*user.Auth, domain_errors.DomainError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accounts = append(m.accounts, auth)
+	return auth, nil
+}
+
+type memorySessions struct {
+	session.SessionRepository
+	mu       sync.Mutex
+	sessions []*session.Session
+}
+
+func (m *memorySessions) Create(s *session.Session) (*session.Session, domain_errors.DomainError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions = append(m.sessions, s)
+	return s, nil
+}
+
+type callbackFixture struct {
+	server   *mockidp.Server
+	service  *user.UserService
+	auths    *memoryAuths
+	sessions *memorySessions
+}
+
+func newCallbackFixture(t *testing.T) *callbackFixture {
+	t.Helper()
+	server, err := mockidp.Start()
+	if err != nil {
+		t.Fatalf("start mock provider: %v", err)
+	}
+	t.Cleanup(server.Close)
+	client := mockidp.Client{ID: "taskflow", Secret: "secret", RedirectURIs: []string{redirectURI}}
+	server.RegisterClient(client)
+
+	registry := oidc.NewRegistry([]oidc.ProviderConfig{server.ProviderConfig("mock", client)}, nil)
+	auths := &memoryAuths{}
+	sessions := &memorySessions{}
+	states := &memoryStates{states: map[string]oidc.LoginState{}}
+	service := user.NewUserService(auths, nil, sessions, states, registry, nil, nil)
+	return &callbackFixture{server: server, service: service, auths: auths, sessions: sessions}
+}
+
+// signIn begins a login and follows it through the provider, returning the state
+// the browser was given and the code and state the provider redirected back with
+func (f *callbackFixture) signIn(t *testing.T, email string) (string, url.Values) {
+	t.Helper()
+	authURL, state, err := f.service.BeginOIDCLogin(context.Background(), "mock")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	target, _ := url.Parse(authURL)
+	query := target.Query()
+	query.Set("login_hint", email)
+	target.RawQuery = query.Encode()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, getErr := client.Get(target.String())
+	if getErr != nil {
+		t.Fatalf("authorize: %v", getErr)
+	}
+	resp.Body.Close()
+	location, locationErr := resp.Location()
+	if locationErr != nil {
+		t.Fatalf("authorize location: %v", locationErr)
+	}
+	return state, location.Query()
+}
+
+func TestCallbackCreatesAccountAndSession(t *testing.T) {
+	f := newCallbackFixture(t)
+	f.server.AddUser(mockidp.User{Subject: "alice-1", Email: "alice@example.com", EmailVerified: true})
+
+	browserState, callback := f.signIn(t, "alice@example.com")
+	result, err := f.service.CompleteOIDCLogin(context.Background(), "mock", callback.Get("code"), callback.Get("state"), browserState, "test-agent", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("complete login: %v", err)
+	}
+	if result.AccessToken == "" || result.RefreshToken == "" {
+		t.Fatalf("login result = %+v, want a token pair", result)
+	}
+	if len(f.auths.accounts) != 1 || f.auths.accounts[0].Email != "alice@example.com" {
+		t.Fatalf("accounts = %+v, want one for alice@example.com", f.auths.accounts)
+	}
+	if len(f.sessions.sessions) != 1 || f.sessions.sessions[0].UserID != f.auths.accounts[0].ID {
+		t.Fatalf("sessions = %+v, want one for the new account", f.sessions.sessions)
+	}
+}
+
+func TestCallbackMatchesExistingAccountIgnoringCase(t *testing.T) {
+	f := newCallbackFixture(t)
+	existing := &user.Auth{ID: uuid.NewString(), Email: "Bob@Example.com", CreatedAt: time.Now().UTC()}
+	f.auths.accounts = append(f.auths.accounts, existing)
+	f.server.AddUser(mockidp.User{Subject: "bob-1", Email: "bob@example.com", EmailVerified: true})
+
+	browserState, callback := f.signIn(t, "bob@example.com")
+	if _, err := f.service.CompleteOIDCLogin(context.Background(), "mock", callback.Get("code"), callback.Get("state"), browserState, "", ""); err != nil {
+		t.Fatalf("complete login: %v", err)
+	}
+	if len(f.auths.accounts) != 1 {
+		t.Fatalf("accounts = %d, want the existing account reused", len(f.auths.accounts))
+	}
+	if len(f.sessions.sessions) != 1 || f.sessions.sessions[0].UserID != existing.ID {
+		t.Fatalf("sessions = %+v, want one for the existing account", f.sessions.sessions)
+	}
+}
+
+func TestCallbackRequiresStateFromSameBrowser(t *testing.T) {
+	cases := []struct {
+		name         string
+		browserState func(own string) string
+	}{
+		{"no cookie", func(string) string { return "" }},
+		{"another login's state", func(string) string { return "state-from-the-attacker" }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newCallbackFixture(t)
+			own, callback := f.signIn(t, "carol@example.com")
+			_, err := f.service.CompleteOIDCLogin(context.Background(), "mock", callback.Get("code"), callback.Get("state"), tc.browserState(own), "", "")
+			if err == nil {
+				t.Fatal("completed a login started in another browser")
+			}
+			if len(f.sessions.sessions) != 0 {
+				t.Fatalf("sessions = %d, want none", len(f.sessions.sessions))
+			}
+		})
+	}
+}
+
+func TestCallbackStateIsSingleUse(t *testing.T) {
+	f := newCallbackFixture(t)
+	browserState, callback := f.signIn(t, "dave@example.com")
+	if _, err := f.service.CompleteOIDCLogin(context.Background(), "mock", callback.Get("code"), callback.Get("state"), browserState, "", ""); err != nil {
+		t.Fatalf("first callback: %v", err)
+	}
+	if _, err := f.service.CompleteOIDCLogin(context.Background(), "mock", callback.Get("code"), callback.Get("state"), browserState, "", ""); err == nil {
+		t.Fatal("replayed callback succeeded")
+	}
+}
+
+func TestCallbackRejectsStateForAnotherProvider(t *testing.T) {
+	f := newCallbackFixture(t)
+	browserState, callback := f.signIn(t, "erin@example.com")
+	_, err := f.service.CompleteOIDCLogin(context.Background(), "other", callback.Get("code"), callback.Get("state"), browserState, "", "")
+	if err == nil {
+		t.Fatal("completed a login against another provider")
+	}
+}
@@ -0,0 +1,376 @@
+// Package mockidp is a minimal in-process OpenID provider for tests and local
+// development. It implements discovery, JWKS, the authorization code flow with
+// PKCE and RS256 signed ID tokens, and signs in whichever user is named by the
+// login_hint parameter (or entered on its login form) without a password.
+package mockidp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ishola-faazele/taskflow/internal/oidc"
+)
+
+const (
+	codeDuration    = time.Minute
+	idTokenDuration = 5 * time.Minute
+)
+
+// User is an account known to the mock provider
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Client is a relying party registered with the mock provider
+type Client struct {
+	ID           string
+	Secret       string
+	RedirectURIs []string
+}
+
+type authorizationCode struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	user          User
+	expiresAt     time.Time
+}
+
+// Server is the mock provider. Unknown login hints are signed in as new users
+// with a verified email unless AutoCreateUsers is turned off.
+type Server struct {
+	issuer          string
+	key             *rsa.PrivateKey
+	kid             string
+	AutoCreateUsers bool
+
+	mu      sync.Mutex
+	clients map[string]Client
+	users   map[string]User
+	codes   map[string]authorizationCode
+
+	httpServer *httptest.Server
+}
+
+// New creates a provider that identifies itself with the given issuer URL
+func New(issuer string) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := oidc.RandomToken(8)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		issuer:          strings.TrimRight(issuer, "/"),
+		key:             key,
+		kid:             kid,
+		AutoCreateUsers: true,
+		clients:         map[string]Client{},
+		users:           map[string]User{},
+		codes:           map[string]authorizationCode{},
+	}, nil
+}
+
+// Start creates a provider listening on a random local port. Call Close when done.
+func Start() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	server, err := New("http://" + listener.Addr().String())
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	httpServer := httptest.NewUnstartedServer(server.Handler())
+	httpServer.Listener.Close()
+	httpServer.Listener = listener
+	httpServer.Start()
+	server.httpServer = httpServer
+	return server, nil
+}
+
+// Close stops a provider created with Start
+func (s *Server) Close() {
+	if s.httpServer != nil {
+		s.httpServer.Close()
+	}
+}
+
+// Issuer returns the provider's issuer URL
+func (s *Server) Issuer() string {
+	return s.issuer
+}
+
+// RegisterClient allows a relying party to use the provider
+func (s *Server) RegisterClient(client Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[client.ID] = client
+}
+
+// AddUser makes a user known to the provider, keyed by email
+func (s *Server) AddUser(user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[strings.ToLower(user.Email)] = user
+}
+
+// ProviderConfig returns a relying-party registration for the given client
+func (s *Server) ProviderConfig(name string, client Client) oidc.ProviderConfig {
+	redirectURL := ""
+	if len(client.RedirectURIs) > 0 {
+		redirectURL = client.RedirectURIs[0]
+	}
+	return oidc.ProviderConfig{
+		Name:         name,
+		Issuer:       s.issuer,
+		ClientID:     client.ID,
+		ClientSecret: client.Secret,
+		RedirectURL:  redirectURL,
+	}
+}
+
+// Handler serves the provider's endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	mux.HandleFunc("GET /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+	return mux
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{jwt.SigningMethodRS256.Alg()},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oidc.JSONWebKeySet{
+		Keys: []oidc.JSONWebKey{oidc.NewRSAJSONWebKey(s.kid, &s.key.PublicKey)},
+	})
+}
+
+var loginForm = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><body>
+<h1>Mock identity provider</h1>
+<form method="get" action="/authorize">
+{{range $key, $values := .}}{{range $values}}<input type="hidden" name="{{$key}}" value="{{.}}">{{end}}{{end}}
+<label>Email <input type="email" name="login_hint" autofocus></label>
+<button type="submit">Sign in</button>
+</form>
+</body></html>`))
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	s.mu.Lock()
+	client, ok := s.clients[query.Get("client_id")]
+	s.mu.Unlock()
+	redirectURI := query.Get("redirect_uri")
+	// errors before the redirect URI is trusted must not be redirected
+	if !ok || !contains(client.RedirectURIs, redirectURI) {
+		http.Error(w, "unknown client or redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("response_type") != "code" {
+		redirectError(w, r, redirectURI, query.Get("state"), "unsupported_response_type")
+		return
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		redirectError(w, r, redirectURI, query.Get("state"), "invalid_request")
+		return
+	}
+	if !contains(strings.Fields(query.Get("scope")), "openid") {
+		redirectError(w, r, redirectURI, query.Get("state"), "invalid_scope")
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(query.Get("login_hint")))
+	if email == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		loginForm.Execute(w, query)
+		return
+	}
+	user, ok := s.lookupUser(email)
+	if !ok {
+		redirectError(w, r, redirectURI, query.Get("state"), "access_denied")
+		return
+	}
+
+	code, err := oidc.RandomToken(32)
+	if err != nil {
+		redirectError(w, r, redirectURI, query.Get("state"), "server_error")
+		return
+	}
+	s.mu.Lock()
+	s.codes[code] = authorizationCode{
+		clientID:      client.ID,
+		redirectURI:   redirectURI,
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		user:          user,
+		expiresAt:     time.Now().Add(codeDuration),
+	}
+	s.mu.Unlock()
+
+	target, _ := url.Parse(redirectURI)
+	params := target.Query()
+	params.Set("code", code)
+	if state := query.Get("state"); state != "" {
+		params.Set("state", state)
+	}
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (s *Server) lookupUser(email string) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user, ok := s.users[email]; ok {
+		return user, true
+	}
+	if !s.AutoCreateUsers {
+		return User{}, false
+	}
+	sum := sha256.Sum256([]byte(email))
+	user := User{
+		Subject:       base64.RawURLEncoding.EncodeToString(sum[:12]),
+		Email:         email,
+		EmailVerified: true,
+	}
+	s.users[email] = user
+	return user, true
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	s.mu.Lock()
+	client, known := s.clients[clientID]
+	s.mu.Unlock()
+	if !known || subtle.ConstantTimeCompare([]byte(client.Secret), []byte(clientSecret)) != 1 {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	// codes are single use whether or not the exchange succeeds
+	s.mu.Lock()
+	code, found := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+	if !found || time.Now().After(code.expiresAt) || code.clientID != client.ID || code.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	if oidc.CodeChallengeS256(r.PostForm.Get("code_verifier")) != code.codeChallenge {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := oidc.IDTokenClaims{
+		Nonce:         code.nonce,
+		Email:         code.user.Email,
+		EmailVerified: code.user.EmailVerified,
+		Name:          code.user.Name,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			Subject:   code.user.Subject,
+			Audience:  jwt.ClaimStrings{client.ID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(idTokenDuration)),
+		},
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = s.kid
+	signed, err := idToken.SignedString(s.key)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	accessToken, err := oidc.RandomToken(32)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, oidc.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(idTokenDuration.Seconds()),
+		IDToken:     signed,
+	})
+}
+
+func redirectError(w http.ResponseWriter, r *http.Request, redirectURI, state, code string) {
+	target, _ := url.Parse(redirectURI)
+	params := target.Query()
+	params.Set("error", code)
+	if state != "" {
+		params.Set("state", state)
+	}
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package mockidp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/ishola-faazele/taskflow/internal/oidc"
+	"github.com/ishola-faazele/taskflow/internal/oidc/mockidp"
+)
+
+const redirectURI = "http://app.test/api/user/oidc/mock/callback"
+
+func startProvider(t *testing.T) (*mockidp.Server, *oidc.Provider) {
+	t.Helper()
+	server, err := mockidp.Start()
+	if err != nil {
+		t.Fatalf("start mock provider: %v", err)
+	}
+	t.Cleanup(server.Close)
+	client := mockidp.Client{ID: "taskflow", Secret: "secret", RedirectURIs: []string{redirectURI}}
+	server.RegisterClient(client)
+	return server, oidc.NewProvider(server.ProviderConfig("mock", client), nil)
+}
+
+// authorize signs email in at the provider and returns the query of the redirect
+// back to the relying party
+func authorize(t *testing.T, provider *oidc.Provider, state, nonce, verifier, email string) url.Values {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("auth code url: %v", err)
+	}
+	target, _ := url.Parse(authURL)
+	query := target.Query()
+	query.Set("login_hint", email)
+	target.RawQuery = query.Encode()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(target.String())
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatalf("authorize location: %v", err)
+	}
+	return location.Query()
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	server, provider := startProvider(t)
+	server.AddUser(mockidp.User{Subject: "alice-1", Email: "Alice@Example.com", EmailVerified: true, Name: "Alice"})
+	verifier, _ := oidc.NewCodeVerifier()
+
+	callback := authorize(t, provider, "state-1", "nonce-1", verifier, "alice@example.com")
+	if got := callback.Get("state"); got != "state-1" {
+		t.Fatalf("callback state = %q, want state-1", got)
+	}
+	token, err := provider.Exchange(context.Background(), callback.Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	identity, err := provider.VerifyIDToken(context.Background(), token.IDToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("verify id token: %v", err)
+	}
+	want := oidc.Identity{Provider: "mock", Subject: "alice-1", Email: "alice@example.com", EmailVerified: true, Name: "Alice"}
+	if *identity != want {
+		t.Fatalf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestExchangeRequiresMatchingVerifier(t *testing.T) {
+	_, provider := startProvider(t)
+	verifier, _ := oidc.NewCodeVerifier()
+	other, _ := oidc.NewCodeVerifier()
+
+	callback := authorize(t, provider, "state", "nonce", verifier, "bob@example.com")
+	if _, err := provider.Exchange(context.Background(), callback.Get("code"), other); err == nil {
+		t.Fatal("exchange with another verifier succeeded")
+	}
+}
+
+func TestCodesAreSingleUse(t *testing.T) {
+	_, provider := startProvider(t)
+	verifier, _ := oidc.NewCodeVerifier()
+
+	callback := authorize(t, provider, "state", "nonce", verifier, "carol@example.com")
+	if _, err := provider.Exchange(context.Background(), callback.Get("code"), verifier); err != nil {
+		t.Fatalf("first exchange: %v", err)
+	}
+	if _, err := provider.Exchange(context.Background(), callback.Get("code"), verifier); err == nil {
+		t.Fatal("second exchange of the same code succeeded")
+	}
+}
+
+func TestVerifyIDTokenChecksNonce(t *testing.T) {
+	_, provider := startProvider(t)
+	verifier, _ := oidc.NewCodeVerifier()
+
+	callback := authorize(t, provider, "state", "nonce-sent", verifier, "dave@example.com")
+	token, err := provider.Exchange(context.Background(), callback.Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if _, err := provider.VerifyIDToken(context.Background(), token.IDToken, "nonce-expected"); !errors.Is(err, oidc.ErrNonceMismatch) {
+		t.Fatalf("verify with another nonce: err = %v, want %v", err, oidc.ErrNonceMismatch)
+	}
+}
+
+func TestVerifyIDTokenRejectsOtherAudience(t *testing.T) {
+	server, provider := startProvider(t)
+	other := mockidp.Client{ID: "other-app", Secret: "other", RedirectURIs: []string{redirectURI}}
+	server.RegisterClient(other)
+	otherProvider := oidc.NewProvider(server.ProviderConfig("mock", other), nil)
+	verifier, _ := oidc.NewCodeVerifier()
+
+	// a token issued to another client must not sign anyone in here
+	callback := authorize(t, otherProvider, "state", "nonce", verifier, "erin@example.com")
+	token, err := otherProvider.Exchange(context.Background(), callback.Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if _, err := provider.VerifyIDToken(context.Background(), token.IDToken, "nonce"); err == nil {
+		t.Fatal("verified an id token issued to another client")
+	}
+}
+
+func TestAuthorizeRequiresPKCE(t *testing.T) {
+	_, provider := startProvider(t)
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier-verifier-verifier-verifier-verifier")
+	if err != nil {
+		t.Fatalf("auth code url: %v", err)
+	}
+	target, _ := url.Parse(authURL)
+	query := target.Query()
+	query.Del("code_challenge")
+	query.Set("login_hint", "frank@example.com")
+	target.RawQuery = query.Encode()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(target.String())
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatalf("authorize location: %v", err)
+	}
+	if got := location.Query().Get("error"); got != "invalid_request" {
+		t.Fatalf("error = %q, want invalid_request", got)
+	}
+	if location.Query().Get("code") != "" {
+		t.Fatal("issued a code without a code challenge")
+	}
+}
@@ -0,0 +1,29 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// RandomToken returns n random bytes encoded as unpadded base64url, suitable for
+// state, nonce and PKCE verifier values
+func RandomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// NewCodeVerifier generates a PKCE code verifier (RFC 7636 section 4.1)
+func NewCodeVerifier() (string, error) {
+	// 32 bytes encode to 43 characters, the minimum verifier length
+	return RandomToken(32)
+}
+
+// CodeChallengeS256 derives the S256 code challenge for a verifier
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
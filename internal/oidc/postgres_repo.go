@@ -0,0 +1,73 @@
+package oidc
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+// PostgresStateRepository handles OIDC login state persistence
+type PostgresStateRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresStateRepository creates a new login state repository
+func NewPostgresStateRepository(db *sql.DB) *PostgresStateRepository {
+	return &PostgresStateRepository{db: db}
+}
+
+func (r *PostgresStateRepository) Save(state *LoginState) domain_errors.DomainError {
+	query := `
+		INSERT INTO oidc_login_state (state, provider, code_verifier, nonce, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.Exec(query, state.State, state.Provider, state.CodeVerifier, state.Nonce, state.CreatedAt, state.ExpiresAt)
+	if err != nil {
+		return domain_errors.NewDatabaseError("OIDC_STATE_CREATION", err)
+	}
+	return nil
+}
+
+func (r *PostgresStateRepository) Consume(state string, now time.Time) (*LoginState, domain_errors.DomainError) {
+	query := `
+		DELETE FROM oidc_login_state
+		WHERE state = $1
+		RETURNING state, provider, code_verifier, nonce, created_at, expires_at
+	`
+
+	result := &LoginState{}
+	err := r.db.QueryRow(query, state).Scan(
+		&result.State,
+		&result.Provider,
+		&result.CodeVerifier,
+		&result.Nonce,
+		&result.CreatedAt,
+		&result.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("OIDC_STATE", state)
+		}
+		return nil, domain_errors.NewDatabaseError("OIDC_STATE_QUERY", err)
+	}
+	if !result.ExpiresAt.After(now) {
+		return nil, domain_errors.NewNotFoundError("OIDC_STATE", state)
+	}
+	return result, nil
+}
+
+func (r *PostgresStateRepository) DeleteExpired(now time.Time) (int, domain_errors.DomainError) {
+	query := `DELETE FROM oidc_login_state WHERE expires_at <= $1`
+
+	result, err := r.db.Exec(query, now)
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("OIDC_STATE_CLEANUP", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("OIDC_STATE_CLEANUP", err)
+	}
+	return int(rows), nil
+}
@@ -0,0 +1,325 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey      = errors.New("oidc: id token signed with an unknown key")
+	ErrNonceMismatch   = errors.New("oidc: id token nonce does not match")
+	ErrMissingIDToken  = errors.New("oidc: token response has no id_token")
+	ErrIssuerMismatch  = errors.New("oidc: discovery issuer does not match configuration")
+	ErrUnsupportedS256 = errors.New("oidc: provider does not support S256 code challenges")
+)
+
+const (
+	discoveryPath      = "/.well-known/openid-configuration"
+	defaultHTTPTimeout = 10 * time.Second
+)
+
+var supportedAlgorithms = []string{jwt.SigningMethodRS256.Alg()}
+
+// Metadata is the part of the provider's discovery document the relying party uses
+type Metadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	JWKSURI                       string   `json:"jwks_uri"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+// JSONWebKey is a single RSA public key from a JWKS document
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JSONWebKeySet is the document served at a provider's jwks_uri
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// TokenResponse is the token endpoint's answer to an authorization code grant
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+}
+
+// IDTokenClaims are the claims read from a verified ID token
+type IDTokenClaims struct {
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// Provider is a relying-party client for one OpenID provider. Discovery and key
+// fetching happen lazily so an unreachable provider does not stop the API from starting.
+type Provider struct {
+	config ProviderConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     map[string]*rsa.PublicKey
+}
+
+// NewProvider creates a client for the given registration. A nil httpClient uses
+// a client with a short timeout.
+func NewProvider(config ProviderConfig, httpClient *http.Client) *Provider {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = DefaultScopes
+	}
+	return &Provider{
+		config: config,
+		client: httpClient,
+		keys:   map[string]*rsa.PublicKey{},
+	}
+}
+
+// Name returns the provider's configured name
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// Metadata fetches and caches the provider's discovery document
+func (p *Provider) Metadata(ctx context.Context) (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var metadata Metadata
+	if err := p.getJSON(ctx, p.config.Issuer+discoveryPath, &metadata); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(metadata.Issuer, "/") != p.config.Issuer {
+		return nil, ErrIssuerMismatch
+	}
+	if len(metadata.CodeChallengeMethodsSupported) > 0 && !contains(metadata.CodeChallengeMethodsSupported, "S256") {
+		return nil, ErrUnsupportedS256
+	}
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+// AuthCodeURL builds the URL the user is redirected to for signing in
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	metadata, err := p.Metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc: invalid authorization endpoint: %w", err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallengeS256(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+// Exchange redeems an authorization code at the token endpoint
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (*TokenResponse, error) {
+	metadata, err := p.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: token endpoint returned %d: %s", resp.StatusCode, body)
+	}
+	var token TokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("oidc: invalid token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return nil, ErrMissingIDToken
+	}
+	return &token, nil
+}
+
+// VerifyIDToken checks the ID token's signature, issuer, audience, expiry and
+// nonce and returns the identity it asserts
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*Identity, error) {
+	claims := &IDTokenClaims{}
+	_, err := jwt.ParseWithClaims(
+		rawIDToken,
+		claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.publicKey(ctx, kid)
+		},
+		jwt.WithValidMethods(supportedAlgorithms),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid id token: %w", err)
+	}
+	if claims.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+	return &Identity{
+		Provider:      p.config.Name,
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(strings.TrimSpace(claims.Email)),
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
+
+// publicKey returns the signing key with the given id, refetching the key set
+// once when the id is unknown so provider key rotation is picked up
+func (p *Provider) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	metadata, err := p.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var set JSONWebKeySet
+	if err := p.getJSON(ctx, metadata.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		publicKey, err := jwk.RSAPublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = publicKey
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// RSAPublicKey decodes the key's modulus and exponent
+func (k JSONWebKey) RSAPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if len(n) == 0 || len(e) == 0 {
+		return nil, errors.New("oidc: empty RSA key parameters")
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+// NewRSAJSONWebKey encodes an RSA public key as a JWK
+func NewRSAJSONWebKey(kid string, key *rsa.PublicKey) JSONWebKey {
+	return JSONWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: jwt.SigningMethodRS256.Alg(),
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func (p *Provider) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("oidc: request to %s failed: %w", target, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %s returned %d", target, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("oidc: invalid response from %s: %w", target, err)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
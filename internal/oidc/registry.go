@@ -0,0 +1,41 @@
+package oidc
+
+import (
+	"net/http"
+	"sort"
+)
+
+// Registry holds the configured providers by name
+type Registry struct {
+	providers map[string]*Provider
+}
+
+// NewRegistry creates a provider client for every registration
+func NewRegistry(configs []ProviderConfig, httpClient *http.Client) *Registry {
+	providers := make(map[string]*Provider, len(configs))
+	for _, config := range configs {
+		providers[config.Name] = NewProvider(config, httpClient)
+	}
+	return &Registry{providers: providers}
+}
+
+// NewRegistryFromEnv creates a registry from LoadProvidersFromEnv
+func NewRegistryFromEnv() *Registry {
+	return NewRegistry(LoadProvidersFromEnv(), nil)
+}
+
+// Get returns the provider with the given name
+func (r *Registry) Get(name string) (*Provider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// Names lists the configured providers in a stable order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,16 @@
+package oidc
+
+import (
+	"time"
+
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+type StateRepository interface {
+	Save(state *LoginState) domain_errors.DomainError
+	// Consume deletes and returns the state if it has not expired, so each
+	// authorization response can be handled at most once.
+	Consume(state string, now time.Time) (*LoginState, domain_errors.DomainError)
+	// DeleteExpired removes abandoned logins and returns how many
+	DeleteExpired(now time.Time) (int, domain_errors.DomainError)
+}
@@ -0,0 +1,143 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/internal/utils/blobstore"
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+const (
+	testWorkspaceID = "6f1c9f5e-4c55-4d0e-9a53-1f9f0c8f6a01"
+	testTaskID      = "0b8a4c2e-7d1f-4e6a-8c3b-5a9d2e7f1c02"
+)
+
+// taskRepo answers the workspace and archive checks of a single writable task
+type taskRepo struct {
+	ProjectRepository
+}
+
+func (taskRepo) GetTaskWorkspaceID(taskID string) (string, domain_errors.DomainError) {
+	return testWorkspaceID, nil
+}
+
+func (taskRepo) TaskArchiveState(taskIDs []string) (*ArchiveState, domain_errors.DomainError) {
+	return &ArchiveState{}, nil
+}
+
+// memoryAttachments records the attachments created
+type memoryAttachments struct {
+	AttachmentRepository
+	created []*TaskAttachment
+}
+
+func (m *memoryAttachments) ReserveBlob(key string, size int64, contentType string, at time.Time) (bool, domain_errors.DomainError) {
+	return false, nil
+}
+
+func (m *memoryAttachments) MarkBlobStored(key string) domain_errors.DomainError {
+	return nil
+}
+
+func (m *memoryAttachments) Create(attachment *TaskAttachment) (*TaskAttachment, domain_errors.DomainError) {
+	m.created = append(m.created, attachment)
+	return attachment, nil
+}
+
+func newAttachmentService(t *testing.T) (*ProjectService, *memoryAttachments) {
+	t.Helper()
+	blobs, err := blobstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new local store: %v", err)
+	}
+	attachments := &memoryAttachments{}
+	return &ProjectService{projectRepo: taskRepo{}, attachmentRepo: attachments, blobs: blobs}, attachments
+}
+
+// padded fills content up to the bytes the type is sniffed from
+func padded(content string) string {
+	return content + strings.Repeat("\x00", 512)
+}
+
+var (
+	elfExecutable = padded("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x3e\x00")
+	peExecutable  = padded("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff\x00\x00This program cannot be run in DOS mode.")
+	oleFile       = padded("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1\x00\x00\x00\x00\x00\x00\x00\x00>\x00\x03\x00\xfe\xff")
+	zipFile       = padded("PK\x03\x04\x14\x00\x06\x00\x08\x00\x00\x00!\x00[Content_Types].xml")
+)
+
+func TestUploadAttachmentRejectsExecutables(t *testing.T) {
+	cases := []struct {
+		name     string
+		filename string
+		content  string
+	}{
+		{"ELF", "tool", elfExecutable},
+		{"ELF named as a document", "report.pdf", elfExecutable},
+		{"PE", "setup.exe", peExecutable},
+		{"PE named as a document", "report.docx", peExecutable},
+		{"installer in an office container", "setup.msi", oleFile},
+		{"unrecognised binary", "data.bin", padded("\x00\x01\x02\x03\xfe\xfd")},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, attachments := newAttachmentService(t)
+			_, err := service.UploadAttachment(context.Background(), testWorkspaceID, testTaskID, "user-1", tc.filename, strings.NewReader(tc.content))
+			if _, ok := err.(*domain_errors.ValidationError); !ok {
+				t.Fatalf("err = %v, want a validation error", err)
+			}
+			if len(attachments.created) != 0 {
+				t.Fatal("attachment created")
+			}
+		})
+	}
+}
+
+func TestUploadAttachmentSniffsContentType(t *testing.T) {
+	cases := []struct {
+		name     string
+		filename string
+		content  string
+		want     string
+	}{
+		{"png", "screenshot.png", padded("\x89PNG\r\n\x1a\n"), "image/png"},
+		{"pdf", "spec.pdf", "%PDF-1.7\n", "application/pdf"},
+		{"text", "notes.txt", "meeting notes", "text/plain; charset=utf-8"},
+		{"zip", "export.zip", zipFile, "application/zip"},
+		{"docx", "Report.DOCX", zipFile, "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{"xlsx", "budget.xlsx", zipFile, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{"odt", "letter.odt", zipFile, "application/vnd.oasis.opendocument.text"},
+		{"doc", "minutes.doc", oleFile, "application/msword"},
+		{"xls", "budget.xls", oleFile, "application/vnd.ms-excel"},
+		{"text named as a document", "notes.docx", "meeting notes", "text/plain; charset=utf-8"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, attachments := newAttachmentService(t)
+			attachment, err := service.UploadAttachment(context.Background(), testWorkspaceID, testTaskID, "user-1", tc.filename, strings.NewReader(tc.content))
+			if err != nil {
+				t.Fatalf("upload: %v", err)
+			}
+			if attachment.ContentType != tc.want {
+				t.Fatalf("content type = %q, want %q", attachment.ContentType, tc.want)
+			}
+			if len(attachments.created) != 1 {
+				t.Fatalf("attachments = %d, want 1", len(attachments.created))
+			}
+			body, _, getErr := service.blobs.Get(context.Background(), attachment.BlobKey)
+			if getErr != nil {
+				t.Fatalf("stored content: %v", getErr)
+			}
+			defer body.Close()
+			var stored bytes.Buffer
+			stored.ReadFrom(body)
+			if stored.String() != tc.content {
+				t.Fatal("stored content differs from the upload")
+			}
+		})
+	}
+}
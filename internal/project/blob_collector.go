@@ -0,0 +1,56 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/internal/shared"
+	"github.com/ishola-faazele/taskflow/internal/utils/blobstore"
+)
+
+const (
+	// blobGracePeriod keeps a blob that was just reserved by an upload, or just lost
+	// its last attachment, from being collected
+	blobGracePeriod = time.Hour
+	// blobCollectionBatch is the most blobs deleted per transaction
+	blobCollectionBatch = 100
+)
+
+// BlobCollector deletes stored attachment content that no attachment references
+// any more, such as the files of tasks removed by a cascading delete
+type BlobCollector struct {
+	repo  AttachmentRepository
+	blobs blobstore.Store
+
+	*shared.Periodic
+}
+
+func NewBlobCollector(db *sql.DB, blobs blobstore.Store, interval time.Duration) *BlobCollector {
+	c := &BlobCollector{
+		repo:  NewPostgresAttachmentRepository(db),
+		blobs: blobs,
+	}
+	c.Periodic = shared.NewPeriodic("FAILED_TO_COLLECT_BLOBS", interval, func(now time.Time) error {
+		_, err := c.Collect(context.Background(), now)
+		return err
+	})
+	return c
+}
+
+// Collect deletes unreferenced blobs older than the grace period and returns how many
+func (c *BlobCollector) Collect(ctx context.Context, now time.Time) (int, error) {
+	total := 0
+	for {
+		removed, err := c.repo.DeleteUnreferencedBlobs(now.Add(-blobGracePeriod), blobCollectionBatch, func(key string) error {
+			return c.blobs.Delete(ctx, key)
+		})
+		if err != nil {
+			return total, err
+		}
+		total += removed
+		if removed < blobCollectionBatch {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,447 @@
+package project
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+// CustomFieldType is the kind of value a custom field holds
+type CustomFieldType string
+
+const (
+	CustomFieldText   CustomFieldType = "text"
+	CustomFieldNumber CustomFieldType = "number"
+	CustomFieldEnum   CustomFieldType = "enum"
+	CustomFieldDate   CustomFieldType = "date"
+	// CustomFieldUser holds the id of a workspace member
+	CustomFieldUser CustomFieldType = "user"
+)
+
+func (t CustomFieldType) IsValid() bool {
+	switch t {
+	case CustomFieldText, CustomFieldNumber, CustomFieldEnum, CustomFieldDate, CustomFieldUser:
+		return true
+	}
+	return false
+}
+
+const (
+	// customFieldDateLayout is how date values are written and stored, so they sort as text
+	customFieldDateLayout = "2006-01-02"
+	maxCustomFieldText    = 1000
+	maxCustomFieldOptions = 100
+	maxCustomFields       = 50
+)
+
+// customFieldKeyPattern restricts keys to identifiers, which also makes them safe
+// to use in index expressions
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// CustomField is a field a project defines for its tasks. Its key and type are
+// fixed once created, since stored values and indexes depend on them.
+type CustomField struct {
+	ID        string          `json:"id"`
+	ProjectID string          `json:"project_id"`
+	Key       string          `json:"key"`
+	Name      string          `json:"name"`
+	Type      CustomFieldType `json:"type"`
+	Options   []string        `json:"options,omitempty"`
+	Required  bool            `json:"required"`
+	Position  int             `json:"position"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type CreateCustomFieldInput struct {
+	Key      string          `json:"key"`
+	Name     string          `json:"name"`
+	Type     CustomFieldType `json:"type"`
+	Options  []string        `json:"options"`
+	Required bool            `json:"required"`
+}
+
+func (input *CreateCustomFieldInput) Validate() domain_errors.DomainError {
+	if !customFieldKeyPattern.MatchString(input.Key) {
+		return domain_errors.NewValidationErrorWithValue("key", input.Key, "KEY MUST START WITH A LETTER AND CONTAIN ONLY LOWERCASE LETTERS, DIGITS AND UNDERSCORES")
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return domain_errors.NewValidationError("name", "NAME CANNOT BE EMPTY")
+	}
+	if !input.Type.IsValid() {
+		return domain_errors.NewValidationErrorWithValue("type", input.Type, "TYPE MUST BE ONE OF text, number, enum, date, user")
+	}
+	if input.Type != CustomFieldEnum {
+		if len(input.Options) > 0 {
+			return domain_errors.NewValidationError("options", "ONLY ENUM FIELDS HAVE OPTIONS")
+		}
+		return nil
+	}
+	options, err := normalizeOptions(input.Options)
+	if err != nil {
+		return err
+	}
+	input.Options = options
+	return nil
+}
+
+type UpdateCustomFieldInput struct {
+	Name     *string   `json:"name"`
+	Options  *[]string `json:"options"`
+	Required *bool     `json:"required"`
+	Position *int      `json:"position"`
+}
+
+func (input *UpdateCustomFieldInput) Validate(field *CustomField) domain_errors.DomainError {
+	if input.Name != nil {
+		*input.Name = strings.TrimSpace(*input.Name)
+		if *input.Name == "" {
+			return domain_errors.NewValidationError("name", "NAME CANNOT BE EMPTY")
+		}
+	}
+	if input.Options != nil {
+		if field.Type != CustomFieldEnum {
+			return domain_errors.NewValidationError("options", "ONLY ENUM FIELDS HAVE OPTIONS")
+		}
+		options, err := normalizeOptions(*input.Options)
+		if err != nil {
+			return err
+		}
+		*input.Options = options
+	}
+	if input.Position != nil && *input.Position < 0 {
+		return domain_errors.NewValidationErrorWithValue("position", *input.Position, "POSITION CANNOT BE NEGATIVE")
+	}
+	return nil
+}
+
+// normalizeOptions trims enum options and rejects empty or duplicate ones
+func normalizeOptions(options []string) ([]string, domain_errors.DomainError) {
+	if len(options) == 0 {
+		return nil, domain_errors.NewValidationError("options", "ENUM FIELDS NEED AT LEAST ONE OPTION")
+	}
+	if len(options) > maxCustomFieldOptions {
+		return nil, domain_errors.NewValidationError("options", "ENUM FIELDS CAN HAVE AT MOST 100 OPTIONS")
+	}
+	normalized := make([]string, 0, len(options))
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return nil, domain_errors.NewValidationError("options", "OPTIONS CANNOT BE EMPTY")
+		}
+		if slices.Contains(normalized, option) {
+			return nil, domain_errors.NewValidationErrorWithValue("options", option, "OPTIONS MUST BE UNIQUE")
+		}
+		normalized = append(normalized, option)
+	}
+	return normalized, nil
+}
+
+// normalizeValue checks a value against the field's type and returns it in the
+// form it is stored in
+func (f *CustomField) normalizeValue(value any) (any, domain_errors.DomainError) {
+	field := "custom_fields." + f.Key
+	switch f.Type {
+	case CustomFieldNumber:
+		var number float64
+		switch v := value.(type) {
+		case float64:
+			number = v
+		case json.Number:
+			parsed, err := v.Float64()
+			if err != nil {
+				return nil, domain_errors.NewValidationErrorWithValue(field, value, "VALUE MUST BE A NUMBER")
+			}
+			number = parsed
+		default:
+			return nil, domain_errors.NewValidationErrorWithValue(field, value, "VALUE MUST BE A NUMBER")
+		}
+		if math.IsNaN(number) || math.IsInf(number, 0) {
+			return nil, domain_errors.NewValidationErrorWithValue(field, value, "VALUE MUST BE A NUMBER")
+		}
+		return number, nil
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return nil, domain_errors.NewValidationErrorWithValue(field, value, "VALUE MUST BE A STRING")
+	}
+	switch f.Type {
+	case CustomFieldText:
+		if len([]rune(text)) > maxCustomFieldText {
+			return nil, domain_errors.NewValidationError(field, "VALUE MUST BE AT MOST 1000 CHARACTERS")
+		}
+	case CustomFieldEnum:
+		if !slices.Contains(f.Options, text) {
+			return nil, domain_errors.NewValidationErrorWithValue(field, text, "VALUE IS NOT ONE OF THE FIELD OPTIONS")
+		}
+	case CustomFieldDate:
+		date, err := time.Parse(customFieldDateLayout, text)
+		if err != nil {
+			return nil, domain_errors.NewValidationErrorWithValue(field, text, "VALUE MUST BE A DATE LIKE 2006-01-02")
+		}
+		text = date.Format(customFieldDateLayout)
+	case CustomFieldUser:
+		if err := uuid.Validate(text); err != nil {
+			return nil, domain_errors.NewValidationErrorWithValue(field, text, "VALUE MUST BE A USER ID")
+		}
+	}
+	return text, nil
+}
+
+// CustomFieldValues maps custom field keys to values. It is stored as a JSONB object.
+type CustomFieldValues map[string]any
+
+func (v CustomFieldValues) Value() (driver.Value, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	encoded, err := json.Marshal(map[string]any(v))
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+func (v *CustomFieldValues) Scan(src any) error {
+	var raw []byte
+	switch s := src.(type) {
+	case nil:
+		*v = CustomFieldValues{}
+		return nil
+	case []byte:
+		raw = s
+	case string:
+		raw = []byte(s)
+	default:
+		return fmt.Errorf("cannot scan %T into CustomFieldValues", src)
+	}
+	values := CustomFieldValues{}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+// validateCustomFields normalizes values against the project's fields. A partial
+// set of values is an update, where null removes a value; otherwise every
+// required field must be given.
+func validateCustomFields(values CustomFieldValues, fields []*CustomField, partial bool) domain_errors.DomainError {
+	byKey := make(map[string]*CustomField, len(fields))
+	for _, field := range fields {
+		byKey[field.Key] = field
+	}
+	for key, value := range values {
+		field, ok := byKey[key]
+		if !ok {
+			return domain_errors.NewValidationErrorWithValue("custom_fields", key, "UNKNOWN CUSTOM FIELD")
+		}
+		if value == nil {
+			if field.Required {
+				return domain_errors.NewValidationError("custom_fields."+key, "REQUIRED CUSTOM FIELD CANNOT BE REMOVED")
+			}
+			if !partial {
+				delete(values, key)
+			}
+			continue
+		}
+		normalized, err := field.normalizeValue(value)
+		if err != nil {
+			return err
+		}
+		values[key] = normalized
+	}
+	if partial {
+		return nil
+	}
+	for _, field := range fields {
+		if _, ok := values[field.Key]; field.Required && !ok {
+			return domain_errors.NewValidationError("custom_fields."+field.Key, "REQUIRED CUSTOM FIELD IS MISSING")
+		}
+	}
+	return nil
+}
+
+// retainCustomFields keeps the values that are valid for the fields, dropping the others
+func retainCustomFields(values CustomFieldValues, fields []*CustomField) CustomFieldValues {
+	byKey := make(map[string]*CustomField, len(fields))
+	for _, field := range fields {
+		byKey[field.Key] = field
+	}
+	retained := CustomFieldValues{}
+	for key, value := range values {
+		field, ok := byKey[key]
+		if !ok || value == nil {
+			continue
+		}
+		if normalized, err := field.normalizeValue(value); err == nil {
+			retained[key] = normalized
+		}
+	}
+	return retained
+}
+
+// userValues returns the user ids held by user fields
+func userValues(values CustomFieldValues, fields []*CustomField) []string {
+	var ids []string
+	for _, field := range fields {
+		if id, ok := values[field.Key].(string); ok && field.Type == CustomFieldUser {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// FILTERING AND SORTING
+
+// Comparison operators of custom field filters
+const (
+	FilterEq  = "eq"
+	FilterNe  = "ne"
+	FilterLt  = "lt"
+	FilterLte = "lte"
+	FilterGt  = "gt"
+	FilterGte = "gte"
+)
+
+// TaskListOptions are the filters and ordering of a task listing as requested
+type TaskListOptions struct {
+	LabelIDs    []string
+	MilestoneID string
+	Status      TaskStatus
+	Priority    TaskPriority
+	// Fields maps custom field keys to a condition written as "value" or "op:value"
+	Fields map[string]string
+	// Sort is a task column or "cf.<key>", prefixed with "-" for descending order
+	Sort string
+}
+
+// CustomFieldFilter compares a custom field's value
+type CustomFieldFilter struct {
+	Field    *CustomField
+	Operator string
+	Value    any
+}
+
+// TaskQuery is a validated task listing the repository can run
+type TaskQuery struct {
+	LabelIDs    []string
+	MilestoneID string
+	Status      TaskStatus
+	Priority    TaskPriority
+	Filters     []CustomFieldFilter
+	// SortColumn is a task column, used when SortField is nil
+	SortColumn string
+	SortField  *CustomField
+	Descending bool
+}
+
+// sortableTaskColumns are the task columns listings can be sorted by
+var sortableTaskColumns = []string{"created_at", "updated_at", "due_date", "name"}
+
+// parseCondition splits "op:value", treating text without a known operator as equality
+func parseCondition(condition string) (string, string) {
+	if op, value, ok := strings.Cut(condition, ":"); ok {
+		switch op {
+		case FilterEq, FilterNe, FilterLt, FilterLte, FilterGt, FilterGte:
+			return op, value
+		}
+	}
+	return FilterEq, condition
+}
+
+// buildTaskQuery resolves the requested filters and ordering against the project's fields
+func buildTaskQuery(options *TaskListOptions, fields []*CustomField) (*TaskQuery, domain_errors.DomainError) {
+	query := &TaskQuery{SortColumn: "created_at", Descending: true}
+	if options == nil {
+		return query, nil
+	}
+	for _, labelID := range options.LabelIDs {
+		if err := uuid.Validate(labelID); err != nil {
+			return nil, domain_errors.NewValidationErrorWithValue("label", labelID, "LABEL ID IS NOT A VALID UUID")
+		}
+	}
+	query.LabelIDs = options.LabelIDs
+	if options.MilestoneID != "" {
+		if err := uuid.Validate(options.MilestoneID); err != nil {
+			return nil, domain_errors.NewValidationErrorWithValue("milestone", options.MilestoneID, "MILESTONE ID IS NOT A VALID UUID")
+		}
+		query.MilestoneID = options.MilestoneID
+	}
+	if options.Status != "" && !options.Status.valid() {
+		return nil, domain_errors.NewValidationErrorWithValue("status", options.Status, "STATUS MUST BE open, in_review OR closed")
+	}
+	if options.Priority != "" && !options.Priority.valid() {
+		return nil, domain_errors.NewValidationErrorWithValue("priority", options.Priority, "PRIORITY MUST BE low, medium OR high")
+	}
+	query.Status, query.Priority = options.Status, options.Priority
+
+	byKey := make(map[string]*CustomField, len(fields))
+	for _, field := range fields {
+		byKey[field.Key] = field
+	}
+	for key, condition := range options.Fields {
+		field, ok := byKey[key]
+		if !ok {
+			return nil, domain_errors.NewValidationErrorWithValue("cf."+key, key, "UNKNOWN CUSTOM FIELD")
+		}
+		op, raw := parseCondition(condition)
+		if op != FilterEq && op != FilterNe && (field.Type == CustomFieldEnum || field.Type == CustomFieldUser) {
+			return nil, domain_errors.NewValidationErrorWithValue("cf."+key, condition, "ENUM AND USER FIELDS ONLY SUPPORT eq AND ne")
+		}
+		var value any = raw
+		if field.Type == CustomFieldNumber {
+			number, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, domain_errors.NewValidationErrorWithValue("cf."+key, raw, "VALUE MUST BE A NUMBER")
+			}
+			value = number
+		}
+		normalized, err := field.normalizeValue(value)
+		if err != nil {
+			return nil, err
+		}
+		query.Filters = append(query.Filters, CustomFieldFilter{Field: field, Operator: op, Value: normalized})
+	}
+
+	if options.Sort != "" {
+		sort, descending := strings.CutPrefix(options.Sort, "-")
+		query.Descending = descending
+		if key, ok := strings.CutPrefix(sort, "cf."); ok {
+			field, ok := byKey[key]
+			if !ok {
+				return nil, domain_errors.NewValidationErrorWithValue("sort", options.Sort, "UNKNOWN CUSTOM FIELD")
+			}
+			query.SortField = field
+		} else if slices.Contains(sortableTaskColumns, sort) {
+			query.SortColumn = sort
+		} else {
+			return nil, domain_errors.NewValidationErrorWithValue("sort", options.Sort, "TASKS CAN BE SORTED BY created_at, updated_at, due_date, name OR A CUSTOM FIELD")
+		}
+	}
+	return query, nil
+}
+
+// customFieldExpression is the SQL expression of a field's value, typed so that
+// it compares and sorts correctly and matches the field's index
+func customFieldExpression(field *CustomField) string {
+	if field.Type == CustomFieldNumber {
+		return fmt.Sprintf("((custom_fields->>'%s')::numeric)", field.Key)
+	}
+	return fmt.Sprintf("(custom_fields->>'%s')", field.Key)
+}
+
+// customFieldIndexName names the expression index created for a field
+func customFieldIndexName(field *CustomField) string {
+	return "idx_task_cf_" + strings.ReplaceAll(field.ID, "-", "")
+}
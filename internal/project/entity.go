@@ -1,7 +1,9 @@
 package project
 
 import (
-	"fmt"
+	"errors"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,10 +17,82 @@ type Project struct {
 	WorkspaceID string    `json:"workspace_id"`
 	Creator     string    `json:"creator"`
 	CreatedAt   time.Time `json:"created_at"`
+	// ArchivedAt is set while the project is archived, which makes it read-only
+	ArchivedAt *time.Time `json:"archived_at"`
+	// Version grows with every update and is the project's ETag
+	Version int `json:"version"`
 }
+
+// ArchiveState tells whether a project, or the workspace it belongs to, is archived
+type ArchiveState struct {
+	Project   bool
+	Workspace bool
+}
+
+// checkWritable refuses writes while the project or its workspace is archived
+func (state *ArchiveState) checkWritable() domain_errors.DomainError {
+	if state.Workspace {
+		return domain_errors.NewInvalidOperationError("archived write", "THE WORKSPACE IS ARCHIVED")
+	}
+	if state.Project {
+		return domain_errors.NewInvalidOperationError("archived write", "THE PROJECT IS ARCHIVED")
+	}
+	return nil
+}
+
 type UpdateProjectInput struct {
 	Name        *string `json:"name"`
 	Description *string `json:"description"`
+	// IfVersion makes the update apply only to that version, taken from If-Match
+	IfVersion *int `json:"-"`
+}
+
+// Validate checks the fields being changed, reporting every invalid field at once
+func (input *UpdateProjectInput) Validate() domain_errors.DomainError {
+	if input.Name == nil && input.Description == nil {
+		return domain_errors.NewValidationError("input", "NO FIELDS TO UPDATE")
+	}
+	var errs fieldErrors
+	if input.Name != nil {
+		errs.add(validateName(input.Name))
+	}
+	return errs.err()
+}
+
+// validateName trims a name, which must not be empty or longer than 255 characters
+func validateName(name *string) domain_errors.DomainError {
+	*name = strings.TrimSpace(*name)
+	if *name == "" {
+		return domain_errors.NewValidationError("name", "NAME CANNOT BE EMPTY")
+	}
+	if len(*name) > 255 {
+		return domain_errors.NewValidationError("name", "NAME CANNOT BE LONGER THAN 255 CHARACTERS")
+	}
+	return nil
+}
+
+// fieldErrors gathers the validation errors of several fields
+type fieldErrors []*domain_errors.ValidationError
+
+// add keeps err when it is a validation error. Other errors are kept as a
+// failure of the input as a whole.
+func (errs *fieldErrors) add(err domain_errors.DomainError) {
+	if err == nil {
+		return
+	}
+	var validation *domain_errors.ValidationError
+	if !errors.As(err, &validation) {
+		validation = domain_errors.NewValidationError("input", err.Message())
+	}
+	*errs = append(*errs, validation)
+}
+
+// err returns a MultiValidationError listing the errors, or nil when there are none
+func (errs fieldErrors) err() domain_errors.DomainError {
+	if len(errs) == 0 {
+		return nil
+	}
+	return domain_errors.NewMultiValidationError(errs)
 }
 
 // TASK TYPES
@@ -31,6 +105,10 @@ const (
 	TaskStatusClosed   TaskStatus = "closed"
 )
 
+func (s TaskStatus) valid() bool {
+	return s == TaskStatusOpen || s == TaskStatusInReview || s == TaskStatusClosed
+}
+
 type TaskPriority string
 
 const (
@@ -39,6 +117,10 @@ const (
 	TaskPriorityHigh   TaskPriority = "high"
 )
 
+func (p TaskPriority) valid() bool {
+	return p == TaskPriorityLow || p == TaskPriorityMedium || p == TaskPriorityHigh
+}
+
 type Task struct {
 	ID          string       `json:"id"`
 	ParentID    *string      `json:"parent_id"`
@@ -48,9 +130,20 @@ type Task struct {
 	Creator     string       `json:"creator"`
 	Status      TaskStatus   `json:"status"`
 	Priority    TaskPriority `json:"priority"`
-	DueDate     time.Time    `json:"due_date"`
+	DueDate     *time.Time   `json:"due_date"`
 	CreatedAt   time.Time    `json:"created_at"`
 	UpdatedAt   time.Time    `json:"updated_at"`
+	// CustomFields holds the values of the project's custom fields by key
+	CustomFields CustomFieldValues `json:"custom_fields"`
+	// Estimates in minutes. Logging work lowers the remaining estimate.
+	OriginalEstimateMinutes  *int     `json:"original_estimate_minutes"`
+	RemainingEstimateMinutes *int     `json:"remaining_estimate_minutes"`
+	MilestoneID              *string  `json:"milestone_id"`
+	Labels                   []*Label `json:"labels,omitempty"`
+	// Mentions are the members mentioned in the description
+	Mentions []*Mention `json:"mentions,omitempty"`
+	// Version grows with every update and is the task's ETag
+	Version int `json:"version"`
 }
 
 // TaskTree represents a task with its subtasks (nested structure)
@@ -58,6 +151,8 @@ type TaskTree struct {
 	Task
 	Subtasks []*TaskTree `json:"subtasks,omitempty"`
 	Depth    int         `json:"depth"`
+	// Time sums the estimates and logged time of the task and its subtasks
+	Time *TimeRollup `json:"time,omitempty"`
 }
 
 // TaskWithChildren represents a task with immediate children only
@@ -75,11 +170,360 @@ type TaskAssignment struct {
 }
 
 type TaskComment struct {
-	ID        string    `json:"id"`
-	Author    string    `json:"author"`
-	TaskID    string    `json:"task_id"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        string     `json:"id"`
+	Author    string     `json:"author"`
+	TaskID    string     `json:"task_id"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Mentions  []*Mention `json:"mentions"`
+}
+
+// Label categorizes tasks. Labels belong to a workspace and can be put on any
+// of its tasks.
+type Label struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspace_id"`
+	Name        string    `json:"name"`
+	Color       string    `json:"color"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	// TaskCount is filled in when listing a workspace's labels
+	TaskCount int `json:"task_count"`
+}
+
+// DefaultLabelColor is used when a label is created without a colour
+const DefaultLabelColor = "#6B7280"
+
+type CreateLabelInput struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type UpdateLabelInput struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+// MILESTONES
+
+type MilestoneKind string
+
+const (
+	MilestoneKindMilestone MilestoneKind = "milestone"
+	MilestoneKindSprint    MilestoneKind = "sprint"
+)
+
+// dateLayout is how calendar dates are written in requests
+const dateLayout = "2006-01-02"
+
+// Milestone groups tasks of a project that are due together. A sprint is a
+// milestone with a fixed timebox.
+type Milestone struct {
+	ID          string        `json:"id"`
+	ProjectID   string        `json:"project_id"`
+	Name        string        `json:"name"`
+	Kind        MilestoneKind `json:"kind"`
+	Description string        `json:"description"`
+	StartDate   time.Time     `json:"start_date"`
+	EndDate     time.Time     `json:"end_date"`
+	CreatedBy   string        `json:"created_by"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type CreateMilestoneInput struct {
+	Name        string        `json:"name"`
+	Kind        MilestoneKind `json:"kind"`
+	Description string        `json:"description"`
+	StartDate   string        `json:"start_date"`
+	EndDate     string        `json:"end_date"`
+}
+
+type UpdateMilestoneInput struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	StartDate   *string `json:"start_date"`
+	EndDate     *string `json:"end_date"`
+}
+
+// parseDate parses a calendar date written as 2006-01-02
+func parseDate(field, value string) (time.Time, domain_errors.DomainError) {
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, domain_errors.NewValidationErrorWithValue(field, value, "DATE MUST BE WRITTEN LIKE 2006-01-02")
+	}
+	return date, nil
+}
+
+// TaskEventKind is what happened to a task
+type TaskEventKind string
+
+const (
+	TaskEventCreated          TaskEventKind = "created"
+	TaskEventStatusChanged    TaskEventKind = "status_changed"
+	TaskEventMilestoneChanged TaskEventKind = "milestone_changed"
+	// TaskEventBulkUpdated records every change a bulk operation made to a task.
+	// Its from and to values are JSON objects holding the previous and new values
+	// of the changed fields; to also lists the assignees and labels added or removed.
+	TaskEventBulkUpdated TaskEventKind = "bulk_updated"
+	// TaskEventMoved records a new parent or project. Its from and to values are
+	// JSON objects holding the parent_id and project_id.
+	TaskEventMoved TaskEventKind = "moved"
+	// TaskEventDeleted and TaskEventRestored record the task being moved to the
+	// trash, with its subtasks, and back out of it
+	TaskEventDeleted  TaskEventKind = "deleted"
+	TaskEventRestored TaskEventKind = "restored"
+)
+
+// TaskEvent is an entry of a task's activity. Besides the change itself it
+// records the task's status and milestone afterwards, so the state of a task at
+// any time is the snapshot of its last event before then.
+type TaskEvent struct {
+	ID          string        `json:"id"`
+	TaskID      string        `json:"task_id"`
+	ActorID     string        `json:"actor_id"`
+	Kind        TaskEventKind `json:"kind"`
+	From        *string       `json:"from"`
+	To          *string       `json:"to"`
+	Status      TaskStatus    `json:"status"`
+	MilestoneID *string       `json:"milestone_id"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// BurndownPoint is the state of a milestone at the end of a day
+type BurndownPoint struct {
+	Date             time.Time `json:"date"`
+	ScopeTasks       int       `json:"scope_tasks"`
+	DoneTasks        int       `json:"done_tasks"`
+	RemainingTasks   int       `json:"remaining_tasks"`
+	ScopeMinutes     int       `json:"scope_minutes"`
+	DoneMinutes      int       `json:"done_minutes"`
+	RemainingMinutes int       `json:"remaining_minutes"`
+	// IdealRemainingTasks burns the scope at the start down evenly to the end date
+	IdealRemainingTasks float64 `json:"ideal_remaining_tasks"`
+}
+
+// ScopeChange is a task added to or removed from a milestone after it started
+type ScopeChange struct {
+	TaskID  string    `json:"task_id"`
+	Change  string    `json:"change"`
+	ActorID string    `json:"actor_id"`
+	At      time.Time `json:"at"`
+}
+
+// Burndown holds the daily burndown and burnup series of a milestone. Minutes
+// weigh tasks by their current original estimate.
+type Burndown struct {
+	MilestoneID  string           `json:"milestone_id"`
+	StartDate    time.Time        `json:"start_date"`
+	EndDate      time.Time        `json:"end_date"`
+	Points       []*BurndownPoint `json:"points"`
+	ScopeChanges []*ScopeChange   `json:"scope_changes"`
+}
+
+type SetTaskMilestoneInput struct {
+	MilestoneID *string `json:"milestone_id"`
+}
+
+// MoveTaskInput gives the task's new place. A nil parent makes the task a root.
+// The project defaults to the parent's or, for a root, to the task's own.
+type MoveTaskInput struct {
+	ParentID  *string `json:"parent_id"`
+	ProjectID *string `json:"project_id"`
+}
+
+// BULK OPERATIONS
+
+// maxBulkTasks bounds the tasks a bulk operation changes
+const maxBulkTasks = 500
+
+// BulkTaskFilter selects the tasks of a project like a task listing does
+type BulkTaskFilter struct {
+	ProjectID   string       `json:"project_id"`
+	LabelIDs    []string     `json:"label_ids"`
+	MilestoneID string       `json:"milestone_id"`
+	Status      TaskStatus   `json:"status"`
+	Priority    TaskPriority `json:"priority"`
+	// Fields maps custom field keys to a condition written as "value" or "op:value"
+	Fields map[string]string `json:"fields"`
+}
+
+// BulkTaskChanges are applied to every selected task. Nil and empty fields are left alone.
+type BulkTaskChanges struct {
+	Status          *TaskStatus   `json:"status"`
+	Priority        *TaskPriority `json:"priority"`
+	DueDate         *time.Time    `json:"due_date"`
+	ClearDueDate    bool          `json:"clear_due_date"`
+	AddAssignees    []string      `json:"add_assignees"`
+	RemoveAssignees []string      `json:"remove_assignees"`
+	AddLabels       []string      `json:"add_labels"`
+	RemoveLabels    []string      `json:"remove_labels"`
+}
+
+func (changes *BulkTaskChanges) Validate() domain_errors.DomainError {
+	if changes.Status == nil && changes.Priority == nil && changes.DueDate == nil && !changes.ClearDueDate &&
+		len(changes.AddAssignees) == 0 && len(changes.RemoveAssignees) == 0 && len(changes.AddLabels) == 0 && len(changes.RemoveLabels) == 0 {
+		return domain_errors.NewValidationError("changes", "AT LEAST ONE CHANGE IS REQUIRED")
+	}
+	if changes.Status != nil && !changes.Status.valid() {
+		return domain_errors.NewValidationErrorWithValue("changes.status", *changes.Status, "STATUS MUST BE open, in_review OR closed")
+	}
+	if changes.Priority != nil && !changes.Priority.valid() {
+		return domain_errors.NewValidationErrorWithValue("changes.priority", *changes.Priority, "PRIORITY MUST BE low, medium OR high")
+	}
+	if changes.DueDate != nil && changes.ClearDueDate {
+		return domain_errors.NewValidationError("changes.due_date", "DUE DATE CANNOT BE SET AND CLEARED")
+	}
+	for _, id := range changes.AddAssignees {
+		if slices.Contains(changes.RemoveAssignees, id) {
+			return domain_errors.NewValidationErrorWithValue("changes.add_assignees", id, "USER CANNOT BE ADDED AND REMOVED")
+		}
+	}
+	for _, id := range changes.AddLabels {
+		if err := uuid.Validate(id); err != nil {
+			return domain_errors.NewValidationErrorWithValue("changes.add_labels", id, "LABEL ID IS NOT A VALID UUID")
+		}
+		if slices.Contains(changes.RemoveLabels, id) {
+			return domain_errors.NewValidationErrorWithValue("changes.add_labels", id, "LABEL CANNOT BE ADDED AND REMOVED")
+		}
+	}
+	return nil
+}
+
+// BulkTaskInput selects tasks by id or by filter, not both
+type BulkTaskInput struct {
+	TaskIDs []string        `json:"task_ids"`
+	Filter  *BulkTaskFilter `json:"filter"`
+	Changes BulkTaskChanges `json:"changes"`
+}
+
+type BulkResult string
+
+const (
+	BulkResultUpdated   BulkResult = "updated"
+	BulkResultUnchanged BulkResult = "unchanged"
+	BulkResultNotFound  BulkResult = "not_found"
+)
+
+type BulkTaskResult struct {
+	TaskID string     `json:"task_id"`
+	Result BulkResult `json:"result"`
+}
+
+type BulkTaskReport struct {
+	Matched   int               `json:"matched"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	NotFound  int               `json:"not_found"`
+	Results   []*BulkTaskResult `json:"results"`
+}
+
+// BulkTaskChange is what a bulk update changed on one task, for the notifications
+// sent once it is committed
+type BulkTaskChange struct {
+	Task          *Task
+	StatusChanged bool
+	// AddedAssignees are the users who were not assigned to the task before
+	AddedAssignees []string
+}
+
+// TIME TRACKING
+
+const (
+	// maxWorkLogMinutes is the longest single work log entry
+	maxWorkLogMinutes = 24 * 60
+	// maxEstimateMinutes bounds estimates to a year of full-time work
+	maxEstimateMinutes = 2000 * 60
+)
+
+// WorkLog records time a user spent on a task
+type WorkLog struct {
+	ID          string    `json:"id"`
+	TaskID      string    `json:"task_id"`
+	UserID      string    `json:"user_id"`
+	StartedAt   time.Time `json:"started_at"`
+	Minutes     int       `json:"minutes"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type CreateWorkLogInput struct {
+	StartedAt   time.Time `json:"started_at"`
+	Minutes     int       `json:"minutes"`
+	Description string    `json:"description"`
+}
+
+func (input *CreateWorkLogInput) Validate() domain_errors.DomainError {
+	if input.Minutes <= 0 || input.Minutes > maxWorkLogMinutes {
+		return domain_errors.NewValidationErrorWithValue("minutes", input.Minutes, "MINUTES MUST BE BETWEEN 1 AND 1440")
+	}
+	if input.StartedAt.IsZero() {
+		return domain_errors.NewValidationError("started_at", "STARTED AT CANNOT BE EMPTY")
+	}
+	if input.StartedAt.After(time.Now().Add(time.Minute)) {
+		return domain_errors.NewValidationErrorWithValue("started_at", input.StartedAt, "STARTED AT CANNOT BE IN THE FUTURE")
+	}
+	return nil
+}
+
+// WorkTimer is a user's running timer. A user runs at most one timer at a time.
+type WorkTimer struct {
+	UserID      string    `json:"user_id"`
+	TaskID      string    `json:"task_id"`
+	StartedAt   time.Time `json:"started_at"`
+	Description string    `json:"description"`
+}
+
+// TimeRollup totals the estimates and logged time of a task and its subtasks
+type TimeRollup struct {
+	TaskID                   string `json:"task_id"`
+	OriginalEstimateMinutes  int    `json:"original_estimate_minutes"`
+	RemainingEstimateMinutes int    `json:"remaining_estimate_minutes"`
+	LoggedMinutes            int    `json:"logged_minutes"`
+}
+
+// TimesheetEntry is the time a user logged on a project in the week starting at WeekStart
+type TimesheetEntry struct {
+	UserID    string    `json:"user_id"`
+	WeekStart time.Time `json:"week_start"`
+	Minutes   int       `json:"minutes"`
+}
+
+// Timesheet reports the time logged on a project by user and week
+type Timesheet struct {
+	ProjectID    string           `json:"project_id"`
+	From         time.Time        `json:"from"`
+	To           time.Time        `json:"to"`
+	TotalMinutes int              `json:"total_minutes"`
+	Users        []*TimesheetUser `json:"users"`
+}
+
+type TimesheetUser struct {
+	UserID       string            `json:"user_id"`
+	TotalMinutes int               `json:"total_minutes"`
+	Weeks        []*TimesheetEntry `json:"weeks"`
+}
+
+// validateEstimate accepts a missing estimate or a number of minutes within bounds
+func validateEstimate(field string, minutes *int) domain_errors.DomainError {
+	if minutes != nil && (*minutes < 0 || *minutes > maxEstimateMinutes) {
+		return domain_errors.NewValidationErrorWithValue(field, *minutes, "ESTIMATE MUST BE BETWEEN 0 AND 120000 MINUTES")
+	}
+	return nil
+}
+
+// TaskAttachment is a file attached to a task. Identical files share one blob,
+// addressed by the SHA-256 of their content.
+type TaskAttachment struct {
+	ID          string    `json:"id"`
+	TaskID      string    `json:"task_id"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	BlobKey     string    `json:"-"`
+	UploadedBy  string    `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 type CreateTaskInput struct {
@@ -90,10 +534,16 @@ type CreateTaskInput struct {
 	Creator     string
 	Status      TaskStatus
 	Priority    TaskPriority
-	DueDate     time.Time
+	DueDate     *time.Time
+	// CustomFields are checked against the project's fields
+	CustomFields CustomFieldValues
+	// The remaining estimate starts at the original estimate when not given
+	OriginalEstimateMinutes  *int
+	RemainingEstimateMinutes *int
 }
 
-func (taskinput *CreateTaskInput) Validate() domain_errors.DomainError {
+// Validate checks the input, normalizing its custom field values against the project's fields
+func (taskinput *CreateTaskInput) Validate(fields []*CustomField) domain_errors.DomainError {
 	if err := uuid.Validate(taskinput.ProjectID); err != nil {
 		return domain_errors.NewValidationErrorWithValue("project_id", taskinput.ProjectID, "PROJECT ID IS NOT A VALID UUID")
 	}
@@ -106,19 +556,28 @@ func (taskinput *CreateTaskInput) Validate() domain_errors.DomainError {
 	if taskinput.Name == "" {
 		return domain_errors.NewValidationError("name", "NAME CANNOT BE EMPTY")
 	}
-	if taskinput.Description == "" {
-		return domain_errors.NewValidationError("description", "DESCRIPTION CANNOT BE EMPTY")
-	}
 	if taskinput.Status == "" {
 		return domain_errors.NewValidationError("status", "STATUS CANNOT BE EMPTY")
 	}
+	if !taskinput.Status.valid() {
+		return domain_errors.NewValidationErrorWithValue("status", taskinput.Status, "STATUS MUST BE open, in_review OR closed")
+	}
 	if taskinput.Priority == "" {
 		return domain_errors.NewValidationError("priority", "PRIORITY CANNOT BE EMPTY")
 	}
-	if taskinput.DueDate.IsZero() {
-		return domain_errors.NewValidationError("due_date", "DUE DATE CANNOT BE EMPTY")
+	if !taskinput.Priority.valid() {
+		return domain_errors.NewValidationErrorWithValue("priority", taskinput.Priority, "PRIORITY MUST BE low, medium OR high")
 	}
-	return nil
+	if err := validateEstimate("original_estimate_minutes", taskinput.OriginalEstimateMinutes); err != nil {
+		return err
+	}
+	if err := validateEstimate("remaining_estimate_minutes", taskinput.RemainingEstimateMinutes); err != nil {
+		return err
+	}
+	if taskinput.CustomFields == nil {
+		taskinput.CustomFields = CustomFieldValues{}
+	}
+	return validateCustomFields(taskinput.CustomFields, fields, false)
 }
 
 type UpdateTaskInput struct {
@@ -127,12 +586,44 @@ type UpdateTaskInput struct {
 	Status      *TaskStatus   `json:"status"`
 	Priority    *TaskPriority `json:"priority"`
 	DueDate     *time.Time    `json:"due_date"`
+	// CustomFields are merged into the task's values; null removes a value
+	CustomFields             CustomFieldValues `json:"custom_fields"`
+	OriginalEstimateMinutes  *int              `json:"original_estimate_minutes"`
+	RemainingEstimateMinutes *int              `json:"remaining_estimate_minutes"`
+	// The Clear fields set nullable fields to null. Only patches set them.
+	ClearDueDate                  bool `json:"-"`
+	ClearOriginalEstimateMinutes  bool `json:"-"`
+	ClearRemainingEstimateMinutes bool `json:"-"`
+	// IfVersion makes the update apply only to that version, taken from If-Match
+	IfVersion *int `json:"-"`
+}
+
+// IsEmpty tells whether the input changes nothing
+func (taskinput *UpdateTaskInput) IsEmpty() bool {
+	return taskinput.Name == nil && taskinput.Description == nil && taskinput.Status == nil &&
+		taskinput.Priority == nil && taskinput.DueDate == nil && len(taskinput.CustomFields) == 0 &&
+		taskinput.OriginalEstimateMinutes == nil && taskinput.RemainingEstimateMinutes == nil &&
+		!taskinput.ClearDueDate && !taskinput.ClearOriginalEstimateMinutes && !taskinput.ClearRemainingEstimateMinutes
 }
 
-func (taskinput *UpdateTaskInput) Validate() domain_errors.DomainError {
-	fmt.Printf("update input: +%v\n", taskinput)
-	if taskinput.Name == nil || taskinput.Description == nil || taskinput.DueDate == nil || taskinput.Priority == nil || taskinput.Status == nil {
-		return domain_errors.NewValidationError("input", "ONE OR MORE OF THE OF INPUTS IS EMPTY")
+// Validate checks the fields being changed, normalizing custom field values
+// against the project's fields. Every invalid field is reported at once.
+func (taskinput *UpdateTaskInput) Validate(fields []*CustomField) domain_errors.DomainError {
+	if taskinput.IsEmpty() {
+		return domain_errors.NewValidationError("input", "NO FIELDS TO UPDATE")
 	}
-	return nil
+	var errs fieldErrors
+	if taskinput.Name != nil {
+		errs.add(validateName(taskinput.Name))
+	}
+	if taskinput.Status != nil && !taskinput.Status.valid() {
+		errs.add(domain_errors.NewValidationErrorWithValue("status", *taskinput.Status, "STATUS MUST BE open, in_review OR closed"))
+	}
+	if taskinput.Priority != nil && !taskinput.Priority.valid() {
+		errs.add(domain_errors.NewValidationErrorWithValue("priority", *taskinput.Priority, "PRIORITY MUST BE low, medium OR high"))
+	}
+	errs.add(validateEstimate("original_estimate_minutes", taskinput.OriginalEstimateMinutes))
+	errs.add(validateEstimate("remaining_estimate_minutes", taskinput.RemainingEstimateMinutes))
+	errs.add(validateCustomFields(taskinput.CustomFields, fields, true))
+	return errs.err()
 }
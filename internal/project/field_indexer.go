@@ -0,0 +1,54 @@
+package project
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/internal/shared"
+)
+
+// fieldIndexBatch is the most custom field indexes built per run
+const fieldIndexBatch = 10
+
+// CustomFieldIndexer builds the task indexes of new custom fields and drops those
+// of deleted ones. Indexes are built and dropped concurrently, which cannot run in
+// a transaction, so requests only record the field and leave the index to it.
+type CustomFieldIndexer struct {
+	repo CustomFieldIndexRepository
+
+	*shared.Periodic
+}
+
+func NewCustomFieldIndexer(db *sql.DB, interval time.Duration) *CustomFieldIndexer {
+	x := &CustomFieldIndexer{
+		repo: NewPostgresCustomFieldRepository(db),
+	}
+	x.Periodic = shared.NewPeriodic("FAILED_TO_INDEX_CUSTOM_FIELDS", interval, func(time.Time) error {
+		_, err := x.Index()
+		return err
+	})
+	return x
+}
+
+// Index drops the indexes of deleted fields and builds those of new fields, and
+// returns how many it built. A field whose index fails to build is logged and
+// retried on the next run without holding up the others.
+func (x *CustomFieldIndexer) Index() (int, error) {
+	if _, err := x.repo.DropOrphanedIndexes(); err != nil {
+		return 0, err
+	}
+	fields, err := x.repo.ListUnindexed(fieldIndexBatch)
+	if err != nil {
+		return 0, err
+	}
+	built := 0
+	for _, field := range fields {
+		if err := x.repo.BuildIndex(field); err != nil {
+			log.Println("FAILED_TO_BUILD_CUSTOM_FIELD_INDEX:", field.ID, err)
+			continue
+		}
+		built++
+	}
+	return built, nil
+}
@@ -3,11 +3,18 @@ package project
 import (
 	"database/sql"
 	"encoding/json"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	domain_middleware "github.com/ishola-faazele/taskflow/internal/middleware"
+	"github.com/ishola-faazele/taskflow/internal/user"
+	"github.com/ishola-faazele/taskflow/internal/utils/blobstore"
 	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type ProjectHandler struct {
@@ -15,8 +22,8 @@ type ProjectHandler struct {
 	responder *domain_errors.APIResponder
 }
 
-func NewProjectHandler(db *sql.DB) *ProjectHandler {
-	service := NewProjectService(NewPostgresProjectRepository(db))
+func NewProjectHandler(db *sql.DB, blobs blobstore.Store, conn *amqp.Connection) *ProjectHandler {
+	service := NewProjectService(NewPostgresProjectRepository(db), NewPostgresAttachmentRepository(db), NewPostgresLabelRepository(db), NewPostgresCustomFieldRepository(db), NewPostgresTimeRepository(db), NewPostgresMilestoneRepository(db), NewPostgresReportRepository(db), NewPostgresTemplateRepository(db), NewPostgresTrashRepository(db), NewPostgresCommentRepository(db), NewPostgresNotificationRepository(db), NewPostgresWatcherRepository(db), user.NewPostgresUserProfileRepository(db), blobs, conn)
 	responder := domain_errors.NewAPIResponder()
 	return &ProjectHandler{
 		service:   service,
@@ -50,6 +57,44 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 	location := "/api/project/" + project.ID
 	h.responder.Success(w, r, http.StatusCreated, location, project)
 }
+
+// setETag sends the version of a task or project as its entity tag
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// ifMatchVersion reads the version an update expects from the If-Match header.
+// Without the header, or with *, the update applies to any version.
+func ifMatchVersion(r *http.Request) (*int, domain_errors.DomainError) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("If-Match", header, "IF-MATCH MUST HOLD A SINGLE ETAG")
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version < 1 {
+		return nil, domain_errors.NewValidationErrorWithValue("If-Match", header, "IF-MATCH MUST HOLD A SINGLE ETAG")
+	}
+	return &version, nil
+}
+
+// patchFormat reads the patch format from the Content-Type. Plain JSON is read
+// as a merge patch.
+func patchFormat(w http.ResponseWriter, r *http.Request) (PatchFormat, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch PatchFormat(mediaType) {
+	case PatchFormatMerge, PatchFormatJSON:
+		return PatchFormat(mediaType), true
+	case "", "application/json":
+		return PatchFormatMerge, true
+	}
+	w.Header().Set("Accept-Patch", string(PatchFormatMerge)+", "+string(PatchFormatJSON))
+	return "", false
+}
+
 func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	project, err := h.service.GetByID(id)
@@ -57,6 +102,7 @@ func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to get project", err)
 		return
 	}
+	setETag(w, project.Version)
 	h.responder.Success(w, r, http.StatusOK, "", project)
 }
 func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
@@ -65,17 +111,56 @@ func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
+	version, verr := ifMatchVersion(r)
+	if verr != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_IF_MATCH", verr)
+		return
+	}
+	req.IfVersion = version
 	id := r.PathValue("id")
 	project, err := h.service.Update(&req, id)
 	if err != nil {
 		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to update project", err)
 		return
 	}
+	setETag(w, project.Version)
 	h.responder.Success(w, r, http.StatusOK, "", project)
 }
+
+// Patches a project with a JSON Merge Patch or a JSON Patch, chosen by Content-Type
+func (h *ProjectHandler) PatchProject(w http.ResponseWriter, r *http.Request) {
+	format, ok := patchFormat(w, r)
+	if !ok {
+		h.responder.Error(w, r, http.StatusUnsupportedMediaType, "UNSUPPORTED_PATCH_FORMAT", nil)
+		return
+	}
+	version, verr := ifMatchVersion(r)
+	if verr != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_IF_MATCH", verr)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxPatchSize))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusRequestEntityTooLarge, "PATCH_TOO_LARGE", err)
+		return
+	}
+	project, err := h.service.PatchProject(r.PathValue("ws_id"), r.PathValue("id"), format, body, version)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_PATCH_PROJECT", err)
+		return
+	}
+	setETag(w, project.Version)
+	h.responder.Success(w, r, http.StatusOK, "Project Patched Successfully", project)
+}
+
 func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
-	err := h.service.Delete(id)
+	actor, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || actor == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	err := h.service.Delete(r.PathValue("ws_id"), id, actor)
 	if err != nil {
 		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to delete project", err)
 		return
@@ -91,7 +176,8 @@ func (h *ProjectHandler) ListProjectsByWorkspace(w http.ResponseWriter, r *http.
 		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
 		return
 	}
-	projects, err := h.service.ListByWorkspace(wsID, requester)
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	projects, err := h.service.ListByWorkspace(wsID, requester, includeArchived)
 	if err != nil {
 		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to list projects", err)
 		return
@@ -99,29 +185,65 @@ func (h *ProjectHandler) ListProjectsByWorkspace(w http.ResponseWriter, r *http.
 	h.responder.Success(w, r, http.StatusOK, "", projects)
 }
 
+func (h *ProjectHandler) ArchiveProject(w http.ResponseWriter, r *http.Request) {
+	requester, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || requester == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	project, err := h.service.ArchiveProject(r.PathValue("ws_id"), r.PathValue("id"), requester)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_ARCHIVE_PROJECT", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Project Archived Successfully", project)
+}
+
+func (h *ProjectHandler) UnarchiveProject(w http.ResponseWriter, r *http.Request) {
+	requester, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || requester == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	project, err := h.service.UnarchiveProject(r.PathValue("ws_id"), r.PathValue("id"), requester)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_UNARCHIVE_PROJECT", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Project Unarchived Successfully", project)
+}
+
 // ============================================================================
 // TASK METHODS
 // ============================================================================
 type CreateTaskDTO struct {
-	ProjectID   string       `json:"project_id"`
-	ParentID    string       `json:"parent_id"`
-	Name        string       `json:"name"`
-	Description string       `json:"description"`
-	Status      TaskStatus   `json:"status"`
-	Priority    TaskPriority `json:"priority"`
-	DueDate     time.Time    `json:"due_date"`
+	ProjectID    string            `json:"project_id"`
+	ParentID     string            `json:"parent_id"`
+	Name         string            `json:"name"`
+	Description  string            `json:"description"`
+	Status       TaskStatus        `json:"status"`
+	Priority     TaskPriority      `json:"priority"`
+	DueDate      *time.Time        `json:"due_date"`
+	CustomFields CustomFieldValues `json:"custom_fields"`
+
+	OriginalEstimateMinutes  *int `json:"original_estimate_minutes"`
+	RemainingEstimateMinutes *int `json:"remaining_estimate_minutes"`
 }
 
 func (dto *CreateTaskDTO) CreateTaskInput() *CreateTaskInput {
 
 	return &CreateTaskInput{
-		ProjectID:   dto.ProjectID,
-		ParentID:    dto.ParentID,
-		Name:        dto.Name,
-		Description: dto.Description,
-		Status:      dto.Status,
-		Priority:    dto.Priority,
-		DueDate:     dto.DueDate,
+		ProjectID:    dto.ProjectID,
+		ParentID:     dto.ParentID,
+		Name:         dto.Name,
+		Description:  dto.Description,
+		Status:       dto.Status,
+		Priority:     dto.Priority,
+		DueDate:      dto.DueDate,
+		CustomFields: dto.CustomFields,
+
+		OriginalEstimateMinutes:  dto.OriginalEstimateMinutes,
+		RemainingEstimateMinutes: dto.RemainingEstimateMinutes,
 	}
 }
 
@@ -156,6 +278,7 @@ func (h *ProjectHandler) GetTaskByID(w http.ResponseWriter, r *http.Request) {
 		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to get task", err)
 		return
 	}
+	setETag(w, task.Version)
 	h.responder.Success(w, r, http.StatusOK, "Task Retrieved Successfully", task)
 }
 
@@ -166,17 +289,65 @@ func (h *ProjectHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
-	task, err := h.service.UpdateTask(&req, id)
+	actor, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || actor == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	version, verr := ifMatchVersion(r)
+	if verr != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_IF_MATCH", verr)
+		return
+	}
+	req.IfVersion = version
+	task, err := h.service.UpdateTask(&req, id, actor)
 	if err != nil {
 		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to get task", err)
 		return
 	}
+	setETag(w, task.Version)
 	h.responder.Success(w, r, http.StatusAccepted, "Task Updated Successfully", task)
 }
 
+// Patches a task with a JSON Merge Patch or a JSON Patch, chosen by Content-Type
+func (h *ProjectHandler) PatchTask(w http.ResponseWriter, r *http.Request) {
+	actor, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || actor == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	format, ok := patchFormat(w, r)
+	if !ok {
+		h.responder.Error(w, r, http.StatusUnsupportedMediaType, "UNSUPPORTED_PATCH_FORMAT", nil)
+		return
+	}
+	version, verr := ifMatchVersion(r)
+	if verr != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_IF_MATCH", verr)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxPatchSize))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusRequestEntityTooLarge, "PATCH_TOO_LARGE", err)
+		return
+	}
+	task, derr := h.service.PatchTask(r.PathValue("ws_id"), r.PathValue("id"), actor, format, body, version)
+	if derr != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_PATCH_TASK", derr)
+		return
+	}
+	setETag(w, task.Version)
+	h.responder.Success(w, r, http.StatusOK, "Task Patched Successfully", task)
+}
+
 func (h *ProjectHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
-	err := h.service.DeleteTask(id)
+	actor, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || actor == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	err := h.service.DeleteTask(r.PathValue("ws_id"), id, actor)
 	if err != nil {
 		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_DELETE_TASK", err)
 		return
@@ -226,10 +397,27 @@ func (h *ProjectHandler) GetRootTasks(w http.ResponseWriter, r *http.Request) {
 }
 
 // Project queries
-// Lists all root task in a project in a flatlist
+// Lists all task in a project in a flatlist. Repeated ?label= parameters keep the
+// tasks carrying every one of the labels, ?milestone= the tasks of a milestone, ?status= and
+// ?priority= the tasks with that status or priority, ?cf.<key>=[op:]value filters on a custom
+// field and ?sort= orders by a column or cf.<key>, descending with a leading "-".
 func (h *ProjectHandler) ListTasksByProject(w http.ResponseWriter, r *http.Request) {
 	projectID := r.PathValue("id")
-	tasks, err := h.service.ListTasksByProject(projectID)
+	query := r.URL.Query()
+	options := &TaskListOptions{
+		LabelIDs:    query["label"],
+		MilestoneID: query.Get("milestone"),
+		Status:      TaskStatus(query.Get("status")),
+		Priority:    TaskPriority(query.Get("priority")),
+		Fields:      map[string]string{},
+		Sort:        query.Get("sort"),
+	}
+	for param, values := range query {
+		if key, ok := strings.CutPrefix(param, "cf."); ok && len(values) > 0 {
+			options.Fields[key] = values[0]
+		}
+	}
+	tasks, err := h.service.ListTasksByProject(projectID, options)
 	if err != nil {
 		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_TASKS_BY_PROJECT", err)
 		return
@@ -247,3 +435,1061 @@ func (h *ProjectHandler) GetProjectTaskTree(w http.ResponseWriter, r *http.Reque
 	}
 	h.responder.Success(w, r, http.StatusOK, "Tasks Retrieved Successfully", taskTree)
 }
+
+// CUSTOM FIELDS
+
+func (h *ProjectHandler) CreateCustomField(w http.ResponseWriter, r *http.Request) {
+	wsID, projectID := r.PathValue("ws_id"), r.PathValue("id")
+	var req CreateCustomFieldInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	field, err := h.service.CreateCustomField(wsID, projectID, &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_CREATE_CUSTOM_FIELD", err)
+		return
+	}
+	location := "/api/workspace/" + wsID + "/project/" + projectID + "/fields/" + field.ID
+	h.responder.Created(w, r, location, field)
+}
+
+func (h *ProjectHandler) ListCustomFields(w http.ResponseWriter, r *http.Request) {
+	fields, err := h.service.ListCustomFields(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_CUSTOM_FIELDS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Custom Fields Retrieved Successfully", fields)
+}
+
+func (h *ProjectHandler) UpdateCustomField(w http.ResponseWriter, r *http.Request) {
+	var req UpdateCustomFieldInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	field, err := h.service.UpdateCustomField(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("field_id"), &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_UPDATE_CUSTOM_FIELD", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Custom Field Updated Successfully", field)
+}
+
+func (h *ProjectHandler) DeleteCustomField(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteCustomField(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("field_id")); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_DELETE_CUSTOM_FIELD", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// MILESTONES
+
+func (h *ProjectHandler) CreateMilestone(w http.ResponseWriter, r *http.Request) {
+	wsID, projectID := r.PathValue("ws_id"), r.PathValue("id")
+	creator, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || creator == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req CreateMilestoneInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	milestone, err := h.service.CreateMilestone(wsID, projectID, creator, &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_CREATE_MILESTONE", err)
+		return
+	}
+	location := "/api/workspace/" + wsID + "/project/" + projectID + "/milestones/" + milestone.ID
+	h.responder.Created(w, r, location, milestone)
+}
+
+func (h *ProjectHandler) ListMilestones(w http.ResponseWriter, r *http.Request) {
+	milestones, err := h.service.ListMilestones(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_MILESTONES", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Milestones Retrieved Successfully", milestones)
+}
+
+func (h *ProjectHandler) GetMilestone(w http.ResponseWriter, r *http.Request) {
+	milestone, err := h.service.GetMilestone(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("milestone_id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_GET_MILESTONE", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Milestone Retrieved Successfully", milestone)
+}
+
+func (h *ProjectHandler) UpdateMilestone(w http.ResponseWriter, r *http.Request) {
+	var req UpdateMilestoneInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	milestone, err := h.service.UpdateMilestone(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("milestone_id"), &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_UPDATE_MILESTONE", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Milestone Updated Successfully", milestone)
+}
+
+func (h *ProjectHandler) DeleteMilestone(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteMilestone(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("milestone_id")); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_DELETE_MILESTONE", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// Returns the milestone's daily burndown and burnup series
+func (h *ProjectHandler) GetBurndown(w http.ResponseWriter, r *http.Request) {
+	burndown, err := h.service.GetBurndown(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("milestone_id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_GET_BURNDOWN", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Burndown Retrieved Successfully", burndown)
+}
+
+func (h *ProjectHandler) SetTaskMilestone(w http.ResponseWriter, r *http.Request) {
+	actor, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || actor == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req SetTaskMilestoneInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	task, err := h.service.SetTaskMilestone(r.PathValue("ws_id"), r.PathValue("id"), actor, req.MilestoneID)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_SET_TASK_MILESTONE", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Task Milestone Updated Successfully", task)
+}
+
+func (h *ProjectHandler) ListTaskActivity(w http.ResponseWriter, r *http.Request) {
+	events, err := h.service.ListTaskActivity(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_TASK_ACTIVITY", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Task Activity Retrieved Successfully", events)
+}
+
+// TIME TRACKING
+
+type StartTimerRequest struct {
+	Description string `json:"description"`
+}
+
+func (h *ProjectHandler) GetTimeRollup(w http.ResponseWriter, r *http.Request) {
+	rollup, err := h.service.GetTimeRollup(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_GET_TIME_ROLLUP", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Time Retrieved Successfully", rollup)
+}
+
+func (h *ProjectHandler) LogWork(w http.ResponseWriter, r *http.Request) {
+	wsID, taskID := r.PathValue("ws_id"), r.PathValue("id")
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req CreateWorkLogInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	log, err := h.service.LogWork(wsID, taskID, userID, &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LOG_WORK", err)
+		return
+	}
+	location := "/api/workspace/" + wsID + "/task/" + taskID + "/worklogs/" + log.ID
+	h.responder.Created(w, r, location, log)
+}
+
+func (h *ProjectHandler) ListWorkLogs(w http.ResponseWriter, r *http.Request) {
+	logs, err := h.service.ListWorkLogs(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_WORK_LOGS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Work Logs Retrieved Successfully", logs)
+}
+
+func (h *ProjectHandler) DeleteWorkLog(w http.ResponseWriter, r *http.Request) {
+	requester, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || requester == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	if err := h.service.DeleteWorkLog(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("worklog_id"), requester); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_DELETE_WORK_LOG", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+func (h *ProjectHandler) StartTimer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req StartTimerRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+	}
+	timer, err := h.service.StartTimer(r.PathValue("ws_id"), r.PathValue("id"), userID, req.Description)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_START_TIMER", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusCreated, "Timer Started Successfully", timer)
+}
+
+func (h *ProjectHandler) GetTimer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	timer, err := h.service.GetTimer(r.PathValue("ws_id"), userID)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_GET_TIMER", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Timer Retrieved Successfully", timer)
+}
+
+// Stops the user's running timer, logging the elapsed time
+func (h *ProjectHandler) StopTimer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	log, err := h.service.StopTimer(r.PathValue("ws_id"), userID)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_STOP_TIMER", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusCreated, "Timer Stopped Successfully", log)
+}
+
+func (h *ProjectHandler) DiscardTimer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	if err := h.service.DiscardTimer(r.PathValue("ws_id"), userID); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_DISCARD_TIMER", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// Reports the project's logged time by user and week. ?from= and ?to= take RFC 3339
+// times and default to the four weeks up to now.
+func (h *ProjectHandler) Timesheet(w http.ResponseWriter, r *http.Request) {
+	to := time.Now().UTC()
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.responder.Error(w, r, http.StatusBadRequest, "INVALID_TO_TIME", err)
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -28)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.responder.Error(w, r, http.StatusBadRequest, "INVALID_FROM_TIME", err)
+			return
+		}
+		from = parsed
+	}
+	sheet, err := h.service.Timesheet(r.PathValue("ws_id"), r.PathValue("id"), from, to)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_GET_TIMESHEET", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Timesheet Retrieved Successfully", sheet)
+}
+
+// Moves a task, with its subtasks, under another parent or to another project
+func (h *ProjectHandler) MoveTask(w http.ResponseWriter, r *http.Request) {
+	actor, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || actor == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req MoveTaskInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	task, err := h.service.MoveTask(r.PathValue("ws_id"), r.PathValue("id"), actor, &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_MOVE_TASK", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Task Moved Successfully", task)
+}
+
+// BULK OPERATIONS
+
+// Applies status, priority, due date, assignee and label changes to many tasks at once
+func (h *ProjectHandler) BulkUpdateTasks(w http.ResponseWriter, r *http.Request) {
+	actor, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || actor == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req BulkTaskInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	report, err := h.service.BulkUpdateTasks(r.PathValue("ws_id"), actor, &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_BULK_UPDATE_TASKS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Tasks Updated Successfully", report)
+}
+
+// IMPORT AND EXPORT
+
+// Imports tasks into the project from a CSV or JSON body. The format comes from
+// ?format= or the content type; ?dry_run=true only validates the file.
+func (h *ProjectHandler) ImportTasks(w http.ResponseWriter, r *http.Request) {
+	creator, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || creator == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	format := ImportFormat(r.URL.Query().Get("format"))
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			format = ImportFormatCSV
+		case "application/json":
+			format = ImportFormatJSON
+		}
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxImportSize))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusRequestEntityTooLarge, "IMPORT_FILE_TOO_LARGE", err)
+		return
+	}
+
+	report, derr := h.service.ImportTasks(r.PathValue("ws_id"), r.PathValue("id"), creator, format, content, dryRun)
+	if derr != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_IMPORT_TASKS", derr)
+		return
+	}
+	switch {
+	case len(report.Errors) > 0:
+		h.responder.Success(w, r, http.StatusUnprocessableEntity, "Import File Has Errors", report)
+	case dryRun:
+		h.responder.Success(w, r, http.StatusOK, "Import File Is Valid", report)
+	default:
+		h.responder.Success(w, r, http.StatusCreated, "Tasks Imported Successfully", report)
+	}
+}
+
+// Downloads the project's task tree as JSON, CSV or Markdown, chosen by ?format=
+func (h *ProjectHandler) ExportProject(w http.ResponseWriter, r *http.Request) {
+	format := ExportFormat(r.URL.Query().Get("format"))
+	if format == "" {
+		format = ExportFormatJSON
+	}
+	var contentType, extension string
+	switch format {
+	case ExportFormatJSON:
+		contentType, extension = "application/json", "json"
+	case ExportFormatCSV:
+		contentType, extension = "text/csv; charset=utf-8", "csv"
+	case ExportFormatMarkdown:
+		contentType, extension = "text/markdown; charset=utf-8", "md"
+	default:
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_EXPORT_FORMAT", domain_errors.NewValidationErrorWithValue("format", format, "FORMAT MUST BE json, csv OR markdown"))
+		return
+	}
+
+	export, err := h.service.ExportProject(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_EXPORT_PROJECT", err)
+		return
+	}
+	filename := sanitizeFilename(export.Project.Name)
+	if filename == "" {
+		filename = export.Project.ID
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename + "." + extension}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	// the status is sent, so a failed write can only cut the download short
+	switch format {
+	case ExportFormatJSON:
+		_ = json.NewEncoder(w).Encode(export)
+	case ExportFormatCSV:
+		_ = writeExportCSV(w, export)
+	case ExportFormatMarkdown:
+		_ = writeExportMarkdown(w, export)
+	}
+}
+
+// TEMPLATES
+
+func (h *ProjectHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	wsID := r.PathValue("ws_id")
+	creator, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || creator == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req CreateTemplateInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	template, err := h.service.CreateTemplate(wsID, creator, &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_CREATE_TEMPLATE", err)
+		return
+	}
+	location := "/api/workspace/" + wsID + "/template/" + template.ID
+	h.responder.Created(w, r, location, template)
+}
+
+func (h *ProjectHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.service.ListTemplates(r.PathValue("ws_id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_TEMPLATES", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Templates Retrieved Successfully", templates)
+}
+
+func (h *ProjectHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := h.service.GetTemplate(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_GET_TEMPLATE", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Template Retrieved Successfully", template)
+}
+
+func (h *ProjectHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteTemplate(r.PathValue("ws_id"), r.PathValue("id")); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_DELETE_TEMPLATE", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// Creates the template's tasks in a project
+func (h *ProjectHandler) InstantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	creator, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || creator == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req InstantiateTemplateInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	tasks, err := h.service.InstantiateTemplate(r.PathValue("ws_id"), r.PathValue("id"), creator, &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_INSTANTIATE_TEMPLATE", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusCreated, "Template Instantiated Successfully", tasks)
+}
+
+// Copies a project with its custom fields, tasks and labels
+func (h *ProjectHandler) CloneProject(w http.ResponseWriter, r *http.Request) {
+	wsID := r.PathValue("ws_id")
+	creator, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || creator == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req CloneProjectInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	project, err := h.service.CloneProject(wsID, r.PathValue("id"), creator, &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_CLONE_PROJECT", err)
+		return
+	}
+	location := "/api/workspace/" + wsID + "/project/" + project.ID
+	h.responder.Created(w, r, location, project)
+}
+
+// TRASH
+
+func (h *ProjectHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	items, err := h.service.ListTrash(r.PathValue("ws_id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_TRASH", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Trash Retrieved Successfully", items)
+}
+
+func (h *ProjectHandler) RestoreProject(w http.ResponseWriter, r *http.Request) {
+	project, err := h.service.RestoreProject(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_RESTORE_PROJECT", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Project Restored Successfully", project)
+}
+
+func (h *ProjectHandler) RestoreTask(w http.ResponseWriter, r *http.Request) {
+	actor, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || actor == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	task, err := h.service.RestoreTask(r.PathValue("ws_id"), r.PathValue("id"), actor)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_RESTORE_TASK", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Task Restored Successfully", task)
+}
+
+// ASSIGNMENTS
+
+type AssignTaskRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+func (h *ProjectHandler) AssignTask(w http.ResponseWriter, r *http.Request) {
+	assigner, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || assigner == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req AssignTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	assignments, err := h.service.AssignTask(r.PathValue("ws_id"), r.PathValue("id"), assigner, req.UserIDs)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_ASSIGN_TASK", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Task Assigned Successfully", assignments)
+}
+
+func (h *ProjectHandler) UnassignTask(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.UnassignTask(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("user_id")); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_UNASSIGN_TASK", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+func (h *ProjectHandler) ListTaskAssignments(w http.ResponseWriter, r *http.Request) {
+	assignments, err := h.service.ListTaskAssignments(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_TASK_ASSIGNMENTS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Task Assignments Retrieved Successfully", assignments)
+}
+
+// REPORTS
+// Every report covers the project given by ?project=, or the whole workspace.
+
+// reportRange reads the from and to query parameters. By default reports cover
+// the twelve weeks up to the end of today, so repeated requests share a cache entry.
+func reportRange(r *http.Request) (time.Time, time.Time, string, error) {
+	to := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, "INVALID_TO_TIME", err
+		}
+		to = parsed
+	}
+	from := to.Add(-defaultReportRange)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, "INVALID_FROM_TIME", err
+		}
+		from = parsed
+	}
+	return from, to, "", nil
+}
+
+func (h *ProjectHandler) TaskSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.service.TaskSummary(r.PathValue("ws_id"), r.URL.Query().Get("project"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_GET_TASK_SUMMARY", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Task Summary Retrieved Successfully", summary)
+}
+
+func (h *ProjectHandler) Throughput(w http.ResponseWriter, r *http.Request) {
+	from, to, msg, err := reportRange(r)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, msg, err)
+		return
+	}
+	throughput, derr := h.service.Throughput(r.PathValue("ws_id"), r.URL.Query().Get("project"), from, to)
+	if derr != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_GET_THROUGHPUT", derr)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Throughput Retrieved Successfully", throughput)
+}
+
+func (h *ProjectHandler) CycleTime(w http.ResponseWriter, r *http.Request) {
+	from, to, msg, err := reportRange(r)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, msg, err)
+		return
+	}
+	cycle, derr := h.service.CycleTime(r.PathValue("ws_id"), r.URL.Query().Get("project"), from, to)
+	if derr != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_GET_CYCLE_TIME", derr)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Cycle Time Retrieved Successfully", cycle)
+}
+
+func (h *ProjectHandler) Workload(w http.ResponseWriter, r *http.Request) {
+	workload, err := h.service.Workload(r.PathValue("ws_id"), r.URL.Query().Get("project"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_GET_WORKLOAD", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Workload Retrieved Successfully", workload)
+}
+
+// ATTACHMENTS
+
+// Uploads the "file" part of a multipart form as an attachment of the task. The
+// part is streamed, so the file is never held in memory.
+func (h *ProjectHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	wsID := r.PathValue("ws_id")
+	taskID := r.PathValue("id")
+	uploader, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || uploader == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	// leave room for the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, MaxAttachmentSize+1<<20)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_MULTIPART_BODY", err)
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			h.responder.Error(w, r, http.StatusBadRequest, "MISSING_FILE_PART", nil)
+			return
+		}
+		if err != nil {
+			h.responder.Error(w, r, http.StatusBadRequest, "INVALID_MULTIPART_BODY", err)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		attachment, uploadErr := h.service.UploadAttachment(r.Context(), wsID, taskID, uploader, part.FileName(), part)
+		part.Close()
+		if uploadErr != nil {
+			h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_UPLOAD_ATTACHMENT", uploadErr)
+			return
+		}
+		location := "/api/workspace/" + wsID + "/task/" + taskID + "/attachments/" + attachment.ID
+		h.responder.Created(w, r, location, attachment)
+		return
+	}
+}
+
+func (h *ProjectHandler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	attachments, err := h.service.ListAttachments(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_ATTACHMENTS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "", attachments)
+}
+
+// Streams an attachment's content as a download
+func (h *ProjectHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, content, err := h.service.OpenAttachment(r.Context(), r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("attachment_id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_DOWNLOAD_ATTACHMENT", err)
+		return
+	}
+	defer content.Close()
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+attachment.SHA256+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, content)
+}
+
+func (h *ProjectHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	requester, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || requester == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	if err := h.service.DeleteAttachment(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("attachment_id"), requester); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_DELETE_ATTACHMENT", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// COMMENTS
+
+func (h *ProjectHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	wsID, taskID := r.PathValue("ws_id"), r.PathValue("id")
+	author, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || author == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req CommentInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	comment, err := h.service.CreateComment(wsID, taskID, author, &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_CREATE_COMMENT", err)
+		return
+	}
+	location := "/api/workspace/" + wsID + "/task/" + taskID + "/comments/" + comment.ID
+	h.responder.Created(w, r, location, comment)
+}
+
+func (h *ProjectHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	comments, err := h.service.ListComments(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_COMMENTS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Comments Retrieved Successfully", comments)
+}
+
+func (h *ProjectHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	requester, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || requester == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req CommentInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	comment, err := h.service.UpdateComment(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("comment_id"), requester, &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_UPDATE_COMMENT", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Comment Updated Successfully", comment)
+}
+
+func (h *ProjectHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	requester, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || requester == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	if err := h.service.DeleteComment(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("comment_id"), requester); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_DELETE_COMMENT", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// NOTIFICATIONS
+
+// Lists the requester's notifications. ?unread=true leaves out the read ones and
+// ?limit caps how many are returned.
+func (h *ProjectHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	query := r.URL.Query()
+	unreadOnly, _ := strconv.ParseBool(query.Get("unread"))
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			h.responder.Error(w, r, http.StatusBadRequest, "INVALID_LIMIT",
+				domain_errors.NewValidationErrorWithValue("limit", value, "LIMIT MUST BE A POSITIVE NUMBER"))
+			return
+		}
+		limit = parsed
+	}
+	notifications, err := h.service.ListNotifications(r.PathValue("ws_id"), userID, unreadOnly, limit)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_NOTIFICATIONS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Notifications Retrieved Successfully", notifications)
+}
+
+func (h *ProjectHandler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	notification, err := h.service.MarkNotificationRead(r.PathValue("ws_id"), userID, r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_MARK_NOTIFICATION_READ", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Notification Marked Read", notification)
+}
+
+func (h *ProjectHandler) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	count, err := h.service.MarkAllNotificationsRead(r.PathValue("ws_id"), userID)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_MARK_NOTIFICATIONS_READ", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Notifications Marked Read", map[string]int{"marked": count})
+}
+
+// WATCHERS
+
+func (h *ProjectHandler) WatchTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	watchers, err := h.service.WatchTask(r.PathValue("ws_id"), r.PathValue("id"), userID)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_WATCH_TASK", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Task Watched Successfully", watchers)
+}
+
+func (h *ProjectHandler) UnwatchTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	if err := h.service.UnwatchTask(r.PathValue("ws_id"), r.PathValue("id"), userID); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_UNWATCH_TASK", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+func (h *ProjectHandler) ListTaskWatchers(w http.ResponseWriter, r *http.Request) {
+	watchers, err := h.service.ListTaskWatchers(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_TASK_WATCHERS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Task Watchers Retrieved Successfully", watchers)
+}
+
+func (h *ProjectHandler) WatchProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	watchers, err := h.service.WatchProject(r.PathValue("ws_id"), r.PathValue("id"), userID)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_WATCH_PROJECT", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Project Watched Successfully", watchers)
+}
+
+func (h *ProjectHandler) UnwatchProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	if err := h.service.UnwatchProject(r.PathValue("ws_id"), r.PathValue("id"), userID); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_UNWATCH_PROJECT", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+func (h *ProjectHandler) ListProjectWatchers(w http.ResponseWriter, r *http.Request) {
+	watchers, err := h.service.ListProjectWatchers(r.PathValue("ws_id"), r.PathValue("id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_PROJECT_WATCHERS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Project Watchers Retrieved Successfully", watchers)
+}
+
+// LABELS
+
+type TaskLabelsRequest struct {
+	LabelIDs []string `json:"label_ids"`
+}
+
+type MergeLabelRequest struct {
+	Into string `json:"into"`
+}
+
+func (h *ProjectHandler) CreateLabel(w http.ResponseWriter, r *http.Request) {
+	wsID := r.PathValue("ws_id")
+	creator, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || creator == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+	var req CreateLabelInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	label, err := h.service.CreateLabel(wsID, creator, &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_CREATE_LABEL", err)
+		return
+	}
+	location := "/api/workspace/" + wsID + "/label/" + label.ID
+	h.responder.Created(w, r, location, label)
+}
+
+func (h *ProjectHandler) ListLabels(w http.ResponseWriter, r *http.Request) {
+	labels, err := h.service.ListLabels(r.PathValue("ws_id"))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_LIST_LABELS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Labels Retrieved Successfully", labels)
+}
+
+func (h *ProjectHandler) UpdateLabel(w http.ResponseWriter, r *http.Request) {
+	var req UpdateLabelInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	label, err := h.service.UpdateLabel(r.PathValue("ws_id"), r.PathValue("id"), &req)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_UPDATE_LABEL", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Label Updated Successfully", label)
+}
+
+func (h *ProjectHandler) DeleteLabel(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteLabel(r.PathValue("ws_id"), r.PathValue("id")); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_DELETE_LABEL", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// Merges the label in the URL into the label given as "into"
+func (h *ProjectHandler) MergeLabel(w http.ResponseWriter, r *http.Request) {
+	var req MergeLabelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	label, err := h.service.MergeLabel(r.PathValue("ws_id"), r.PathValue("id"), req.Into)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_MERGE_LABEL", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Labels Merged Successfully", label)
+}
+
+func (h *ProjectHandler) AddTaskLabels(w http.ResponseWriter, r *http.Request) {
+	var req TaskLabelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	task, err := h.service.AddTaskLabels(r.PathValue("ws_id"), r.PathValue("id"), req.LabelIDs)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_ADD_TASK_LABELS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Task Labels Updated Successfully", task)
+}
+
+func (h *ProjectHandler) SetTaskLabels(w http.ResponseWriter, r *http.Request) {
+	var req TaskLabelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	task, err := h.service.SetTaskLabels(r.PathValue("ws_id"), r.PathValue("id"), req.LabelIDs)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_SET_TASK_LABELS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "Task Labels Updated Successfully", task)
+}
+
+func (h *ProjectHandler) RemoveTaskLabel(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.RemoveTaskLabel(r.PathValue("ws_id"), r.PathValue("id"), r.PathValue("label_id")); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_REMOVE_TASK_LABEL", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
@@ -0,0 +1,76 @@
+package project
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	domain_middleware "github.com/ishola-faazele/taskflow/internal/middleware"
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+const testProjectID = "3d6e2a91-5b7c-4f08-9e1d-7c4a8b2f6e03"
+
+// memberRepo holds one project of the test workspace, where every requester
+// has the same role
+type memberRepo struct {
+	ProjectRepository
+	role string
+}
+
+func (m memberRepo) GetByID(id string) (*Project, domain_errors.DomainError) {
+	return &Project{ID: id, WorkspaceID: testWorkspaceID}, nil
+}
+
+func (m memberRepo) GetMemberRole(wsID, userID string) (string, domain_errors.DomainError) {
+	return m.role, nil
+}
+
+func (m memberRepo) WorkspaceArchived(wsID string) (bool, domain_errors.DomainError) {
+	return false, nil
+}
+
+func (m memberRepo) SetArchived(id string, archivedAt *time.Time) (*Project, domain_errors.DomainError) {
+	return &Project{ID: id, WorkspaceID: testWorkspaceID}, nil
+}
+
+func TestArchiveProjectStatus(t *testing.T) {
+	cases := []struct {
+		name      string
+		role      string
+		requester string
+		want      int
+	}{
+		{"owner", "owner", "user-1", http.StatusOK},
+		{"admin", "admin", "user-1", http.StatusOK},
+		{"member", "member", "user-1", http.StatusForbidden},
+		{"non-member", "", "user-1", http.StatusForbidden},
+		{"signed out", "admin", "", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		handler := &ProjectHandler{
+			service:   &ProjectService{projectRepo: memberRepo{role: tc.role}},
+			responder: domain_errors.NewAPIResponder(),
+		}
+		for action, handle := range map[string]http.HandlerFunc{
+			"archive":   handler.ArchiveProject,
+			"unarchive": handler.UnarchiveProject,
+		} {
+			t.Run(tc.name+" "+action, func(t *testing.T) {
+				r := httptest.NewRequest("POST", "/workspaces/"+testWorkspaceID+"/projects/"+testProjectID+"/"+action, nil)
+				r.SetPathValue("ws_id", testWorkspaceID)
+				r.SetPathValue("id", testProjectID)
+				if tc.requester != "" {
+					r = r.WithContext(context.WithValue(r.Context(), domain_middleware.UserIDKey, tc.requester))
+				}
+				w := httptest.NewRecorder()
+				handle(w, r)
+				if w.Code != tc.want {
+					t.Fatalf("status = %d, want %d", w.Code, tc.want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package project
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+const (
+	// MaxImportSize bounds the size of an import file
+	MaxImportSize = 10 << 20
+	// maxImportTasks bounds the tasks of an import file
+	maxImportTasks = 5000
+	// customFieldColumnPrefix starts the CSV columns holding custom field values
+	customFieldColumnPrefix = "cf."
+)
+
+type ImportFormat string
+
+const (
+	ImportFormatCSV  ImportFormat = "csv"
+	ImportFormatJSON ImportFormat = "json"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatCSV      ExportFormat = "csv"
+	ExportFormatJSON     ExportFormat = "json"
+	ExportFormatMarkdown ExportFormat = "markdown"
+)
+
+// ImportTaskRow is a task of an import file. ID only names the row so other rows
+// can use it as their parent_id; created tasks get new ids. A parent_id naming no
+// row of the file must be a task of the project. Subtasks nest rows in JSON files.
+type ImportTaskRow struct {
+	ID                       string            `json:"id"`
+	ParentID                 string            `json:"parent_id"`
+	Name                     string            `json:"name"`
+	Description              string            `json:"description"`
+	Status                   TaskStatus        `json:"status"`
+	Priority                 TaskPriority      `json:"priority"`
+	DueDate                  string            `json:"due_date"`
+	OriginalEstimateMinutes  *int              `json:"original_estimate_minutes"`
+	RemainingEstimateMinutes *int              `json:"remaining_estimate_minutes"`
+	CustomFields             CustomFieldValues `json:"custom_fields"`
+	Subtasks                 []*ImportTaskRow  `json:"subtasks"`
+
+	// row is the line of a CSV row, or the position of a JSON row in the file
+	row int
+	// ref names the row for its subtasks, also when it has no ID
+	ref string
+	// parentRef is the ref of the row's parent in the file
+	parentRef string
+}
+
+// ImportError is a problem with a row of an import file. Row 0 concerns the whole file.
+type ImportError struct {
+	Row    int    `json:"row"`
+	ID     string `json:"id,omitempty"`
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ImportReport describes an import. Nothing is created when it has errors or is a dry run.
+type ImportReport struct {
+	DryRun  bool           `json:"dry_run"`
+	Tasks   int            `json:"tasks"`
+	Created int            `json:"created"`
+	Errors  []*ImportError `json:"errors"`
+	// IDs maps the ids of the file's rows to the ids of the tasks created from them
+	IDs map[string]string `json:"ids,omitempty"`
+}
+
+func (report *ImportReport) addError(row *ImportTaskRow, err domain_errors.DomainError) {
+	importErr := &ImportError{Reason: err.Message()}
+	if row != nil {
+		importErr.Row, importErr.ID = row.row, row.ID
+	}
+	var validation *domain_errors.ValidationError
+	if errors.As(err, &validation) {
+		importErr.Field, importErr.Reason = validation.Field, validation.Reason
+	}
+	report.Errors = append(report.Errors, importErr)
+}
+
+// ProjectExport is a project with its whole task tree
+type ProjectExport struct {
+	Project      *Project       `json:"project"`
+	CustomFields []*CustomField `json:"custom_fields"`
+	Tasks        []*TaskTree    `json:"tasks"`
+	ExportedAt   time.Time      `json:"exported_at"`
+}
+
+// parseImportDate reads a due date given as RFC 3339 or as a plain date
+func parseImportDate(value string) (*time.Time, domain_errors.DomainError) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+	parsed, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("due_date", value, "DUE DATE MUST BE RFC 3339 OR A DATE LIKE 2006-01-02")
+	}
+	return &parsed, nil
+}
+
+// parseImport reads the rows of an import file, flattening nested subtasks.
+// Problems with single rows are added to the report; an unreadable file is an error.
+func parseImport(format ImportFormat, content []byte, fields []*CustomField, report *ImportReport) ([]*ImportTaskRow, domain_errors.DomainError) {
+	switch format {
+	case ImportFormatCSV:
+		return parseImportCSV(content, fields, report)
+	case ImportFormatJSON:
+		return parseImportJSON(content)
+	default:
+		return nil, domain_errors.NewValidationErrorWithValue("format", format, "FORMAT MUST BE csv OR json")
+	}
+}
+
+func parseImportJSON(content []byte) ([]*ImportTaskRow, domain_errors.DomainError) {
+	// a file is either a list of tasks or an object holding them, like an export
+	var roots []*ImportTaskRow
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var file struct {
+			Tasks []*ImportTaskRow `json:"tasks"`
+		}
+		if err := json.Unmarshal(trimmed, &file); err != nil {
+			return nil, domain_errors.NewValidationError("file", "FILE IS NOT VALID JSON: "+err.Error())
+		}
+		roots = file.Tasks
+	} else if err := json.Unmarshal(trimmed, &roots); err != nil {
+		return nil, domain_errors.NewValidationError("file", "FILE IS NOT VALID JSON: "+err.Error())
+	}
+
+	rows := []*ImportTaskRow{}
+	var flatten func(parent *ImportTaskRow, children []*ImportTaskRow)
+	flatten = func(parent *ImportTaskRow, children []*ImportTaskRow) {
+		for _, row := range children {
+			if row == nil {
+				continue
+			}
+			rows = append(rows, row)
+			row.row = len(rows)
+			row.ref = row.ID
+			if row.ref == "" {
+				row.ref = fmt.Sprintf("row:%d", row.row)
+			}
+			// nesting takes precedence over the parent_id of a subtask
+			if parent != nil {
+				row.parentRef = parent.ref
+			}
+			flatten(row, row.Subtasks)
+			row.Subtasks = nil
+		}
+	}
+	flatten(nil, roots)
+	return rows, nil
+}
+
+func parseImportCSV(content []byte, fields []*CustomField, report *ImportReport) ([]*ImportTaskRow, domain_errors.DomainError) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []*ImportTaskRow{}, nil
+	}
+	if err != nil {
+		return nil, domain_errors.NewValidationError("file", "FILE IS NOT VALID CSV: "+err.Error())
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, domain_errors.NewValidationError("file", "CSV HEADER MUST HAVE A name COLUMN")
+	}
+	fieldTypes := map[string]CustomFieldType{}
+	for _, field := range fields {
+		fieldTypes[field.Key] = field.Type
+	}
+
+	rows := []*ImportTaskRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, domain_errors.NewValidationError("file", "FILE IS NOT VALID CSV: "+err.Error())
+		}
+		line, _ := reader.FieldPos(0)
+		cell := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := &ImportTaskRow{
+			ID:           cell("id"),
+			ParentID:     cell("parent_id"),
+			Name:         cell("name"),
+			Description:  cell("description"),
+			Status:       TaskStatus(cell("status")),
+			Priority:     TaskPriority(cell("priority")),
+			DueDate:      cell("due_date"),
+			CustomFields: CustomFieldValues{},
+			row:          line,
+		}
+		row.ref = row.ID
+		if row.ref == "" {
+			row.ref = fmt.Sprintf("row:%d", line)
+		}
+		rows = append(rows, row)
+
+		for _, column := range []string{"original_estimate_minutes", "remaining_estimate_minutes"} {
+			value := cell(column)
+			if value == "" {
+				continue
+			}
+			minutes, err := strconv.Atoi(value)
+			if err != nil {
+				report.addError(row, domain_errors.NewValidationErrorWithValue(column, value, "ESTIMATE MUST BE A WHOLE NUMBER OF MINUTES"))
+				continue
+			}
+			if column == "original_estimate_minutes" {
+				row.OriginalEstimateMinutes = &minutes
+			} else {
+				row.RemainingEstimateMinutes = &minutes
+			}
+		}
+		for column, i := range columns {
+			key, ok := strings.CutPrefix(column, customFieldColumnPrefix)
+			if !ok || i >= len(record) || strings.TrimSpace(record[i]) == "" {
+				continue
+			}
+			value := strings.TrimSpace(record[i])
+			if fieldTypes[key] == CustomFieldNumber {
+				row.CustomFields[key] = json.Number(value)
+			} else {
+				row.CustomFields[key] = value
+			}
+		}
+	}
+	return rows, nil
+}
+
+// exportCSVHeader lists the columns of a CSV export. Custom field columns follow.
+var exportCSVHeader = []string{
+	"id", "parent_id", "name", "description", "status", "priority", "due_date",
+	"original_estimate_minutes", "remaining_estimate_minutes", "milestone_id", "labels",
+	"created_at", "updated_at",
+}
+
+// writeExportCSV writes a row per task, parents before their subtasks. The file
+// can be imported back.
+func writeExportCSV(w io.Writer, export *ProjectExport) error {
+	writer := csv.NewWriter(w)
+	header := append([]string{}, exportCSVHeader...)
+	for _, field := range export.CustomFields {
+		header = append(header, customFieldColumnPrefix+field.Key)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	var write func(trees []*TaskTree) error
+	write = func(trees []*TaskTree) error {
+		for _, tree := range trees {
+			task := &tree.Task
+			labels := make([]string, 0, len(task.Labels))
+			for _, label := range task.Labels {
+				labels = append(labels, label.Name)
+			}
+			record := []string{
+				task.ID,
+				derefString(task.ParentID),
+				task.Name,
+				task.Description,
+				string(task.Status),
+				string(task.Priority),
+				formatOptionalTime(task.DueDate),
+				formatOptionalInt(task.OriginalEstimateMinutes),
+				formatOptionalInt(task.RemainingEstimateMinutes),
+				derefString(task.MilestoneID),
+				strings.Join(labels, ";"),
+				task.CreatedAt.Format(time.RFC3339),
+				task.UpdatedAt.Format(time.RFC3339),
+			}
+			for _, field := range export.CustomFields {
+				value, ok := task.CustomFields[field.Key]
+				if !ok || value == nil {
+					record = append(record, "")
+					continue
+				}
+				record = append(record, fmt.Sprint(value))
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+			if err := write(tree.Subtasks); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := write(export.Tasks); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeExportMarkdown writes the project as a nested checklist
+func writeExportMarkdown(w io.Writer, export *ProjectExport) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", escapeMarkdown(export.Project.Name))
+	if export.Project.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", export.Project.Description)
+	}
+	fmt.Fprintf(&b, "_Exported %s_\n\n", export.ExportedAt.Format(time.RFC3339))
+
+	var write func(trees []*TaskTree, depth int)
+	write = func(trees []*TaskTree, depth int) {
+		for _, tree := range trees {
+			indent := strings.Repeat("  ", depth)
+			check := " "
+			if tree.Status == TaskStatusClosed {
+				check = "x"
+			}
+			details := []string{string(tree.Priority), string(tree.Status)}
+			if tree.DueDate != nil {
+				details = append(details, "due "+tree.DueDate.Format(dateLayout))
+			}
+			for _, label := range tree.Labels {
+				details = append(details, "#"+escapeMarkdown(label.Name))
+			}
+			fmt.Fprintf(&b, "%s- [%s] **%s** (%s)\n", indent, check, escapeMarkdown(tree.Name), strings.Join(details, ", "))
+			if tree.Description != "" {
+				for _, line := range strings.Split(tree.Description, "\n") {
+					fmt.Fprintf(&b, "%s  > %s\n", indent, line)
+				}
+			}
+			write(tree.Subtasks, depth+1)
+		}
+	}
+	write(export.Tasks, 0)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "#", `\#`, "<", `\<`, ">", `\>`,
+)
+
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func formatOptionalTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.Format(time.RFC3339)
+}
+
+func formatOptionalInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
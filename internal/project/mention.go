@@ -0,0 +1,141 @@
+package project
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ishola-faazele/taskflow/internal/user"
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+const (
+	// maxCommentLength bounds the characters of a comment
+	maxCommentLength = 10000
+	// maxMentionsPerText bounds the handles read from one description or comment
+	maxMentionsPerText = 50
+	// DefaultNotificationLimit is how many notifications are listed unless asked otherwise
+	DefaultNotificationLimit = 50
+	maxNotificationLimit     = 200
+)
+
+// Mention is a workspace member mentioned as @handle in a task's description, or
+// in one of its comments when CommentID is set
+type Mention struct {
+	ID        string  `json:"id"`
+	TaskID    string  `json:"task_id"`
+	CommentID *string `json:"comment_id,omitempty"`
+	UserID    string  `json:"user_id"`
+	AuthorID  string  `json:"author_id"`
+	// Handle is the text after the @, lowercased
+	Handle    string    `json:"handle"`
+	CreatedAt time.Time `json:"created_at"`
+	// Profile is the mentioned member's public profile, resolved when responding
+	Profile *user.PublicProfile `json:"profile,omitempty"`
+}
+
+type NotificationKind string
+
+const (
+	NotificationMention NotificationKind = "mention"
+	// The kinds below go to the watchers of the task and of its project
+	NotificationTaskCreated   NotificationKind = "task_created"
+	NotificationStatusChanged NotificationKind = "status_changed"
+	NotificationAssigned      NotificationKind = "assigned"
+	NotificationComment       NotificationKind = "comment"
+	NotificationMoved         NotificationKind = "task_moved"
+)
+
+// Notification tells a user about something that happened in a workspace
+type Notification struct {
+	ID          string           `json:"id"`
+	UserID      string           `json:"user_id"`
+	WorkspaceID string           `json:"workspace_id"`
+	Kind        NotificationKind `json:"kind"`
+	TaskID      string           `json:"task_id"`
+	CommentID   *string          `json:"comment_id,omitempty"`
+	ActorID     string           `json:"actor_id"`
+	// Detail is the new status of a status change and the comma separated ids of
+	// the users assigned by an assignment
+	Detail    string     `json:"detail,omitempty"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	// Actor is the public profile of the user who caused the notification
+	Actor *user.PublicProfile `json:"actor,omitempty"`
+}
+
+type CommentInput struct {
+	Content string `json:"content"`
+}
+
+func (input *CommentInput) Validate() domain_errors.DomainError {
+	input.Content = strings.TrimSpace(input.Content)
+	if input.Content == "" {
+		return domain_errors.NewValidationError("content", "CONTENT CANNOT BE EMPTY")
+	}
+	if utf8.RuneCountInString(input.Content) > maxCommentLength {
+		return domain_errors.NewValidationError("content", "CONTENT CANNOT BE LONGER THAN 10000 CHARACTERS")
+	}
+	return nil
+}
+
+// mentionPattern matches @handle, or @ followed by a whole email address, when
+// the @ does not follow a word character, so addresses written out are not mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.+-]+(?:@[\w-]+(?:\.[\w-]+)+)?)`)
+
+// parseMentions returns the lowercased handles mentioned in text, each once, in
+// the order they first appear
+func parseMentions(text string) []string {
+	handles := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		handle := strings.ToLower(match[1])
+		if !strings.Contains(handle, "@") {
+			// a mention can end a sentence
+			handle = strings.TrimRight(handle, ".-")
+		}
+		if handle == "" || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+		if len(handles) == maxMentionsPerText {
+			break
+		}
+	}
+	return handles
+}
+
+// resolveMentions maps each handle to the member it names. A handle with an @ is
+// a whole email address; any other handle is the local part of a member's address
+// and only resolves when exactly one member's address has it.
+func resolveMentions(handles []string, emails map[string]string) map[string]string {
+	byEmail := map[string]string{}
+	byLocalPart := map[string][]string{}
+	for userID, email := range emails {
+		email = strings.ToLower(email)
+		byEmail[email] = userID
+		local, _, _ := strings.Cut(email, "@")
+		byLocalPart[local] = append(byLocalPart[local], userID)
+	}
+	resolved := map[string]string{}
+	for _, handle := range handles {
+		if strings.Contains(handle, "@") {
+			if userID, ok := byEmail[handle]; ok {
+				resolved[handle] = userID
+			}
+		} else if users := byLocalPart[handle]; len(users) == 1 {
+			resolved[handle] = users[0]
+		}
+	}
+	return resolved
+}
+
+// notificationLimit clamps a requested page size, using the default when unset
+func notificationLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultNotificationLimit
+	}
+	return min(limit, maxNotificationLimit)
+}
@@ -0,0 +1,468 @@
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+// PatchFormat is the media type of a PATCH request body
+type PatchFormat string
+
+const (
+	// PatchFormatMerge is a JSON Merge Patch (RFC 7396): the fields to change,
+	// where null clears a field
+	PatchFormatMerge PatchFormat = "application/merge-patch+json"
+	// PatchFormatJSON is a JSON Patch (RFC 6902): operations applied in order
+	PatchFormatJSON PatchFormat = "application/json-patch+json"
+)
+
+const (
+	// MaxPatchSize bounds the body of a PATCH request
+	MaxPatchSize = 1 << 20
+	// maxPatchOperations bounds the operations of a JSON Patch
+	maxPatchOperations = 100
+)
+
+// PatchOperation is an operation of a JSON Patch. Value is nil when the
+// operation has no value, and holds null when the value is null.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// The fields of tasks and projects that are returned but cannot be patched
+var (
+	readOnlyTaskFields    = []string{"id", "parent_id", "project_id", "creator", "created_at", "updated_at", "milestone_id", "labels", "mentions", "version"}
+	readOnlyProjectFields = []string{"id", "workspace_id", "creator", "created_at", "archived_at", "version"}
+)
+
+// mergePatch reads a patch of either format as the merge patch of the fields it
+// changes. A JSON Patch is applied to current, the resource as it is now.
+func mergePatch(format PatchFormat, body []byte, current any) (map[string]json.RawMessage, domain_errors.DomainError) {
+	switch format {
+	case PatchFormatMerge:
+		var patch map[string]json.RawMessage
+		if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+			return nil, domain_errors.NewValidationError("body", "MERGE PATCH MUST BE A JSON OBJECT")
+		}
+		return patch, nil
+	case PatchFormatJSON:
+		var operations []*PatchOperation
+		if err := json.Unmarshal(body, &operations); err != nil {
+			return nil, domain_errors.NewValidationError("body", "JSON PATCH MUST BE AN ARRAY OF OPERATIONS")
+		}
+		if len(operations) > maxPatchOperations {
+			return nil, domain_errors.NewValidationErrorWithValue("body", len(operations), "JSON PATCH CAN HOLD AT MOST 100 OPERATIONS")
+		}
+		before, err := toDocument(current)
+		if err != nil {
+			return nil, err
+		}
+		after, err := toDocument(current)
+		if err != nil {
+			return nil, err
+		}
+		for i, operation := range operations {
+			if after, err = applyPatchOperation(after, operation, i); err != nil {
+				return nil, err
+			}
+		}
+		afterFields, ok := after.(map[string]any)
+		if !ok {
+			return nil, domain_errors.NewValidationError("body", "JSON PATCH MUST LEAVE AN OBJECT")
+		}
+		return diffDocuments(before.(map[string]any), afterFields)
+	}
+	return nil, domain_errors.NewValidationErrorWithValue("format", format, "PATCH FORMAT MUST BE application/merge-patch+json OR application/json-patch+json")
+}
+
+// toDocument returns the JSON form of a resource as maps, slices and values
+func toDocument(resource any) (any, domain_errors.DomainError) {
+	encoded, err := json.Marshal(resource)
+	if err != nil {
+		return nil, domain_errors.NewInternalError("FAILED_TO_ENCODE_PATCH_DOCUMENT", err)
+	}
+	var document any
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		return nil, domain_errors.NewInternalError("FAILED_TO_ENCODE_PATCH_DOCUMENT", err)
+	}
+	return document, nil
+}
+
+// diffDocuments returns the merge patch turning before into after
+func diffDocuments(before, after map[string]any) (map[string]json.RawMessage, domain_errors.DomainError) {
+	patch := map[string]json.RawMessage{}
+	for key, value := range after {
+		previous, existed := before[key]
+		if existed && reflect.DeepEqual(previous, value) {
+			continue
+		}
+		afterObject, isObject := value.(map[string]any)
+		if beforeObject, wasObject := previous.(map[string]any); isObject && wasObject {
+			nested, err := diffDocuments(beforeObject, afterObject)
+			if err != nil {
+				return nil, err
+			}
+			encoded, _ := json.Marshal(nested)
+			patch[key] = encoded
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, domain_errors.NewInternalError("FAILED_TO_ENCODE_PATCH_DOCUMENT", err)
+		}
+		patch[key] = encoded
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			patch[key] = json.RawMessage("null")
+		}
+	}
+	return patch, nil
+}
+
+// applyPatchOperation applies the index-th operation of a JSON Patch to document
+func applyPatchOperation(document any, operation *PatchOperation, index int) (any, domain_errors.DomainError) {
+	field := fmt.Sprintf("operations[%d]", index)
+	path, err := parsePointer(field+".path", operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	value := func() (any, domain_errors.DomainError) {
+		if operation.Value == nil {
+			return nil, domain_errors.NewValidationError(field+".value", "OPERATION REQUIRES A VALUE")
+		}
+		var decoded any
+		if err := json.Unmarshal(operation.Value, &decoded); err != nil {
+			return nil, domain_errors.NewValidationError(field+".value", "VALUE IS NOT VALID JSON")
+		}
+		return decoded, nil
+	}
+
+	switch operation.Op {
+	case "add", "replace":
+		decoded, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(document, path, decoded, operation.Op == "replace", field+".path")
+	case "remove":
+		document, _, err := pointerRemove(document, path, field+".path")
+		return document, err
+	case "move", "copy":
+		from, err := parsePointer(field+".from", operation.From)
+		if err != nil {
+			return nil, err
+		}
+		var moved any
+		if operation.Op == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				return nil, domain_errors.NewValidationError(field+".path", "A VALUE CANNOT BE MOVED INTO ITSELF")
+			}
+			if document, moved, err = pointerRemove(document, from, field+".from"); err != nil {
+				return nil, err
+			}
+		} else {
+			found, ok := pointerGet(document, from)
+			if !ok {
+				return nil, domain_errors.NewValidationErrorWithValue(field+".from", operation.From, "PATH DOES NOT EXIST")
+			}
+			// copies must not share maps or slices with their source
+			if moved, err = toDocument(found); err != nil {
+				return nil, err
+			}
+		}
+		return pointerAdd(document, path, moved, false, field+".path")
+	case "test":
+		expected, err := value()
+		if err != nil {
+			return nil, err
+		}
+		found, ok := pointerGet(document, path)
+		if !ok || !reflect.DeepEqual(found, expected) {
+			return nil, domain_errors.NewInvalidOperationError("json patch", fmt.Sprintf("TEST OF %s FAILED", operation.Path))
+		}
+		return document, nil
+	}
+	return nil, domain_errors.NewValidationErrorWithValue(field+".op", operation.Op, "OP MUST BE add, remove, replace, move, copy OR test")
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its reference tokens
+func parsePointer(field, pointer string) ([]string, domain_errors.DomainError) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, domain_errors.NewValidationErrorWithValue(field, pointer, "PATH MUST BE A JSON POINTER")
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	return len(prefix) <= len(path) && slices.Equal(prefix, path[:len(prefix)])
+}
+
+// arrayIndex reads a token as an index of an array of size elements
+func arrayIndex(token string, size int) (int, bool) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, false
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index >= size {
+		return 0, false
+	}
+	return index, true
+}
+
+func pointerGet(document any, path []string) (any, bool) {
+	for _, token := range path {
+		switch node := document.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, false
+			}
+			document = value
+		case []any:
+			index, ok := arrayIndex(token, len(node))
+			if !ok {
+				return nil, false
+			}
+			document = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return document, true
+}
+
+// pointerAdd adds value at path and returns the document. With replace, the
+// path must already exist.
+func pointerAdd(document any, path []string, value any, replace bool, field string) (any, domain_errors.DomainError) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	missing := domain_errors.NewValidationErrorWithValue(field, "/"+strings.Join(path, "/"), "PATH DOES NOT EXIST")
+	token := path[0]
+	switch node := document.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			if _, ok := node[token]; replace && !ok {
+				return nil, missing
+			}
+			node[token] = value
+			return node, nil
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, missing
+		}
+		updated, err := pointerAdd(child, path[1:], value, replace, field)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = updated
+		return node, nil
+	case []any:
+		if len(path) == 1 {
+			if replace {
+				index, ok := arrayIndex(token, len(node))
+				if !ok {
+					return nil, missing
+				}
+				node[index] = value
+				return node, nil
+			}
+			if token == "-" {
+				return append(node, value), nil
+			}
+			index, ok := arrayIndex(token, len(node)+1)
+			if !ok {
+				return nil, missing
+			}
+			return slices.Insert(node, index, value), nil
+		}
+		index, ok := arrayIndex(token, len(node))
+		if !ok {
+			return nil, missing
+		}
+		updated, err := pointerAdd(node[index], path[1:], value, replace, field)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = updated
+		return node, nil
+	}
+	return nil, missing
+}
+
+// pointerRemove removes the value at path and returns the document and the value
+func pointerRemove(document any, path []string, field string) (any, any, domain_errors.DomainError) {
+	missing := domain_errors.NewValidationErrorWithValue(field, "/"+strings.Join(path, "/"), "PATH DOES NOT EXIST")
+	if len(path) == 0 {
+		return nil, nil, domain_errors.NewValidationError(field, "THE WHOLE DOCUMENT CANNOT BE REMOVED")
+	}
+	token := path[0]
+	switch node := document.(type) {
+	case map[string]any:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, missing
+		}
+		if len(path) == 1 {
+			delete(node, token)
+			return node, child, nil
+		}
+		updated, removed, err := pointerRemove(child, path[1:], field)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[token] = updated
+		return node, removed, nil
+	case []any:
+		index, ok := arrayIndex(token, len(node))
+		if !ok {
+			return nil, nil, missing
+		}
+		if len(path) == 1 {
+			removed := node[index]
+			return slices.Delete(node, index, index+1), removed, nil
+		}
+		updated, removed, err := pointerRemove(node[index], path[1:], field)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[index] = updated
+		return node, removed, nil
+	}
+	return nil, nil, missing
+}
+
+// patchReader turns the fields of a merge patch into input values, gathering
+// the errors of every field
+type patchReader struct {
+	errs fieldErrors
+}
+
+func isNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// decode reads a field's value into target, reporting reason when it does not fit
+func (reader *patchReader) decode(field string, raw json.RawMessage, target any, reason string) bool {
+	if isNull(raw) {
+		reader.errs.add(domain_errors.NewValidationError(field, "FIELD CANNOT BE NULL"))
+		return false
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		reader.errs.add(domain_errors.NewValidationErrorWithValue(field, string(raw), reason))
+		return false
+	}
+	return true
+}
+
+// other reports a field the resource cannot patch
+func (reader *patchReader) other(field string, readOnly []string) {
+	if slices.Contains(readOnly, field) {
+		reader.errs.add(domain_errors.NewValidationError(field, "FIELD IS READ ONLY"))
+		return
+	}
+	reader.errs.add(domain_errors.NewValidationError(field, "UNKNOWN FIELD"))
+}
+
+// sortedFields lists the fields of a patch in a stable order
+func sortedFields(patch map[string]json.RawMessage) []string {
+	fields := make([]string, 0, len(patch))
+	for field := range patch {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+// taskPatchInput reads a merge patch of a task. Null clears the due date and
+// the estimates, and empties the description.
+func taskPatchInput(patch map[string]json.RawMessage) (*UpdateTaskInput, domain_errors.DomainError) {
+	input := &UpdateTaskInput{}
+	reader := &patchReader{}
+	for _, field := range sortedFields(patch) {
+		raw := patch[field]
+		switch field {
+		case "name":
+			reader.decode(field, raw, &input.Name, "NAME MUST BE A STRING")
+		case "description":
+			if isNull(raw) {
+				input.Description = new(string)
+			} else {
+				reader.decode(field, raw, &input.Description, "DESCRIPTION MUST BE A STRING")
+			}
+		case "status":
+			reader.decode(field, raw, &input.Status, "STATUS MUST BE open, in_review OR closed")
+		case "priority":
+			reader.decode(field, raw, &input.Priority, "PRIORITY MUST BE low, medium OR high")
+		case "due_date":
+			if isNull(raw) {
+				input.ClearDueDate = true
+			} else {
+				var dueDate time.Time
+				if reader.decode(field, raw, &dueDate, "DUE DATE MUST BE AN RFC 3339 TIME") {
+					input.DueDate = &dueDate
+				}
+			}
+		case "original_estimate_minutes":
+			if isNull(raw) {
+				input.ClearOriginalEstimateMinutes = true
+			} else {
+				reader.decode(field, raw, &input.OriginalEstimateMinutes, "ESTIMATE MUST BE A WHOLE NUMBER OF MINUTES")
+			}
+		case "remaining_estimate_minutes":
+			if isNull(raw) {
+				input.ClearRemainingEstimateMinutes = true
+			} else {
+				reader.decode(field, raw, &input.RemainingEstimateMinutes, "ESTIMATE MUST BE A WHOLE NUMBER OF MINUTES")
+			}
+		case "custom_fields":
+			// values are merged, so each value is cleared with its own null
+			reader.decode(field, raw, &input.CustomFields, "CUSTOM FIELDS MUST BE AN OBJECT")
+		default:
+			reader.other(field, readOnlyTaskFields)
+		}
+	}
+	return input, reader.errs.err()
+}
+
+// projectPatchInput reads a merge patch of a project. Null empties the description.
+func projectPatchInput(patch map[string]json.RawMessage) (*UpdateProjectInput, domain_errors.DomainError) {
+	input := &UpdateProjectInput{}
+	reader := &patchReader{}
+	for _, field := range sortedFields(patch) {
+		raw := patch[field]
+		switch field {
+		case "name":
+			reader.decode(field, raw, &input.Name, "NAME MUST BE A STRING")
+		case "description":
+			if isNull(raw) {
+				input.Description = new(string)
+			} else {
+				reader.decode(field, raw, &input.Description, "DESCRIPTION MUST BE A STRING")
+			}
+		default:
+			reader.other(field, readOnlyProjectFields)
+		}
+	}
+	return input, reader.errs.err()
+}
@@ -0,0 +1,326 @@
+package project
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// applyPatch applies a JSON Patch to a document, both given as JSON
+func applyPatch(t *testing.T, document, patch string) (any, error) {
+	t.Helper()
+	var current any
+	if err := json.Unmarshal([]byte(document), &current); err != nil {
+		t.Fatalf("document: %v", err)
+	}
+	var operations []*PatchOperation
+	if err := json.Unmarshal([]byte(patch), &operations); err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	for i, operation := range operations {
+		var err error
+		if current, err = applyPatchOperation(current, operation, i); err != nil {
+			return nil, err
+		}
+	}
+	return current, nil
+}
+
+// TestJSONPatchRFC6902Examples runs the examples of RFC 6902 appendix A
+func TestJSONPatchRFC6902Examples(t *testing.T) {
+	cases := []struct {
+		name     string
+		document string
+		patch    string
+		// want is empty when the patch must fail
+		want string
+	}{
+		{
+			name:     "A.1 adding an object member",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:     `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:     "A.2 adding an array element",
+			document: `{"foo": ["bar", "baz"]}`,
+			patch:    `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			want:     `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:     "A.3 removing an object member",
+			document: `{"baz": "qux", "foo": "bar"}`,
+			patch:    `[{"op": "remove", "path": "/baz"}]`,
+			want:     `{"foo": "bar"}`,
+		},
+		{
+			name:     "A.4 removing an array element",
+			document: `{"foo": ["bar", "qux", "baz"]}`,
+			patch:    `[{"op": "remove", "path": "/foo/1"}]`,
+			want:     `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:     "A.5 replacing a value",
+			document: `{"baz": "qux", "foo": "bar"}`,
+			patch:    `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:     `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:     "A.6 moving a value",
+			document: `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch:    `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:     `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:     "A.7 moving an array element",
+			document: `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch:    `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:     `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name:     "A.8 testing a value: success",
+			document: `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch:    `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			want:     `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:     "A.9 testing a value: error",
+			document: `{"baz": "qux"}`,
+			patch:    `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+		},
+		{
+			name:     "A.10 adding a nested member object",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			want:     `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			name:     "A.11 ignoring unrecognized elements",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			want:     `{"foo": "bar", "baz": "qux"}`,
+		},
+		{
+			name:     "A.12 adding to a nonexistent target",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+		},
+		{
+			name:     "A.13 invalid JSON Patch document",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz", "value": "qux", "op": "remove"}]`,
+		},
+		{
+			name:     "A.14 ~ escape ordering",
+			document: `{"/": 9, "~1": 10}`,
+			patch:    `[{"op": "test", "path": "/~01", "value": 10}]`,
+			want:     `{"/": 9, "~1": 10}`,
+		},
+		{
+			name:     "A.15 comparing strings and numbers",
+			document: `{"/": 9, "~1": 10}`,
+			patch:    `[{"op": "test", "path": "/~01", "value": "10"}]`,
+		},
+		{
+			name:     "A.16 adding an array value",
+			document: `{"foo": ["bar"]}`,
+			patch:    `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			want:     `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := applyPatch(t, tc.document, tc.patch)
+			if tc.want == "" {
+				if err == nil {
+					t.Fatalf("patch succeeded with %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("patch: %v", err)
+			}
+			var want any
+			if err := json.Unmarshal([]byte(tc.want), &want); err != nil {
+				t.Fatalf("want: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("document = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		patch string
+	}{
+		{"unknown op", `[{"op": "merge", "path": "/foo", "value": 1}]`},
+		{"add without value", `[{"op": "add", "path": "/baz"}]`},
+		{"replace of missing member", `[{"op": "replace", "path": "/baz", "value": 1}]`},
+		{"remove of missing member", `[{"op": "remove", "path": "/baz"}]`},
+		{"remove of the whole document", `[{"op": "remove", "path": ""}]`},
+		{"path not a pointer", `[{"op": "add", "path": "baz", "value": 1}]`},
+		{"array index with leading zero", `[{"op": "add", "path": "/list/01", "value": 1}]`},
+		{"array index past the end", `[{"op": "add", "path": "/list/3", "value": 1}]`},
+		{"copy from missing member", `[{"op": "copy", "from": "/baz", "path": "/qux"}]`},
+		{"move into itself", `[{"op": "move", "from": "/obj", "path": "/obj/inner"}]`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, err := applyPatch(t, `{"foo": "bar", "list": [1, 2], "obj": {"a": 1}}`, tc.patch); err == nil {
+				t.Fatalf("patch succeeded with %v, want an error", got)
+			}
+		})
+	}
+}
+
+func TestJSONPatchCopyDoesNotShareValues(t *testing.T) {
+	got, err := applyPatch(t, `{"a": {"b": 1}}`, `[
+		{"op": "copy", "from": "/a", "path": "/c"},
+		{"op": "replace", "path": "/c/b", "value": 2}
+	]`)
+	if err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	want := map[string]any{"a": map[string]any{"b": 1.0}, "c": map[string]any{"b": 2.0}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("document = %v, want %v", got, want)
+	}
+}
+
+func TestMergePatchOfJSONPatch(t *testing.T) {
+	due := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	current := &Task{ID: "task-1", Name: "Write report", Description: "draft", DueDate: &due}
+	cases := []struct {
+		name  string
+		patch string
+		want  map[string]string
+	}{
+		{
+			name:  "replace a field",
+			patch: `[{"op": "replace", "path": "/name", "value": "Send report"}]`,
+			want:  map[string]string{"name": `"Send report"`},
+		},
+		{
+			name:  "remove a field becomes null",
+			patch: `[{"op": "remove", "path": "/due_date"}]`,
+			want:  map[string]string{"due_date": "null"},
+		},
+		{
+			name:  "a passing test changes nothing",
+			patch: `[{"op": "test", "path": "/description", "value": "draft"}]`,
+			want:  map[string]string{},
+		},
+		{
+			name:  "a value set back to what it was changes nothing",
+			patch: `[{"op": "replace", "path": "/name", "value": "x"}, {"op": "replace", "path": "/name", "value": "Write report"}]`,
+			want:  map[string]string{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			patch, err := mergePatch(PatchFormatJSON, []byte(tc.patch), current)
+			if err != nil {
+				t.Fatalf("merge patch: %v", err)
+			}
+			got := map[string]string{}
+			for field, raw := range patch {
+				got[field] = string(raw)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("merge patch = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMergePatchFormats(t *testing.T) {
+	cases := []struct {
+		name    string
+		format  PatchFormat
+		body    string
+		wantErr bool
+	}{
+		{"merge patch object", PatchFormatMerge, `{"name": "x"}`, false},
+		{"merge patch array", PatchFormatMerge, `[{"name": "x"}]`, true},
+		{"merge patch null", PatchFormatMerge, `null`, true},
+		{"json patch object", PatchFormatJSON, `{"op": "add"}`, true},
+		{"json patch replacing the document", PatchFormatJSON, `[{"op": "replace", "path": "", "value": 1}]`, true},
+		{"unknown format", PatchFormat("application/json"), `{"name": "x"}`, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := mergePatch(tc.format, []byte(tc.body), &Task{ID: "task-1"})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestTaskPatchInputDueDate(t *testing.T) {
+	due := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name      string
+		format    PatchFormat
+		body      string
+		wantDue   *time.Time
+		wantClear bool
+		wantErr   bool
+	}{
+		{"merge patch null clears", PatchFormatMerge, `{"due_date": null}`, nil, true, false},
+		{"json patch remove clears", PatchFormatJSON, `[{"op": "remove", "path": "/due_date"}]`, nil, true, false},
+		{"json patch null clears", PatchFormatJSON, `[{"op": "replace", "path": "/due_date", "value": null}]`, nil, true, false},
+		{"merge patch sets", PatchFormatMerge, `{"due_date": "2026-03-01T12:00:00Z"}`, &due, false, false},
+		{"merge patch not a time", PatchFormatMerge, `{"due_date": "tomorrow"}`, nil, false, true},
+		{"absent leaves it", PatchFormatMerge, `{"name": "x"}`, nil, false, false},
+	}
+	current := &Task{ID: "task-1", Name: "Write report", DueDate: &due}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			patch, err := mergePatch(tc.format, []byte(tc.body), current)
+			if err != nil {
+				t.Fatalf("merge patch: %v", err)
+			}
+			input, err := taskPatchInput(patch)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if input.ClearDueDate != tc.wantClear {
+				t.Fatalf("ClearDueDate = %v, want %v", input.ClearDueDate, tc.wantClear)
+			}
+			if (input.DueDate == nil) != (tc.wantDue == nil) || (tc.wantDue != nil && !input.DueDate.Equal(*tc.wantDue)) {
+				t.Fatalf("DueDate = %v, want %v", input.DueDate, tc.wantDue)
+			}
+		})
+	}
+}
+
+func TestTaskPatchInputRejectsFields(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+	}{
+		{"read only", `{"created_at": "2026-01-01T00:00:00Z"}`},
+		{"unknown", `{"colour": "red"}`},
+		{"null name", `{"name": null}`},
+		{"wrong type", `{"priority": 3}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			patch, err := mergePatch(PatchFormatMerge, []byte(tc.body), &Task{})
+			if err != nil {
+				t.Fatalf("merge patch: %v", err)
+			}
+			if _, err := taskPatchInput(patch); err == nil {
+				t.Fatal("patch accepted, want a validation error")
+			}
+		})
+	}
+}
@@ -2,10 +2,17 @@ package project
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/ishola-faazele/taskflow/internal/user"
 	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type PostgresProjectRepository struct {
@@ -15,12 +22,19 @@ type PostgresProjectRepository struct {
 func NewPostgresProjectRepository(db *sql.DB) *PostgresProjectRepository {
 	return &PostgresProjectRepository{db: db}
 }
+
+const projectColumns = `id, name, description, workspace_id, creator, created_at, archived_at, version`
+
+func scanProject(row interface{ Scan(dest ...any) error }, project *Project) error {
+	return row.Scan(&project.ID, &project.Name, &project.Description, &project.WorkspaceID, &project.Creator, &project.CreatedAt, &project.ArchivedAt, &project.Version)
+}
+
 func (r *PostgresProjectRepository) Create(project *Project) (*Project, domain_errors.DomainError) {
 	query := `INSERT INTO project (id, name, description, workspace_id, creator, created_at)
-			  VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, name, description, workspace_id, creator, created_at`
+			  VALUES ($1, $2, $3, $4, $5, $6) RETURNING ` + projectColumns
 	row := r.db.QueryRow(query, project.ID, project.Name, project.Description, project.WorkspaceID, project.Creator, project.CreatedAt)
 	var createdProject Project
-	err := row.Scan(&createdProject.ID, &createdProject.Name, &createdProject.Description, &createdProject.WorkspaceID, &createdProject.Creator, &createdProject.CreatedAt)
+	err := scanProject(row, &createdProject)
 	if err != nil {
 		return nil, domain_errors.NewDatabaseError("project creation", err)
 	}
@@ -28,10 +42,10 @@ func (r *PostgresProjectRepository) Create(project *Project) (*Project, domain_e
 }
 
 func (r *PostgresProjectRepository) GetByID(id string) (*Project, domain_errors.DomainError) {
-	query := `SELECT id, name, description, workspace_id, creator, created_at FROM project WHERE id = $1`
+	query := `SELECT ` + projectColumns + ` FROM project WHERE id = $1 AND deleted_at IS NULL`
 	row := r.db.QueryRow(query, id)
 	var project Project
-	err := row.Scan(&project.ID, &project.Name, &project.Description, &project.WorkspaceID, &project.Creator, &project.CreatedAt)
+	err := scanProject(row, &project)
 	if err != nil {
 		return nil, domain_errors.NewNotFoundError("Project", id)
 	}
@@ -39,19 +53,37 @@ func (r *PostgresProjectRepository) GetByID(id string) (*Project, domain_errors.
 }
 
 func (r *PostgresProjectRepository) Update(input *UpdateProjectInput, id string) (*Project, domain_errors.DomainError) {
-	query := `UPDATE project SET name = COALESCE($1, name), description = COALESCE($2, description) WHERE id = $3 RETURNING id, name, description, workspace_id, creator, created_at`
-	row := r.db.QueryRow(query, input.Name, input.Description, id)
+	query := `
+		UPDATE project SET name = COALESCE($1, name), description = COALESCE($2, description), version = version + 1
+		WHERE id = $3 AND deleted_at IS NULL AND ($4::int IS NULL OR version = $4)
+		RETURNING ` + projectColumns
+	row := r.db.QueryRow(query, input.Name, input.Description, id, input.IfVersion)
 	var updatedProject Project
-	err := row.Scan(&updatedProject.ID, &updatedProject.Name, &updatedProject.Description, &updatedProject.WorkspaceID, &updatedProject.Creator, &updatedProject.CreatedAt)
+	err := scanProject(row, &updatedProject)
 	if err != nil {
-		return nil, domain_errors.NewNotFoundError("Project", id)
+		if err != sql.ErrNoRows {
+			return nil, domain_errors.NewDatabaseError("project update", err)
+		}
+		// the project is gone, or was changed since the client read it
+		current, err := r.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, domain_errors.NewVersionConflictError("project", id, current)
 	}
 	return &updatedProject, nil
 }
 
-func (r *PostgresProjectRepository) Delete(id string) domain_errors.DomainError {
-	query := `DELETE FROM project WHERE id = $1`
-	result, err := r.db.Exec(query, id)
+func (r *PostgresProjectRepository) Delete(id, actor string, at time.Time) domain_errors.DomainError {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return domain_errors.NewDatabaseError("project deletion", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.Exec(`UPDATE project SET deleted_at = $2, deleted_by = $3 WHERE id = $1 AND deleted_at IS NULL`, id, at, actor)
 	if err != nil {
 		return domain_errors.NewDatabaseError("project deletion", err)
 	}
@@ -62,12 +94,20 @@ func (r *PostgresProjectRepository) Delete(id string) domain_errors.DomainError
 	if rowsAffected == 0 {
 		return domain_errors.NewNotFoundError("Project", id)
 	}
+	// the tasks share the project's deletion time, which tells them apart from
+	// the tasks that were already in the trash when it is restored
+	if _, err := tx.Exec(`UPDATE task SET deleted_at = $2, deleted_by = $3 WHERE project_id = $1 AND deleted_at IS NULL`, id, at, actor); err != nil {
+		return domain_errors.NewDatabaseError("project deletion", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return domain_errors.NewDatabaseError("project deletion", err)
+	}
 	return nil
 }
 
-func (r *PostgresProjectRepository) ListByWorkspace(wsID string) ([]*Project, domain_errors.DomainError) {
-	query := `SELECT id, name, description, workspace_id, creator, created_at FROM project WHERE workspace_id = $1`
-	rows, err := r.db.Query(query, wsID)
+func (r *PostgresProjectRepository) ListByWorkspace(wsID string, includeArchived bool) ([]*Project, domain_errors.DomainError) {
+	query := `SELECT ` + projectColumns + ` FROM project WHERE workspace_id = $1 AND deleted_at IS NULL AND ($2 OR archived_at IS NULL)`
+	rows, err := r.db.Query(query, wsID, includeArchived)
 	if err != nil {
 		return nil, domain_errors.NewDatabaseError("project listing", err)
 	}
@@ -76,7 +116,7 @@ func (r *PostgresProjectRepository) ListByWorkspace(wsID string) ([]*Project, do
 	var projects []*Project
 	for rows.Next() {
 		var project Project
-		if err := rows.Scan(&project.ID, &project.Name, &project.Description, &project.WorkspaceID, &project.Creator, &project.CreatedAt); err != nil {
+		if err := scanProject(rows, &project); err != nil {
 			return nil, domain_errors.NewDatabaseError("project listing", err)
 		}
 		projects = append(projects, &project)
@@ -87,18 +127,214 @@ func (r *PostgresProjectRepository) ListByWorkspace(wsID string) ([]*Project, do
 	return projects, nil
 }
 
+func (r *PostgresProjectRepository) SetArchived(id string, archivedAt *time.Time) (*Project, domain_errors.DomainError) {
+	query := `UPDATE project SET archived_at = $2, version = version + 1 WHERE id = $1 AND deleted_at IS NULL RETURNING ` + projectColumns
+	project := &Project{}
+	if err := scanProject(r.db.QueryRow(query, id, archivedAt), project); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("Project", id)
+		}
+		return nil, domain_errors.NewDatabaseError("project archiving", err)
+	}
+	return project, nil
+}
+
+func (r *PostgresProjectRepository) WorkspaceArchived(wsID string) (bool, domain_errors.DomainError) {
+	var archived bool
+	if err := r.db.QueryRow(`SELECT archived_at IS NOT NULL FROM workspace WHERE id = $1`, wsID).Scan(&archived); err != nil {
+		if err == sql.ErrNoRows {
+			return false, domain_errors.NewNotFoundError("workspace", wsID)
+		}
+		return false, domain_errors.NewDatabaseError("workspace archive state query", err)
+	}
+	return archived, nil
+}
+
+func (r *PostgresProjectRepository) ProjectArchiveState(projectID string) (*ArchiveState, domain_errors.DomainError) {
+	query := `
+		SELECT p.archived_at IS NOT NULL, w.archived_at IS NOT NULL
+		FROM project p
+		INNER JOIN workspace w ON w.id = p.workspace_id
+		WHERE p.id = $1
+	`
+	state := &ArchiveState{}
+	if err := r.db.QueryRow(query, projectID).Scan(&state.Project, &state.Workspace); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("Project", projectID)
+		}
+		return nil, domain_errors.NewDatabaseError("project archive state query", err)
+	}
+	return state, nil
+}
+
+func (r *PostgresProjectRepository) TaskArchiveState(taskIDs []string) (*ArchiveState, domain_errors.DomainError) {
+	query := `
+		SELECT COALESCE(BOOL_OR(p.archived_at IS NOT NULL), FALSE), COALESCE(BOOL_OR(w.archived_at IS NOT NULL), FALSE)
+		FROM task t
+		INNER JOIN project p ON p.id = t.project_id
+		INNER JOIN workspace w ON w.id = p.workspace_id
+		WHERE t.id = ANY($1)
+	`
+	state := &ArchiveState{}
+	if err := r.db.QueryRow(query, taskIDs).Scan(&state.Project, &state.Workspace); err != nil {
+		return nil, domain_errors.NewDatabaseError("task archive state query", err)
+	}
+	return state, nil
+}
+
+func (r *PostgresProjectRepository) GetMemberRole(wsID, userID string) (string, domain_errors.DomainError) {
+	var role string
+	if err := r.db.QueryRow(`SELECT role FROM membership WHERE workspace_id = $1 AND user_id = $2`, wsID, userID).Scan(&role); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", domain_errors.NewDatabaseError("membership role query", err)
+	}
+	return role, nil
+}
+
 // ============================================================================
 // TASK METHODS
 // ============================================================================
 
+const taskColumns = `id, parent_id, project_id, name, description, creator, status, priority, due_date, created_at, updated_at, custom_fields, original_estimate_minutes, remaining_estimate_minutes, milestone_id, version`
+
+// qualifiedTaskColumns prefixes the task columns with a table alias
+func qualifiedTaskColumns(alias string) string {
+	columns := strings.Split(taskColumns, ", ")
+	for i, column := range columns {
+		columns[i] = alias + "." + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+func scanTask(row interface{ Scan(dest ...any) error }, task *Task, extra ...any) error {
+	return row.Scan(append([]any{
+		&task.ID,
+		&task.ParentID,
+		&task.ProjectID,
+		&task.Name,
+		&task.Description,
+		&task.Creator,
+		&task.Status,
+		&task.Priority,
+		&task.DueDate,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+		&task.CustomFields,
+		&task.OriginalEstimateMinutes,
+		&task.RemainingEstimateMinutes,
+		&task.MilestoneID,
+		&task.Version,
+	}, extra...)...)
+}
+
+// queryTasks runs a query selecting taskColumns and scans every row
+func (r *PostgresProjectRepository) queryTasks(operation, query string, args ...any) ([]*Task, domain_errors.DomainError) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError(operation+" query", err)
+	}
+	defer rows.Close()
+
+	var tasks []*Task
+	for rows.Next() {
+		task := &Task{}
+		if err := scanTask(rows, task); err != nil {
+			return nil, domain_errors.NewDatabaseError(operation+" scan", err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError(operation+" iteration", err)
+	}
+
+	return tasks, nil
+}
+
 func (r *PostgresProjectRepository) CreateTask(task *Task) (*Task, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("task creation transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, derr := insertTask(tx, task)
+	if derr != nil {
+		return nil, derr
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("task creation commit", err)
+	}
+	return result, nil
+}
+
+func (r *PostgresProjectRepository) ImportTasks(tasks []*Task) domain_errors.DomainError {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return domain_errors.NewDatabaseError("task import transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, task := range tasks {
+		if _, err := insertTask(tx, task); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain_errors.NewDatabaseError("task import commit", err)
+	}
+	return nil
+}
+
+func (r *PostgresProjectRepository) CloneProject(project *Project, fields []*CustomField, tasks []*Task) (*Project, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("project clone transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := `INSERT INTO project (id, name, description, workspace_id, creator, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6) RETURNING ` + projectColumns
+	row := tx.QueryRow(query, project.ID, project.Name, project.Description, project.WorkspaceID, project.Creator, project.CreatedAt)
+	created := &Project{}
+	if err := scanProject(row, created); err != nil {
+		return nil, domain_errors.NewDatabaseError("project clone", err)
+	}
+	for _, field := range fields {
+		if _, err := insertCustomField(tx, field); err != nil {
+			return nil, err
+		}
+	}
+	for _, task := range tasks {
+		if _, err := insertTask(tx, task); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("project clone commit", err)
+	}
+	return created, nil
+}
+
+// insertTask stores the task, its labels and its created event
+func insertTask(tx *sql.Tx, task *Task) (*Task, domain_errors.DomainError) {
 	query := `
-		INSERT INTO task (id, parent_id, project_id, name, description,creator,  status, priority, due_date, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		RETURNING id, parent_id, project_id, name, description, creator, status, priority, due_date, created_at, updated_at
-	`
+		INSERT INTO task (` + taskColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, 1)
+		RETURNING ` + taskColumns
 
-	row := r.db.QueryRow(
+	row := tx.QueryRow(
 		query,
 		task.ID,
 		task.ParentID,
@@ -111,51 +347,54 @@ func (r *PostgresProjectRepository) CreateTask(task *Task) (*Task, domain_errors
 		task.DueDate,
 		task.CreatedAt,
 		task.UpdatedAt,
+		task.CustomFields,
+		task.OriginalEstimateMinutes,
+		task.RemainingEstimateMinutes,
+		task.MilestoneID,
 	)
 
 	result := &Task{}
-	err := row.Scan(
-		&result.ID,
-		&result.ParentID,
-		&result.ProjectID,
-		&result.Name,
-		&result.Description,
-		&result.Creator,
-		&result.Status,
-		&result.Priority,
-		&result.DueDate,
-		&result.CreatedAt,
-		&result.UpdatedAt,
-	)
-	if err != nil {
+	if err := scanTask(row, result); err != nil {
 		return nil, domain_errors.NewDatabaseError("task creation", err)
 	}
-
+	if len(task.Labels) > 0 {
+		labelIDs := make([]string, len(task.Labels))
+		for i, label := range task.Labels {
+			labelIDs[i] = label.ID
+		}
+		insert := `
+			INSERT INTO task_label (task_id, label_id, created_at)
+			SELECT $1, label_id, $3 FROM unnest($2::text[]) AS label_id
+			ON CONFLICT (task_id, label_id) DO NOTHING
+		`
+		if _, err := tx.Exec(insert, result.ID, labelIDs, result.CreatedAt); err != nil {
+			return nil, domain_errors.NewDatabaseError("task labels creation", err)
+		}
+		result.Labels = task.Labels
+	}
+	if err := insertTaskEvent(tx, result, task.Creator, TaskEventCreated, nil, nil, result.CreatedAt); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
-func (r *PostgresProjectRepository) GetTaskByID(id string) (*Task, domain_errors.DomainError) {
+// insertTaskEvent records a change of the task, snapshotting its state afterwards
+func insertTaskEvent(tx *sql.Tx, task *Task, actor string, kind TaskEventKind, from, to *string, at time.Time) domain_errors.DomainError {
 	query := `
-		SELECT id, parent_id, project_id, name, description, creator, status, priority, due_date, created_at, updated_at
-		FROM task
-		WHERE id = $1
+		INSERT INTO task_activity (id, task_id, actor_id, kind, from_value, to_value, status, milestone_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
+	if _, err := tx.Exec(query, uuid.NewString(), task.ID, actor, kind, from, to, task.Status, task.MilestoneID, at); err != nil {
+		return domain_errors.NewDatabaseError("task activity creation", err)
+	}
+	return nil
+}
+
+func (r *PostgresProjectRepository) GetTaskByID(id string) (*Task, domain_errors.DomainError) {
+	query := `SELECT ` + taskColumns + ` FROM task WHERE id = $1 AND deleted_at IS NULL`
 
 	task := &Task{}
-	err := r.db.QueryRow(query, id).Scan(
-		&task.ID,
-		&task.ParentID,
-		&task.ProjectID,
-		&task.Name,
-		&task.Description,
-		&task.Creator,
-		&task.Status,
-		&task.Priority,
-		&task.DueDate,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-	)
-	if err != nil {
+	if err := scanTask(r.db.QueryRow(query, id), task); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain_errors.NewNotFoundError("task", id)
 		}
@@ -165,9 +404,26 @@ func (r *PostgresProjectRepository) GetTaskByID(id string) (*Task, domain_errors
 	return task, nil
 }
 
-func (r *PostgresProjectRepository) UpdateTask(input *UpdateTaskInput, id string) (*Task, domain_errors.DomainError) {
-	query := `UPDATE task SET updated_at = $1`
-	args := []interface{}{time.Now().UTC()}
+func (r *PostgresProjectRepository) UpdateTask(input *UpdateTaskInput, id, actor string) (*Task, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("task update transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	previous := &Task{}
+	if err := scanTask(tx.QueryRow(`SELECT `+taskColumns+` FROM task WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id), previous); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("task", id)
+		}
+		return nil, domain_errors.NewDatabaseError("task update", err)
+	}
+
+	now := time.Now().UTC()
+	query := `UPDATE task SET updated_at = $1, version = version + 1`
+	args := []interface{}{now}
 	argIdx := 2
 
 	if input.Name != nil {
@@ -194,145 +450,619 @@ func (r *PostgresProjectRepository) UpdateTask(input *UpdateTaskInput, id string
 		query += fmt.Sprintf(", due_date = $%d", argIdx)
 		args = append(args, *input.DueDate)
 		argIdx++
+	} else if input.ClearDueDate {
+		query += ", due_date = NULL"
+	}
+	if input.OriginalEstimateMinutes != nil {
+		query += fmt.Sprintf(", original_estimate_minutes = $%d", argIdx)
+		args = append(args, *input.OriginalEstimateMinutes)
+		argIdx++
+	} else if input.ClearOriginalEstimateMinutes {
+		query += ", original_estimate_minutes = NULL"
+	}
+	if input.RemainingEstimateMinutes != nil {
+		query += fmt.Sprintf(", remaining_estimate_minutes = $%d", argIdx)
+		args = append(args, *input.RemainingEstimateMinutes)
+		argIdx++
+	} else if input.ClearRemainingEstimateMinutes {
+		query += ", remaining_estimate_minutes = NULL"
+	}
+	if len(input.CustomFields) > 0 {
+		// merged into the stored values, with nulls removing values
+		query += fmt.Sprintf(", custom_fields = jsonb_strip_nulls(custom_fields || $%d::jsonb)", argIdx)
+		args = append(args, input.CustomFields)
+		argIdx++
 	}
 
-	query += fmt.Sprintf(" WHERE id = $%d", argIdx)
-	args = append(args, id)
+	query += fmt.Sprintf(" WHERE id = $%d AND ($%d::int IS NULL OR version = $%d)", argIdx, argIdx+1, argIdx+1)
+	args = append(args, id, input.IfVersion)
 
-	query += ` RETURNING id, parent_id, project_id, name, description, creator, status, priority, due_date, created_at, updated_at`
+	query += ` RETURNING ` + taskColumns
 
 	task := &Task{}
-	err := r.db.QueryRow(query, args...).Scan(
-		&task.ID,
-		&task.ParentID,
-		&task.ProjectID,
-		&task.Name,
-		&task.Description,
-		&task.Creator,
-		&task.Status,
-		&task.Priority,
-		&task.DueDate,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-	)
-	if err != nil {
+	if err := scanTask(tx.QueryRow(query, args...), task); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, domain_errors.NewNotFoundError("task", id)
+			// the row is locked, so only a stale version leaves it unmatched
+			return nil, domain_errors.NewVersionConflictError("task", id, previous)
 		}
 		return nil, domain_errors.NewDatabaseError("task update", err)
 	}
+	if task.Status != previous.Status {
+		from, to := string(previous.Status), string(task.Status)
+		if err := insertTaskEvent(tx, task, actor, TaskEventStatusChanged, &from, &to, now); err != nil {
+			return nil, err
+		}
+	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("task update commit", err)
+	}
 	return task, nil
 }
 
-func (r *PostgresProjectRepository) DeleteTask(id string) domain_errors.DomainError {
-	query := `DELETE FROM task WHERE id = $1`
-
-	result, err := r.db.Exec(query, id)
+func (r *PostgresProjectRepository) SetTaskMilestone(taskID string, milestoneID *string, actor string, at time.Time) (*Task, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return domain_errors.NewDatabaseError("task deletion", err)
+		return nil, domain_errors.NewDatabaseError("task milestone transaction", err)
 	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return domain_errors.NewDatabaseError("task deletion", err)
+	var previous *string
+	if err := tx.QueryRow(`SELECT milestone_id FROM task WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, taskID).Scan(&previous); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("task", taskID)
+		}
+		return nil, domain_errors.NewDatabaseError("task milestone update", err)
 	}
 
-	if rows == 0 {
-		return domain_errors.NewNotFoundError("task", id)
+	query := `UPDATE task SET milestone_id = $2, updated_at = $3, version = version + 1 WHERE id = $1 RETURNING ` + taskColumns
+	task := &Task{}
+	if err := scanTask(tx.QueryRow(query, taskID, milestoneID, at), task); err != nil {
+		return nil, domain_errors.NewDatabaseError("task milestone update", err)
+	}
+	if !equalIDs(previous, milestoneID) {
+		if err := insertTaskEvent(tx, task, actor, TaskEventMilestoneChanged, previous, milestoneID, at); err != nil {
+			return nil, err
+		}
 	}
 
-	return nil
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("task milestone commit", err)
+	}
+	return task, nil
 }
 
-func (r *PostgresProjectRepository) ListSubtasks(parentID string) ([]*Task, domain_errors.DomainError) {
-	query := `
-		SELECT id, parent_id, project_id, name, description, status, creator, priority, due_date, created_at, updated_at
-		FROM task
-		WHERE parent_id = $1
-		ORDER BY created_at ASC
-	`
-
-	rows, err := r.db.Query(query, parentID)
+func (r *PostgresProjectRepository) MoveTask(taskID string, parentID *string, projectID string, fields []*CustomField, maxDepth int, actor string, at time.Time) (*Task, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return nil, domain_errors.NewDatabaseError("subtasks query", err)
+		return nil, domain_errors.NewDatabaseError("task move transaction", err)
 	}
-	defer rows.Close()
+	defer func() {
+		_ = tx.Rollback()
+	}()
 
-	var tasks []*Task
-	for rows.Next() {
-		task := &Task{}
-		err := rows.Scan(
-			&task.ID,
-			&task.ParentID,
-			&task.ProjectID,
-			&task.Name,
-			&task.Description,
-			&task.Creator,
-			&task.Status,
-			&task.Priority,
-			&task.DueDate,
-			&task.CreatedAt,
-			&task.UpdatedAt,
+	// moves within a workspace run one at a time. Moving A under B and B under A
+	// at once would otherwise each lock a different subtree, both find no cycle
+	// and leave a parent loop that the recursive queries never leave.
+	if _, err := tx.Exec(`
+		SELECT pg_advisory_xact_lock(hashtext('task_move'), hashtext(p.workspace_id))
+		FROM task t INNER JOIN project p ON p.id = t.project_id
+		WHERE t.id = $1
+	`, taskID); err != nil {
+		return nil, domain_errors.NewDatabaseError("task move lock", err)
+	}
+
+	// lock the subtree so concurrent edits of its tasks wait for this one
+	subtree, derr := queryTasksTx(tx, "task move lock", `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM task WHERE id = $1
+			UNION ALL
+			SELECT t.id FROM task t INNER JOIN subtree s ON t.parent_id = s.id
 		)
+		SELECT `+taskColumns+` FROM task WHERE id IN (SELECT id FROM subtree) ORDER BY id FOR UPDATE
+	`, taskID)
+	if derr != nil {
+		return nil, derr
+	}
+	var task *Task
+	for _, t := range subtree {
+		if t.ID == taskID {
+			task = t
+		}
+	}
+	if task == nil {
+		return nil, domain_errors.NewNotFoundError("task", taskID)
+	}
+	parentDepth := -1
+	if parentID != nil {
+		// lock the new parent's ancestors too, so the chain checked is the one the
+		// task ends up under
+		ancestors, err := queryStrings(tx, "task move ancestor lock", `
+			WITH RECURSIVE ancestors AS (
+				SELECT id, parent_id FROM task WHERE id = $1
+				UNION
+				SELECT t.id, t.parent_id FROM task t INNER JOIN ancestors a ON t.id = a.parent_id
+			)
+			SELECT id FROM task WHERE id IN (SELECT id FROM ancestors) ORDER BY id FOR UPDATE
+		`, *parentID)
 		if err != nil {
-			return nil, domain_errors.NewDatabaseError("subtasks scan", err)
+			return nil, err
 		}
-		tasks = append(tasks, task)
+		if len(ancestors) == 0 {
+			return nil, domain_errors.NewNotFoundError("task", *parentID)
+		}
+		for _, t := range subtree {
+			if slices.Contains(ancestors, t.ID) {
+				return nil, domain_errors.NewInvalidOperationError("task move", "A TASK CANNOT BE MOVED UNDER ITSELF OR ITS SUBTASKS")
+			}
+		}
+		parentDepth = len(ancestors) - 1
+	}
+	var height int
+	if err := tx.QueryRow(subtreeHeightQuery, taskID).Scan(&height); err != nil {
+		return nil, domain_errors.NewDatabaseError("subtree height query", err)
+	}
+	if parentDepth+1+height > maxDepth {
+		return nil, domain_errors.NewInvalidOperationError("task placement", fmt.Sprintf("TASK TREES ARE AT MOST %d LEVELS DEEP", maxDepth))
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, domain_errors.NewDatabaseError("subtasks iteration", err)
+	if _, err := tx.Exec(`UPDATE task SET parent_id = $2, updated_at = $3, version = version + 1 WHERE id = $1`, taskID, parentID, at); err != nil {
+		return nil, domain_errors.NewDatabaseError("task move", err)
 	}
 
-	return tasks, nil
-}
+	// milestones and custom fields belong to the project, so tasks leaving it
+	// leave their milestone and keep only the values the new project defines
+	if projectID != task.ProjectID {
+		for _, t := range subtree {
+			values := retainCustomFields(t.CustomFields, fields)
+			if _, err := tx.Exec(
+				`UPDATE task SET project_id = $2, milestone_id = NULL, custom_fields = $3, updated_at = $4, version = version + 1 WHERE id = $1`,
+				t.ID, projectID, values, at,
+			); err != nil {
+				return nil, domain_errors.NewDatabaseError("task move", err)
+			}
+			if t.MilestoneID != nil {
+				previous := t.MilestoneID
+				t.MilestoneID = nil
+				if err := insertTaskEvent(tx, t, actor, TaskEventMilestoneChanged, previous, nil, at); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
 
-func (r *PostgresProjectRepository) GetTaskTree(rootID string) (*TaskTree, domain_errors.DomainError) {
-	query := `
-		WITH RECURSIVE task_tree AS (
-			SELECT id, parent_id, project_id, name, description, creator, status, priority, due_date, created_at, updated_at, 0 as depth
-			FROM task
-			WHERE id = $1
-			
-			UNION ALL
-			
-			SELECT t.id, t.parent_id, t.project_id, t.name, t.description, t.creator, t.status, t.priority, t.due_date, t.created_at, t.updated_at, tt.depth + 1
-			FROM task t
-			INNER JOIN task_tree tt ON t.parent_id = tt.id
-		)
-		SELECT id, parent_id, project_id, name, description, creator, status, priority, due_date, created_at, updated_at, depth
-		FROM task_tree
-		ORDER BY depth, created_at
-	`
+	from, err := json.Marshal(map[string]any{"parent_id": task.ParentID, "project_id": task.ProjectID})
+	if err != nil {
+		return nil, domain_errors.NewInternalError("FAILED_TO_ENCODE_ACTIVITY", err)
+	}
+	to, err := json.Marshal(map[string]any{"parent_id": parentID, "project_id": projectID})
+	if err != nil {
+		return nil, domain_errors.NewInternalError("FAILED_TO_ENCODE_ACTIVITY", err)
+	}
+	fromValue, toValue := string(from), string(to)
+	if err := insertTaskEvent(tx, task, actor, TaskEventMoved, &fromValue, &toValue, at); err != nil {
+		return nil, err
+	}
 
-	rows, err := r.db.Query(query, rootID)
+	result := &Task{}
+	if err := scanTask(tx.QueryRow(`SELECT `+taskColumns+` FROM task WHERE id = $1`, taskID), result); err != nil {
+		return nil, domain_errors.NewDatabaseError("task move", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("task move commit", err)
+	}
+	return result, nil
+}
+
+// queryTasksTx runs a query selecting taskColumns within the transaction and scans every row
+func queryTasksTx(tx *sql.Tx, operation, query string, args ...any) ([]*Task, domain_errors.DomainError) {
+	rows, err := tx.Query(query, args...)
 	if err != nil {
-		return nil, domain_errors.NewDatabaseError("task tree query", err)
+		return nil, domain_errors.NewDatabaseError(operation+" query", err)
 	}
 	defer rows.Close()
 
-	taskMap := make(map[string]*TaskTree)
-	var rootTask *TaskTree
-
+	tasks := []*Task{}
 	for rows.Next() {
 		task := &Task{}
-		var depth int
-		err := rows.Scan(
-			&task.ID,
-			&task.ParentID,
-			&task.ProjectID,
-			&task.Name,
-			&task.Description,
-			&task.Creator,
-			&task.Status,
-			&task.Priority,
-			&task.DueDate,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-			&depth,
-		)
-		if err != nil {
+		if err := scanTask(rows, task); err != nil {
+			return nil, domain_errors.NewDatabaseError(operation+" scan", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError(operation+" rows iteration", err)
+	}
+	return tasks, nil
+}
+
+// subtreeHeightQuery counts the levels of subtasks below the task
+const subtreeHeightQuery = `
+	WITH RECURSIVE subtree AS (
+		SELECT id, 0 AS height FROM task WHERE id = $1
+		UNION ALL
+		SELECT t.id, s.height + 1
+		FROM task t
+		INNER JOIN subtree s ON t.parent_id = s.id
+		WHERE t.deleted_at IS NULL
+	)
+	SELECT COALESCE(MAX(height), 0) FROM subtree
+`
+
+// equalIDs compares optional ids
+func equalIDs(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+const taskEventColumns = `id, task_id, actor_id, kind, from_value, to_value, status, milestone_id, created_at`
+
+func scanTaskEvent(row interface{ Scan(dest ...any) error }, event *TaskEvent) error {
+	return row.Scan(
+		&event.ID,
+		&event.TaskID,
+		&event.ActorID,
+		&event.Kind,
+		&event.From,
+		&event.To,
+		&event.Status,
+		&event.MilestoneID,
+		&event.CreatedAt,
+	)
+}
+
+// queryTaskEvents runs a query selecting taskEventColumns and scans every row
+func queryTaskEvents(db *sql.DB, operation, query string, args ...any) ([]*TaskEvent, domain_errors.DomainError) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError(operation+" query", err)
+	}
+	defer rows.Close()
+
+	events := []*TaskEvent{}
+	for rows.Next() {
+		event := &TaskEvent{}
+		if err := scanTaskEvent(rows, event); err != nil {
+			return nil, domain_errors.NewDatabaseError(operation+" scan", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError(operation+" iteration", err)
+	}
+	return events, nil
+}
+
+func (r *PostgresProjectRepository) ListTaskActivity(taskID string) ([]*TaskEvent, domain_errors.DomainError) {
+	query := `SELECT ` + taskEventColumns + ` FROM task_activity WHERE task_id = $1 ORDER BY created_at DESC, id`
+	return queryTaskEvents(r.db, "task activity", query, taskID)
+}
+
+func (r *PostgresProjectRepository) GetTaskWorkspaceID(taskID string) (string, domain_errors.DomainError) {
+	query := `
+		SELECT p.workspace_id
+		FROM task t
+		JOIN project p ON p.id = t.project_id
+		WHERE t.id = $1 AND t.deleted_at IS NULL
+	`
+
+	var workspaceID string
+	if err := r.db.QueryRow(query, taskID).Scan(&workspaceID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", domain_errors.NewNotFoundError("task", taskID)
+		}
+		return "", domain_errors.NewDatabaseError("task workspace query", err)
+	}
+	return workspaceID, nil
+}
+
+func (r *PostgresProjectRepository) CountWorkspaceMembers(wsID string, userIDs []string) (int, domain_errors.DomainError) {
+	query := `SELECT COUNT(DISTINCT user_id) FROM membership WHERE workspace_id = $1 AND user_id = ANY($2)`
+
+	var count int
+	if err := r.db.QueryRow(query, wsID, userIDs).Scan(&count); err != nil {
+		return 0, domain_errors.NewDatabaseError("workspace member count", err)
+	}
+	return count, nil
+}
+
+func (r *PostgresProjectRepository) BulkUpdateTasks(taskIDs []string, changes *BulkTaskChanges, actor string, at time.Time) (map[string]*BulkTaskChange, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("bulk task update transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// locking in id order keeps concurrent bulk operations from deadlocking
+	rows, err := tx.Query(`SELECT `+taskColumns+` FROM task WHERE id = ANY($1) ORDER BY id FOR UPDATE`, taskIDs)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("bulk task lock", err)
+	}
+	tasks := []*Task{}
+	for rows.Next() {
+		task := &Task{}
+		if err := scanTask(rows, task); err != nil {
+			rows.Close()
+			return nil, domain_errors.NewDatabaseError("bulk task scan", err)
+		}
+		tasks = append(tasks, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("bulk task rows iteration", err)
+	}
+
+	changed := map[string]*BulkTaskChange{}
+	for _, task := range tasks {
+		change := &BulkTaskChange{Task: task}
+		from, to := map[string]any{}, map[string]any{}
+		sets := []string{}
+		args := []any{task.ID}
+		set := func(column string, value any) {
+			args = append(args, value)
+			sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+
+		if changes.Status != nil && *changes.Status != task.Status {
+			from["status"], to["status"] = task.Status, *changes.Status
+			task.Status = *changes.Status
+			change.StatusChanged = true
+			set("status", task.Status)
+		}
+		if changes.Priority != nil && *changes.Priority != task.Priority {
+			from["priority"], to["priority"] = task.Priority, *changes.Priority
+			task.Priority = *changes.Priority
+			set("priority", task.Priority)
+		}
+		if changes.DueDate != nil && (task.DueDate == nil || !task.DueDate.Equal(*changes.DueDate)) {
+			from["due_date"], to["due_date"] = task.DueDate, *changes.DueDate
+			set("due_date", *changes.DueDate)
+		}
+		if changes.ClearDueDate && task.DueDate != nil {
+			from["due_date"], to["due_date"] = task.DueDate, nil
+			set("due_date", nil)
+		}
+
+		if len(changes.AddAssignees) > 0 {
+			ids := make([]string, len(changes.AddAssignees))
+			for i := range ids {
+				ids[i] = uuid.NewString()
+			}
+			added, err := queryStrings(tx, "bulk task assignment", `
+				INSERT INTO task_assignment (id, task_id, assigner, assignee, created_at)
+				SELECT u.id, $1, $2, u.assignee, $3
+				FROM unnest($4::text[], $5::text[]) AS u(id, assignee)
+				ON CONFLICT (task_id, assignee) DO NOTHING
+				RETURNING assignee
+			`, task.ID, actor, at, ids, changes.AddAssignees)
+			if err != nil {
+				return nil, err
+			}
+			if len(added) > 0 {
+				to["added_assignees"] = added
+				change.AddedAssignees = added
+			}
+		}
+		if len(changes.RemoveAssignees) > 0 {
+			removed, err := queryStrings(tx, "bulk task unassignment",
+				`DELETE FROM task_assignment WHERE task_id = $1 AND assignee = ANY($2) RETURNING assignee`, task.ID, changes.RemoveAssignees)
+			if err != nil {
+				return nil, err
+			}
+			if len(removed) > 0 {
+				to["removed_assignees"] = removed
+			}
+		}
+		if len(changes.AddLabels) > 0 {
+			added, err := queryStrings(tx, "bulk task labelling", `
+				INSERT INTO task_label (task_id, label_id, created_at)
+				SELECT $1, label_id, $2 FROM unnest($3::text[]) AS label_id
+				ON CONFLICT (task_id, label_id) DO NOTHING
+				RETURNING label_id
+			`, task.ID, at, changes.AddLabels)
+			if err != nil {
+				return nil, err
+			}
+			if len(added) > 0 {
+				to["added_labels"] = added
+			}
+		}
+		if len(changes.RemoveLabels) > 0 {
+			removed, err := queryStrings(tx, "bulk task unlabelling",
+				`DELETE FROM task_label WHERE task_id = $1 AND label_id = ANY($2) RETURNING label_id`, task.ID, changes.RemoveLabels)
+			if err != nil {
+				return nil, err
+			}
+			if len(removed) > 0 {
+				to["removed_labels"] = removed
+			}
+		}
+
+		if len(to) == 0 {
+			continue
+		}
+		set("updated_at", at)
+		sets = append(sets, "version = version + 1")
+		if _, err := tx.Exec(`UPDATE task SET `+strings.Join(sets, ", ")+` WHERE id = $1`, args...); err != nil {
+			return nil, domain_errors.NewDatabaseError("bulk task update", err)
+		}
+		fromJSON, err := json.Marshal(from)
+		if err != nil {
+			return nil, domain_errors.NewInternalError("FAILED_TO_ENCODE_ACTIVITY", err)
+		}
+		toJSON, err := json.Marshal(to)
+		if err != nil {
+			return nil, domain_errors.NewInternalError("FAILED_TO_ENCODE_ACTIVITY", err)
+		}
+		fromValue, toValue := string(fromJSON), string(toJSON)
+		if err := insertTaskEvent(tx, task, actor, TaskEventBulkUpdated, &fromValue, &toValue, at); err != nil {
+			return nil, err
+		}
+		changed[task.ID] = change
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("bulk task update commit", err)
+	}
+	return changed, nil
+}
+
+// queryStrings runs a query returning a single text column within the transaction
+func queryStrings(tx *sql.Tx, operation, query string, args ...any) ([]string, domain_errors.DomainError) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError(operation, err)
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, domain_errors.NewDatabaseError(operation+" scan", err)
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError(operation+" rows iteration", err)
+	}
+	return values, nil
+}
+
+func (r *PostgresProjectRepository) ListWorkspaceTaskIDs(wsID string, ids []string) ([]string, domain_errors.DomainError) {
+	query := `
+		SELECT t.id
+		FROM task t
+		INNER JOIN project p ON p.id = t.project_id
+		WHERE p.workspace_id = $1 AND t.id = ANY($2) AND t.deleted_at IS NULL
+	`
+	rows, err := r.db.Query(query, wsID, ids)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("workspace task ids query", err)
+	}
+	defer rows.Close()
+
+	found := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, domain_errors.NewDatabaseError("workspace task ids scan", err)
+		}
+		found = append(found, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("workspace task ids iteration", err)
+	}
+	return found, nil
+}
+
+func (r *PostgresProjectRepository) ListProjectTaskIDs(projectID string, ids []string) ([]string, domain_errors.DomainError) {
+	rows, err := r.db.Query(`SELECT id FROM task WHERE project_id = $1 AND id = ANY($2) AND deleted_at IS NULL`, projectID, ids)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("project task ids query", err)
+	}
+	defer rows.Close()
+
+	found := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, domain_errors.NewDatabaseError("project task ids scan", err)
+		}
+		found = append(found, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("project task ids iteration", err)
+	}
+	return found, nil
+}
+
+func (r *PostgresProjectRepository) DeleteTask(id, actor string, at time.Time) domain_errors.DomainError {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return domain_errors.NewDatabaseError("task deletion transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	task := &Task{}
+	if err := scanTask(tx.QueryRow(`SELECT `+taskColumns+` FROM task WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id), task); err != nil {
+		if err == sql.ErrNoRows {
+			return domain_errors.NewNotFoundError("task", id)
+		}
+		return domain_errors.NewDatabaseError("task deletion", err)
+	}
+	// subtasks already in the trash keep their own deletion time
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM task WHERE id = $1
+			UNION ALL
+			SELECT t.id FROM task t INNER JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at IS NULL
+		)
+		UPDATE task SET deleted_at = $2, deleted_by = $3 WHERE id IN (SELECT id FROM subtree)
+	`
+	if _, err := tx.Exec(query, id, at, actor); err != nil {
+		return domain_errors.NewDatabaseError("task deletion", err)
+	}
+	if err := insertTaskEvent(tx, task, actor, TaskEventDeleted, nil, nil, at); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain_errors.NewDatabaseError("task deletion commit", err)
+	}
+	return nil
+}
+
+func (r *PostgresProjectRepository) ListSubtasks(parentID string) ([]*Task, domain_errors.DomainError) {
+	query := `
+		SELECT ` + taskColumns + `
+		FROM task
+		WHERE parent_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at ASC
+	`
+	return r.queryTasks("subtasks", query, parentID)
+}
+
+func (r *PostgresProjectRepository) GetTaskTree(rootID string) (*TaskTree, domain_errors.DomainError) {
+	query := `
+		WITH RECURSIVE task_tree AS (
+			SELECT ` + taskColumns + `, 0 as depth
+			FROM task
+			WHERE id = $1 AND deleted_at IS NULL
+			
+			UNION ALL
+			
+			SELECT ` + qualifiedTaskColumns("t") + `, tt.depth + 1
+			FROM task t
+			INNER JOIN task_tree tt ON t.parent_id = tt.id
+			WHERE t.deleted_at IS NULL
+		)
+		SELECT ` + taskColumns + `, depth
+		FROM task_tree
+		ORDER BY depth, created_at
+	`
+
+	rows, err := r.db.Query(query, rootID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("task tree query", err)
+	}
+	defer rows.Close()
+
+	taskMap := make(map[string]*TaskTree)
+	var rootTask *TaskTree
+
+	for rows.Next() {
+		task := &Task{}
+		var depth int
+		if err := scanTask(rows, task, &depth); err != nil {
 			return nil, domain_errors.NewDatabaseError("task tree scan", err)
 		}
 
@@ -388,79 +1118,77 @@ func (r *PostgresProjectRepository) GetTaskWithChildren(id string) (*TaskWithChi
 
 func (r *PostgresProjectRepository) GetRootTasks(projectID string) ([]*Task, domain_errors.DomainError) {
 	query := `
-		SELECT id, parent_id, project_id, name, description, creator, status, priority, due_date, created_at, updated_at
+		SELECT ` + taskColumns + `
 		FROM task
-		WHERE project_id = $1 AND (parent_id IS NULL OR parent_id = '')
+		WHERE project_id = $1 AND (parent_id IS NULL OR parent_id = '') AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
+	return r.queryTasks("root tasks", query, projectID)
+}
 
-	rows, err := r.db.Query(query, projectID)
-	if err != nil {
-		return nil, domain_errors.NewDatabaseError("root tasks query", err)
+func (r *PostgresProjectRepository) ListTasksByProject(projectID string, taskQuery *TaskQuery) ([]*Task, domain_errors.DomainError) {
+	conditions := []string{"project_id = $1", "deleted_at IS NULL"}
+	args := []any{projectID}
+	addArg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
 	}
-	defer rows.Close()
 
-	var tasks []*Task
-	for rows.Next() {
-		task := &Task{}
-		err := rows.Scan(
-			&task.ID,
-			&task.ParentID,
-			&task.ProjectID,
-			&task.Name,
-			&task.Description,
-			&task.Creator,
-			&task.Status,
-			&task.Priority,
-			&task.DueDate,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-		)
-		if err != nil {
-			return nil, domain_errors.NewDatabaseError("root tasks scan", err)
+	if taskQuery.MilestoneID != "" {
+		conditions = append(conditions, "milestone_id = "+addArg(taskQuery.MilestoneID))
+	}
+	if taskQuery.Status != "" {
+		conditions = append(conditions, "status = "+addArg(taskQuery.Status))
+	}
+	if taskQuery.Priority != "" {
+		conditions = append(conditions, "priority = "+addArg(taskQuery.Priority))
+	}
+	if len(taskQuery.LabelIDs) > 0 {
+		labels := addArg(taskQuery.LabelIDs)
+		conditions = append(conditions, fmt.Sprintf(`id IN (
+			SELECT task_id
+			FROM task_label
+			WHERE label_id = ANY(%[1]s)
+			GROUP BY task_id
+			HAVING COUNT(DISTINCT label_id) = cardinality(%[1]s::text[])
+		)`, labels))
+	}
+	for _, filter := range taskQuery.Filters {
+		switch filter.Operator {
+		case FilterEq, FilterNe:
+			// containment is answered by the GIN index on custom_fields
+			contains, err := json.Marshal(map[string]any{filter.Field.Key: filter.Value})
+			if err != nil {
+				return nil, domain_errors.NewInternalError("FAILED_TO_ENCODE_FILTER", err)
+			}
+			condition := "custom_fields @> " + addArg(string(contains)) + "::jsonb"
+			if filter.Operator == FilterNe {
+				condition = "NOT " + condition
+			}
+			conditions = append(conditions, condition)
+		default:
+			operators := map[string]string{FilterLt: "<", FilterLte: "<=", FilterGt: ">", FilterGte: ">="}
+			conditions = append(conditions, fmt.Sprintf("%s %s %s", customFieldExpression(filter.Field), operators[filter.Operator], addArg(filter.Value)))
 		}
-		tasks = append(tasks, task)
 	}
 
-	return tasks, nil
-}
-func (r *PostgresProjectRepository) ListTasksByProject(projectID string) ([]*Task, domain_errors.DomainError) {
-	query := `
-		SELECT id, parent_id, project_id, name, description,creator, status, priority, due_date, created_at, updated_at
-		FROM task
-		WHERE project_id = $1
-		ORDER BY created_at DESC
-	`
-
-	rows, err := r.db.Query(query, projectID)
-	if err != nil {
-		return nil, domain_errors.NewDatabaseError("project tasks query", err)
+	order := taskQuery.SortColumn
+	if taskQuery.SortField != nil {
+		order = customFieldExpression(taskQuery.SortField)
 	}
-	defer rows.Close()
-
-	var tasks []*Task
-	for rows.Next() {
-		task := &Task{}
-		err := rows.Scan(
-			&task.ID,
-			&task.ParentID,
-			&task.ProjectID,
-			&task.Name,
-			&task.Description,
-			&task.Creator,
-			&task.Status,
-			&task.Priority,
-			&task.DueDate,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-		)
-		if err != nil {
-			return nil, domain_errors.NewDatabaseError("project tasks scan", err)
-		}
-		tasks = append(tasks, task)
+	direction := "ASC"
+	if taskQuery.Descending {
+		direction = "DESC"
 	}
 
-	return tasks, nil
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM task
+		WHERE %s
+		ORDER BY %s %s NULLS LAST, created_at DESC
+	`, taskColumns, strings.Join(conditions, " AND "), order, direction)
+
+	return r.queryTasks("project tasks", query, args...)
 }
 
 func (r *PostgresProjectRepository) GetProjectTaskTree(projectID string) ([]*TaskTree, domain_errors.DomainError) {
@@ -483,6 +1211,59 @@ func (r *PostgresProjectRepository) GetProjectTaskTree(projectID string) ([]*Tas
 	return trees, nil
 }
 
+func (r *PostgresProjectRepository) GetTimeRollups(rootIDs []string) (map[string]*TimeRollup, domain_errors.DomainError) {
+	// closure pairs every task of the trees with each of its ancestors in them,
+	// so summing over the pairs of an ancestor covers its whole subtree
+	query := `
+		WITH RECURSIVE nodes AS (
+			SELECT id FROM task WHERE id = ANY($1) AND deleted_at IS NULL
+			UNION ALL
+			SELECT t.id FROM task t INNER JOIN nodes n ON t.parent_id = n.id WHERE t.deleted_at IS NULL
+		),
+		closure AS (
+			SELECT id AS ancestor_id, id AS task_id FROM nodes
+			UNION ALL
+			SELECT c.ancestor_id, t.id
+			FROM closure c
+			INNER JOIN task t ON t.parent_id = c.task_id
+			WHERE t.deleted_at IS NULL
+		),
+		logged AS (
+			SELECT task_id, SUM(minutes) AS minutes
+			FROM work_log
+			WHERE task_id IN (SELECT id FROM nodes)
+			GROUP BY task_id
+		)
+		SELECT c.ancestor_id,
+			COALESCE(SUM(t.original_estimate_minutes), 0),
+			COALESCE(SUM(t.remaining_estimate_minutes), 0),
+			COALESCE(SUM(l.minutes), 0)
+		FROM closure c
+		INNER JOIN task t ON t.id = c.task_id
+		LEFT JOIN logged l ON l.task_id = c.task_id
+		GROUP BY c.ancestor_id
+	`
+
+	rows, err := r.db.Query(query, rootIDs)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("time rollup query", err)
+	}
+	defer rows.Close()
+
+	rollups := map[string]*TimeRollup{}
+	for rows.Next() {
+		rollup := &TimeRollup{}
+		if err := rows.Scan(&rollup.TaskID, &rollup.OriginalEstimateMinutes, &rollup.RemainingEstimateMinutes, &rollup.LoggedMinutes); err != nil {
+			return nil, domain_errors.NewDatabaseError("time rollup scan", err)
+		}
+		rollups[rollup.TaskID] = rollup
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("time rollup iteration", err)
+	}
+	return rollups, nil
+}
+
 func (r *PostgresProjectRepository) GetTaskDepth(id string) (int, domain_errors.DomainError) {
 	query := `
 		WITH RECURSIVE task_depth AS (
@@ -496,7 +1277,7 @@ func (r *PostgresProjectRepository) GetTaskDepth(id string) (int, domain_errors.
 			FROM task t
 			INNER JOIN task_depth td ON t.id = td.parent_id
 		)
-		SELECT MAX(depth) FROM task_depth
+		SELECT COALESCE(MAX(depth), 0) FROM task_depth
 	`
 
 	var depth int
@@ -511,10 +1292,11 @@ func (r *PostgresProjectRepository) GetTaskDepth(id string) (int, domain_errors.
 func (r *PostgresProjectRepository) CountSubtasks(parentID string) (int, domain_errors.DomainError) {
 	query := `
 		WITH RECURSIVE task_tree AS (
-			SELECT id FROM task WHERE parent_id = $1
+			SELECT id FROM task WHERE parent_id = $1 AND deleted_at IS NULL
 			UNION ALL
 			SELECT t.id FROM task t
 			INNER JOIN task_tree tt ON t.parent_id = tt.id
+			WHERE t.deleted_at IS NULL
 		)
 		SELECT COUNT(*) FROM task_tree
 	`
@@ -527,3 +1309,2214 @@ func (r *PostgresProjectRepository) CountSubtasks(parentID string) (int, domain_
 
 	return count, nil
 }
+
+func (r *PostgresProjectRepository) AssignTask(assignments []*TaskAssignment) domain_errors.DomainError {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return domain_errors.NewDatabaseError("task assignment transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := `
+		INSERT INTO task_assignment (id, task_id, assigner, assignee, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (task_id, assignee) DO NOTHING
+	`
+	for _, assignment := range assignments {
+		if _, err := tx.Exec(query, assignment.ID, assignment.TaskID, assignment.Assigner, assignment.Assignee, assignment.CreatedAt); err != nil {
+			return domain_errors.NewDatabaseError("task assignment creation", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return domain_errors.NewDatabaseError("task assignment commit", err)
+	}
+	return nil
+}
+
+func (r *PostgresProjectRepository) UnassignTask(taskID, assignee string) domain_errors.DomainError {
+	result, err := r.db.Exec(`DELETE FROM task_assignment WHERE task_id = $1 AND assignee = $2`, taskID, assignee)
+	if err != nil {
+		return domain_errors.NewDatabaseError("task assignment deletion", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError("task assignment deletion", err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError("task assignment", assignee)
+	}
+	return nil
+}
+
+func (r *PostgresProjectRepository) ListTaskAssignments(taskID string) ([]*TaskAssignment, domain_errors.DomainError) {
+	query := `
+		SELECT id, assigner, assignee, task_id, created_at
+		FROM task_assignment
+		WHERE task_id = $1
+		ORDER BY created_at, assignee
+	`
+
+	rows, err := r.db.Query(query, taskID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("task assignment list query", err)
+	}
+	defer rows.Close()
+
+	assignments := []*TaskAssignment{}
+	for rows.Next() {
+		assignment := &TaskAssignment{}
+		if err := rows.Scan(&assignment.ID, &assignment.Assigner, &assignment.Assignee, &assignment.TaskID, &assignment.CreatedAt); err != nil {
+			return nil, domain_errors.NewDatabaseError("task assignment scan", err)
+		}
+		assignments = append(assignments, assignment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("task assignment rows iteration", err)
+	}
+	return assignments, nil
+}
+
+// ============================================================================
+// ATTACHMENTS
+// ============================================================================
+
+type PostgresAttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAttachmentRepository(db *sql.DB) *PostgresAttachmentRepository {
+	return &PostgresAttachmentRepository{db: db}
+}
+
+const attachmentColumns = `id, task_id, name, content_type, size, sha256, blob_key, uploaded_by, created_at`
+
+func scanAttachment(row interface{ Scan(dest ...any) error }, attachment *TaskAttachment) error {
+	return row.Scan(
+		&attachment.ID,
+		&attachment.TaskID,
+		&attachment.Name,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.SHA256,
+		&attachment.BlobKey,
+		&attachment.UploadedBy,
+		&attachment.CreatedAt,
+	)
+}
+
+func (r *PostgresAttachmentRepository) ReserveBlob(key string, size int64, contentType string, at time.Time) (bool, domain_errors.DomainError) {
+	// a row whose upload never finished is reserved again but stays unstored, so
+	// the next upload of the same content writes it
+	query := `
+		INSERT INTO blob (key, size, content_type, created_at, last_referenced_at, stored)
+		VALUES ($1, $2, $3, $4, $4, FALSE)
+		ON CONFLICT (key) DO UPDATE SET last_referenced_at = EXCLUDED.last_referenced_at
+		RETURNING stored
+	`
+
+	var stored bool
+	if err := r.db.QueryRow(query, key, size, contentType, at).Scan(&stored); err != nil {
+		return false, domain_errors.NewDatabaseError("blob reservation", err)
+	}
+	return stored, nil
+}
+
+func (r *PostgresAttachmentRepository) MarkBlobStored(key string) domain_errors.DomainError {
+	if _, err := r.db.Exec(`UPDATE blob SET stored = TRUE WHERE key = $1`, key); err != nil {
+		return domain_errors.NewDatabaseError("blob storage", err)
+	}
+	return nil
+}
+
+func (r *PostgresAttachmentRepository) Create(attachment *TaskAttachment) (*TaskAttachment, domain_errors.DomainError) {
+	query := `
+		INSERT INTO task_attachment (` + attachmentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING ` + attachmentColumns
+
+	created := &TaskAttachment{}
+	row := r.db.QueryRow(query, attachment.ID, attachment.TaskID, attachment.Name, attachment.ContentType,
+		attachment.Size, attachment.SHA256, attachment.BlobKey, attachment.UploadedBy, attachment.CreatedAt)
+	if err := scanAttachment(row, created); err != nil {
+		return nil, domain_errors.NewDatabaseError("attachment creation", err)
+	}
+	return created, nil
+}
+
+func (r *PostgresAttachmentRepository) GetByID(taskID, id string) (*TaskAttachment, domain_errors.DomainError) {
+	query := `SELECT ` + attachmentColumns + ` FROM task_attachment WHERE id = $1 AND task_id = $2`
+
+	attachment := &TaskAttachment{}
+	if err := scanAttachment(r.db.QueryRow(query, id, taskID), attachment); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("attachment", id)
+		}
+		return nil, domain_errors.NewDatabaseError("attachment query", err)
+	}
+	return attachment, nil
+}
+
+func (r *PostgresAttachmentRepository) ListByTask(taskID string) ([]*TaskAttachment, domain_errors.DomainError) {
+	query := `SELECT ` + attachmentColumns + ` FROM task_attachment WHERE task_id = $1 ORDER BY created_at ASC`
+
+	rows, err := r.db.Query(query, taskID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("attachment list", err)
+	}
+	defer rows.Close()
+
+	attachments := []*TaskAttachment{}
+	for rows.Next() {
+		attachment := &TaskAttachment{}
+		if err := scanAttachment(rows, attachment); err != nil {
+			return nil, domain_errors.NewDatabaseError("attachment scan", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("attachment rows iteration", err)
+	}
+	return attachments, nil
+}
+
+func (r *PostgresAttachmentRepository) Delete(taskID, id string) domain_errors.DomainError {
+	result, err := r.db.Exec(`DELETE FROM task_attachment WHERE id = $1 AND task_id = $2`, id, taskID)
+	if err != nil {
+		return domain_errors.NewDatabaseError("attachment deletion", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError("attachment deletion", err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError("attachment", id)
+	}
+	return nil
+}
+
+func (r *PostgresAttachmentRepository) DeleteUnreferencedBlobs(before time.Time, limit int, remove func(key string) error) (int, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("blob collection transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := `
+		SELECT b.key
+		FROM blob b
+		WHERE b.last_referenced_at < $1
+			AND NOT EXISTS (SELECT 1 FROM task_attachment a WHERE a.blob_key = b.key)
+		ORDER BY b.last_referenced_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.Query(query, before, limit)
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("unreferenced blob query", err)
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return 0, domain_errors.NewDatabaseError("unreferenced blob scan", err)
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, domain_errors.NewDatabaseError("unreferenced blob rows iteration", err)
+	}
+
+	for _, key := range keys {
+		if err := remove(key); err != nil {
+			return 0, domain_errors.NewInternalError("blob removal", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM blob WHERE key = $1`, key); err != nil {
+			return 0, domain_errors.NewDatabaseError("blob deletion", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, domain_errors.NewDatabaseError("blob collection commit", err)
+	}
+	return len(keys), nil
+}
+
+// ============================================================================
+// LABELS
+// ============================================================================
+
+type PostgresLabelRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresLabelRepository(db *sql.DB) *PostgresLabelRepository {
+	return &PostgresLabelRepository{db: db}
+}
+
+const labelColumns = `l.id, l.workspace_id, l.name, l.color, l.created_by, l.created_at`
+
+func scanLabel(row interface{ Scan(dest ...any) error }, label *Label, extra ...any) error {
+	return row.Scan(append([]any{
+		&label.ID,
+		&label.WorkspaceID,
+		&label.Name,
+		&label.Color,
+		&label.CreatedBy,
+		&label.CreatedAt,
+	}, extra...)...)
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (r *PostgresLabelRepository) Create(label *Label) (*Label, domain_errors.DomainError) {
+	query := `
+		INSERT INTO label AS l (id, workspace_id, name, color, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + labelColumns
+
+	created := &Label{}
+	row := r.db.QueryRow(query, label.ID, label.WorkspaceID, label.Name, label.Color, label.CreatedBy, label.CreatedAt)
+	if err := scanLabel(row, created); err != nil {
+		if isUniqueViolation(err) {
+			return nil, domain_errors.NewConflictError("label", "LABEL WITH THIS NAME ALREADY EXISTS")
+		}
+		return nil, domain_errors.NewDatabaseError("label creation", err)
+	}
+	return created, nil
+}
+
+func (r *PostgresLabelRepository) GetByID(wsID, id string) (*Label, domain_errors.DomainError) {
+	query := `SELECT ` + labelColumns + ` FROM label l WHERE l.id = $1 AND l.workspace_id = $2`
+
+	label := &Label{}
+	if err := scanLabel(r.db.QueryRow(query, id, wsID), label); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("label", id)
+		}
+		return nil, domain_errors.NewDatabaseError("label query", err)
+	}
+	return label, nil
+}
+
+func (r *PostgresLabelRepository) queryLabels(operation, query string, args ...any) ([]*Label, domain_errors.DomainError) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError(operation, err)
+	}
+	defer rows.Close()
+
+	labels := []*Label{}
+	for rows.Next() {
+		label := &Label{}
+		if err := scanLabel(rows, label, &label.TaskCount); err != nil {
+			return nil, domain_errors.NewDatabaseError(operation, err)
+		}
+		labels = append(labels, label)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError(operation, err)
+	}
+	return labels, nil
+}
+
+func (r *PostgresLabelRepository) ListByWorkspace(wsID string) ([]*Label, domain_errors.DomainError) {
+	query := `
+		SELECT ` + labelColumns + `, COUNT(tl.task_id)
+		FROM label l
+		LEFT JOIN task_label tl ON tl.label_id = l.id
+		WHERE l.workspace_id = $1
+		GROUP BY l.id
+		ORDER BY LOWER(l.name)
+	`
+	return r.queryLabels("label list", query, wsID)
+}
+
+func (r *PostgresLabelRepository) ListByIDs(wsID string, ids []string) ([]*Label, domain_errors.DomainError) {
+	query := `
+		SELECT ` + labelColumns + `, 0
+		FROM label l
+		WHERE l.workspace_id = $1 AND l.id = ANY($2)
+	`
+	return r.queryLabels("label list", query, wsID, ids)
+}
+
+func (r *PostgresLabelRepository) Update(wsID, id string, input *UpdateLabelInput) (*Label, domain_errors.DomainError) {
+	query := `
+		UPDATE label AS l
+		SET name = COALESCE($3, l.name),
+			color = COALESCE($4, l.color)
+		WHERE l.id = $1 AND l.workspace_id = $2
+		RETURNING ` + labelColumns
+
+	label := &Label{}
+	if err := scanLabel(r.db.QueryRow(query, id, wsID, input.Name, input.Color), label); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("label", id)
+		}
+		if isUniqueViolation(err) {
+			return nil, domain_errors.NewConflictError("label", "LABEL WITH THIS NAME ALREADY EXISTS, MERGE THE LABELS INSTEAD")
+		}
+		return nil, domain_errors.NewDatabaseError("label update", err)
+	}
+	return label, nil
+}
+
+func (r *PostgresLabelRepository) Delete(wsID, id string) domain_errors.DomainError {
+	result, err := r.db.Exec(`DELETE FROM label WHERE id = $1 AND workspace_id = $2`, id, wsID)
+	if err != nil {
+		return domain_errors.NewDatabaseError("label deletion", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError("label deletion", err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError("label", id)
+	}
+	return nil
+}
+
+func (r *PostgresLabelRepository) Merge(wsID, sourceID, targetID string) (*Label, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("label merge transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// lock both labels so neither is renamed or merged elsewhere meanwhile
+	var found int
+	lock := `SELECT COUNT(*) FROM (SELECT id FROM label WHERE id IN ($1, $2) AND workspace_id = $3 FOR UPDATE) locked`
+	if err := tx.QueryRow(lock, sourceID, targetID, wsID).Scan(&found); err != nil {
+		return nil, domain_errors.NewDatabaseError("label merge", err)
+	}
+	if found != 2 {
+		return nil, domain_errors.NewNotFoundError("label", sourceID+", "+targetID)
+	}
+
+	move := `
+		INSERT INTO task_label (task_id, label_id, created_at)
+		SELECT task_id, $2, created_at FROM task_label WHERE label_id = $1
+		ON CONFLICT (task_id, label_id) DO NOTHING
+	`
+	if _, err := tx.Exec(move, sourceID, targetID); err != nil {
+		return nil, domain_errors.NewDatabaseError("label merge", err)
+	}
+	// the source's task_label rows cascade
+	if _, err := tx.Exec(`DELETE FROM label WHERE id = $1`, sourceID); err != nil {
+		return nil, domain_errors.NewDatabaseError("label merge", err)
+	}
+
+	label := &Label{}
+	query := `
+		SELECT ` + labelColumns + `, (SELECT COUNT(*) FROM task_label WHERE label_id = l.id)
+		FROM label l
+		WHERE l.id = $1
+	`
+	if err := scanLabel(tx.QueryRow(query, targetID), label, &label.TaskCount); err != nil {
+		return nil, domain_errors.NewDatabaseError("label merge", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("label merge commit", err)
+	}
+	return label, nil
+}
+
+func (r *PostgresLabelRepository) AddToTask(taskID, labelID string, at time.Time) domain_errors.DomainError {
+	query := `
+		INSERT INTO task_label (task_id, label_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (task_id, label_id) DO NOTHING
+	`
+	if _, err := r.db.Exec(query, taskID, labelID, at); err != nil {
+		return domain_errors.NewDatabaseError("task label creation", err)
+	}
+	return nil
+}
+
+func (r *PostgresLabelRepository) RemoveFromTask(taskID, labelID string) domain_errors.DomainError {
+	result, err := r.db.Exec(`DELETE FROM task_label WHERE task_id = $1 AND label_id = $2`, taskID, labelID)
+	if err != nil {
+		return domain_errors.NewDatabaseError("task label deletion", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError("task label deletion", err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError("task label", labelID)
+	}
+	return nil
+}
+
+func (r *PostgresLabelRepository) SetTaskLabels(taskID string, labelIDs []string, at time.Time) domain_errors.DomainError {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return domain_errors.NewDatabaseError("task labels transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.Exec(`DELETE FROM task_label WHERE task_id = $1 AND NOT (label_id = ANY($2))`, taskID, labelIDs); err != nil {
+		return domain_errors.NewDatabaseError("task labels update", err)
+	}
+	insert := `
+		INSERT INTO task_label (task_id, label_id, created_at)
+		SELECT $1, label_id, $3 FROM unnest($2::text[]) AS label_id
+		ON CONFLICT (task_id, label_id) DO NOTHING
+	`
+	if _, err := tx.Exec(insert, taskID, labelIDs, at); err != nil {
+		return domain_errors.NewDatabaseError("task labels update", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return domain_errors.NewDatabaseError("task labels commit", err)
+	}
+	return nil
+}
+
+func (r *PostgresLabelRepository) ListByTasks(taskIDs []string) (map[string][]*Label, domain_errors.DomainError) {
+	query := `
+		SELECT tl.task_id, ` + labelColumns + `
+		FROM task_label tl
+		JOIN label l ON l.id = tl.label_id
+		WHERE tl.task_id = ANY($1)
+		ORDER BY LOWER(l.name)
+	`
+
+	rows, err := r.db.Query(query, taskIDs)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("task labels query", err)
+	}
+	defer rows.Close()
+
+	labels := map[string][]*Label{}
+	for rows.Next() {
+		var taskID string
+		label := &Label{}
+		if err := rows.Scan(&taskID, &label.ID, &label.WorkspaceID, &label.Name, &label.Color, &label.CreatedBy, &label.CreatedAt); err != nil {
+			return nil, domain_errors.NewDatabaseError("task labels scan", err)
+		}
+		labels[taskID] = append(labels[taskID], label)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("task labels rows iteration", err)
+	}
+	return labels, nil
+}
+
+// ============================================================================
+// CUSTOM FIELDS
+// ============================================================================
+
+type PostgresCustomFieldRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresCustomFieldRepository(db *sql.DB) *PostgresCustomFieldRepository {
+	return &PostgresCustomFieldRepository{db: db}
+}
+
+const customFieldColumns = `id, project_id, key, name, type, options, required, position, created_at`
+
+func scanCustomField(row interface{ Scan(dest ...any) error }, field *CustomField) error {
+	var options []byte
+	if err := row.Scan(
+		&field.ID,
+		&field.ProjectID,
+		&field.Key,
+		&field.Name,
+		&field.Type,
+		&options,
+		&field.Required,
+		&field.Position,
+		&field.CreatedAt,
+	); err != nil {
+		return err
+	}
+	return json.Unmarshal(options, &field.Options)
+}
+
+func encodeOptions(options []string) string {
+	if options == nil {
+		options = []string{}
+	}
+	encoded, _ := json.Marshal(options)
+	return string(encoded)
+}
+
+func (r *PostgresCustomFieldRepository) Create(field *CustomField) (*CustomField, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("custom field transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	created, derr := insertCustomField(tx, field)
+	if derr != nil {
+		return nil, derr
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("custom field commit", err)
+	}
+	return created, nil
+}
+
+// insertCustomField stores the field at the end of its project's fields. Its
+// index is built by the CustomFieldIndexer, outside of the transaction.
+func insertCustomField(tx *sql.Tx, field *CustomField) (*CustomField, domain_errors.DomainError) {
+	query := `
+		INSERT INTO custom_field (` + customFieldColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7,
+			(SELECT COALESCE(MAX(position) + 1, 0) FROM custom_field WHERE project_id = $2), $8)
+		RETURNING ` + customFieldColumns
+
+	created := &CustomField{}
+	row := tx.QueryRow(query, field.ID, field.ProjectID, field.Key, field.Name, field.Type, encodeOptions(field.Options), field.Required, field.CreatedAt)
+	if err := scanCustomField(row, created); err != nil {
+		if isUniqueViolation(err) {
+			return nil, domain_errors.NewConflictError("custom field", "CUSTOM FIELD WITH THIS KEY ALREADY EXISTS")
+		}
+		return nil, domain_errors.NewDatabaseError("custom field creation", err)
+	}
+	return created, nil
+}
+
+// customFieldIndexSQL concurrently creates the typed index that keeps filtering
+// and sorting on the field fast. The key is a checked identifier and the project
+// id a uuid, so both can be inlined.
+func customFieldIndexSQL(field *CustomField) string {
+	return fmt.Sprintf(`CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON task (%s) WHERE project_id = '%s'`,
+		customFieldIndexName(field), customFieldExpression(field), field.ProjectID)
+}
+
+func (r *PostgresCustomFieldRepository) ListUnindexed(limit int) ([]*CustomField, domain_errors.DomainError) {
+	query := `SELECT ` + customFieldColumns + ` FROM custom_field WHERE NOT indexed ORDER BY created_at LIMIT $1`
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("unindexed custom field list", err)
+	}
+	defer rows.Close()
+
+	fields := []*CustomField{}
+	for rows.Next() {
+		field := &CustomField{}
+		if err := scanCustomField(rows, field); err != nil {
+			return nil, domain_errors.NewDatabaseError("unindexed custom field scan", err)
+		}
+		fields = append(fields, field)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("unindexed custom field rows iteration", err)
+	}
+	return fields, nil
+}
+
+func (r *PostgresCustomFieldRepository) BuildIndex(field *CustomField) domain_errors.DomainError {
+	name := customFieldIndexName(field)
+	// a concurrent build that failed leaves an invalid index, which IF NOT EXISTS
+	// would take for a finished one
+	var valid bool
+	err := r.db.QueryRow(`SELECT i.indisvalid FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid WHERE c.relname = $1`, name).Scan(&valid)
+	if err != nil && err != sql.ErrNoRows {
+		return domain_errors.NewDatabaseError("custom field index check", err)
+	}
+	if err == nil && !valid {
+		if _, err := r.db.Exec(`DROP INDEX CONCURRENTLY IF EXISTS ` + name); err != nil {
+			return domain_errors.NewDatabaseError("invalid custom field index deletion", err)
+		}
+	}
+
+	// builds concurrently, outside of any transaction, so tasks stay writable
+	if _, err := r.db.Exec(customFieldIndexSQL(field)); err != nil {
+		return domain_errors.NewDatabaseError("custom field index creation", err)
+	}
+	// the index of a field deleted meanwhile is dropped by DropOrphanedIndexes
+	if _, err := r.db.Exec(`UPDATE custom_field SET indexed = TRUE WHERE id = $1`, field.ID); err != nil {
+		return domain_errors.NewDatabaseError("custom field index creation", err)
+	}
+	return nil
+}
+
+func (r *PostgresCustomFieldRepository) DropOrphanedIndexes() (int, domain_errors.DomainError) {
+	query := `
+		SELECT c.relname
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		JOIN pg_class t ON t.oid = i.indrelid
+		WHERE t.relname = 'task'
+			AND c.relname LIKE 'idx\_task\_cf\_%'
+			AND NOT EXISTS (
+				SELECT 1 FROM custom_field cf WHERE 'idx_task_cf_' || replace(cf.id, '-', '') = c.relname
+			)
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("orphaned custom field index query", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return 0, domain_errors.NewDatabaseError("orphaned custom field index scan", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, domain_errors.NewDatabaseError("orphaned custom field index rows iteration", err)
+	}
+
+	for i, name := range names {
+		// the name comes from the catalog and matched the pattern above
+		if _, err := r.db.Exec(`DROP INDEX CONCURRENTLY IF EXISTS "` + name + `"`); err != nil {
+			return i, domain_errors.NewDatabaseError("orphaned custom field index deletion", err)
+		}
+	}
+	return len(names), nil
+}
+
+func (r *PostgresCustomFieldRepository) GetByID(projectID, id string) (*CustomField, domain_errors.DomainError) {
+	query := `SELECT ` + customFieldColumns + ` FROM custom_field WHERE id = $1 AND project_id = $2`
+
+	field := &CustomField{}
+	if err := scanCustomField(r.db.QueryRow(query, id, projectID), field); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("custom field", id)
+		}
+		return nil, domain_errors.NewDatabaseError("custom field query", err)
+	}
+	return field, nil
+}
+
+func (r *PostgresCustomFieldRepository) ListByProject(projectID string) ([]*CustomField, domain_errors.DomainError) {
+	query := `SELECT ` + customFieldColumns + ` FROM custom_field WHERE project_id = $1 ORDER BY position, created_at`
+
+	rows, err := r.db.Query(query, projectID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("custom field list", err)
+	}
+	defer rows.Close()
+
+	fields := []*CustomField{}
+	for rows.Next() {
+		field := &CustomField{}
+		if err := scanCustomField(rows, field); err != nil {
+			return nil, domain_errors.NewDatabaseError("custom field scan", err)
+		}
+		fields = append(fields, field)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("custom field rows iteration", err)
+	}
+	return fields, nil
+}
+
+func (r *PostgresCustomFieldRepository) Update(field *CustomField, input *UpdateCustomFieldInput) (*CustomField, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("custom field transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var options *string
+	if input.Options != nil {
+		encoded := encodeOptions(*input.Options)
+		options = &encoded
+
+		// options still in use by tasks cannot be removed
+		var inUse int
+		check := `
+			SELECT COUNT(*)
+			FROM task
+			WHERE project_id = $1
+				AND custom_fields ? $2
+				AND NOT (custom_fields->>$2 = ANY($3))
+		`
+		if err := tx.QueryRow(check, field.ProjectID, field.Key, *input.Options).Scan(&inUse); err != nil {
+			return nil, domain_errors.NewDatabaseError("custom field option check", err)
+		}
+		if inUse > 0 {
+			return nil, domain_errors.NewConflictError("custom field", "REMOVED OPTIONS ARE STILL USED BY TASKS")
+		}
+	}
+
+	query := `
+		UPDATE custom_field
+		SET name = COALESCE($3, name),
+			options = COALESCE($4::jsonb, options),
+			required = COALESCE($5, required),
+			position = COALESCE($6, position)
+		WHERE id = $1 AND project_id = $2
+		RETURNING ` + customFieldColumns
+
+	updated := &CustomField{}
+	row := tx.QueryRow(query, field.ID, field.ProjectID, input.Name, options, input.Required, input.Position)
+	if err := scanCustomField(row, updated); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("custom field", field.ID)
+		}
+		return nil, domain_errors.NewDatabaseError("custom field update", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("custom field commit", err)
+	}
+	return updated, nil
+}
+
+func (r *PostgresCustomFieldRepository) Delete(field *CustomField) domain_errors.DomainError {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return domain_errors.NewDatabaseError("custom field transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.Exec(`DELETE FROM custom_field WHERE id = $1 AND project_id = $2`, field.ID, field.ProjectID)
+	if err != nil {
+		return domain_errors.NewDatabaseError("custom field deletion", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError("custom field deletion", err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError("custom field", field.ID)
+	}
+
+	if _, err := tx.Exec(`UPDATE task SET custom_fields = custom_fields - $2, version = version + 1 WHERE project_id = $1 AND custom_fields ? $2`, field.ProjectID, field.Key); err != nil {
+		return domain_errors.NewDatabaseError("custom field value deletion", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain_errors.NewDatabaseError("custom field commit", err)
+	}
+	return nil
+}
+
+// ============================================================================
+// TIME TRACKING
+// ============================================================================
+
+type PostgresTimeRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTimeRepository(db *sql.DB) *PostgresTimeRepository {
+	return &PostgresTimeRepository{db: db}
+}
+
+const workLogColumns = `id, task_id, user_id, started_at, minutes, description, created_at`
+
+func scanWorkLog(row interface{ Scan(dest ...any) error }, log *WorkLog) error {
+	return row.Scan(
+		&log.ID,
+		&log.TaskID,
+		&log.UserID,
+		&log.StartedAt,
+		&log.Minutes,
+		&log.Description,
+		&log.CreatedAt,
+	)
+}
+
+// insertWorkLog stores the entry and takes its time off the task's remaining estimate
+func insertWorkLog(tx *sql.Tx, log *WorkLog) (*WorkLog, domain_errors.DomainError) {
+	query := `
+		INSERT INTO work_log (` + workLogColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + workLogColumns
+
+	created := &WorkLog{}
+	row := tx.QueryRow(query, log.ID, log.TaskID, log.UserID, log.StartedAt, log.Minutes, log.Description, log.CreatedAt)
+	if err := scanWorkLog(row, created); err != nil {
+		return nil, domain_errors.NewDatabaseError("work log creation", err)
+	}
+
+	estimate := `
+		UPDATE task
+		SET remaining_estimate_minutes = GREATEST(remaining_estimate_minutes - $2, 0), version = version + 1
+		WHERE id = $1 AND remaining_estimate_minutes IS NOT NULL
+	`
+	if _, err := tx.Exec(estimate, log.TaskID, log.Minutes); err != nil {
+		return nil, domain_errors.NewDatabaseError("remaining estimate update", err)
+	}
+	return created, nil
+}
+
+func (r *PostgresTimeRepository) CreateWorkLog(log *WorkLog) (*WorkLog, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("work log transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	created, domainErr := insertWorkLog(tx, log)
+	if domainErr != nil {
+		return nil, domainErr
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("work log commit", err)
+	}
+	return created, nil
+}
+
+func (r *PostgresTimeRepository) GetWorkLog(taskID, id string) (*WorkLog, domain_errors.DomainError) {
+	query := `SELECT ` + workLogColumns + ` FROM work_log WHERE id = $1 AND task_id = $2`
+
+	log := &WorkLog{}
+	if err := scanWorkLog(r.db.QueryRow(query, id, taskID), log); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("work log", id)
+		}
+		return nil, domain_errors.NewDatabaseError("work log query", err)
+	}
+	return log, nil
+}
+
+func (r *PostgresTimeRepository) ListWorkLogs(taskID string) ([]*WorkLog, domain_errors.DomainError) {
+	query := `SELECT ` + workLogColumns + ` FROM work_log WHERE task_id = $1 ORDER BY started_at DESC`
+
+	rows, err := r.db.Query(query, taskID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("work log list", err)
+	}
+	defer rows.Close()
+
+	logs := []*WorkLog{}
+	for rows.Next() {
+		log := &WorkLog{}
+		if err := scanWorkLog(rows, log); err != nil {
+			return nil, domain_errors.NewDatabaseError("work log scan", err)
+		}
+		logs = append(logs, log)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("work log rows iteration", err)
+	}
+	return logs, nil
+}
+
+func (r *PostgresTimeRepository) DeleteWorkLog(taskID, id string) domain_errors.DomainError {
+	result, err := r.db.Exec(`DELETE FROM work_log WHERE id = $1 AND task_id = $2`, id, taskID)
+	if err != nil {
+		return domain_errors.NewDatabaseError("work log deletion", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError("work log deletion", err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError("work log", id)
+	}
+	return nil
+}
+
+func (r *PostgresTimeRepository) StartTimer(timer *WorkTimer) (*WorkTimer, domain_errors.DomainError) {
+	query := `
+		INSERT INTO work_timer (user_id, task_id, started_at, description)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := r.db.Exec(query, timer.UserID, timer.TaskID, timer.StartedAt, timer.Description); err != nil {
+		if isUniqueViolation(err) {
+			return nil, domain_errors.NewConflictError("work timer", "A TIMER IS ALREADY RUNNING, STOP IT FIRST")
+		}
+		return nil, domain_errors.NewDatabaseError("work timer creation", err)
+	}
+	return timer, nil
+}
+
+func (r *PostgresTimeRepository) GetTimer(userID string) (*WorkTimer, domain_errors.DomainError) {
+	query := `SELECT user_id, task_id, started_at, description FROM work_timer WHERE user_id = $1`
+
+	timer := &WorkTimer{}
+	if err := r.db.QueryRow(query, userID).Scan(&timer.UserID, &timer.TaskID, &timer.StartedAt, &timer.Description); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("work timer", userID)
+		}
+		return nil, domain_errors.NewDatabaseError("work timer query", err)
+	}
+	return timer, nil
+}
+
+func (r *PostgresTimeRepository) StopTimer(userID string, log func(timer *WorkTimer) *WorkLog) (*WorkLog, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("work timer transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// deleting the timer first means a concurrent stop cannot log it twice
+	query := `DELETE FROM work_timer WHERE user_id = $1 RETURNING user_id, task_id, started_at, description`
+	timer := &WorkTimer{}
+	if err := tx.QueryRow(query, userID).Scan(&timer.UserID, &timer.TaskID, &timer.StartedAt, &timer.Description); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("work timer", userID)
+		}
+		return nil, domain_errors.NewDatabaseError("work timer deletion", err)
+	}
+
+	created, domainErr := insertWorkLog(tx, log(timer))
+	if domainErr != nil {
+		return nil, domainErr
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("work timer commit", err)
+	}
+	return created, nil
+}
+
+func (r *PostgresTimeRepository) DiscardTimer(userID string) domain_errors.DomainError {
+	result, err := r.db.Exec(`DELETE FROM work_timer WHERE user_id = $1`, userID)
+	if err != nil {
+		return domain_errors.NewDatabaseError("work timer deletion", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError("work timer deletion", err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError("work timer", userID)
+	}
+	return nil
+}
+
+func (r *PostgresTimeRepository) Timesheet(projectID string, from, to time.Time) ([]*TimesheetEntry, domain_errors.DomainError) {
+	// weeks start on Monday, as date_trunc and ISO 8601 define them
+	query := `
+		SELECT w.user_id, date_trunc('week', w.started_at) AS week_start, SUM(w.minutes)
+		FROM work_log w
+		INNER JOIN task t ON t.id = w.task_id
+		WHERE t.project_id = $1 AND t.deleted_at IS NULL AND w.started_at >= $2 AND w.started_at < $3
+		GROUP BY w.user_id, week_start
+		ORDER BY w.user_id, week_start
+	`
+
+	rows, err := r.db.Query(query, projectID, from, to)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("timesheet query", err)
+	}
+	defer rows.Close()
+
+	entries := []*TimesheetEntry{}
+	for rows.Next() {
+		entry := &TimesheetEntry{}
+		if err := rows.Scan(&entry.UserID, &entry.WeekStart, &entry.Minutes); err != nil {
+			return nil, domain_errors.NewDatabaseError("timesheet scan", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("timesheet rows iteration", err)
+	}
+	return entries, nil
+}
+
+// ============================================================================
+// MILESTONES
+// ============================================================================
+
+type PostgresMilestoneRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresMilestoneRepository(db *sql.DB) *PostgresMilestoneRepository {
+	return &PostgresMilestoneRepository{db: db}
+}
+
+const milestoneColumns = `id, project_id, name, kind, description, start_date, end_date, created_by, created_at`
+
+func scanMilestone(row interface{ Scan(dest ...any) error }, milestone *Milestone) error {
+	return row.Scan(
+		&milestone.ID,
+		&milestone.ProjectID,
+		&milestone.Name,
+		&milestone.Kind,
+		&milestone.Description,
+		&milestone.StartDate,
+		&milestone.EndDate,
+		&milestone.CreatedBy,
+		&milestone.CreatedAt,
+	)
+}
+
+func (r *PostgresMilestoneRepository) Create(milestone *Milestone) (*Milestone, domain_errors.DomainError) {
+	query := `
+		INSERT INTO milestone (` + milestoneColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING ` + milestoneColumns
+
+	created := &Milestone{}
+	row := r.db.QueryRow(query, milestone.ID, milestone.ProjectID, milestone.Name, milestone.Kind, milestone.Description,
+		milestone.StartDate, milestone.EndDate, milestone.CreatedBy, milestone.CreatedAt)
+	if err := scanMilestone(row, created); err != nil {
+		return nil, domain_errors.NewDatabaseError("milestone creation", err)
+	}
+	return created, nil
+}
+
+func (r *PostgresMilestoneRepository) GetByID(projectID, id string) (*Milestone, domain_errors.DomainError) {
+	query := `SELECT ` + milestoneColumns + ` FROM milestone WHERE id = $1 AND project_id = $2`
+
+	milestone := &Milestone{}
+	if err := scanMilestone(r.db.QueryRow(query, id, projectID), milestone); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("milestone", id)
+		}
+		return nil, domain_errors.NewDatabaseError("milestone query", err)
+	}
+	return milestone, nil
+}
+
+func (r *PostgresMilestoneRepository) ListByProject(projectID string) ([]*Milestone, domain_errors.DomainError) {
+	query := `SELECT ` + milestoneColumns + ` FROM milestone WHERE project_id = $1 ORDER BY start_date, created_at`
+
+	rows, err := r.db.Query(query, projectID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("milestone list", err)
+	}
+	defer rows.Close()
+
+	milestones := []*Milestone{}
+	for rows.Next() {
+		milestone := &Milestone{}
+		if err := scanMilestone(rows, milestone); err != nil {
+			return nil, domain_errors.NewDatabaseError("milestone scan", err)
+		}
+		milestones = append(milestones, milestone)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("milestone rows iteration", err)
+	}
+	return milestones, nil
+}
+
+func (r *PostgresMilestoneRepository) Update(milestone *Milestone) (*Milestone, domain_errors.DomainError) {
+	query := `
+		UPDATE milestone
+		SET name = $3, description = $4, start_date = $5, end_date = $6
+		WHERE id = $1 AND project_id = $2
+		RETURNING ` + milestoneColumns
+
+	updated := &Milestone{}
+	row := r.db.QueryRow(query, milestone.ID, milestone.ProjectID, milestone.Name, milestone.Description, milestone.StartDate, milestone.EndDate)
+	if err := scanMilestone(row, updated); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("milestone", milestone.ID)
+		}
+		return nil, domain_errors.NewDatabaseError("milestone update", err)
+	}
+	return updated, nil
+}
+
+func (r *PostgresMilestoneRepository) Delete(projectID, id string) domain_errors.DomainError {
+	result, err := r.db.Exec(`DELETE FROM milestone WHERE id = $1 AND project_id = $2`, id, projectID)
+	if err != nil {
+		return domain_errors.NewDatabaseError("milestone deletion", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError("milestone deletion", err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError("milestone", id)
+	}
+	return nil
+}
+
+func (r *PostgresMilestoneRepository) ListEvents(milestoneID string) ([]*TaskEvent, domain_errors.DomainError) {
+	query := `
+		SELECT ` + taskEventColumns + `
+		FROM task_activity
+		WHERE task_id IN (
+			SELECT a.task_id
+			FROM task_activity a
+			INNER JOIN task t ON t.id = a.task_id
+			WHERE a.milestone_id = $1 AND t.deleted_at IS NULL
+		)
+		ORDER BY created_at, id
+	`
+	return queryTaskEvents(r.db, "milestone events", query, milestoneID)
+}
+
+func (r *PostgresMilestoneRepository) TaskEstimates(milestoneID string) (map[string]int, domain_errors.DomainError) {
+	query := `
+		SELECT DISTINCT t.id, COALESCE(t.original_estimate_minutes, 0)
+		FROM task t
+		INNER JOIN task_activity a ON a.task_id = t.id
+		WHERE a.milestone_id = $1 AND t.deleted_at IS NULL
+	`
+
+	rows, err := r.db.Query(query, milestoneID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("milestone estimates query", err)
+	}
+	defer rows.Close()
+
+	estimates := map[string]int{}
+	for rows.Next() {
+		var taskID string
+		var minutes int
+		if err := rows.Scan(&taskID, &minutes); err != nil {
+			return nil, domain_errors.NewDatabaseError("milestone estimates scan", err)
+		}
+		estimates[taskID] = minutes
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("milestone estimates iteration", err)
+	}
+	return estimates, nil
+}
+
+// ============================================================================
+// REPORTS
+// ============================================================================
+
+type PostgresReportRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresReportRepository(db *sql.DB) *PostgresReportRepository {
+	return &PostgresReportRepository{db: db}
+}
+
+// scopedTasks selects the tasks of the scope given as $1 (workspace) and $2
+// (project, or empty for the whole workspace) under the alias t, leaving out
+// the trash. It ends with its WHERE clause, so queries add their own conditions with AND.
+const scopedTasks = `
+	task t
+	INNER JOIN project p ON p.id = t.project_id
+	WHERE p.workspace_id = $1 AND ($2 = '' OR t.project_id = $2) AND t.deleted_at IS NULL
+`
+
+// scopedCloses selects the moments tasks of the scope were closed: the events
+// whose status snapshot is closed, given as $5, when the previous one was not.
+// This covers closes by any kind of change.
+const scopedCloses = `
+	SELECT task_id, created_at, task_created_at, task_status
+	FROM (
+		SELECT a.task_id, a.created_at, a.status, t.created_at AS task_created_at, t.status AS task_status,
+			LAG(a.status) OVER (PARTITION BY a.task_id ORDER BY a.created_at, a.id) AS previous_status
+		FROM task_activity a, ` + scopedTasks + ` AND t.id = a.task_id
+	) events
+	WHERE status = $5 AND previous_status IS DISTINCT FROM $5
+`
+
+func (r *PostgresReportRepository) TaskSummary(scope ReportScope, now time.Time) (*TaskSummary, domain_errors.DomainError) {
+	query := `
+		SELECT t.status, t.priority, COUNT(*),
+			COUNT(*) FILTER (WHERE t.status <> $3 AND t.due_date < $4)
+		FROM ` + scopedTasks + `
+		GROUP BY t.status, t.priority
+	`
+
+	rows, err := r.db.Query(query, scope.WorkspaceID, scope.ProjectID, TaskStatusClosed, now)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("task summary query", err)
+	}
+	defer rows.Close()
+
+	summary := &TaskSummary{
+		WorkspaceID: scope.WorkspaceID,
+		ProjectID:   scope.ProjectID,
+		ByStatus:    map[TaskStatus]int{TaskStatusOpen: 0, TaskStatusInReview: 0, TaskStatusClosed: 0},
+		ByPriority:  map[TaskPriority]int{TaskPriorityLow: 0, TaskPriorityMedium: 0, TaskPriorityHigh: 0},
+		GeneratedAt: now,
+	}
+	for rows.Next() {
+		var status TaskStatus
+		var priority TaskPriority
+		var count, overdue int
+		if err := rows.Scan(&status, &priority, &count, &overdue); err != nil {
+			return nil, domain_errors.NewDatabaseError("task summary scan", err)
+		}
+		summary.Total += count
+		summary.ByStatus[status] += count
+		summary.ByPriority[priority] += count
+		summary.Overdue += overdue
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("task summary iteration", err)
+	}
+	return summary, nil
+}
+
+func (r *PostgresReportRepository) Throughput(scope ReportScope, from, to time.Time) ([]*ThroughputWeek, domain_errors.DomainError) {
+	// every week of the period is listed, including those without closes
+	query := `
+		WITH weeks AS (
+			SELECT generate_series(date_trunc('week', $3::timestamp), $4::timestamp, interval '1 week') AS week_start
+		),
+		closes AS (
+			SELECT task_id, created_at
+			FROM (` + scopedCloses + `) closes
+			WHERE created_at >= $3 AND created_at < $4
+		)
+		SELECT w.week_start, COUNT(DISTINCT c.task_id)
+		FROM weeks w
+		LEFT JOIN closes c ON date_trunc('week', c.created_at) = w.week_start
+		GROUP BY w.week_start
+		ORDER BY w.week_start
+	`
+
+	rows, err := r.db.Query(query, scope.WorkspaceID, scope.ProjectID, from, to, TaskStatusClosed)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("throughput query", err)
+	}
+	defer rows.Close()
+
+	weeks := []*ThroughputWeek{}
+	for rows.Next() {
+		week := &ThroughputWeek{}
+		if err := rows.Scan(&week.WeekStart, &week.Closed); err != nil {
+			return nil, domain_errors.NewDatabaseError("throughput scan", err)
+		}
+		weeks = append(weeks, week)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("throughput iteration", err)
+	}
+	return weeks, nil
+}
+
+func (r *PostgresReportRepository) CycleTime(scope ReportScope, from, to time.Time) (*CycleTime, domain_errors.DomainError) {
+	// a task reopened and closed again counts once, up to its last close
+	query := `
+		WITH closed AS (
+			SELECT DISTINCT ON (task_id)
+				EXTRACT(EPOCH FROM created_at - task_created_at) / 3600 AS hours,
+				created_at
+			FROM (` + scopedCloses + `) closes
+			WHERE task_status = $5
+			ORDER BY task_id, created_at DESC
+		)
+		SELECT COUNT(*),
+			COALESCE(AVG(hours), 0),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY hours), 0),
+			COALESCE(percentile_cont(0.85) WITHIN GROUP (ORDER BY hours), 0),
+			COALESCE(MAX(hours), 0)
+		FROM closed
+		WHERE created_at >= $3 AND created_at < $4
+	`
+
+	cycle := &CycleTime{WorkspaceID: scope.WorkspaceID, ProjectID: scope.ProjectID, From: from, To: to}
+	err := r.db.QueryRow(query, scope.WorkspaceID, scope.ProjectID, from, to, TaskStatusClosed).Scan(
+		&cycle.Tasks, &cycle.AverageHours, &cycle.MedianHours, &cycle.P85Hours, &cycle.MaxHours,
+	)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("cycle time query", err)
+	}
+	return cycle, nil
+}
+
+func (r *PostgresReportRepository) Workload(scope ReportScope, now time.Time) (*Workload, domain_errors.DomainError) {
+	query := `
+		SELECT a.assignee,
+			COUNT(*) FILTER (WHERE t.status = $3),
+			COUNT(*) FILTER (WHERE t.status = $4),
+			COUNT(*) FILTER (WHERE t.due_date < $6),
+			COALESCE(SUM(t.remaining_estimate_minutes), 0)
+		FROM task_assignment a, ` + scopedTasks + ` AND t.id = a.task_id AND t.status <> $5
+		GROUP BY a.assignee
+		ORDER BY COUNT(*) DESC, a.assignee
+	`
+
+	rows, err := r.db.Query(query, scope.WorkspaceID, scope.ProjectID, TaskStatusOpen, TaskStatusInReview, TaskStatusClosed, now)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("workload query", err)
+	}
+	defer rows.Close()
+
+	workload := &Workload{WorkspaceID: scope.WorkspaceID, ProjectID: scope.ProjectID, Assignees: []*AssigneeWorkload{}, GeneratedAt: now}
+	for rows.Next() {
+		assignee := &AssigneeWorkload{}
+		if err := rows.Scan(&assignee.UserID, &assignee.OpenTasks, &assignee.InReviewTasks, &assignee.OverdueTasks, &assignee.RemainingEstimateMinutes); err != nil {
+			return nil, domain_errors.NewDatabaseError("workload scan", err)
+		}
+		workload.Assignees = append(workload.Assignees, assignee)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("workload iteration", err)
+	}
+
+	unassigned := `
+		SELECT COUNT(*)
+		FROM ` + scopedTasks + ` AND t.status <> $3
+			AND NOT EXISTS (SELECT 1 FROM task_assignment a WHERE a.task_id = t.id)
+	`
+	if err := r.db.QueryRow(unassigned, scope.WorkspaceID, scope.ProjectID, TaskStatusClosed).Scan(&workload.Unassigned); err != nil {
+		return nil, domain_errors.NewDatabaseError("unassigned task count", err)
+	}
+	return workload, nil
+}
+
+// ============================================================================
+// TEMPLATES
+// ============================================================================
+
+type PostgresTemplateRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTemplateRepository(db *sql.DB) *PostgresTemplateRepository {
+	return &PostgresTemplateRepository{db: db}
+}
+
+const templateColumns = `id, workspace_id, name, description, tasks, created_by, created_at`
+
+func scanTemplate(row interface{ Scan(dest ...any) error }, template *TaskTemplate) error {
+	var tasks []byte
+	if err := row.Scan(
+		&template.ID,
+		&template.WorkspaceID,
+		&template.Name,
+		&template.Description,
+		&tasks,
+		&template.CreatedBy,
+		&template.CreatedAt,
+	); err != nil {
+		return err
+	}
+	return json.Unmarshal(tasks, &template.Tasks)
+}
+
+func (r *PostgresTemplateRepository) Create(template *TaskTemplate) (*TaskTemplate, domain_errors.DomainError) {
+	tasks, err := json.Marshal(template.Tasks)
+	if err != nil {
+		return nil, domain_errors.NewInternalError("template tasks encoding", err)
+	}
+	query := `
+		INSERT INTO task_template (` + templateColumns + `)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6, $7)
+		RETURNING ` + templateColumns
+
+	created := &TaskTemplate{}
+	row := r.db.QueryRow(query, template.ID, template.WorkspaceID, template.Name, template.Description, string(tasks), template.CreatedBy, template.CreatedAt)
+	if err := scanTemplate(row, created); err != nil {
+		if isUniqueViolation(err) {
+			return nil, domain_errors.NewConflictError("template", "TEMPLATE WITH THIS NAME ALREADY EXISTS")
+		}
+		return nil, domain_errors.NewDatabaseError("template creation", err)
+	}
+	return created, nil
+}
+
+func (r *PostgresTemplateRepository) GetByID(wsID, id string) (*TaskTemplate, domain_errors.DomainError) {
+	query := `SELECT ` + templateColumns + ` FROM task_template WHERE id = $1 AND workspace_id = $2`
+
+	template := &TaskTemplate{}
+	if err := scanTemplate(r.db.QueryRow(query, id, wsID), template); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("template", id)
+		}
+		return nil, domain_errors.NewDatabaseError("template query", err)
+	}
+	return template, nil
+}
+
+func (r *PostgresTemplateRepository) ListByWorkspace(wsID string) ([]*TaskTemplate, domain_errors.DomainError) {
+	query := `SELECT ` + templateColumns + ` FROM task_template WHERE workspace_id = $1 ORDER BY LOWER(name)`
+
+	rows, err := r.db.Query(query, wsID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("template list", err)
+	}
+	defer rows.Close()
+
+	templates := []*TaskTemplate{}
+	for rows.Next() {
+		template := &TaskTemplate{}
+		if err := scanTemplate(rows, template); err != nil {
+			return nil, domain_errors.NewDatabaseError("template scan", err)
+		}
+		templates = append(templates, template)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("template rows iteration", err)
+	}
+	return templates, nil
+}
+
+func (r *PostgresTemplateRepository) Delete(wsID, id string) domain_errors.DomainError {
+	result, err := r.db.Exec(`DELETE FROM task_template WHERE id = $1 AND workspace_id = $2`, id, wsID)
+	if err != nil {
+		return domain_errors.NewDatabaseError("template deletion", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError("template deletion", err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError("template", id)
+	}
+	return nil
+}
+
+// ============================================================================
+// TRASH
+// ============================================================================
+
+type PostgresTrashRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTrashRepository(db *sql.DB) *PostgresTrashRepository {
+	return &PostgresTrashRepository{db: db}
+}
+
+func (r *PostgresTrashRepository) List(wsID string) ([]*TrashItem, domain_errors.DomainError) {
+	// a task is listed unless it was deleted along with its project or parent,
+	// which is when it shares their deletion time
+	query := `
+		SELECT kind, id, name, project_id, deleted_at, COALESCE(deleted_by, '')
+		FROM (
+			SELECT $2 AS kind, p.id, p.name, p.id AS project_id, p.deleted_at, p.deleted_by
+			FROM project p
+			WHERE p.workspace_id = $1 AND p.deleted_at IS NOT NULL
+			UNION ALL
+			SELECT $3, t.id, t.name, t.project_id, t.deleted_at, t.deleted_by
+			FROM task t
+			INNER JOIN project p ON p.id = t.project_id
+			WHERE p.workspace_id = $1 AND t.deleted_at IS NOT NULL
+				AND p.deleted_at IS DISTINCT FROM t.deleted_at
+				AND NOT EXISTS (
+					SELECT 1 FROM task parent WHERE parent.id = t.parent_id AND parent.deleted_at = t.deleted_at
+				)
+		) trash
+		ORDER BY deleted_at DESC, id
+	`
+
+	rows, err := r.db.Query(query, wsID, TrashItemProject, TrashItemTask)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("trash list", err)
+	}
+	defer rows.Close()
+
+	items := []*TrashItem{}
+	for rows.Next() {
+		item := &TrashItem{}
+		if err := rows.Scan(&item.Kind, &item.ID, &item.Name, &item.ProjectID, &item.DeletedAt, &item.DeletedBy); err != nil {
+			return nil, domain_errors.NewDatabaseError("trash scan", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("trash rows iteration", err)
+	}
+	return items, nil
+}
+
+func (r *PostgresTrashRepository) RestoreProject(wsID, id string) (*Project, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("project restoration transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var deletedAt time.Time
+	lock := `SELECT deleted_at FROM project WHERE id = $1 AND workspace_id = $2 AND deleted_at IS NOT NULL FOR UPDATE`
+	if err := tx.QueryRow(lock, id, wsID).Scan(&deletedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("Project", id)
+		}
+		return nil, domain_errors.NewDatabaseError("project restoration", err)
+	}
+	if _, err := tx.Exec(`UPDATE task SET deleted_at = NULL, deleted_by = NULL WHERE project_id = $1 AND deleted_at = $2`, id, deletedAt); err != nil {
+		return nil, domain_errors.NewDatabaseError("project restoration", err)
+	}
+	query := `
+		UPDATE project SET deleted_at = NULL, deleted_by = NULL WHERE id = $1
+		RETURNING ` + projectColumns + `
+	`
+	project := &Project{}
+	if err := scanProject(tx.QueryRow(query, id), project); err != nil {
+		return nil, domain_errors.NewDatabaseError("project restoration", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("project restoration commit", err)
+	}
+	return project, nil
+}
+
+func (r *PostgresTrashRepository) RestoreTask(wsID, id, actor string, at time.Time) (*Task, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("task restoration transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	task := &Task{}
+	var deletedAt time.Time
+	var projectDeleted bool
+	lock := `
+		SELECT ` + qualifiedTaskColumns("t") + `, t.deleted_at, p.deleted_at IS NOT NULL
+		FROM task t
+		INNER JOIN project p ON p.id = t.project_id
+		WHERE t.id = $1 AND p.workspace_id = $2 AND t.deleted_at IS NOT NULL
+		FOR UPDATE OF t
+	`
+	if err := scanTask(tx.QueryRow(lock, id, wsID), task, &deletedAt, &projectDeleted); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("task", id)
+		}
+		return nil, domain_errors.NewDatabaseError("task restoration", err)
+	}
+	if projectDeleted {
+		return nil, domain_errors.NewInvalidOperationError("task restoration", "THE TASK'S PROJECT IS IN THE TRASH AND HAS TO BE RESTORED FIRST")
+	}
+	if task.ParentID != nil {
+		var parentDeleted bool
+		if err := tx.QueryRow(`SELECT deleted_at IS NOT NULL FROM task WHERE id = $1`, *task.ParentID).Scan(&parentDeleted); err != nil {
+			return nil, domain_errors.NewDatabaseError("task restoration", err)
+		}
+		if parentDeleted {
+			return nil, domain_errors.NewInvalidOperationError("task restoration", "THE PARENT TASK IS IN THE TRASH AND HAS TO BE RESTORED FIRST")
+		}
+	}
+	// only the subtasks deleted along with the task come back
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM task WHERE id = $1
+			UNION ALL
+			SELECT t.id FROM task t INNER JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at = $2
+		)
+		UPDATE task SET deleted_at = NULL, deleted_by = NULL WHERE id IN (SELECT id FROM subtree)
+	`
+	if _, err := tx.Exec(query, id, deletedAt); err != nil {
+		return nil, domain_errors.NewDatabaseError("task restoration", err)
+	}
+	if err := insertTaskEvent(tx, task, actor, TaskEventRestored, nil, nil, at); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("task restoration commit", err)
+	}
+	return task, nil
+}
+
+func (r *PostgresTrashRepository) PurgeProjects(before time.Time, limit int) (int, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("project purge transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	ids, derr := queryStrings(tx, "project purge", `
+		SELECT id FROM project
+		WHERE deleted_at < $1
+		ORDER BY deleted_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, before, limit)
+	if derr != nil {
+		return 0, derr
+	}
+	for _, id := range ids {
+		if err := purgeProject(tx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, domain_errors.NewDatabaseError("project purge commit", err)
+	}
+	return len(ids), nil
+}
+
+// purgeProject deletes the project for good, with everything cascading from it
+func purgeProject(tx *sql.Tx, id string) domain_errors.DomainError {
+	// custom fields cascade but their task indexes have to be dropped
+	rows, err := tx.Query(`SELECT id FROM custom_field WHERE project_id = $1`, id)
+	if err != nil {
+		return domain_errors.NewDatabaseError("project purge", err)
+	}
+	var indexes []string
+	for rows.Next() {
+		field := &CustomField{}
+		if err := rows.Scan(&field.ID); err != nil {
+			rows.Close()
+			return domain_errors.NewDatabaseError("project purge", err)
+		}
+		indexes = append(indexes, customFieldIndexName(field))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return domain_errors.NewDatabaseError("project purge", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM project WHERE id = $1`, id); err != nil {
+		return domain_errors.NewDatabaseError("project purge", err)
+	}
+	for _, index := range indexes {
+		if _, err := tx.Exec(`DROP INDEX IF EXISTS ` + index); err != nil {
+			return domain_errors.NewDatabaseError("project purge", err)
+		}
+	}
+	return nil
+}
+
+func (r *PostgresTrashRepository) PurgeTasks(before time.Time, limit int) (int, domain_errors.DomainError) {
+	// subtasks go with their parent, so only the top of each deleted subtree is picked
+	query := `
+		DELETE FROM task
+		WHERE id IN (
+			SELECT t.id FROM task t
+			WHERE t.deleted_at < $1
+				AND (t.parent_id IS NULL OR EXISTS (
+					SELECT 1 FROM task parent WHERE parent.id = t.parent_id AND parent.deleted_at IS NULL
+				))
+			ORDER BY t.deleted_at
+			LIMIT $2
+		)
+	`
+	result, err := r.db.Exec(query, before, limit)
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("task purge", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("task purge", err)
+	}
+	return int(rows), nil
+}
+
+// ============================================================================
+// COMMENTS AND MENTIONS
+// ============================================================================
+
+type PostgresCommentRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresCommentRepository(db *sql.DB) *PostgresCommentRepository {
+	return &PostgresCommentRepository{db: db}
+}
+
+const commentColumns = `id, task_id, author, content, created_at, updated_at`
+
+func scanComment(row interface{ Scan(dest ...any) error }, comment *TaskComment) error {
+	return row.Scan(&comment.ID, &comment.TaskID, &comment.Author, &comment.Content, &comment.CreatedAt, &comment.UpdatedAt)
+}
+
+func (r *PostgresCommentRepository) CreateComment(comment *TaskComment) (*TaskComment, domain_errors.DomainError) {
+	query := `
+		INSERT INTO task_comment (` + commentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + commentColumns
+
+	created := &TaskComment{}
+	row := r.db.QueryRow(query, comment.ID, comment.TaskID, comment.Author, comment.Content, comment.CreatedAt, comment.UpdatedAt)
+	if err := scanComment(row, created); err != nil {
+		return nil, domain_errors.NewDatabaseError("comment creation", err)
+	}
+	return created, nil
+}
+
+func (r *PostgresCommentRepository) GetComment(taskID, id string) (*TaskComment, domain_errors.DomainError) {
+	query := `SELECT ` + commentColumns + ` FROM task_comment WHERE id = $1 AND task_id = $2`
+
+	comment := &TaskComment{}
+	if err := scanComment(r.db.QueryRow(query, id, taskID), comment); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("comment", id)
+		}
+		return nil, domain_errors.NewDatabaseError("comment query", err)
+	}
+	return comment, nil
+}
+
+func (r *PostgresCommentRepository) ListComments(taskID string) ([]*TaskComment, domain_errors.DomainError) {
+	query := `SELECT ` + commentColumns + ` FROM task_comment WHERE task_id = $1 ORDER BY created_at ASC, id`
+
+	rows, err := r.db.Query(query, taskID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("comment list", err)
+	}
+	defer rows.Close()
+
+	comments := []*TaskComment{}
+	for rows.Next() {
+		comment := &TaskComment{}
+		if err := scanComment(rows, comment); err != nil {
+			return nil, domain_errors.NewDatabaseError("comment scan", err)
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("comment rows iteration", err)
+	}
+	return comments, nil
+}
+
+func (r *PostgresCommentRepository) UpdateComment(taskID, id, content string, at time.Time) (*TaskComment, domain_errors.DomainError) {
+	query := `
+		UPDATE task_comment SET content = $3, updated_at = $4
+		WHERE id = $1 AND task_id = $2
+		RETURNING ` + commentColumns
+
+	comment := &TaskComment{}
+	if err := scanComment(r.db.QueryRow(query, id, taskID, content, at), comment); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("comment", id)
+		}
+		return nil, domain_errors.NewDatabaseError("comment update", err)
+	}
+	return comment, nil
+}
+
+func (r *PostgresCommentRepository) DeleteComment(taskID, id string) domain_errors.DomainError {
+	result, err := r.db.Exec(`DELETE FROM task_comment WHERE id = $1 AND task_id = $2`, id, taskID)
+	if err != nil {
+		return domain_errors.NewDatabaseError("comment deletion", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError("comment deletion", err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError("comment", id)
+	}
+	return nil
+}
+
+func (r *PostgresCommentRepository) ListMemberEmails(wsID string, handles []string) (map[string]string, domain_errors.DomainError) {
+	query := `
+		SELECT a.id, a.email
+		FROM membership m
+		JOIN auth a ON a.id = m.user_id
+		WHERE m.workspace_id = $1
+			AND (LOWER(a.email) = ANY($2) OR LOWER(split_part(a.email, '@', 1)) = ANY($2))
+	`
+
+	rows, err := r.db.Query(query, wsID, handles)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("mention member query", err)
+	}
+	defer rows.Close()
+
+	emails := map[string]string{}
+	for rows.Next() {
+		var userID, email string
+		if err := rows.Scan(&userID, &email); err != nil {
+			return nil, domain_errors.NewDatabaseError("mention member scan", err)
+		}
+		emails[userID] = email
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("mention member rows iteration", err)
+	}
+	return emails, nil
+}
+
+const mentionColumns = `id, task_id, comment_id, user_id, author_id, handle, created_at`
+
+func scanMention(row interface{ Scan(dest ...any) error }, mention *Mention) error {
+	return row.Scan(&mention.ID, &mention.TaskID, &mention.CommentID, &mention.UserID, &mention.AuthorID, &mention.Handle, &mention.CreatedAt)
+}
+
+func (r *PostgresCommentRepository) SetMentions(taskID string, commentID *string, mentions []*Mention) ([]*Mention, domain_errors.DomainError) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("mention transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	userIDs := make([]string, len(mentions))
+	for i, mention := range mentions {
+		userIDs[i] = mention.UserID
+	}
+	remove := `
+		DELETE FROM task_mention
+		WHERE task_id = $1 AND comment_id IS NOT DISTINCT FROM $2::varchar AND NOT (user_id = ANY($3))
+	`
+	if _, err := tx.Exec(remove, taskID, commentID, userIDs); err != nil {
+		return nil, domain_errors.NewDatabaseError("mention update", err)
+	}
+	// users already mentioned keep their mention, so they are not told again
+	insert := `
+		INSERT INTO task_mention (` + mentionColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (task_id, (COALESCE(comment_id, '')), user_id) DO NOTHING
+		RETURNING ` + mentionColumns
+	added := []*Mention{}
+	for _, mention := range mentions {
+		created := &Mention{}
+		row := tx.QueryRow(insert, mention.ID, taskID, commentID, mention.UserID, mention.AuthorID, mention.Handle, mention.CreatedAt)
+		if err := scanMention(row, created); err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			return nil, domain_errors.NewDatabaseError("mention creation", err)
+		}
+		added = append(added, created)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, domain_errors.NewDatabaseError("mention commit", err)
+	}
+	return added, nil
+}
+
+func (r *PostgresCommentRepository) ListMentions(taskID string) ([]*Mention, domain_errors.DomainError) {
+	query := `SELECT ` + mentionColumns + ` FROM task_mention WHERE task_id = $1 ORDER BY created_at ASC, id`
+
+	rows, err := r.db.Query(query, taskID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("mention list", err)
+	}
+	defer rows.Close()
+
+	mentions := []*Mention{}
+	for rows.Next() {
+		mention := &Mention{}
+		if err := scanMention(rows, mention); err != nil {
+			return nil, domain_errors.NewDatabaseError("mention scan", err)
+		}
+		mentions = append(mentions, mention)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("mention rows iteration", err)
+	}
+	return mentions, nil
+}
+
+// ============================================================================
+// NOTIFICATIONS
+// ============================================================================
+
+type PostgresNotificationRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresNotificationRepository(db *sql.DB) *PostgresNotificationRepository {
+	return &PostgresNotificationRepository{db: db}
+}
+
+const notificationColumns = `n.id, n.user_id, n.workspace_id, n.kind, n.task_id, n.comment_id, n.actor_id, n.detail, n.read_at, n.created_at`
+
+func scanNotification(row interface{ Scan(dest ...any) error }, notification *Notification) error {
+	return row.Scan(
+		&notification.ID,
+		&notification.UserID,
+		&notification.WorkspaceID,
+		&notification.Kind,
+		&notification.TaskID,
+		&notification.CommentID,
+		&notification.ActorID,
+		&notification.Detail,
+		&notification.ReadAt,
+		&notification.CreatedAt,
+	)
+}
+
+func (r *PostgresNotificationRepository) Create(notifications []*Notification) domain_errors.DomainError {
+	if len(notifications) == 0 {
+		return nil
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return domain_errors.NewDatabaseError("notification transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	insert := `
+		INSERT INTO notification (id, user_id, workspace_id, kind, task_id, comment_id, actor_id, detail, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	for _, n := range notifications {
+		if _, err := tx.Exec(insert, n.ID, n.UserID, n.WorkspaceID, n.Kind, n.TaskID, n.CommentID, n.ActorID, n.Detail, n.CreatedAt); err != nil {
+			return domain_errors.NewDatabaseError("notification creation", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return domain_errors.NewDatabaseError("notification commit", err)
+	}
+	return nil
+}
+
+func (r *PostgresNotificationRepository) List(userID, wsID string, unreadOnly bool, limit int) ([]*Notification, domain_errors.DomainError) {
+	// notifications about tasks in the trash are hidden until they are restored
+	query := `
+		SELECT ` + notificationColumns + `
+		FROM notification n
+		JOIN task t ON t.id = n.task_id
+		WHERE n.user_id = $1 AND n.workspace_id = $2 AND t.deleted_at IS NULL
+			AND (NOT $3 OR n.read_at IS NULL)
+		ORDER BY n.created_at DESC, n.id
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(query, userID, wsID, unreadOnly, limit)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("notification list", err)
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+	for rows.Next() {
+		notification := &Notification{}
+		if err := scanNotification(rows, notification); err != nil {
+			return nil, domain_errors.NewDatabaseError("notification scan", err)
+		}
+		notifications = append(notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("notification rows iteration", err)
+	}
+	return notifications, nil
+}
+
+func (r *PostgresNotificationRepository) MarkRead(userID, wsID, id string, at time.Time) (*Notification, domain_errors.DomainError) {
+	query := `
+		UPDATE notification n SET read_at = COALESCE(n.read_at, $4)
+		WHERE n.id = $3 AND n.user_id = $1 AND n.workspace_id = $2
+		RETURNING ` + notificationColumns
+
+	notification := &Notification{}
+	if err := scanNotification(r.db.QueryRow(query, userID, wsID, id, at), notification); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("notification", id)
+		}
+		return nil, domain_errors.NewDatabaseError("notification update", err)
+	}
+	return notification, nil
+}
+
+func (r *PostgresNotificationRepository) MarkAllRead(userID, wsID string, at time.Time) (int, domain_errors.DomainError) {
+	result, err := r.db.Exec(`UPDATE notification SET read_at = $3 WHERE user_id = $1 AND workspace_id = $2 AND read_at IS NULL`, userID, wsID, at)
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("notification update", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("notification update", err)
+	}
+	return int(rows), nil
+}
+
+// ============================================================================
+// WATCHERS
+// ============================================================================
+
+type PostgresWatcherRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresWatcherRepository(db *sql.DB) *PostgresWatcherRepository {
+	return &PostgresWatcherRepository{db: db}
+}
+
+func (r *PostgresWatcherRepository) WatchTasks(taskIDs, userIDs []string, at time.Time) domain_errors.DomainError {
+	if len(taskIDs) == 0 || len(userIDs) == 0 {
+		return nil
+	}
+	query := `
+		INSERT INTO task_watcher (task_id, user_id, created_at)
+		SELECT task_id, user_id, $3
+		FROM unnest($1::text[]) AS task_id CROSS JOIN unnest($2::text[]) AS user_id
+		ON CONFLICT (task_id, user_id) DO NOTHING
+	`
+	if _, err := r.db.Exec(query, taskIDs, userIDs, at); err != nil {
+		return domain_errors.NewDatabaseError("task watch", err)
+	}
+	return nil
+}
+
+func (r *PostgresWatcherRepository) UnwatchTask(taskID, userID string) domain_errors.DomainError {
+	if _, err := r.db.Exec(`DELETE FROM task_watcher WHERE task_id = $1 AND user_id = $2`, taskID, userID); err != nil {
+		return domain_errors.NewDatabaseError("task unwatch", err)
+	}
+	return nil
+}
+
+func (r *PostgresWatcherRepository) ListTaskWatchers(taskID string) ([]*Watcher, domain_errors.DomainError) {
+	return r.listWatchers("task watcher", `SELECT user_id, created_at FROM task_watcher WHERE task_id = $1 ORDER BY created_at, user_id`, taskID)
+}
+
+func (r *PostgresWatcherRepository) WatchProject(projectID, userID string, at time.Time) domain_errors.DomainError {
+	query := `
+		INSERT INTO project_watcher (project_id, user_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (project_id, user_id) DO NOTHING
+	`
+	if _, err := r.db.Exec(query, projectID, userID, at); err != nil {
+		return domain_errors.NewDatabaseError("project watch", err)
+	}
+	return nil
+}
+
+func (r *PostgresWatcherRepository) UnwatchProject(projectID, userID string) domain_errors.DomainError {
+	if _, err := r.db.Exec(`DELETE FROM project_watcher WHERE project_id = $1 AND user_id = $2`, projectID, userID); err != nil {
+		return domain_errors.NewDatabaseError("project unwatch", err)
+	}
+	return nil
+}
+
+func (r *PostgresWatcherRepository) ListProjectWatchers(projectID string) ([]*Watcher, domain_errors.DomainError) {
+	return r.listWatchers("project watcher", `SELECT user_id, created_at FROM project_watcher WHERE project_id = $1 ORDER BY created_at, user_id`, projectID)
+}
+
+func (r *PostgresWatcherRepository) listWatchers(operation, query, id string) ([]*Watcher, domain_errors.DomainError) {
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError(operation+" list", err)
+	}
+	defer rows.Close()
+
+	watchers := []*Watcher{}
+	for rows.Next() {
+		watcher := &Watcher{}
+		if err := rows.Scan(&watcher.UserID, &watcher.CreatedAt); err != nil {
+			return nil, domain_errors.NewDatabaseError(operation+" scan", err)
+		}
+		watchers = append(watchers, watcher)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError(operation+" rows iteration", err)
+	}
+	return watchers, nil
+}
+
+func (r *PostgresWatcherRepository) TaskAudience(taskID string) ([]string, domain_errors.DomainError) {
+	// watchers who left the workspace keep their watches but are not told anything
+	query := `
+		SELECT m.user_id
+		FROM task t
+		JOIN project p ON p.id = t.project_id
+		JOIN membership m ON m.workspace_id = p.workspace_id
+		WHERE t.id = $1
+			AND (
+				EXISTS (SELECT 1 FROM task_watcher tw WHERE tw.task_id = t.id AND tw.user_id = m.user_id)
+				OR EXISTS (SELECT 1 FROM project_watcher pw WHERE pw.project_id = t.project_id AND pw.user_id = m.user_id)
+			)
+		ORDER BY m.user_id
+	`
+
+	rows, err := r.db.Query(query, taskID)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("task audience query", err)
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, domain_errors.NewDatabaseError("task audience scan", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("task audience rows iteration", err)
+	}
+	return userIDs, nil
+}
+
+// ============================================================================
+// DUE REMINDERS
+// ============================================================================
+
+type PostgresReminderRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresReminderRepository(db *sql.DB) *PostgresReminderRepository {
+	return &PostgresReminderRepository{db: db}
+}
+
+func (r *PostgresReminderRepository) ListDueAssignments(from, to time.Time) ([]*DueAssignment, domain_errors.DomainError) {
+	// assignees who left the workspace keep their assignments but are not reminded
+	query := `
+		SELECT t.id, t.name, p.workspace_id, t.due_date, a.id, a.email,
+			COALESCE(up.timezone, 'UTC'), COALESCE(up.locale, '')
+		FROM task t
+		JOIN project p ON p.id = t.project_id
+		JOIN task_assignment ta ON ta.task_id = t.id
+		JOIN membership m ON m.workspace_id = p.workspace_id AND m.user_id = ta.assignee
+		JOIN auth a ON a.id = ta.assignee
+		LEFT JOIN user_profile up ON up.id = ta.assignee
+		LEFT JOIN task_due_reminder r ON r.task_id = t.id AND r.user_id = ta.assignee
+		WHERE t.due_date >= $1 AND t.due_date < $2
+			AND t.status <> $3
+			AND t.deleted_at IS NULL AND p.deleted_at IS NULL
+			AND r.due_date IS DISTINCT FROM t.due_date
+		ORDER BY t.due_date, t.id, a.id
+	`
+
+	rows, err := r.db.Query(query, from, to, TaskStatusClosed)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("due assignment list", err)
+	}
+	defer rows.Close()
+
+	assignments := []*DueAssignment{}
+	for rows.Next() {
+		assignment := &DueAssignment{Assignee: &user.UserProfile{}}
+		if err := rows.Scan(
+			&assignment.TaskID, &assignment.TaskName, &assignment.WorkspaceID, &assignment.DueDate,
+			&assignment.UserID, &assignment.Email, &assignment.Assignee.Timezone, &assignment.Assignee.Locale,
+		); err != nil {
+			return nil, domain_errors.NewDatabaseError("due assignment scan", err)
+		}
+		assignment.Assignee.ID = assignment.UserID
+		assignments = append(assignments, assignment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("due assignment rows iteration", err)
+	}
+	return assignments, nil
+}
+
+func (r *PostgresReminderRepository) ClaimReminder(taskID, userID string, dueDate, at time.Time) (bool, domain_errors.DomainError) {
+	// an earlier reminder only counts for the due date it was sent for
+	query := `
+		INSERT INTO task_due_reminder (task_id, user_id, due_date, sent_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (task_id, user_id) DO UPDATE
+			SET due_date = EXCLUDED.due_date, sent_at = EXCLUDED.sent_at
+			WHERE task_due_reminder.due_date <> EXCLUDED.due_date
+	`
+	result, err := r.db.Exec(query, taskID, userID, dueDate, at)
+	if err != nil {
+		return false, domain_errors.NewDatabaseError("due reminder claim", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, domain_errors.NewDatabaseError("due reminder claim", err)
+	}
+	return rows == 1, nil
+}
@@ -0,0 +1,123 @@
+package project
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/internal/shared"
+	"github.com/ishola-faazele/taskflow/internal/user"
+	amqp_utils "github.com/ishola-faazele/taskflow/internal/utils/amqp"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// reminderHour is the hour of the assignee's day they are reminded at
+	reminderHour = 9
+	// reminderLead bounds how long before the due date a reminder can be sent:
+	// reminderAt is never a whole day ahead
+	reminderLead = 24 * time.Hour
+	// reminderDueFormat writes the due date in the reminder emails
+	reminderDueFormat = "2006-01-02 15:04 MST"
+)
+
+// DueAssignment is an assignee of an open task coming due
+type DueAssignment struct {
+	TaskID      string
+	TaskName    string
+	WorkspaceID string
+	DueDate     time.Time
+	UserID      string
+	Email       string
+	// Assignee holds the time zone and locale the reminder is written for
+	Assignee *user.UserProfile
+}
+
+// reminderAt is when an assignee is reminded of a due date: at reminderHour on
+// the day it is due in their time zone, or on the day before when the task is
+// due earlier in the day than that
+func reminderAt(due time.Time, loc *time.Location) time.Time {
+	local := due.In(loc)
+	at := time.Date(local.Year(), local.Month(), local.Day(), reminderHour, 0, 0, 0, loc)
+	if !at.Before(due) {
+		at = at.AddDate(0, 0, -1)
+	}
+	return at
+}
+
+// DueReminder emails the assignees of open tasks on the morning they are due,
+// once per due date
+type DueReminder struct {
+	repo ReminderRepository
+	conn *amqp.Connection
+	// baseURL is the base of the task links in the reminders
+	baseURL string
+
+	*shared.Periodic
+}
+
+func NewDueReminder(db *sql.DB, conn *amqp.Connection, interval time.Duration) *DueReminder {
+	d := &DueReminder{
+		repo:    NewPostgresReminderRepository(db),
+		conn:    conn,
+		baseURL: publicBaseURLFromEnv(),
+	}
+	d.Periodic = shared.NewPeriodic("FAILED_TO_SEND_DUE_REMINDERS", interval, func(now time.Time) error {
+		_, err := d.Remind(now)
+		return err
+	})
+	return d
+}
+
+// Remind emails the assignees whose reminder time has come and returns how many.
+// A reminder is claimed before it is sent, so it is not sent twice even when
+// several servers run the reminder.
+func (d *DueReminder) Remind(now time.Time) (int, error) {
+	if d.conn == nil {
+		return 0, nil
+	}
+	assignments, err := d.repo.ListDueAssignments(now, now.Add(reminderLead))
+	if err != nil {
+		return 0, err
+	}
+	var ch *amqp.Channel
+	defer func() {
+		if ch != nil {
+			ch.Close()
+		}
+	}()
+	sent := 0
+	for _, assignment := range assignments {
+		loc := assignment.Assignee.Location()
+		if now.Before(reminderAt(assignment.DueDate, loc)) {
+			continue
+		}
+		claimed, claimErr := d.repo.ClaimReminder(assignment.TaskID, assignment.UserID, assignment.DueDate, now)
+		if claimErr != nil {
+			return sent, claimErr
+		}
+		if !claimed {
+			continue
+		}
+		if ch == nil {
+			var chErr error
+			if ch, chErr = d.conn.Channel(); chErr != nil {
+				return sent, chErr
+			}
+		}
+		emailMsg, msgErr := amqp_utils.NewTaskDueMessage(
+			assignment.Email,
+			assignment.TaskName,
+			assignment.DueDate.In(loc).Format(reminderDueFormat),
+			taskURL(d.baseURL, assignment.WorkspaceID, assignment.TaskID),
+			assignment.Assignee.Locale,
+		)
+		if msgErr != nil {
+			return sent, msgErr
+		}
+		if err := amqp_utils.PublishEmailMessage(ch, emailMsg); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
@@ -0,0 +1,133 @@
+package project
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+const (
+	// reportCacheTTL is how stale a report may be. Reports are not invalidated
+	// when tasks change, so dashboards catch up within this delay.
+	reportCacheTTL = time.Minute
+	// maxReportCacheEntries bounds the cache; expired reports are dropped past it
+	maxReportCacheEntries = 1024
+	// defaultReportRange is the period of throughput and cycle time reports when
+	// the request does not give one
+	defaultReportRange = 12 * 7 * 24 * time.Hour
+	maxReportRange     = 366 * 24 * time.Hour
+)
+
+// ReportScope selects the tasks a report covers: those of a project, or of the
+// whole workspace when ProjectID is empty
+type ReportScope struct {
+	WorkspaceID string
+	ProjectID   string
+}
+
+// TaskSummary counts the tasks in scope by status and priority
+type TaskSummary struct {
+	WorkspaceID string               `json:"workspace_id"`
+	ProjectID   string               `json:"project_id,omitempty"`
+	Total       int                  `json:"total"`
+	ByStatus    map[TaskStatus]int   `json:"by_status"`
+	ByPriority  map[TaskPriority]int `json:"by_priority"`
+	// Overdue counts the unfinished tasks whose due date has passed
+	Overdue     int       `json:"overdue"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// ThroughputWeek counts the tasks closed during the week starting on Monday at WeekStart
+type ThroughputWeek struct {
+	WeekStart time.Time `json:"week_start"`
+	Closed    int       `json:"closed"`
+}
+
+type Throughput struct {
+	WorkspaceID string            `json:"workspace_id"`
+	ProjectID   string            `json:"project_id,omitempty"`
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	Weeks       []*ThroughputWeek `json:"weeks"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+// CycleTime describes how long the tasks closed during the period took from
+// creation to their last close, in hours
+type CycleTime struct {
+	WorkspaceID  string    `json:"workspace_id"`
+	ProjectID    string    `json:"project_id,omitempty"`
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	Tasks        int       `json:"tasks"`
+	AverageHours float64   `json:"average_hours"`
+	MedianHours  float64   `json:"median_hours"`
+	P85Hours     float64   `json:"p85_hours"`
+	MaxHours     float64   `json:"max_hours"`
+	GeneratedAt  time.Time `json:"generated_at"`
+}
+
+// AssigneeWorkload sums the unfinished work assigned to a user
+type AssigneeWorkload struct {
+	UserID                   string `json:"user_id"`
+	OpenTasks                int    `json:"open_tasks"`
+	InReviewTasks            int    `json:"in_review_tasks"`
+	OverdueTasks             int    `json:"overdue_tasks"`
+	RemainingEstimateMinutes int    `json:"remaining_estimate_minutes"`
+}
+
+type Workload struct {
+	WorkspaceID string              `json:"workspace_id"`
+	ProjectID   string              `json:"project_id,omitempty"`
+	Assignees   []*AssigneeWorkload `json:"assignees"`
+	// Unassigned counts the unfinished tasks nobody is assigned to
+	Unassigned  int       `json:"unassigned"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// reportCache keeps computed reports for reportCacheTTL
+type reportCache struct {
+	mu      sync.Mutex
+	entries map[string]reportCacheEntry
+}
+
+type reportCacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+func newReportCache() *reportCache {
+	return &reportCache{entries: map[string]reportCacheEntry{}}
+}
+
+// cachedReport returns the cached report for key, or computes and caches it.
+// Reports are computed outside the lock, so concurrent misses may both compute.
+func cachedReport[T any](cache *reportCache, key string, now time.Time, compute func() (T, domain_errors.DomainError)) (T, domain_errors.DomainError) {
+	cache.mu.Lock()
+	entry, ok := cache.entries[key]
+	cache.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.value.(T), nil
+	}
+
+	value, err := compute()
+	if err != nil {
+		return value, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if len(cache.entries) >= maxReportCacheEntries {
+		for k, e := range cache.entries {
+			if !now.Before(e.expires) {
+				delete(cache.entries, k)
+			}
+		}
+		if len(cache.entries) >= maxReportCacheEntries {
+			cache.entries = map[string]reportCacheEntry{}
+		}
+	}
+	cache.entries[key] = reportCacheEntry{value: value, expires: now.Add(reportCacheTTL)}
+	return value, nil
+}
@@ -1,20 +1,58 @@
 package project
 
-import "github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+import (
+	"time"
+
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
 
 type ProjectRepository interface {
 	Create(project *Project) (*Project, domain_errors.DomainError)
 	GetByID(id string) (*Project, domain_errors.DomainError)
 	Update(input *UpdateProjectInput, id string) (*Project, domain_errors.DomainError)
-	Delete(id string) domain_errors.DomainError
-	ListByWorkspace(wsID string) ([]*Project, domain_errors.DomainError)
+	// Delete moves the project and its tasks to the trash
+	Delete(id, actor string, at time.Time) domain_errors.DomainError
+	// ListByWorkspace lists the workspace's projects, leaving out the archived ones
+	// unless includeArchived is set
+	ListByWorkspace(wsID string, includeArchived bool) ([]*Project, domain_errors.DomainError)
+	// SetArchived archives the project at archivedAt, or unarchives it when nil
+	SetArchived(id string, archivedAt *time.Time) (*Project, domain_errors.DomainError)
+	WorkspaceArchived(wsID string) (bool, domain_errors.DomainError)
+	ProjectArchiveState(projectID string) (*ArchiveState, domain_errors.DomainError)
+	// TaskArchiveState tells whether any of the tasks is in an archived project or workspace
+	TaskArchiveState(taskIDs []string) (*ArchiveState, domain_errors.DomainError)
 
 	// methods for tasks
 	// Basic CRUD
 	CreateTask(task *Task) (*Task, domain_errors.DomainError)
+	// BulkUpdateTasks applies the changes to the tasks in one transaction, recording
+	// a single activity entry per changed task, and returns what it changed by task id
+	BulkUpdateTasks(taskIDs []string, changes *BulkTaskChanges, actor string, at time.Time) (map[string]*BulkTaskChange, domain_errors.DomainError)
+	// ImportTasks creates the tasks, with their labels, in a single transaction.
+	// Parents come before their subtasks.
+	ImportTasks(tasks []*Task) domain_errors.DomainError
+	// CloneProject creates the project with its custom fields and tasks in a single
+	// transaction. Parents come before their subtasks.
+	CloneProject(project *Project, fields []*CustomField, tasks []*Task) (*Project, domain_errors.DomainError)
 	GetTaskByID(id string) (*Task, domain_errors.DomainError)
-	UpdateTask(input *UpdateTaskInput, id string) (*Task, domain_errors.DomainError)
-	DeleteTask(id string) domain_errors.DomainError
+	// UpdateTask records a status change in the task's activity
+	UpdateTask(input *UpdateTaskInput, id, actor string) (*Task, domain_errors.DomainError)
+	// DeleteTask moves the task and its subtasks to the trash
+	DeleteTask(id, actor string, at time.Time) domain_errors.DomainError
+	// SetTaskMilestone moves the task to a milestone, or out of any when nil, and
+	// records the change in its activity
+	SetTaskMilestone(taskID string, milestoneID *string, actor string, at time.Time) (*Task, domain_errors.DomainError)
+	ListTaskActivity(taskID string) ([]*TaskEvent, domain_errors.DomainError)
+	// MoveTask puts the task, with its subtasks, under parentID or at the root of
+	// projectID. It refuses to move a task under itself or its subtasks, or to make
+	// the tree deeper than maxDepth. Tasks moved to another project leave their
+	// milestone and keep only the custom field values valid for fields, the new
+	// project's fields.
+	MoveTask(taskID string, parentID *string, projectID string, fields []*CustomField, maxDepth int, actor string, at time.Time) (*Task, domain_errors.DomainError)
+	// AssignTask stores the assignments, skipping users already assigned to their task
+	AssignTask(assignments []*TaskAssignment) domain_errors.DomainError
+	UnassignTask(taskID, assignee string) domain_errors.DomainError
+	ListTaskAssignments(taskID string) ([]*TaskAssignment, domain_errors.DomainError)
 
 	// Tree queries
 	ListSubtasks(parentID string) ([]*Task, domain_errors.DomainError)
@@ -23,10 +61,200 @@ type ProjectRepository interface {
 	GetRootTasks(projectID string) ([]*Task, domain_errors.DomainError)
 
 	// Project queries
-	ListTasksByProject(projectID string) ([]*Task, domain_errors.DomainError)
+	// ListTasksByProject lists the project's tasks matching the query's labels and
+	// custom field filters, in the query's order
+	ListTasksByProject(projectID string, query *TaskQuery) ([]*Task, domain_errors.DomainError)
 	GetProjectTaskTree(projectID string) ([]*TaskTree, domain_errors.DomainError)
+	// GetTimeRollups totals estimates and logged time for every task of the trees
+	// rooted at rootIDs, keyed by task id
+	GetTimeRollups(rootIDs []string) (map[string]*TimeRollup, domain_errors.DomainError)
 
 	// Utility
+	GetTaskWorkspaceID(taskID string) (string, domain_errors.DomainError)
+	// CountWorkspaceMembers counts how many of the users are members of the workspace
+	CountWorkspaceMembers(wsID string, userIDs []string) (int, domain_errors.DomainError)
+	// GetMemberRole returns the user's role in the workspace, empty when they are not a member
+	GetMemberRole(wsID, userID string) (string, domain_errors.DomainError)
+	// ListWorkspaceTaskIDs returns the ids among ids of tasks of the workspace
+	ListWorkspaceTaskIDs(wsID string, ids []string) ([]string, domain_errors.DomainError)
+	// ListProjectTaskIDs returns the ids among ids of tasks of the project
+	ListProjectTaskIDs(projectID string, ids []string) ([]string, domain_errors.DomainError)
 	GetTaskDepth(id string) (int, domain_errors.DomainError)
 	CountSubtasks(parentID string) (int, domain_errors.DomainError)
 }
+
+type AttachmentRepository interface {
+	// ReserveBlob records that key is about to be referenced, protecting it from
+	// collection, and reports whether the blob's content was already stored
+	ReserveBlob(key string, size int64, contentType string, at time.Time) (bool, domain_errors.DomainError)
+	// MarkBlobStored records that the blob's content has been written to the store
+	MarkBlobStored(key string) domain_errors.DomainError
+	Create(attachment *TaskAttachment) (*TaskAttachment, domain_errors.DomainError)
+	GetByID(taskID, id string) (*TaskAttachment, domain_errors.DomainError)
+	ListByTask(taskID string) ([]*TaskAttachment, domain_errors.DomainError)
+	Delete(taskID, id string) domain_errors.DomainError
+	// DeleteUnreferencedBlobs removes up to limit blobs no attachment references and
+	// that were not reserved since before. remove deletes the stored content and runs
+	// while the blob's row is locked, so a concurrent upload cannot reuse it.
+	DeleteUnreferencedBlobs(before time.Time, limit int, remove func(key string) error) (int, domain_errors.DomainError)
+}
+
+type LabelRepository interface {
+	Create(label *Label) (*Label, domain_errors.DomainError)
+	GetByID(wsID, id string) (*Label, domain_errors.DomainError)
+	ListByWorkspace(wsID string) ([]*Label, domain_errors.DomainError)
+	// ListByIDs returns the labels of the workspace among ids
+	ListByIDs(wsID string, ids []string) ([]*Label, domain_errors.DomainError)
+	Update(wsID, id string, input *UpdateLabelInput) (*Label, domain_errors.DomainError)
+	Delete(wsID, id string) domain_errors.DomainError
+	// Merge moves every task of the source label to the target label and deletes the source
+	Merge(wsID, sourceID, targetID string) (*Label, domain_errors.DomainError)
+
+	AddToTask(taskID, labelID string, at time.Time) domain_errors.DomainError
+	RemoveFromTask(taskID, labelID string) domain_errors.DomainError
+	// SetTaskLabels replaces the labels of a task
+	SetTaskLabels(taskID string, labelIDs []string, at time.Time) domain_errors.DomainError
+	// ListByTasks returns the labels of each of the tasks keyed by task id
+	ListByTasks(taskIDs []string) (map[string][]*Label, domain_errors.DomainError)
+}
+
+type CustomFieldRepository interface {
+	// Create stores the field at the end of the project's fields. Its index is
+	// built later by the CustomFieldIndexer.
+	Create(field *CustomField) (*CustomField, domain_errors.DomainError)
+	GetByID(projectID, id string) (*CustomField, domain_errors.DomainError)
+	ListByProject(projectID string) ([]*CustomField, domain_errors.DomainError)
+	Update(field *CustomField, input *UpdateCustomFieldInput) (*CustomField, domain_errors.DomainError)
+	// Delete removes the field and its values from every task. Its index is
+	// dropped later by the CustomFieldIndexer.
+	Delete(field *CustomField) domain_errors.DomainError
+}
+
+// CustomFieldIndexRepository builds and drops the task indexes of custom fields
+// without locking the task table against writes
+type CustomFieldIndexRepository interface {
+	// ListUnindexed returns up to limit fields whose index is not built yet, oldest first
+	ListUnindexed(limit int) ([]*CustomField, domain_errors.DomainError)
+	// BuildIndex builds the field's index concurrently and records it as built
+	BuildIndex(field *CustomField) domain_errors.DomainError
+	// DropOrphanedIndexes drops the indexes of deleted fields and returns how many
+	DropOrphanedIndexes() (int, domain_errors.DomainError)
+}
+
+type TimeRepository interface {
+	// CreateWorkLog stores the entry and lowers the task's remaining estimate by its time
+	CreateWorkLog(log *WorkLog) (*WorkLog, domain_errors.DomainError)
+	GetWorkLog(taskID, id string) (*WorkLog, domain_errors.DomainError)
+	ListWorkLogs(taskID string) ([]*WorkLog, domain_errors.DomainError)
+	DeleteWorkLog(taskID, id string) domain_errors.DomainError
+
+	StartTimer(timer *WorkTimer) (*WorkTimer, domain_errors.DomainError)
+	GetTimer(userID string) (*WorkTimer, domain_errors.DomainError)
+	// StopTimer removes the user's timer and stores the work log built from it
+	StopTimer(userID string, log func(timer *WorkTimer) *WorkLog) (*WorkLog, domain_errors.DomainError)
+	DiscardTimer(userID string) domain_errors.DomainError
+
+	// Timesheet sums the time logged on the project's tasks by user and week
+	Timesheet(projectID string, from, to time.Time) ([]*TimesheetEntry, domain_errors.DomainError)
+}
+
+type MilestoneRepository interface {
+	Create(milestone *Milestone) (*Milestone, domain_errors.DomainError)
+	GetByID(projectID, id string) (*Milestone, domain_errors.DomainError)
+	ListByProject(projectID string) ([]*Milestone, domain_errors.DomainError)
+	Update(milestone *Milestone) (*Milestone, domain_errors.DomainError)
+	Delete(projectID, id string) domain_errors.DomainError
+	// ListEvents returns the whole activity of every task that was ever in the
+	// milestone, oldest first
+	ListEvents(milestoneID string) ([]*TaskEvent, domain_errors.DomainError)
+	// TaskEstimates returns the original estimate of every task that was ever in the milestone
+	TaskEstimates(milestoneID string) (map[string]int, domain_errors.DomainError)
+}
+
+// ReportRepository aggregates tasks for reports. Each method covers the tasks of
+// the scope's project, or of its whole workspace.
+type ReportRepository interface {
+	// TaskSummary counts tasks by status and priority and the unfinished ones overdue at now
+	TaskSummary(scope ReportScope, now time.Time) (*TaskSummary, domain_errors.DomainError)
+	// Throughput counts the tasks closed in each week between from and to
+	Throughput(scope ReportScope, from, to time.Time) ([]*ThroughputWeek, domain_errors.DomainError)
+	// CycleTime measures creation to close for the closed tasks last closed between from and to
+	CycleTime(scope ReportScope, from, to time.Time) (*CycleTime, domain_errors.DomainError)
+	// Workload sums the unfinished tasks of each assignee
+	Workload(scope ReportScope, now time.Time) (*Workload, domain_errors.DomainError)
+}
+
+type TemplateRepository interface {
+	Create(template *TaskTemplate) (*TaskTemplate, domain_errors.DomainError)
+	GetByID(wsID, id string) (*TaskTemplate, domain_errors.DomainError)
+	ListByWorkspace(wsID string) ([]*TaskTemplate, domain_errors.DomainError)
+	Delete(wsID, id string) domain_errors.DomainError
+}
+
+// TrashRepository manages deleted projects and tasks. Everything deleted at once
+// shares its deletion time, which is how it is restored together.
+type TrashRepository interface {
+	// List returns the deleted projects of the workspace and its tasks deleted on
+	// their own, most recently deleted first
+	List(wsID string) ([]*TrashItem, domain_errors.DomainError)
+	// RestoreProject restores the project with the tasks deleted along with it
+	RestoreProject(wsID, id string) (*Project, domain_errors.DomainError)
+	// RestoreTask restores the task with the subtasks deleted along with it. It
+	// refuses while the task's project or parent is in the trash.
+	RestoreTask(wsID, id, actor string, at time.Time) (*Task, domain_errors.DomainError)
+	// PurgeProjects permanently deletes up to limit projects deleted before before
+	PurgeProjects(before time.Time, limit int) (int, domain_errors.DomainError)
+	// PurgeTasks permanently deletes up to limit tasks deleted before before, with their subtasks
+	PurgeTasks(before time.Time, limit int) (int, domain_errors.DomainError)
+}
+
+type CommentRepository interface {
+	CreateComment(comment *TaskComment) (*TaskComment, domain_errors.DomainError)
+	GetComment(taskID, id string) (*TaskComment, domain_errors.DomainError)
+	// ListComments returns the task's comments, oldest first
+	ListComments(taskID string) ([]*TaskComment, domain_errors.DomainError)
+	UpdateComment(taskID, id, content string, at time.Time) (*TaskComment, domain_errors.DomainError)
+	DeleteComment(taskID, id string) domain_errors.DomainError
+
+	// ListMemberEmails returns the email of each workspace member whose address,
+	// or its local part, is among handles, keyed by user id
+	ListMemberEmails(wsID string, handles []string) (map[string]string, domain_errors.DomainError)
+	// SetMentions replaces the mentions of the task's description, or of one of
+	// its comments when commentID is set, and returns those of users who were not
+	// mentioned there before
+	SetMentions(taskID string, commentID *string, mentions []*Mention) ([]*Mention, domain_errors.DomainError)
+	// ListMentions returns every mention of the task, in its description and comments
+	ListMentions(taskID string) ([]*Mention, domain_errors.DomainError)
+}
+
+type NotificationRepository interface {
+	Create(notifications []*Notification) domain_errors.DomainError
+	// List returns up to limit of the user's notifications in the workspace, newest first
+	List(userID, wsID string, unreadOnly bool, limit int) ([]*Notification, domain_errors.DomainError)
+	MarkRead(userID, wsID, id string, at time.Time) (*Notification, domain_errors.DomainError)
+	// MarkAllRead marks every unread notification of the user in the workspace read
+	// and returns how many
+	MarkAllRead(userID, wsID string, at time.Time) (int, domain_errors.DomainError)
+}
+
+type WatcherRepository interface {
+	// WatchTasks makes each user watch each task, keeping existing watches
+	WatchTasks(taskIDs, userIDs []string, at time.Time) domain_errors.DomainError
+	UnwatchTask(taskID, userID string) domain_errors.DomainError
+	ListTaskWatchers(taskID string) ([]*Watcher, domain_errors.DomainError)
+	WatchProject(projectID, userID string, at time.Time) domain_errors.DomainError
+	UnwatchProject(projectID, userID string) domain_errors.DomainError
+	ListProjectWatchers(projectID string) ([]*Watcher, domain_errors.DomainError)
+	// TaskAudience returns the ids of the members of the task's workspace who
+	// watch the task or its project
+	TaskAudience(taskID string) ([]string, domain_errors.DomainError)
+}
+
+type ReminderRepository interface {
+	// ListDueAssignments returns the assignments of open tasks due in [from, to)
+	// whose assignee has not been reminded of the task's current due date
+	ListDueAssignments(from, to time.Time) ([]*DueAssignment, domain_errors.DomainError)
+	// ClaimReminder records that the assignee is reminded of the due date and
+	// reports false when they already were
+	ClaimReminder(taskID, userID string, dueDate, at time.Time) (bool, domain_errors.DomainError)
+}
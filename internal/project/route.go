@@ -1,52 +1,129 @@
 package project
 
 import (
-	"database/sql"
-
 	domain_middleware "github.com/ishola-faazele/taskflow/internal/middleware"
+	"github.com/ishola-faazele/taskflow/internal/shared"
 	workspace_repository "github.com/ishola-faazele/taskflow/internal/workspace/db"
 	workspace_service "github.com/ishola-faazele/taskflow/internal/workspace/service"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func RegisterProjectRoutes(r chi.Router, DB *sql.DB) {
+func RegisterProjectRoutes(r chi.Router, as *shared.AppState) {
+	DB := as.DB
 	// Middleware
 	workspaceService := workspace_service.WorkspaceService{
 		MembershipRepo: workspace_repository.NewPostgresMembershipRepository(DB),
 	}
-	dm := domain_middleware.NewDomainMiddlewareWithWorkspace(&workspaceService)
-	handler := NewProjectHandler(DB)
+	dm := domain_middleware.NewDomainMiddlewareWithWorkspace(DB, &workspaceService)
+	handler := NewProjectHandler(DB, as.Blobs, as.AmqpConn)
 	r.Use(dm.Authenticate)
+	r.Use(dm.RequireResourceScope("projects"))
 	r.Use(dm.CheckMembership)
 	// Project routes
 	r.Post("/", handler.CreateProject)
 	r.Get("/all", handler.ListProjectsByWorkspace)
 	r.Get("/{id}", handler.GetProject)
 	r.Put("/{id}", handler.UpdateProject)
+	r.Patch("/{id}", handler.PatchProject)
 	r.Delete("/{id}", handler.DeleteProject)
+	r.Post("/{id}/clone", handler.CloneProject)
+	r.Post("/{id}/archive", handler.ArchiveProject)
+	r.Post("/{id}/unarchive", handler.UnarchiveProject)
+
+	// Watchers
+	r.Post("/{id}/watch", handler.WatchProject)
+	r.Delete("/{id}/watch", handler.UnwatchProject)
+	r.Get("/{id}/watchers", handler.ListProjectWatchers)
+
+	// Custom fields
+	r.Post("/{id}/fields", handler.CreateCustomField)
+	r.Get("/{id}/fields", handler.ListCustomFields)
+	r.Put("/{id}/fields/{field_id}", handler.UpdateCustomField)
+	r.Delete("/{id}/fields/{field_id}", handler.DeleteCustomField)
+
+	// Milestones
+	r.Post("/{id}/milestones", handler.CreateMilestone)
+	r.Get("/{id}/milestones", handler.ListMilestones)
+	r.Get("/{id}/milestones/{milestone_id}", handler.GetMilestone)
+	r.Put("/{id}/milestones/{milestone_id}", handler.UpdateMilestone)
+	r.Delete("/{id}/milestones/{milestone_id}", handler.DeleteMilestone)
+	r.Get("/{id}/milestones/{milestone_id}/burndown", handler.GetBurndown)
+
+	// Import and export
+	r.Post("/{id}/import", handler.ImportTasks)
+	r.Get("/{id}/export", handler.ExportProject)
+
+	// Reports
+	r.Get("/{id}/timesheet", handler.Timesheet)
 }
 
-func RegisterTaskRoutes(r chi.Router, DB *sql.DB) {
+func RegisterTaskRoutes(r chi.Router, as *shared.AppState) {
+	DB := as.DB
 	workspaceService := workspace_service.WorkspaceService{
 		MembershipRepo: workspace_repository.NewPostgresMembershipRepository(DB),
 	}
-	dm := domain_middleware.NewDomainMiddlewareWithWorkspace(&workspaceService)
+	dm := domain_middleware.NewDomainMiddlewareWithWorkspace(DB, &workspaceService)
 	r.Use(dm.Authenticate)
+	r.Use(dm.RequireResourceScope("tasks"))
 	r.Use(dm.CheckMembership)
-	handler := NewProjectHandler(DB)
+	handler := NewProjectHandler(DB, as.Blobs, as.AmqpConn)
 
 	// BASIC CRUD APIS
 	r.Post("/", handler.CreateTask)
 	r.Get("/{id}", handler.GetTaskByID)
 	r.Put("/{id}", handler.UpdateTask)
+	r.Patch("/{id}", handler.PatchTask)
 	r.Delete("/{id}", handler.DeleteTask)
+	r.Post("/bulk", handler.BulkUpdateTasks)
 	
 	// TREE QUERIES
 	r.Get("/{id}/subtasks", handler.ListSubtasks)
 	r.Get("/{id}/tree", handler.GetTaskTree)
 	r.Get("/{id}/children", handler.GetTaskWithChildren)
 	r.Get("/{id}/root", handler.GetRootTasks)
+	r.Post("/{id}/move", handler.MoveTask)
+
+	// ATTACHMENTS
+	r.Post("/{id}/attachments", handler.UploadAttachment)
+	r.Get("/{id}/attachments", handler.ListAttachments)
+	r.Get("/{id}/attachments/{attachment_id}", handler.DownloadAttachment)
+	r.Delete("/{id}/attachments/{attachment_id}", handler.DeleteAttachment)
+
+	// ASSIGNMENTS
+	r.Post("/{id}/assignees", handler.AssignTask)
+	r.Get("/{id}/assignees", handler.ListTaskAssignments)
+	r.Delete("/{id}/assignees/{user_id}", handler.UnassignTask)
+
+	// COMMENTS
+	r.Post("/{id}/comments", handler.CreateComment)
+	r.Get("/{id}/comments", handler.ListComments)
+	r.Put("/{id}/comments/{comment_id}", handler.UpdateComment)
+	r.Delete("/{id}/comments/{comment_id}", handler.DeleteComment)
+
+	// WATCHERS
+	r.Post("/{id}/watch", handler.WatchTask)
+	r.Delete("/{id}/watch", handler.UnwatchTask)
+	r.Get("/{id}/watchers", handler.ListTaskWatchers)
+
+	// MILESTONES AND ACTIVITY
+	r.Put("/{id}/milestone", handler.SetTaskMilestone)
+	r.Get("/{id}/activity", handler.ListTaskActivity)
+
+	// TIME TRACKING
+	r.Get("/timer", handler.GetTimer)
+	r.Post("/timer/stop", handler.StopTimer)
+	r.Delete("/timer", handler.DiscardTimer)
+	r.Post("/{id}/timer", handler.StartTimer)
+	r.Get("/{id}/time", handler.GetTimeRollup)
+	r.Post("/{id}/worklogs", handler.LogWork)
+	r.Get("/{id}/worklogs", handler.ListWorkLogs)
+	r.Delete("/{id}/worklogs/{worklog_id}", handler.DeleteWorkLog)
+
+	// LABELS
+	r.Post("/{id}/labels", handler.AddTaskLabels)
+	r.Put("/{id}/labels", handler.SetTaskLabels)
+	r.Delete("/{id}/labels/{label_id}", handler.RemoveTaskLabel)
 
 	// PROJECT QUERIES
 	r.Get("/{id}/project_tasks", handler.ListTasksByProject)
@@ -55,3 +132,88 @@ func RegisterTaskRoutes(r chi.Router, DB *sql.DB) {
 	// r.Get("/task/{id}/depth", handler.GetTaskDepth)
 	// r.Get("/task/{id}/count", handler.CountSubtasks)
 }
+
+func RegisterLabelRoutes(r chi.Router, as *shared.AppState) {
+	DB := as.DB
+	workspaceService := workspace_service.WorkspaceService{
+		MembershipRepo: workspace_repository.NewPostgresMembershipRepository(DB),
+	}
+	dm := domain_middleware.NewDomainMiddlewareWithWorkspace(DB, &workspaceService)
+	r.Use(dm.Authenticate)
+	r.Use(dm.RequireResourceScope("tasks"))
+	r.Use(dm.CheckMembership)
+	handler := NewProjectHandler(DB, as.Blobs, as.AmqpConn)
+
+	r.Post("/", handler.CreateLabel)
+	r.Get("/", handler.ListLabels)
+	r.Put("/{id}", handler.UpdateLabel)
+	r.Delete("/{id}", handler.DeleteLabel)
+	r.Post("/{id}/merge", handler.MergeLabel)
+}
+
+func RegisterReportRoutes(r chi.Router, as *shared.AppState) {
+	DB := as.DB
+	workspaceService := workspace_service.WorkspaceService{
+		MembershipRepo: workspace_repository.NewPostgresMembershipRepository(DB),
+	}
+	dm := domain_middleware.NewDomainMiddlewareWithWorkspace(DB, &workspaceService)
+	r.Use(dm.Authenticate)
+	r.Use(dm.RequireResourceScope("projects"))
+	r.Use(dm.CheckMembership)
+	handler := NewProjectHandler(DB, as.Blobs, as.AmqpConn)
+
+	r.Get("/summary", handler.TaskSummary)
+	r.Get("/throughput", handler.Throughput)
+	r.Get("/cycle_time", handler.CycleTime)
+	r.Get("/workload", handler.Workload)
+}
+
+func RegisterTemplateRoutes(r chi.Router, as *shared.AppState) {
+	DB := as.DB
+	workspaceService := workspace_service.WorkspaceService{
+		MembershipRepo: workspace_repository.NewPostgresMembershipRepository(DB),
+	}
+	dm := domain_middleware.NewDomainMiddlewareWithWorkspace(DB, &workspaceService)
+	r.Use(dm.Authenticate)
+	r.Use(dm.RequireResourceScope("projects"))
+	r.Use(dm.CheckMembership)
+	handler := NewProjectHandler(DB, as.Blobs, as.AmqpConn)
+
+	r.Post("/", handler.CreateTemplate)
+	r.Get("/", handler.ListTemplates)
+	r.Get("/{id}", handler.GetTemplate)
+	r.Delete("/{id}", handler.DeleteTemplate)
+	r.Post("/{id}/instantiate", handler.InstantiateTemplate)
+}
+
+func RegisterTrashRoutes(r chi.Router, as *shared.AppState) {
+	DB := as.DB
+	workspaceService := workspace_service.WorkspaceService{
+		MembershipRepo: workspace_repository.NewPostgresMembershipRepository(DB),
+	}
+	dm := domain_middleware.NewDomainMiddlewareWithWorkspace(DB, &workspaceService)
+	r.Use(dm.Authenticate)
+	r.Use(dm.RequireResourceScope("projects"))
+	r.Use(dm.CheckMembership)
+	handler := NewProjectHandler(DB, as.Blobs, as.AmqpConn)
+
+	r.Get("/", handler.ListTrash)
+	r.Post("/project/{id}/restore", handler.RestoreProject)
+	r.Post("/task/{id}/restore", handler.RestoreTask)
+}
+
+func RegisterNotificationRoutes(r chi.Router, as *shared.AppState) {
+	DB := as.DB
+	workspaceService := workspace_service.WorkspaceService{
+		MembershipRepo: workspace_repository.NewPostgresMembershipRepository(DB),
+	}
+	dm := domain_middleware.NewDomainMiddlewareWithWorkspace(DB, &workspaceService)
+	r.Use(dm.Authenticate)
+	r.Use(dm.RequireResourceScope("tasks"))
+	r.Use(dm.CheckMembership)
+	handler := NewProjectHandler(DB, as.Blobs, as.AmqpConn)
+
+	r.Get("/", handler.ListNotifications)
+	r.Post("/read", handler.MarkAllNotificationsRead)
+	r.Post("/{id}/read", handler.MarkNotificationRead)
+}
@@ -1,19 +1,98 @@
 package project
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
+	"github.com/ishola-faazele/taskflow/internal/emailservice"
+	"github.com/ishola-faazele/taskflow/internal/user"
+	amqp_utils "github.com/ishola-faazele/taskflow/internal/utils/amqp"
+	"github.com/ishola-faazele/taskflow/internal/utils/blobstore"
 	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type ProjectService struct {
-	projectRepo ProjectRepository
+	projectRepo      ProjectRepository
+	attachmentRepo   AttachmentRepository
+	labelRepo        LabelRepository
+	fieldRepo        CustomFieldRepository
+	timeRepo         TimeRepository
+	milestoneRepo    MilestoneRepository
+	reportRepo       ReportRepository
+	templateRepo     TemplateRepository
+	trashRepo        TrashRepository
+	commentRepo      CommentRepository
+	notificationRepo NotificationRepository
+	watcherRepo      WatcherRepository
+	// profileRepo resolves mentioned members and notification email recipients
+	profileRepo user.UserProfileRepository
+	reports     *reportCache
+	blobs       blobstore.Store
+	// conn publishes mention emails. Without one no email is sent.
+	conn *amqp.Connection
+	// maxTaskDepth is how many levels of subtasks a root task can have
+	maxTaskDepth int
+	// trashRetention is how long deleted projects and tasks can be restored
+	trashRetention time.Duration
+	// publicBaseURL is where the app is served, the base of the links in emails
+	publicBaseURL string
 }
 
-func NewProjectService(pjRepo ProjectRepository) *ProjectService {
+// DefaultMaxTaskDepth limits task trees unless TASK_MAX_DEPTH sets another limit
+const DefaultMaxTaskDepth = 10
+
+func maxTaskDepthFromEnv() int {
+	if depth, err := strconv.Atoi(os.Getenv("TASK_MAX_DEPTH")); err == nil && depth > 0 {
+		return depth
+	}
+	return DefaultMaxTaskDepth
+}
+
+// publicBaseURLFromEnv reads PUBLIC_BASE_URL, falling back to FRONTEND_URL
+func publicBaseURLFromEnv() string {
+	return strings.TrimRight(cmp.Or(os.Getenv("PUBLIC_BASE_URL"), os.Getenv("FRONTEND_URL")), "/")
+}
+
+func NewProjectService(pjRepo ProjectRepository, attachmentRepo AttachmentRepository, labelRepo LabelRepository, fieldRepo CustomFieldRepository, timeRepo TimeRepository, milestoneRepo MilestoneRepository, reportRepo ReportRepository, templateRepo TemplateRepository, trashRepo TrashRepository, commentRepo CommentRepository, notificationRepo NotificationRepository, watcherRepo WatcherRepository, profileRepo user.UserProfileRepository, blobs blobstore.Store, conn *amqp.Connection) *ProjectService {
 	return &ProjectService{
-		projectRepo: pjRepo,
+		projectRepo:      pjRepo,
+		attachmentRepo:   attachmentRepo,
+		labelRepo:        labelRepo,
+		fieldRepo:        fieldRepo,
+		timeRepo:         timeRepo,
+		milestoneRepo:    milestoneRepo,
+		reportRepo:       reportRepo,
+		templateRepo:     templateRepo,
+		trashRepo:        trashRepo,
+		commentRepo:      commentRepo,
+		notificationRepo: notificationRepo,
+		watcherRepo:      watcherRepo,
+		profileRepo:      profileRepo,
+		reports:          newReportCache(),
+		blobs:            blobs,
+		conn:             conn,
+		maxTaskDepth:     maxTaskDepthFromEnv(),
+		trashRetention:   trashRetentionFromEnv(),
+		publicBaseURL:    publicBaseURLFromEnv(),
 	}
 }
 
@@ -26,7 +105,17 @@ func (pjs *ProjectService) Create(name, desc, ws_id, creator string) (*Project,
 		Creator:     creator,
 		CreatedAt:   time.Now().UTC(),
 	}
-	return pjs.projectRepo.Create(project)
+	if err := pjs.checkWorkspaceWritable(ws_id); err != nil {
+		return nil, err
+	}
+	created, err := pjs.projectRepo.Create(project)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.watcherRepo.WatchProject(created.ID, creator, created.CreatedAt); err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
 func (pjs *ProjectService) GetByID(id string) (*Project, error) {
@@ -39,30 +128,152 @@ func (pjs *ProjectService) GetByID(id string) (*Project, error) {
 
 func (pjs *ProjectService) Update(input *UpdateProjectInput, id string) (*Project, error) {
 	// validate input
-	if input.Name == nil && input.Description == nil {
-		return nil, domain_errors.NewValidationError("input", "NO FIELDS TO UPDATE")
+	if err := input.Validate(); err != nil {
+		return nil, err
 	}
 	// validate id
 	if err := uuid.Validate(id); err != nil {
 		return nil, domain_errors.NewValidationErrorWithValue("id", id, "PROJECT ID IS NOT A VALID UUID")
 	}
+	if err := pjs.checkProjectWritable(id); err != nil {
+		return nil, err
+	}
 	return pjs.projectRepo.Update(input, id)
 }
 
-func (pjs *ProjectService) Delete(id string) error {
-	// validate id
-	if err := uuid.Validate(id); err != nil {
-		return domain_errors.NewValidationErrorWithValue("id", id, "PROJECT ID IS NOT A VALID UUID")
+// Applies a JSON Merge Patch or a JSON Patch to a project. ifVersion, when set,
+// is the version the patch was written against.
+func (pjs *ProjectService) PatchProject(wsID, id string, format PatchFormat, body []byte, ifVersion *int) (*Project, error) {
+	if err := pjs.checkProjectInWorkspace(wsID, id); err != nil {
+		return nil, err
+	}
+	var current *Project
+	if format == PatchFormatJSON {
+		var err domain_errors.DomainError
+		if current, err = pjs.projectRepo.GetByID(id); err != nil {
+			return nil, err
+		}
+		if ifVersion, err = patchedVersion("project", id, current, current.Version, ifVersion); err != nil {
+			return nil, err
+		}
+	}
+	patch, err := mergePatch(format, body, current)
+	if err != nil {
+		return nil, err
+	}
+	input, err := projectPatchInput(patch)
+	if err != nil {
+		return nil, err
+	}
+	if input.Name == nil && input.Description == nil {
+		if current != nil {
+			return current, nil
+		}
+		return pjs.projectRepo.GetByID(id)
+	}
+	input.IfVersion = ifVersion
+	return pjs.Update(input, id)
+}
+
+// patchedVersion returns the version a JSON Patch applies to. The patch is
+// computed against the current resource, so a concurrent update in between
+// fails the update rather than being overwritten.
+func patchedVersion(resource, id string, current any, version int, ifVersion *int) (*int, domain_errors.DomainError) {
+	if ifVersion != nil && *ifVersion != version {
+		return nil, domain_errors.NewVersionConflictError(resource, id, current)
+	}
+	return &version, nil
+}
+
+// Moves a project and its tasks to the trash
+func (pjs *ProjectService) Delete(wsID, id, actor string) error {
+	if err := pjs.checkProjectInWorkspace(wsID, id); err != nil {
+		return err
 	}
-	return pjs.projectRepo.Delete(id)
+	if err := pjs.checkProjectWritable(id); err != nil {
+		return err
+	}
+	return pjs.projectRepo.Delete(id, actor, time.Now().UTC())
 }
-func (pjs *ProjectService) ListByWorkspace(wsID, requester string) ([]*Project, error) {
+
+// Lists the projects of a workspace, leaving out archived ones unless includeArchived is set
+func (pjs *ProjectService) ListByWorkspace(wsID, requester string, includeArchived bool) ([]*Project, error) {
 	// validate wsID
 	if err := uuid.Validate(wsID); err != nil {
 		return nil, domain_errors.NewValidationErrorWithValue("workspace_id", wsID, "WORKSPACE ID IS NOT A VALID UUID")
 	}
 	// check requester membership in workspace could be added here
-	return pjs.projectRepo.ListByWorkspace(wsID)
+	return pjs.projectRepo.ListByWorkspace(wsID, includeArchived)
+}
+
+// Makes a project and its tasks read-only until it is unarchived
+func (pjs *ProjectService) ArchiveProject(wsID, id, requester string) (*Project, domain_errors.DomainError) {
+	if err := pjs.checkCanArchive(wsID, id, requester, "archive"); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkWorkspaceWritable(wsID); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	return pjs.projectRepo.SetArchived(id, &now)
+}
+
+// Makes an archived project writable again
+func (pjs *ProjectService) UnarchiveProject(wsID, id, requester string) (*Project, domain_errors.DomainError) {
+	if err := pjs.checkCanArchive(wsID, id, requester, "unarchive"); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkWorkspaceWritable(wsID); err != nil {
+		return nil, err
+	}
+	return pjs.projectRepo.SetArchived(id, nil)
+}
+
+// checkCanArchive allows only workspace admins and owners to archive and
+// unarchive projects. Other members are signed in, so they are forbidden.
+func (pjs *ProjectService) checkCanArchive(wsID, projectID, requester, action string) domain_errors.DomainError {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return err
+	}
+	role, err := pjs.projectRepo.GetMemberRole(wsID, requester)
+	if err != nil {
+		return err
+	}
+	if role != "admin" && role != "owner" {
+		return domain_errors.NewForbiddenError("project", action)
+	}
+	return nil
+}
+
+// checkWorkspaceWritable refuses writes to an archived workspace
+func (pjs *ProjectService) checkWorkspaceWritable(wsID string) domain_errors.DomainError {
+	archived, err := pjs.projectRepo.WorkspaceArchived(wsID)
+	if err != nil {
+		return err
+	}
+	if archived {
+		return domain_errors.NewInvalidOperationError("archived write", "THE WORKSPACE IS ARCHIVED")
+	}
+	return nil
+}
+
+// checkProjectWritable refuses writes to an archived project or to a project of
+// an archived workspace
+func (pjs *ProjectService) checkProjectWritable(projectID string) domain_errors.DomainError {
+	state, err := pjs.projectRepo.ProjectArchiveState(projectID)
+	if err != nil {
+		return err
+	}
+	return state.checkWritable()
+}
+
+// checkTasksWritable refuses writes to tasks of an archived project or workspace
+func (pjs *ProjectService) checkTasksWritable(taskIDs ...string) domain_errors.DomainError {
+	state, err := pjs.projectRepo.TaskArchiveState(taskIDs)
+	if err != nil {
+		return err
+	}
+	return state.checkWritable()
 }
 
 // ============================================================================
@@ -70,52 +281,299 @@ func (pjs *ProjectService) ListByWorkspace(wsID, requester string) ([]*Project,
 // ============================================================================
 
 func (pjs *ProjectService) CreateTask(input *CreateTaskInput) (*Task, domain_errors.DomainError) {
-	err := input.Validate()
+	var fields []*CustomField
+	if uuid.Validate(input.ProjectID) == nil {
+		var err domain_errors.DomainError
+		if fields, err = pjs.fieldRepo.ListByProject(input.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+	err := input.Validate(fields)
 	if err != nil {
 		return nil, err
 	}
+	if err := pjs.checkProjectWritable(input.ProjectID); err != nil {
+		return nil, err
+	}
+	if users := userValues(input.CustomFields, fields); len(users) > 0 {
+		project, err := pjs.projectRepo.GetByID(input.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		if err := pjs.checkMembers(project.WorkspaceID, users); err != nil {
+			return nil, err
+		}
+	}
 	task := &Task{
-		ID:          uuid.NewString(),
-		ProjectID:   input.ProjectID,
-		ParentID:    &input.ParentID,
-		Name:        input.Name,
-		Description: input.Description,
-		Creator:     input.Creator,
-		Status:      input.Status,
-		Priority:    input.Priority,
-		DueDate:     input.DueDate,
-		CreatedAt:   time.Now().UTC(),
-		UpdatedAt:   time.Now().UTC(),
+		ID:           uuid.NewString(),
+		ProjectID:    input.ProjectID,
+		ParentID:     &input.ParentID,
+		Name:         input.Name,
+		Description:  input.Description,
+		Creator:      input.Creator,
+		Status:       input.Status,
+		Priority:     input.Priority,
+		DueDate:      input.DueDate,
+		CustomFields: input.CustomFields,
+		CreatedAt:    time.Now().UTC(),
+
+		OriginalEstimateMinutes:  input.OriginalEstimateMinutes,
+		RemainingEstimateMinutes: input.RemainingEstimateMinutes,
+		UpdatedAt:                time.Now().UTC(),
 	}
 	var parentID *string
 	if input.ParentID != "" {
 		parentID = &input.ParentID
+		// tasks cannot be added under a task in the trash
+		if _, err := pjs.projectRepo.GetTaskByID(input.ParentID); err != nil {
+			return nil, err
+		}
+		depth, err := pjs.projectRepo.GetTaskDepth(input.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if err := pjs.checkTaskDepth(depth + 1); err != nil {
+			return nil, err
+		}
 	}
 	task.ParentID = parentID
-	return pjs.projectRepo.CreateTask(task)
+	if task.RemainingEstimateMinutes == nil {
+		task.RemainingEstimateMinutes = task.OriginalEstimateMinutes
+	}
+	created, err := pjs.projectRepo.CreateTask(task)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.watcherRepo.WatchTasks([]string{created.ID}, []string{created.Creator}, created.CreatedAt); err != nil {
+		return nil, err
+	}
+	project, err := pjs.projectRepo.GetByID(created.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	mentioned, err := pjs.syncDescriptionMentions(project.WorkspaceID, created, created.Creator)
+	if err != nil {
+		return nil, err
+	}
+	// the members mentioned were already told about the task
+	pjs.notifyWatchers(project.WorkspaceID, &taskActivity{
+		kind:        NotificationTaskCreated,
+		task:        created,
+		actor:       created.Creator,
+		emailDetail: project.Name,
+	}, mentioned)
+	return created, nil
+}
+
+// checkTaskDepth refuses to place a task deeper than the configured limit
+func (pjs *ProjectService) checkTaskDepth(depth int) domain_errors.DomainError {
+	if depth > pjs.maxTaskDepth {
+		return domain_errors.NewInvalidOperationError("task placement", fmt.Sprintf("TASK TREES ARE AT MOST %d LEVELS DEEP", pjs.maxTaskDepth))
+	}
+	return nil
+}
+
+// Moves a task with its subtasks under another parent or to another project of
+// the same workspace
+func (pjs *ProjectService) MoveTask(wsID, taskID, actor string, input *MoveTaskInput) (*Task, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	task, err := pjs.projectRepo.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	projectID := task.ProjectID
+	if input.ProjectID != nil {
+		if err := pjs.checkProjectInWorkspace(wsID, *input.ProjectID); err != nil {
+			return nil, err
+		}
+		projectID = *input.ProjectID
+	}
+	var parent *Task
+	if input.ParentID != nil {
+		if *input.ParentID == taskID {
+			return nil, domain_errors.NewInvalidOperationError("task move", "A TASK CANNOT BE MOVED UNDER ITSELF OR ITS SUBTASKS")
+		}
+		if err := pjs.checkTaskInWorkspace(wsID, *input.ParentID); err != nil {
+			return nil, err
+		}
+		if parent, err = pjs.projectRepo.GetTaskByID(*input.ParentID); err != nil {
+			return nil, err
+		}
+		if input.ProjectID == nil {
+			projectID = parent.ProjectID
+		} else if parent.ProjectID != projectID {
+			return nil, domain_errors.NewValidationErrorWithValue("parent_id", *input.ParentID, "PARENT MUST BELONG TO THE TARGET PROJECT")
+		}
+	}
+	if equalIDs(task.ParentID, input.ParentID) && projectID == task.ProjectID {
+		return task, nil
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkProjectWritable(projectID); err != nil {
+		return nil, err
+	}
+
+	var fields []*CustomField
+	if projectID != task.ProjectID {
+		if fields, err = pjs.fieldRepo.ListByProject(projectID); err != nil {
+			return nil, err
+		}
+	}
+	// the cycle and depth checks run in the move's transaction, with the trees locked
+	moved, err := pjs.projectRepo.MoveTask(taskID, input.ParentID, projectID, fields, pjs.maxTaskDepth, actor, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	project, err := pjs.projectRepo.GetByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	destination := project.Name
+	if parent != nil {
+		destination += " › " + parent.Name
+	}
+	pjs.notifyWatchers(wsID, &taskActivity{
+		kind:        NotificationMoved,
+		task:        moved,
+		actor:       actor,
+		detail:      projectID,
+		emailDetail: destination,
+	}, nil)
+	return moved, nil
 }
+
 func (pjs *ProjectService) GetTaskByID(id string) (*Task, domain_errors.DomainError) {
 	if err := uuid.Validate(id); err != nil {
 		return nil, domain_errors.NewValidationErrorWithValue("id", id, "TASK ID IS NOT A VALID UUID")
 	}
-	return pjs.projectRepo.GetTaskByID(id)
+	task, err := pjs.projectRepo.GetTaskByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.attachLabels([]*Task{task}); err != nil {
+		return nil, err
+	}
+	if err := pjs.attachDescriptionMentions(task); err != nil {
+		return nil, err
+	}
+	return task, nil
 }
 
-func (pjs *ProjectService) UpdateTask(input *UpdateTaskInput, id string) (*Task, domain_errors.DomainError) {
+func (pjs *ProjectService) UpdateTask(input *UpdateTaskInput, id, actor string) (*Task, domain_errors.DomainError) {
 	if err := uuid.Validate(id); err != nil {
 		return nil, domain_errors.NewValidationErrorWithValue("task_id", id, "TASK ID IS NOT A VALID UUID")
 	}
-	if err := input.Validate(); err != nil {
+	var fields []*CustomField
+	if len(input.CustomFields) > 0 {
+		task, err := pjs.projectRepo.GetTaskByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if fields, err = pjs.fieldRepo.ListByProject(task.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+	if err := input.Validate(fields); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkTasksWritable(id); err != nil {
+		return nil, err
+	}
+	if users := userValues(input.CustomFields, fields); len(users) > 0 {
+		wsID, err := pjs.projectRepo.GetTaskWorkspaceID(id)
+		if err != nil {
+			return nil, err
+		}
+		if err := pjs.checkMembers(wsID, users); err != nil {
+			return nil, err
+		}
+	}
+	var previous *Task
+	if input.Status != nil {
+		var err domain_errors.DomainError
+		if previous, err = pjs.projectRepo.GetTaskByID(id); err != nil {
+			return nil, err
+		}
+	}
+	task, err := pjs.projectRepo.UpdateTask(input, id, actor)
+	if err != nil {
+		return nil, err
+	}
+	statusChanged := previous != nil && previous.Status != task.Status
+	var wsID string
+	if input.Description != nil || statusChanged {
+		if wsID, err = pjs.projectRepo.GetTaskWorkspaceID(id); err != nil {
+			return nil, err
+		}
+	}
+	if input.Description != nil {
+		_, err = pjs.syncDescriptionMentions(wsID, task, actor)
+	} else {
+		err = pjs.attachDescriptionMentions(task)
+	}
+	if err != nil {
 		return nil, err
 	}
-	return pjs.projectRepo.UpdateTask(input, id)
+	if statusChanged {
+		pjs.notifyWatchers(wsID, &taskActivity{
+			kind:        NotificationStatusChanged,
+			task:        task,
+			actor:       actor,
+			detail:      string(task.Status),
+			emailDetail: string(task.Status),
+		}, nil)
+	}
+	return task, nil
 }
 
-func (pjs *ProjectService) DeleteTask(id string) domain_errors.DomainError {
-	if err := uuid.Validate(id); err != nil {
-		return domain_errors.NewValidationErrorWithValue("task_id", id, "TASK ID IS NOT A VALID UUID")
+// Applies a JSON Merge Patch or a JSON Patch to a task. ifVersion, when set, is
+// the version the patch was written against.
+func (pjs *ProjectService) PatchTask(wsID, id, actor string, format PatchFormat, body []byte, ifVersion *int) (*Task, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, id); err != nil {
+		return nil, err
+	}
+	var current *Task
+	if format == PatchFormatJSON {
+		var err domain_errors.DomainError
+		if current, err = pjs.GetTaskByID(id); err != nil {
+			return nil, err
+		}
+		if ifVersion, err = patchedVersion("task", id, current, current.Version, ifVersion); err != nil {
+			return nil, err
+		}
+	}
+	patch, err := mergePatch(format, body, current)
+	if err != nil {
+		return nil, err
+	}
+	input, err := taskPatchInput(patch)
+	if err != nil {
+		return nil, err
+	}
+	if input.IsEmpty() {
+		if current != nil {
+			return current, nil
+		}
+		return pjs.GetTaskByID(id)
+	}
+	input.IfVersion = ifVersion
+	return pjs.UpdateTask(input, id, actor)
+}
+
+// Moves a task and its subtasks to the trash
+func (pjs *ProjectService) DeleteTask(wsID, id, actor string) domain_errors.DomainError {
+	if err := pjs.checkTaskInWorkspace(wsID, id); err != nil {
+		return err
+	}
+	if err := pjs.checkTasksWritable(id); err != nil {
+		return err
 	}
-	return pjs.projectRepo.DeleteTask(id)
+	return pjs.projectRepo.DeleteTask(id, actor, time.Now().UTC())
 }
 
 // Tree queries
@@ -133,7 +591,14 @@ func (pjs *ProjectService) GetTaskTree(rootID string) (*TaskTree, domain_errors.
 	if err := uuid.Validate(rootID); err != nil {
 		return nil, domain_errors.NewValidationErrorWithValue("root_id", rootID, "ROOT ID IS NOT A VALID UUID")
 	}
-	return pjs.projectRepo.GetTaskTree(rootID)
+	tree, err := pjs.projectRepo.GetTaskTree(rootID)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.attachTimeRollups([]*TaskTree{tree}); err != nil {
+		return nil, err
+	}
+	return tree, nil
 }
 
 // Returns a task and its immediate childres in a tree
@@ -154,12 +619,28 @@ func (pjs *ProjectService) GetRootTasks(projectID string) ([]*Task, domain_error
 
 // Project queries
 
-// Returns all tasks in a project in a flatlist
-func (pjs *ProjectService) ListTasksByProject(projectID string) ([]*Task, domain_errors.DomainError) {
+// Returns all tasks in a project in a flatlist, filtered by labels and custom
+// field values and sorted as requested
+func (pjs *ProjectService) ListTasksByProject(projectID string, options *TaskListOptions) ([]*Task, domain_errors.DomainError) {
 	if err := uuid.Validate(projectID); err != nil {
 		return nil, domain_errors.NewValidationErrorWithValue("project_id", projectID, "PROJECT ID IS NOT A VALID UUID")
 	}
-	return pjs.projectRepo.ListTasksByProject(projectID)
+	fields, err := pjs.fieldRepo.ListByProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	query, err := buildTaskQuery(options, fields)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := pjs.projectRepo.ListTasksByProject(projectID, query)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.attachLabels(tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
 }
 
 // Returns all tasks in a project in a tree
@@ -168,7 +649,38 @@ func (pjs *ProjectService) GetProjectTaskTree(projectID string) ([]*TaskTree, do
 		return nil, domain_errors.NewValidationErrorWithValue("project_id", projectID, "PROJECT ID IS NOT A VALID UUID")
 
 	}
-	return pjs.projectRepo.GetProjectTaskTree(projectID)
+	trees, err := pjs.projectRepo.GetProjectTaskTree(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.attachTimeRollups(trees); err != nil {
+		return nil, err
+	}
+	return trees, nil
+}
+
+// attachTimeRollups fills in the time totals of every node of the trees
+func (pjs *ProjectService) attachTimeRollups(trees []*TaskTree) domain_errors.DomainError {
+	if len(trees) == 0 {
+		return nil
+	}
+	rootIDs := make([]string, len(trees))
+	for i, tree := range trees {
+		rootIDs[i] = tree.ID
+	}
+	rollups, err := pjs.projectRepo.GetTimeRollups(rootIDs)
+	if err != nil {
+		return err
+	}
+	var walk func(nodes []*TaskTree)
+	walk = func(nodes []*TaskTree) {
+		for _, node := range nodes {
+			node.Time = rollups[node.ID]
+			walk(node.Subtasks)
+		}
+	}
+	walk(trees)
+	return nil
 }
 
 // Utility
@@ -186,3 +698,2492 @@ func (pjs *ProjectService) CountSubtasks(parentID string) (int, domain_errors.Do
 	}
 	return pjs.projectRepo.CountSubtasks(parentID)
 }
+
+// ============================================================================
+// ATTACHMENT METHODS
+// ============================================================================
+
+// MaxAttachmentSize is the largest file that can be attached to a task
+const MaxAttachmentSize = 25 << 20
+
+// allowedAttachmentTypes lists the content types, as sniffed from the file itself,
+// that may be attached. Content that is not recognised, such as executables, is
+// sniffed as application/octet-stream and refused.
+var allowedAttachmentTypes = map[string]bool{
+	"image/png":          true,
+	"image/jpeg":         true,
+	"image/gif":          true,
+	"image/webp":         true,
+	"application/pdf":    true,
+	"application/zip":    true,
+	"application/x-gzip": true,
+	"text/plain":         true,
+	"text/csv":           true,
+	"application/json":   true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+	"application/vnd.oasis.opendocument.text":                                   true,
+	"application/vnd.oasis.opendocument.spreadsheet":                            true,
+	"application/vnd.oasis.opendocument.presentation":                           true,
+	"application/msword":            true,
+	"application/vnd.ms-excel":      true,
+	"application/vnd.ms-powerpoint": true,
+}
+
+// oleContentType is the sniffed type of OLE2 compound files, the container of
+// legacy office documents but also of installers
+const oleContentType = "application/x-ole-storage"
+
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// officeTypes are the content types of office documents by extension, with the
+// container their content is sniffed as
+var officeTypes = map[string]struct{ container, contentType string }{
+	".docx": {"application/zip", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	".xlsx": {"application/zip", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	".pptx": {"application/zip", "application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+	".odt":  {"application/zip", "application/vnd.oasis.opendocument.text"},
+	".ods":  {"application/zip", "application/vnd.oasis.opendocument.spreadsheet"},
+	".odp":  {"application/zip", "application/vnd.oasis.opendocument.presentation"},
+	".doc":  {oleContentType, "application/msword"},
+	".xls":  {oleContentType, "application/vnd.ms-excel"},
+	".ppt":  {oleContentType, "application/vnd.ms-powerpoint"},
+}
+
+// sniffAttachmentType returns the content type of a file from its first bytes.
+// A container is only taken for an office document when the name says it is one,
+// so a renamed file is never given a type its content does not have.
+func sniffAttachmentType(name string, head []byte) string {
+	contentType := http.DetectContentType(head)
+	if bytes.HasPrefix(head, oleMagic) {
+		contentType = oleContentType
+	}
+	if office, ok := officeTypes[strings.ToLower(filepath.Ext(name))]; ok && office.container == contentType {
+		return office.contentType
+	}
+	return contentType
+}
+
+// checkTaskInWorkspace makes sure the task belongs to the workspace in the URL
+func (pjs *ProjectService) checkTaskInWorkspace(wsID, taskID string) domain_errors.DomainError {
+	if err := uuid.Validate(taskID); err != nil {
+		return domain_errors.NewValidationErrorWithValue("task_id", taskID, "TASK ID IS NOT A VALID UUID")
+	}
+	taskWorkspaceID, err := pjs.projectRepo.GetTaskWorkspaceID(taskID)
+	if err != nil {
+		return err
+	}
+	if taskWorkspaceID != wsID {
+		return domain_errors.NewNotFoundError("task", taskID)
+	}
+	return nil
+}
+
+// Attaches a file to a task. The content is hashed while it is spooled to disk, and
+// a file whose content is already stored only adds a reference to the existing blob.
+func (pjs *ProjectService) UploadAttachment(ctx context.Context, wsID, taskID, uploader, filename string, content io.Reader) (*TaskAttachment, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return nil, err
+	}
+	name := sanitizeFilename(filename)
+	if name == "" {
+		return nil, domain_errors.NewValidationErrorWithValue("name", filename, "FILE NAME CANNOT BE EMPTY")
+	}
+
+	spool, spoolErr := os.CreateTemp("", "attachment-*")
+	if spoolErr != nil {
+		return nil, domain_errors.NewInternalError("attachment spooling", spoolErr)
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+	hasher := sha256.New()
+	size, copyErr := io.Copy(io.MultiWriter(spool, hasher), io.LimitReader(content, MaxAttachmentSize+1))
+	if copyErr != nil {
+		return nil, domain_errors.NewValidationError("file", "FAILED TO READ UPLOAD")
+	}
+	if size > MaxAttachmentSize {
+		return nil, domain_errors.NewValidationError("file", "FILE EXCEEDS THE 25MB LIMIT")
+	}
+	if size == 0 {
+		return nil, domain_errors.NewValidationError("file", "FILE CANNOT BE EMPTY")
+	}
+
+	// trust the content, not the name or the client's content type
+	head := make([]byte, 512)
+	n, _ := spool.ReadAt(head, 0)
+	contentType := sniffAttachmentType(name, head[:n])
+	if base, _, _ := strings.Cut(contentType, ";"); !allowedAttachmentTypes[base] {
+		return nil, domain_errors.NewValidationErrorWithValue("file", base, "FILE TYPE IS NOT ALLOWED")
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	key := "attachments/sha256/" + sum
+	now := time.Now().UTC()
+	stored, err := pjs.attachmentRepo.ReserveBlob(key, size, contentType, now)
+	if err != nil {
+		return nil, err
+	}
+	// the content is only shared once an upload has finished writing it. Until
+	// then every upload of the same bytes writes them itself, which is harmless
+	// as the key is their hash, and a failed write is retried by the next one.
+	if !stored {
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return nil, domain_errors.NewInternalError("attachment spooling", err)
+		}
+		if _, err := pjs.blobs.Put(ctx, key, contentType, spool); err != nil {
+			return nil, domain_errors.NewInternalError("attachment storage", err)
+		}
+		if err := pjs.attachmentRepo.MarkBlobStored(key); err != nil {
+			return nil, err
+		}
+	}
+
+	attachment := &TaskAttachment{
+		ID:          uuid.NewString(),
+		TaskID:      taskID,
+		Name:        name,
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      sum,
+		BlobKey:     key,
+		UploadedBy:  uploader,
+		CreatedAt:   now,
+	}
+	return pjs.attachmentRepo.Create(attachment)
+}
+
+// Lists the files attached to a task
+func (pjs *ProjectService) ListAttachments(wsID, taskID string) ([]*TaskAttachment, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	return pjs.attachmentRepo.ListByTask(taskID)
+}
+
+// Opens an attachment for download. The caller closes the reader.
+func (pjs *ProjectService) OpenAttachment(ctx context.Context, wsID, taskID, id string) (*TaskAttachment, io.ReadCloser, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, nil, err
+	}
+	if err := uuid.Validate(id); err != nil {
+		return nil, nil, domain_errors.NewValidationErrorWithValue("attachment_id", id, "ATTACHMENT ID IS NOT A VALID UUID")
+	}
+	attachment, err := pjs.attachmentRepo.GetByID(taskID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, _, getErr := pjs.blobs.Get(ctx, attachment.BlobKey)
+	if getErr != nil {
+		if errors.Is(getErr, blobstore.ErrNotFound) {
+			return nil, nil, domain_errors.NewNotFoundError("attachment content", id)
+		}
+		return nil, nil, domain_errors.NewInternalError("attachment storage", getErr)
+	}
+	return attachment, reader, nil
+}
+
+// Removes an attachment. Only its uploader or the task's creator may remove it.
+// The blob is collected once no attachment references it.
+func (pjs *ProjectService) DeleteAttachment(wsID, taskID, id, requester string) domain_errors.DomainError {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return err
+	}
+	if err := uuid.Validate(id); err != nil {
+		return domain_errors.NewValidationErrorWithValue("attachment_id", id, "ATTACHMENT ID IS NOT A VALID UUID")
+	}
+	attachment, err := pjs.attachmentRepo.GetByID(taskID, id)
+	if err != nil {
+		return err
+	}
+	if attachment.UploadedBy != requester {
+		task, err := pjs.projectRepo.GetTaskByID(taskID)
+		if err != nil {
+			return err
+		}
+		if task.Creator != requester {
+			return domain_errors.NewForbiddenError("attachment", "delete")
+		}
+	}
+	return pjs.attachmentRepo.Delete(taskID, id)
+}
+
+// checkMembers makes sure every user is a member of the workspace
+func (pjs *ProjectService) checkMembers(wsID string, userIDs []string) domain_errors.DomainError {
+	unique := map[string]bool{}
+	for _, id := range userIDs {
+		unique[id] = true
+	}
+	count, err := pjs.projectRepo.CountWorkspaceMembers(wsID, userIDs)
+	if err != nil {
+		return err
+	}
+	if count != len(unique) {
+		return domain_errors.NewValidationError("custom_fields", "USER FIELDS MUST HOLD WORKSPACE MEMBERS")
+	}
+	return nil
+}
+
+// ============================================================================
+// BULK METHODS
+// ============================================================================
+
+// Applies the same changes to a list of tasks, or to the tasks a filter selects,
+// in one transaction. Listed tasks outside the workspace are reported as not found.
+func (pjs *ProjectService) BulkUpdateTasks(wsID, actor string, input *BulkTaskInput) (*BulkTaskReport, domain_errors.DomainError) {
+	changes := &input.Changes
+	if err := changes.Validate(); err != nil {
+		return nil, err
+	}
+	if (len(input.TaskIDs) > 0) == (input.Filter != nil) {
+		return nil, domain_errors.NewValidationError("task_ids", "SELECT TASKS BY task_ids OR BY filter")
+	}
+
+	var taskIDs []string
+	if input.Filter != nil {
+		filter := input.Filter
+		if err := pjs.checkProjectInWorkspace(wsID, filter.ProjectID); err != nil {
+			return nil, err
+		}
+		tasks, err := pjs.ListTasksByProject(filter.ProjectID, &TaskListOptions{
+			LabelIDs:    filter.LabelIDs,
+			MilestoneID: filter.MilestoneID,
+			Status:      filter.Status,
+			Priority:    filter.Priority,
+			Fields:      filter.Fields,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(tasks) > maxBulkTasks {
+			return nil, domain_errors.NewValidationErrorWithValue("filter", len(tasks), "FILTER MATCHES MORE THAN 500 TASKS")
+		}
+		for _, task := range tasks {
+			taskIDs = append(taskIDs, task.ID)
+		}
+	} else {
+		seen := map[string]bool{}
+		for _, id := range input.TaskIDs {
+			if err := uuid.Validate(id); err != nil {
+				return nil, domain_errors.NewValidationErrorWithValue("task_ids", id, "TASK ID IS NOT A VALID UUID")
+			}
+			if !seen[id] {
+				seen[id] = true
+				taskIDs = append(taskIDs, id)
+			}
+		}
+		if len(taskIDs) > maxBulkTasks {
+			return nil, domain_errors.NewValidationErrorWithValue("task_ids", len(taskIDs), "AT MOST 500 TASKS CAN BE CHANGED AT ONCE")
+		}
+	}
+
+	if err := pjs.checkBulkChanges(wsID, changes); err != nil {
+		return nil, err
+	}
+	report := &BulkTaskReport{Matched: len(taskIDs), Results: []*BulkTaskResult{}}
+	if len(taskIDs) == 0 {
+		return report, nil
+	}
+	found, err := pjs.projectRepo.ListWorkspaceTaskIDs(wsID, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	changed := map[string]*BulkTaskChange{}
+	if len(found) > 0 {
+		if err := pjs.checkTasksWritable(found...); err != nil {
+			return nil, err
+		}
+		now := time.Now().UTC()
+		if changed, err = pjs.projectRepo.BulkUpdateTasks(found, changes, actor, now); err != nil {
+			return nil, err
+		}
+		if len(changes.AddAssignees) > 0 {
+			if err := pjs.watcherRepo.WatchTasks(found, changes.AddAssignees, now); err != nil {
+				return nil, err
+			}
+		}
+		pjs.notifyBulkChanges(wsID, actor, found, changed, now)
+	}
+
+	inWorkspace := map[string]bool{}
+	for _, id := range found {
+		inWorkspace[id] = true
+	}
+	for _, id := range taskIDs {
+		result := &BulkTaskResult{TaskID: id, Result: BulkResultUnchanged}
+		switch {
+		case !inWorkspace[id]:
+			result.Result = BulkResultNotFound
+			report.NotFound++
+		case changed[id] != nil:
+			result.Result = BulkResultUpdated
+			report.Updated++
+		default:
+			report.Unchanged++
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// notifyBulkChanges tells the watchers of each changed task about its new status
+// and assignees, as changing the tasks one by one would. The changes are already
+// committed, so failures are only logged.
+func (pjs *ProjectService) notifyBulkChanges(wsID, actor string, taskIDs []string, changed map[string]*BulkTaskChange, at time.Time) {
+	for _, id := range taskIDs {
+		change := changed[id]
+		if change == nil {
+			continue
+		}
+		if change.StatusChanged {
+			pjs.notifyWatchers(wsID, &taskActivity{
+				kind:        NotificationStatusChanged,
+				task:        change.Task,
+				actor:       actor,
+				detail:      string(change.Task.Status),
+				emailDetail: string(change.Task.Status),
+			}, nil)
+		}
+		if len(change.AddedAssignees) > 0 {
+			if err := pjs.notifyAssigned(wsID, id, actor, change.AddedAssignees, at); err != nil {
+				log.Println("FAILED_TO_NOTIFY_ASSIGNEES:", err)
+			}
+		}
+	}
+}
+
+// checkBulkChanges makes sure added assignees are workspace members and added
+// labels belong to the workspace
+func (pjs *ProjectService) checkBulkChanges(wsID string, changes *BulkTaskChanges) domain_errors.DomainError {
+	changes.AddAssignees = slices.Compact(slices.Sorted(slices.Values(changes.AddAssignees)))
+	changes.AddLabels = slices.Compact(slices.Sorted(slices.Values(changes.AddLabels)))
+	if len(changes.AddAssignees) > 0 {
+		count, err := pjs.projectRepo.CountWorkspaceMembers(wsID, changes.AddAssignees)
+		if err != nil {
+			return err
+		}
+		if count != len(changes.AddAssignees) {
+			return domain_errors.NewValidationError("changes.add_assignees", "ASSIGNEES MUST BE WORKSPACE MEMBERS")
+		}
+	}
+	if len(changes.AddLabels) > 0 {
+		labels, err := pjs.labelRepo.ListByIDs(wsID, changes.AddLabels)
+		if err != nil {
+			return err
+		}
+		if len(labels) != len(changes.AddLabels) {
+			return domain_errors.NewValidationError("changes.add_labels", "LABELS MUST BELONG TO THE WORKSPACE")
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// IMPORT AND EXPORT METHODS
+// ============================================================================
+
+// Validates every row of an import file and, unless it is a dry run or a row is
+// invalid, creates all of its tasks in one transaction
+func (pjs *ProjectService) ImportTasks(wsID, projectID, creator string, format ImportFormat, content []byte, dryRun bool) (*ImportReport, domain_errors.DomainError) {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkProjectWritable(projectID); err != nil {
+		return nil, err
+	}
+	fields, err := pjs.fieldRepo.ListByProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	report := &ImportReport{DryRun: dryRun, Errors: []*ImportError{}}
+	rows, err := parseImport(format, content, fields, report)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, domain_errors.NewValidationError("file", "FILE HAS NO TASKS")
+	}
+	if len(rows) > maxImportTasks {
+		return nil, domain_errors.NewValidationErrorWithValue("file", len(rows), "FILES HOLD AT MOST 5000 TASKS")
+	}
+	report.Tasks = len(rows)
+
+	byRef := map[string]*ImportTaskRow{}
+	for _, row := range rows {
+		if _, ok := byRef[row.ref]; ok {
+			report.addError(row, domain_errors.NewValidationErrorWithValue("id", row.ID, "ID IS USED BY ANOTHER ROW"))
+			continue
+		}
+		byRef[row.ref] = row
+	}
+
+	// a parent named by no row must already be a task of the project
+	var external []string
+	for _, row := range rows {
+		if row.parentRef != "" || row.ParentID == "" {
+			continue
+		}
+		if _, ok := byRef[row.ParentID]; ok {
+			row.parentRef = row.ParentID
+		} else {
+			external = append(external, row.ParentID)
+		}
+	}
+	existing := map[string]bool{}
+	if len(external) > 0 {
+		ids, err := pjs.projectRepo.ListProjectTaskIDs(projectID, external)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			existing[id] = true
+		}
+	}
+
+	inputs := map[*ImportTaskRow]*CreateTaskInput{}
+	rowsByUser := map[string][]*ImportTaskRow{}
+	for _, row := range rows {
+		if row.parentRef == "" && row.ParentID != "" && !existing[row.ParentID] {
+			report.addError(row, domain_errors.NewValidationErrorWithValue("parent_id", row.ParentID, "PARENT IS NEITHER A ROW OF THE FILE NOR A TASK OF THE PROJECT"))
+			continue
+		}
+		dueDate, err := parseImportDate(row.DueDate)
+		if err != nil {
+			report.addError(row, err)
+			continue
+		}
+		input := &CreateTaskInput{
+			ProjectID:                projectID,
+			Name:                     strings.TrimSpace(row.Name),
+			Description:              row.Description,
+			Creator:                  creator,
+			Status:                   row.Status,
+			Priority:                 row.Priority,
+			DueDate:                  dueDate,
+			CustomFields:             row.CustomFields,
+			OriginalEstimateMinutes:  row.OriginalEstimateMinutes,
+			RemainingEstimateMinutes: row.RemainingEstimateMinutes,
+		}
+		if input.Status == "" {
+			input.Status = TaskStatusOpen
+		}
+		if input.Priority == "" {
+			input.Priority = TaskPriorityMedium
+		}
+		if err := input.Validate(fields); err != nil {
+			report.addError(row, err)
+			continue
+		}
+		for _, userID := range userValues(input.CustomFields, fields) {
+			rowsByUser[userID] = append(rowsByUser[userID], row)
+		}
+		inputs[row] = input
+	}
+	if err := pjs.checkImportMembers(wsID, rowsByUser, report); err != nil {
+		return nil, err
+	}
+
+	ordered, cyclic := orderImportRows(rows, byRef)
+	for _, row := range cyclic {
+		report.addError(row, domain_errors.NewValidationErrorWithValue("parent_id", row.ParentID, "PARENT REFERENCES FORM A CYCLE"))
+	}
+	if err := pjs.checkImportDepths(ordered, report); err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(report.Errors, func(a, b *ImportError) int {
+		return a.Row - b.Row
+	})
+	if len(report.Errors) > 0 || dryRun {
+		return report, nil
+	}
+
+	now := time.Now().UTC()
+	created := map[string]string{}
+	report.IDs = map[string]string{}
+	tasks := make([]*Task, 0, len(ordered))
+	for _, row := range ordered {
+		input := inputs[row]
+		task := &Task{
+			ID:                       uuid.NewString(),
+			ProjectID:                projectID,
+			Name:                     input.Name,
+			Description:              input.Description,
+			Creator:                  creator,
+			Status:                   input.Status,
+			Priority:                 input.Priority,
+			DueDate:                  input.DueDate,
+			CustomFields:             input.CustomFields,
+			OriginalEstimateMinutes:  input.OriginalEstimateMinutes,
+			RemainingEstimateMinutes: input.RemainingEstimateMinutes,
+			CreatedAt:                now,
+			UpdatedAt:                now,
+		}
+		if task.RemainingEstimateMinutes == nil {
+			task.RemainingEstimateMinutes = task.OriginalEstimateMinutes
+		}
+		if row.parentRef != "" {
+			parentID := created[row.parentRef]
+			task.ParentID = &parentID
+		} else if row.ParentID != "" {
+			parentID := row.ParentID
+			task.ParentID = &parentID
+		}
+		created[row.ref] = task.ID
+		if row.ID != "" {
+			report.IDs[row.ID] = task.ID
+		}
+		tasks = append(tasks, task)
+	}
+	if err := pjs.projectRepo.ImportTasks(tasks); err != nil {
+		return nil, err
+	}
+	if err := pjs.watchCreatedTasks(tasks, creator); err != nil {
+		return nil, err
+	}
+	report.Created = len(tasks)
+	return report, nil
+}
+
+// watchCreatedTasks has the creator of tasks created together watch each of them
+func (pjs *ProjectService) watchCreatedTasks(tasks []*Task, creator string) domain_errors.DomainError {
+	if len(tasks) == 0 {
+		return nil
+	}
+	taskIDs := make([]string, len(tasks))
+	for i, task := range tasks {
+		taskIDs[i] = task.ID
+	}
+	return pjs.watcherRepo.WatchTasks(taskIDs, []string{creator}, tasks[0].CreatedAt)
+}
+
+// checkImportDepths reports the rows that would be deeper than the task depth limit
+func (pjs *ProjectService) checkImportDepths(ordered []*ImportTaskRow, report *ImportReport) domain_errors.DomainError {
+	depths := map[string]int{}
+	existing := map[string]int{}
+	for _, row := range ordered {
+		depth := 0
+		switch {
+		case row.parentRef != "":
+			depth = depths[row.parentRef] + 1
+		case row.ParentID != "":
+			parentDepth, ok := existing[row.ParentID]
+			if !ok {
+				var err domain_errors.DomainError
+				if parentDepth, err = pjs.projectRepo.GetTaskDepth(row.ParentID); err != nil {
+					return err
+				}
+				existing[row.ParentID] = parentDepth
+			}
+			depth = parentDepth + 1
+		}
+		depths[row.ref] = depth
+		if depth > pjs.maxTaskDepth {
+			report.addError(row, domain_errors.NewValidationErrorWithValue("parent_id", row.ParentID, fmt.Sprintf("TASK TREES ARE AT MOST %d LEVELS DEEP", pjs.maxTaskDepth)))
+		}
+	}
+	return nil
+}
+
+// checkImportMembers reports the rows whose user fields hold users outside the workspace
+func (pjs *ProjectService) checkImportMembers(wsID string, rowsByUser map[string][]*ImportTaskRow, report *ImportReport) domain_errors.DomainError {
+	if len(rowsByUser) == 0 {
+		return nil
+	}
+	userIDs := make([]string, 0, len(rowsByUser))
+	for userID := range rowsByUser {
+		userIDs = append(userIDs, userID)
+	}
+	count, err := pjs.projectRepo.CountWorkspaceMembers(wsID, userIDs)
+	if err != nil {
+		return err
+	}
+	if count == len(userIDs) {
+		return nil
+	}
+	// only look for the outsiders one by one once we know there are some
+	slices.Sort(userIDs)
+	for _, userID := range userIDs {
+		count, err := pjs.projectRepo.CountWorkspaceMembers(wsID, []string{userID})
+		if err != nil {
+			return err
+		}
+		if count == 1 {
+			continue
+		}
+		for _, row := range rowsByUser[userID] {
+			report.addError(row, domain_errors.NewValidationErrorWithValue("custom_fields", userID, "USER FIELDS MUST HOLD WORKSPACE MEMBERS"))
+		}
+	}
+	return nil
+}
+
+// orderImportRows puts every row after its parent. Rows whose parent references
+// loop, or lead into a loop, cannot be ordered and are returned separately.
+func orderImportRows(rows []*ImportTaskRow, byRef map[string]*ImportTaskRow) ([]*ImportTaskRow, []*ImportTaskRow) {
+	const (
+		visiting = iota + 1
+		ordered
+		broken
+	)
+	state := map[*ImportTaskRow]int{}
+	result := make([]*ImportTaskRow, 0, len(rows))
+	var cyclic []*ImportTaskRow
+
+	var visit func(row *ImportTaskRow) bool
+	visit = func(row *ImportTaskRow) bool {
+		switch state[row] {
+		case ordered:
+			return true
+		case visiting, broken:
+			return false
+		}
+		state[row] = visiting
+		if parent, ok := byRef[row.parentRef]; ok && row.parentRef != "" && !visit(parent) {
+			state[row] = broken
+			cyclic = append(cyclic, row)
+			return false
+		}
+		state[row] = ordered
+		result = append(result, row)
+		return true
+	}
+	for _, row := range rows {
+		visit(row)
+	}
+	return result, cyclic
+}
+
+// Collects a project with its whole task tree for export
+func (pjs *ProjectService) ExportProject(wsID, projectID string) (*ProjectExport, domain_errors.DomainError) {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return nil, err
+	}
+	project, err := pjs.projectRepo.GetByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := pjs.fieldRepo.ListByProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	trees, err := pjs.GetProjectTaskTree(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []*Task
+	var collect func(trees []*TaskTree)
+	collect = func(trees []*TaskTree) {
+		for _, tree := range trees {
+			tasks = append(tasks, &tree.Task)
+			collect(tree.Subtasks)
+		}
+	}
+	collect(trees)
+	if trees == nil {
+		trees = []*TaskTree{}
+	}
+	if err := pjs.attachLabels(tasks); err != nil {
+		return nil, err
+	}
+	return &ProjectExport{
+		Project:      project,
+		CustomFields: fields,
+		Tasks:        trees,
+		ExportedAt:   time.Now().UTC(),
+	}, nil
+}
+
+// ============================================================================
+// TEMPLATE METHODS
+// ============================================================================
+
+// Saves the tree of a task, or every task of a project, as a template of the workspace
+func (pjs *ProjectService) CreateTemplate(wsID, creator string, input *CreateTemplateInput) (*TaskTemplate, domain_errors.DomainError) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkWorkspaceWritable(wsID); err != nil {
+		return nil, err
+	}
+	var trees []*TaskTree
+	var anchor time.Time
+	if input.TaskID != "" {
+		if err := pjs.checkTaskInWorkspace(wsID, input.TaskID); err != nil {
+			return nil, err
+		}
+		tree, err := pjs.projectRepo.GetTaskTree(input.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		trees, anchor = []*TaskTree{tree}, tree.CreatedAt
+	} else {
+		if err := pjs.checkProjectInWorkspace(wsID, input.ProjectID); err != nil {
+			return nil, err
+		}
+		project, err := pjs.projectRepo.GetByID(input.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		if trees, err = pjs.projectRepo.GetProjectTaskTree(input.ProjectID); err != nil {
+			return nil, err
+		}
+		anchor = project.CreatedAt
+	}
+
+	var tasks []*Task
+	var collect func(trees []*TaskTree)
+	collect = func(trees []*TaskTree) {
+		for _, tree := range trees {
+			tasks = append(tasks, &tree.Task)
+			collect(tree.Subtasks)
+		}
+	}
+	collect(trees)
+	if len(tasks) > maxTemplateTasks {
+		return nil, domain_errors.NewValidationError("tasks", fmt.Sprintf("A TEMPLATE CAN HOLD AT MOST %d TASKS", maxTemplateTasks))
+	}
+	if err := pjs.attachLabels(tasks); err != nil {
+		return nil, err
+	}
+	return pjs.templateRepo.Create(&TaskTemplate{
+		ID:          uuid.NewString(),
+		WorkspaceID: wsID,
+		Name:        input.Name,
+		Description: input.Description,
+		Tasks:       templateTasks(trees, anchor),
+		CreatedBy:   creator,
+		CreatedAt:   time.Now().UTC(),
+	})
+}
+
+func (pjs *ProjectService) ListTemplates(wsID string) ([]*TaskTemplate, domain_errors.DomainError) {
+	return pjs.templateRepo.ListByWorkspace(wsID)
+}
+
+func (pjs *ProjectService) GetTemplate(wsID, id string) (*TaskTemplate, domain_errors.DomainError) {
+	if err := uuid.Validate(id); err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("template_id", id, "TEMPLATE ID IS NOT A VALID UUID")
+	}
+	return pjs.templateRepo.GetByID(wsID, id)
+}
+
+func (pjs *ProjectService) DeleteTemplate(wsID, id string) domain_errors.DomainError {
+	if err := uuid.Validate(id); err != nil {
+		return domain_errors.NewValidationErrorWithValue("template_id", id, "TEMPLATE ID IS NOT A VALID UUID")
+	}
+	if err := pjs.checkWorkspaceWritable(wsID); err != nil {
+		return err
+	}
+	return pjs.templateRepo.Delete(wsID, id)
+}
+
+// Creates the template's tasks in a project, at its root or under one of its tasks.
+// Due dates are placed after the start date, custom field values the project does
+// not define are dropped, and so are the labels deleted since the template was saved.
+func (pjs *ProjectService) InstantiateTemplate(wsID, templateID, creator string, input *InstantiateTemplateInput) ([]*Task, domain_errors.DomainError) {
+	template, err := pjs.GetTemplate(wsID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.checkProjectInWorkspace(wsID, input.ProjectID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkProjectWritable(input.ProjectID); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if input.StartDate != "" {
+		if start, err = parseDate("start_date", input.StartDate); err != nil {
+			return nil, err
+		}
+	}
+	parentDepth := -1
+	if input.ParentID != "" {
+		if err := pjs.checkTaskInWorkspace(wsID, input.ParentID); err != nil {
+			return nil, err
+		}
+		parent, err := pjs.projectRepo.GetTaskByID(input.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.ProjectID != input.ProjectID {
+			return nil, domain_errors.NewValidationErrorWithValue("parent_id", input.ParentID, "PARENT MUST BELONG TO THE TARGET PROJECT")
+		}
+		if parentDepth, err = pjs.projectRepo.GetTaskDepth(input.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	_, height := countTemplateTasks(template.Tasks)
+	if err := pjs.checkTaskDepth(parentDepth + 1 + height); err != nil {
+		return nil, err
+	}
+
+	fields, err := pjs.fieldRepo.ListByProject(input.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	var labelIDs []string
+	var collectLabels func(tasks []*TemplateTask)
+	collectLabels = func(tasks []*TemplateTask) {
+		for _, task := range tasks {
+			labelIDs = append(labelIDs, task.LabelIDs...)
+			collectLabels(task.Subtasks)
+		}
+	}
+	collectLabels(template.Tasks)
+	labels := map[string]*Label{}
+	if len(labelIDs) > 0 {
+		existing, err := pjs.labelRepo.ListByIDs(wsID, labelIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, label := range existing {
+			labels[label.ID] = label
+		}
+	}
+
+	var tasks []*Task
+	var users []string
+	var build func(templates []*TemplateTask, parentID string) domain_errors.DomainError
+	build = func(templates []*TemplateTask, parentID string) domain_errors.DomainError {
+		for _, node := range templates {
+			taskInput := &CreateTaskInput{
+				ProjectID:               input.ProjectID,
+				ParentID:                parentID,
+				Name:                    node.Name,
+				Description:             node.Description,
+				Creator:                 creator,
+				Status:                  TaskStatusOpen,
+				Priority:                node.Priority,
+				CustomFields:            retainCustomFields(node.CustomFields, fields),
+				OriginalEstimateMinutes: node.OriginalEstimateMinutes,
+			}
+			if taskInput.Priority == "" {
+				taskInput.Priority = TaskPriorityMedium
+			}
+			if node.DueOffsetDays != nil {
+				due := start.AddDate(0, 0, *node.DueOffsetDays)
+				taskInput.DueDate = &due
+			}
+			if err := taskInput.Validate(fields); err != nil {
+				return err
+			}
+			task := &Task{
+				ID:                       uuid.NewString(),
+				ProjectID:                input.ProjectID,
+				Name:                     taskInput.Name,
+				Description:              taskInput.Description,
+				Creator:                  creator,
+				Status:                   taskInput.Status,
+				Priority:                 taskInput.Priority,
+				DueDate:                  taskInput.DueDate,
+				CustomFields:             taskInput.CustomFields,
+				OriginalEstimateMinutes:  taskInput.OriginalEstimateMinutes,
+				RemainingEstimateMinutes: taskInput.OriginalEstimateMinutes,
+				CreatedAt:                now,
+				UpdatedAt:                now,
+			}
+			if parentID != "" {
+				task.ParentID = &parentID
+			}
+			for _, id := range node.LabelIDs {
+				if label, ok := labels[id]; ok {
+					task.Labels = append(task.Labels, label)
+				}
+			}
+			users = append(users, userValues(task.CustomFields, fields)...)
+			tasks = append(tasks, task)
+			if err := build(node.Subtasks, task.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := build(template.Tasks, input.ParentID); err != nil {
+		return nil, err
+	}
+	if len(users) > 0 {
+		if err := pjs.checkMembers(wsID, users); err != nil {
+			return nil, err
+		}
+	}
+	if err := pjs.projectRepo.ImportTasks(tasks); err != nil {
+		return nil, err
+	}
+	if err := pjs.watchCreatedTasks(tasks, creator); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// Copies a project with its custom fields and every task, keeping their state and
+// labels. Milestones, assignments, attachments and logged time are not copied.
+func (pjs *ProjectService) CloneProject(wsID, projectID, creator string, input *CloneProjectInput) (*Project, domain_errors.DomainError) {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkWorkspaceWritable(wsID); err != nil {
+		return nil, err
+	}
+	source, err := pjs.projectRepo.GetByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	project := &Project{
+		ID:          uuid.NewString(),
+		Name:        strings.TrimSpace(input.Name),
+		Description: source.Description,
+		WorkspaceID: wsID,
+		Creator:     creator,
+		CreatedAt:   now,
+	}
+	if project.Name == "" {
+		return nil, domain_errors.NewValidationError("name", "NAME CANNOT BE EMPTY")
+	}
+	if input.Description != nil {
+		project.Description = *input.Description
+	}
+
+	fields, err := pjs.fieldRepo.ListByProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		field.ID = uuid.NewString()
+		field.ProjectID = project.ID
+		field.CreatedAt = now
+	}
+	trees, err := pjs.projectRepo.GetProjectTaskTree(projectID)
+	if err != nil {
+		return nil, err
+	}
+	// sources[i] is the task tasks[i] copies
+	var sources, tasks []*Task
+	var collect func(trees []*TaskTree, parentID *string)
+	collect = func(trees []*TaskTree, parentID *string) {
+		for _, tree := range trees {
+			task := tree.Task
+			task.ID = uuid.NewString()
+			task.ParentID = parentID
+			task.ProjectID = project.ID
+			task.Creator = creator
+			task.MilestoneID = nil
+			task.CreatedAt = now
+			task.UpdatedAt = now
+			sources = append(sources, &tree.Task)
+			tasks = append(tasks, &task)
+			collect(tree.Subtasks, &task.ID)
+		}
+	}
+	collect(trees, nil)
+	// labels belong to the workspace, so the copies share the source's labels
+	if err := pjs.attachLabels(sources); err != nil {
+		return nil, err
+	}
+	for i, source := range sources {
+		tasks[i].Labels = source.Labels
+	}
+	cloned, err := pjs.projectRepo.CloneProject(project, fields, tasks)
+	if err != nil {
+		return nil, err
+	}
+	// watching the copy covers each of its tasks
+	if err := pjs.watcherRepo.WatchProject(cloned.ID, creator, now); err != nil {
+		return nil, err
+	}
+	return cloned, nil
+}
+
+// ============================================================================
+// TRASH METHODS
+// ============================================================================
+
+// Lists the deleted projects and tasks of the workspace with when they will be purged
+func (pjs *ProjectService) ListTrash(wsID string) ([]*TrashItem, domain_errors.DomainError) {
+	items, err := pjs.trashRepo.List(wsID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		item.PurgeAt = item.DeletedAt.Add(pjs.trashRetention)
+	}
+	return items, nil
+}
+
+// Restores a deleted project with the tasks deleted along with it
+func (pjs *ProjectService) RestoreProject(wsID, id string) (*Project, domain_errors.DomainError) {
+	if err := uuid.Validate(id); err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("project_id", id, "PROJECT ID IS NOT A VALID UUID")
+	}
+	if err := pjs.checkWorkspaceWritable(wsID); err != nil {
+		return nil, err
+	}
+	return pjs.trashRepo.RestoreProject(wsID, id)
+}
+
+// Restores a deleted task with the subtasks deleted along with it
+func (pjs *ProjectService) RestoreTask(wsID, id, actor string) (*Task, domain_errors.DomainError) {
+	if err := uuid.Validate(id); err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("task_id", id, "TASK ID IS NOT A VALID UUID")
+	}
+	// restored tasks go back into their project, which must be writable
+	if err := pjs.checkTasksWritable(id); err != nil {
+		return nil, err
+	}
+	return pjs.trashRepo.RestoreTask(wsID, id, actor, time.Now().UTC())
+}
+
+// ============================================================================
+// ASSIGNMENT METHODS
+// ============================================================================
+
+// maxAssigneesPerRequest bounds the users assigned by a single request
+const maxAssigneesPerRequest = 50
+
+// Assigns the task to workspace members and returns all of its assignments
+func (pjs *ProjectService) AssignTask(wsID, taskID, assigner string, userIDs []string) ([]*TaskAssignment, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return nil, err
+	}
+	if len(userIDs) == 0 {
+		return nil, domain_errors.NewValidationError("user_ids", "AT LEAST ONE USER IS REQUIRED")
+	}
+	if len(userIDs) > maxAssigneesPerRequest {
+		return nil, domain_errors.NewValidationError("user_ids", "TOO MANY USERS IN ONE REQUEST")
+	}
+
+	now := time.Now().UTC()
+	unique := map[string]bool{}
+	assignments := []*TaskAssignment{}
+	for _, userID := range userIDs {
+		if unique[userID] {
+			continue
+		}
+		unique[userID] = true
+		assignments = append(assignments, &TaskAssignment{
+			ID:        uuid.NewString(),
+			Assigner:  assigner,
+			Assignee:  userID,
+			TaskID:    taskID,
+			CreatedAt: now,
+		})
+	}
+	count, err := pjs.projectRepo.CountWorkspaceMembers(wsID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	if count != len(unique) {
+		return nil, domain_errors.NewValidationError("user_ids", "ASSIGNEES MUST BE WORKSPACE MEMBERS")
+	}
+	previous, err := pjs.projectRepo.ListTaskAssignments(taskID)
+	if err != nil {
+		return nil, err
+	}
+	for _, assignment := range previous {
+		delete(unique, assignment.Assignee)
+	}
+	if err := pjs.projectRepo.AssignTask(assignments); err != nil {
+		return nil, err
+	}
+	if len(unique) > 0 {
+		if err := pjs.notifyAssigned(wsID, taskID, assigner, slices.Sorted(maps.Keys(unique)), now); err != nil {
+			return nil, err
+		}
+	}
+	return pjs.projectRepo.ListTaskAssignments(taskID)
+}
+
+// notifyAssigned has the newly assigned users watch the task and tells its
+// watchers, them included, who was assigned
+func (pjs *ProjectService) notifyAssigned(wsID, taskID, assigner string, assignees []string, at time.Time) domain_errors.DomainError {
+	if err := pjs.watcherRepo.WatchTasks([]string{taskID}, assignees, at); err != nil {
+		return err
+	}
+	task, err := pjs.projectRepo.GetTaskByID(taskID)
+	if err != nil {
+		return err
+	}
+	profiles, err := pjs.publicProfiles(assignees)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for _, userID := range assignees {
+		if profile := profiles[userID]; profile != nil {
+			names = append(names, cmp.Or(profile.Name, profile.Email))
+		}
+	}
+	pjs.notifyWatchers(wsID, &taskActivity{
+		kind:        NotificationAssigned,
+		task:        task,
+		actor:       assigner,
+		detail:      strings.Join(assignees, ","),
+		emailDetail: strings.Join(names, ", "),
+	}, nil)
+	return nil
+}
+
+func (pjs *ProjectService) UnassignTask(wsID, taskID, userID string) domain_errors.DomainError {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return err
+	}
+	return pjs.projectRepo.UnassignTask(taskID, userID)
+}
+
+func (pjs *ProjectService) ListTaskAssignments(wsID, taskID string) ([]*TaskAssignment, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	return pjs.projectRepo.ListTaskAssignments(taskID)
+}
+
+// ============================================================================
+// COMMENT AND MENTION METHODS
+// ============================================================================
+// Members are mentioned as @handle in task descriptions and comments. Newly
+// mentioned members are notified and emailed; removing a mention does not
+// notify anyone. Tasks created by import, template or cloning do not mention.
+
+func (pjs *ProjectService) CreateComment(wsID, taskID, author string, input *CommentInput) (*TaskComment, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return nil, err
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	task, err := pjs.projectRepo.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	comment, err := pjs.commentRepo.CreateComment(&TaskComment{
+		ID:        uuid.NewString(),
+		Author:    author,
+		TaskID:    taskID,
+		Content:   input.Content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.watcherRepo.WatchTasks([]string{taskID}, []string{author}, now); err != nil {
+		return nil, err
+	}
+	mentioned, err := pjs.syncMentions(wsID, task, &comment.ID, comment.Content, author)
+	if err != nil {
+		return nil, err
+	}
+	// the members mentioned were already told about the comment
+	pjs.notifyWatchers(wsID, &taskActivity{
+		kind:        NotificationComment,
+		task:        task,
+		actor:       author,
+		commentID:   &comment.ID,
+		emailDetail: comment.Content,
+	}, mentioned)
+	if err := pjs.attachCommentMentions(taskID, []*TaskComment{comment}); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (pjs *ProjectService) ListComments(wsID, taskID string) ([]*TaskComment, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	comments, err := pjs.commentRepo.ListComments(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.attachCommentMentions(taskID, comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// Edits a comment. Only its author can.
+func (pjs *ProjectService) UpdateComment(wsID, taskID, id, requester string, input *CommentInput) (*TaskComment, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return nil, err
+	}
+	if err := uuid.Validate(id); err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("comment_id", id, "COMMENT ID IS NOT A VALID UUID")
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	comment, err := pjs.commentRepo.GetComment(taskID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Author != requester {
+		return nil, domain_errors.NewForbiddenError("comment", "update")
+	}
+	task, err := pjs.projectRepo.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if comment, err = pjs.commentRepo.UpdateComment(taskID, id, input.Content, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if _, err := pjs.syncMentions(wsID, task, &comment.ID, comment.Content, requester); err != nil {
+		return nil, err
+	}
+	if err := pjs.attachCommentMentions(taskID, []*TaskComment{comment}); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// Deletes a comment with its mentions. Its author or the task's creator can.
+func (pjs *ProjectService) DeleteComment(wsID, taskID, id, requester string) domain_errors.DomainError {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return err
+	}
+	if err := uuid.Validate(id); err != nil {
+		return domain_errors.NewValidationErrorWithValue("comment_id", id, "COMMENT ID IS NOT A VALID UUID")
+	}
+	comment, err := pjs.commentRepo.GetComment(taskID, id)
+	if err != nil {
+		return err
+	}
+	if comment.Author != requester {
+		task, err := pjs.projectRepo.GetTaskByID(taskID)
+		if err != nil {
+			return err
+		}
+		if task.Creator != requester {
+			return domain_errors.NewForbiddenError("comment", "delete")
+		}
+	}
+	return pjs.commentRepo.DeleteComment(taskID, id)
+}
+
+// syncDescriptionMentions records the members mentioned in the task's
+// description, puts them on the task and returns the ones newly mentioned
+func (pjs *ProjectService) syncDescriptionMentions(wsID string, task *Task, actor string) ([]string, domain_errors.DomainError) {
+	mentioned, err := pjs.syncMentions(wsID, task, nil, task.Description, actor)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.attachDescriptionMentions(task); err != nil {
+		return nil, err
+	}
+	return mentioned, nil
+}
+
+// syncMentions replaces the mentions of the task's description, or of its
+// comment when commentID is set, with the members mentioned in text. It notifies
+// the members who were not mentioned there before and returns their ids.
+func (pjs *ProjectService) syncMentions(wsID string, task *Task, commentID *string, text, actor string) ([]string, domain_errors.DomainError) {
+	mentions := []*Mention{}
+	if handles := parseMentions(text); len(handles) > 0 {
+		emails, err := pjs.commentRepo.ListMemberEmails(wsID, handles)
+		if err != nil {
+			return nil, err
+		}
+		resolved := resolveMentions(handles, emails)
+		now := time.Now().UTC()
+		mentioned := map[string]bool{}
+		for _, handle := range handles {
+			userID, ok := resolved[handle]
+			if !ok || mentioned[userID] {
+				continue
+			}
+			mentioned[userID] = true
+			mentions = append(mentions, &Mention{
+				ID:        uuid.NewString(),
+				TaskID:    task.ID,
+				CommentID: commentID,
+				UserID:    userID,
+				AuthorID:  actor,
+				Handle:    handle,
+				CreatedAt: now,
+			})
+		}
+	}
+	added, err := pjs.commentRepo.SetMentions(task.ID, commentID, mentions)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]string, len(added))
+	for i, mention := range added {
+		userIDs[i] = mention.UserID
+	}
+	pjs.notify(wsID, &taskActivity{kind: NotificationMention, task: task, actor: actor, commentID: commentID}, userIDs)
+	return userIDs, nil
+}
+
+// taskActivity is something that happened to a task which users are told about
+type taskActivity struct {
+	kind      NotificationKind
+	task      *Task
+	actor     string
+	commentID *string
+	// detail is stored on the notifications, emailDetail quoted in their emails
+	detail      string
+	emailDetail string
+}
+
+// activityMessageTypes are the emails of the notifications sent to watchers
+var activityMessageTypes = map[NotificationKind]emailservice.MessageType{
+	NotificationTaskCreated:   emailservice.MessageTypeTaskCreated,
+	NotificationStatusChanged: emailservice.MessageTypeTaskStatus,
+	NotificationAssigned:      emailservice.MessageTypeTaskAssigned,
+	NotificationComment:       emailservice.MessageTypeTaskComment,
+	NotificationMoved:         emailservice.MessageTypeTaskMoved,
+}
+
+// notifyWatchers tells the members watching the task or its project about the
+// activity, leaving out the users in skip
+func (pjs *ProjectService) notifyWatchers(wsID string, activity *taskActivity, skip []string) {
+	audience, err := pjs.watcherRepo.TaskAudience(activity.task.ID)
+	if err != nil {
+		log.Println("FAILED_TO_LIST_TASK_WATCHERS:", err)
+		return
+	}
+	recipients := slices.DeleteFunc(audience, func(userID string) bool {
+		return slices.Contains(skip, userID)
+	})
+	pjs.notify(wsID, activity, recipients)
+}
+
+// notify stores a notification of the activity for each recipient but the actor
+// and emails them. The activity already happened, so failures are only logged.
+func (pjs *ProjectService) notify(wsID string, activity *taskActivity, recipients []string) {
+	now := time.Now().UTC()
+	notifications := []*Notification{}
+	for _, userID := range recipients {
+		if userID == activity.actor {
+			continue
+		}
+		notifications = append(notifications, &Notification{
+			ID:          uuid.NewString(),
+			UserID:      userID,
+			WorkspaceID: wsID,
+			Kind:        activity.kind,
+			TaskID:      activity.task.ID,
+			CommentID:   activity.commentID,
+			ActorID:     activity.actor,
+			Detail:      activity.detail,
+			CreatedAt:   now,
+		})
+	}
+	if len(notifications) == 0 {
+		return
+	}
+	if err := pjs.notificationRepo.Create(notifications); err != nil {
+		log.Println("FAILED_TO_CREATE_NOTIFICATIONS:", err)
+		return
+	}
+	if err := pjs.sendNotificationEmails(wsID, activity, notifications); err != nil {
+		log.Println("FAILED_TO_SEND_NOTIFICATION_EMAILS:", err)
+	}
+}
+
+func (pjs *ProjectService) sendNotificationEmails(wsID string, activity *taskActivity, notifications []*Notification) error {
+	if pjs.conn == nil {
+		return nil
+	}
+	userIDs := []string{activity.actor}
+	for _, notification := range notifications {
+		userIDs = append(userIDs, notification.UserID)
+	}
+	profiles, err := pjs.publicProfiles(userIDs)
+	if err != nil {
+		return err
+	}
+	actorName := "Someone"
+	if profile := profiles[activity.actor]; profile != nil {
+		actorName = cmp.Or(profile.Name, profile.Email)
+	}
+	taskURL := taskURL(pjs.publicBaseURL, wsID, activity.task.ID)
+
+	ch, chErr := pjs.conn.Channel()
+	if chErr != nil {
+		return chErr
+	}
+	defer ch.Close()
+	for _, notification := range notifications {
+		recipient := profiles[notification.UserID]
+		if recipient == nil {
+			continue
+		}
+		locale := ""
+		if profile, err := pjs.profileRepo.GetProfile(notification.UserID); err == nil {
+			locale = profile.Locale
+		}
+		var emailMsg *emailservice.EmailMessage
+		var msgErr error
+		if activity.kind == NotificationMention {
+			emailMsg, msgErr = amqp_utils.NewMentionMessage(recipient.Email, actorName, activity.task.Name, taskURL, locale)
+		} else {
+			emailMsg, msgErr = amqp_utils.NewTaskActivityMessage(activityMessageTypes[activity.kind], recipient.Email, actorName, activity.task.Name, activity.emailDetail, taskURL, locale)
+		}
+		if msgErr != nil {
+			return msgErr
+		}
+		if err := amqp_utils.PublishEmailMessage(ch, emailMsg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taskURL is the link to a task in the emails about it
+func taskURL(baseURL, wsID, taskID string) string {
+	return fmt.Sprintf("%s/workspace/%s/task/%s", baseURL, wsID, taskID)
+}
+
+// attachDescriptionMentions puts the members mentioned in the description on the task
+func (pjs *ProjectService) attachDescriptionMentions(task *Task) domain_errors.DomainError {
+	mentions, err := pjs.commentRepo.ListMentions(task.ID)
+	if err != nil {
+		return err
+	}
+	task.Mentions = nil
+	for _, mention := range mentions {
+		if mention.CommentID == nil {
+			task.Mentions = append(task.Mentions, mention)
+		}
+	}
+	return pjs.attachMentionProfiles(task.Mentions)
+}
+
+// attachCommentMentions puts on each comment of the task the members it mentions
+func (pjs *ProjectService) attachCommentMentions(taskID string, comments []*TaskComment) domain_errors.DomainError {
+	if len(comments) == 0 {
+		return nil
+	}
+	mentions, err := pjs.commentRepo.ListMentions(taskID)
+	if err != nil {
+		return err
+	}
+	byComment := map[string][]*Mention{}
+	for _, mention := range mentions {
+		if mention.CommentID != nil {
+			byComment[*mention.CommentID] = append(byComment[*mention.CommentID], mention)
+		}
+	}
+	attached := []*Mention{}
+	for _, comment := range comments {
+		comment.Mentions = byComment[comment.ID]
+		if comment.Mentions == nil {
+			comment.Mentions = []*Mention{}
+		}
+		attached = append(attached, comment.Mentions...)
+	}
+	return pjs.attachMentionProfiles(attached)
+}
+
+func (pjs *ProjectService) attachMentionProfiles(mentions []*Mention) domain_errors.DomainError {
+	userIDs := make([]string, len(mentions))
+	for i, mention := range mentions {
+		userIDs[i] = mention.UserID
+	}
+	profiles, err := pjs.publicProfiles(userIDs)
+	if err != nil {
+		return err
+	}
+	for _, mention := range mentions {
+		mention.Profile = profiles[mention.UserID]
+	}
+	return nil
+}
+
+// publicProfiles looks up the public profile of each user, keyed by user id.
+// Users without a profile are left out.
+func (pjs *ProjectService) publicProfiles(userIDs []string) (map[string]*user.PublicProfile, domain_errors.DomainError) {
+	profiles := map[string]*user.PublicProfile{}
+	for _, userID := range userIDs {
+		if _, seen := profiles[userID]; seen {
+			continue
+		}
+		profile, err := pjs.profileRepo.GetPublicProfile(userID)
+		if err != nil && !domain_errors.IsNotFound(err) {
+			return nil, err
+		}
+		profiles[userID] = profile
+	}
+	return profiles, nil
+}
+
+// ============================================================================
+// NOTIFICATION METHODS
+// ============================================================================
+
+// Lists the user's notifications in the workspace, newest first
+func (pjs *ProjectService) ListNotifications(wsID, userID string, unreadOnly bool, limit int) ([]*Notification, domain_errors.DomainError) {
+	notifications, err := pjs.notificationRepo.List(userID, wsID, unreadOnly, notificationLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.attachNotificationActors(notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (pjs *ProjectService) MarkNotificationRead(wsID, userID, id string) (*Notification, domain_errors.DomainError) {
+	if err := uuid.Validate(id); err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("id", id, "NOTIFICATION ID IS NOT A VALID UUID")
+	}
+	notification, err := pjs.notificationRepo.MarkRead(userID, wsID, id, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.attachNotificationActors([]*Notification{notification}); err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// Marks every unread notification of the user in the workspace read and returns how many
+func (pjs *ProjectService) MarkAllNotificationsRead(wsID, userID string) (int, domain_errors.DomainError) {
+	return pjs.notificationRepo.MarkAllRead(userID, wsID, time.Now().UTC())
+}
+
+func (pjs *ProjectService) attachNotificationActors(notifications []*Notification) domain_errors.DomainError {
+	userIDs := make([]string, len(notifications))
+	for i, notification := range notifications {
+		userIDs[i] = notification.ActorID
+	}
+	profiles, err := pjs.publicProfiles(userIDs)
+	if err != nil {
+		return err
+	}
+	for _, notification := range notifications {
+		notification.Actor = profiles[notification.ActorID]
+	}
+	return nil
+}
+
+// ============================================================================
+// WATCHER METHODS
+// ============================================================================
+// Watching is idempotent, and unwatching something not watched does nothing.
+
+// Makes the user watch the task, returning its watchers
+func (pjs *ProjectService) WatchTask(wsID, taskID, userID string) ([]*Watcher, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	if err := pjs.watcherRepo.WatchTasks([]string{taskID}, []string{userID}, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return pjs.listTaskWatchers(taskID)
+}
+
+func (pjs *ProjectService) UnwatchTask(wsID, taskID, userID string) domain_errors.DomainError {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return err
+	}
+	return pjs.watcherRepo.UnwatchTask(taskID, userID)
+}
+
+// Lists the users watching the task itself, not those watching its project
+func (pjs *ProjectService) ListTaskWatchers(wsID, taskID string) ([]*Watcher, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	return pjs.listTaskWatchers(taskID)
+}
+
+func (pjs *ProjectService) listTaskWatchers(taskID string) ([]*Watcher, domain_errors.DomainError) {
+	watchers, err := pjs.watcherRepo.ListTaskWatchers(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.attachWatcherProfiles(watchers); err != nil {
+		return nil, err
+	}
+	return watchers, nil
+}
+
+// Makes the user watch every task of the project, returning its watchers
+func (pjs *ProjectService) WatchProject(wsID, projectID, userID string) ([]*Watcher, domain_errors.DomainError) {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return nil, err
+	}
+	if err := pjs.watcherRepo.WatchProject(projectID, userID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return pjs.listProjectWatchers(projectID)
+}
+
+func (pjs *ProjectService) UnwatchProject(wsID, projectID, userID string) domain_errors.DomainError {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return err
+	}
+	return pjs.watcherRepo.UnwatchProject(projectID, userID)
+}
+
+func (pjs *ProjectService) ListProjectWatchers(wsID, projectID string) ([]*Watcher, domain_errors.DomainError) {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return nil, err
+	}
+	return pjs.listProjectWatchers(projectID)
+}
+
+func (pjs *ProjectService) listProjectWatchers(projectID string) ([]*Watcher, domain_errors.DomainError) {
+	watchers, err := pjs.watcherRepo.ListProjectWatchers(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.attachWatcherProfiles(watchers); err != nil {
+		return nil, err
+	}
+	return watchers, nil
+}
+
+func (pjs *ProjectService) attachWatcherProfiles(watchers []*Watcher) domain_errors.DomainError {
+	userIDs := make([]string, len(watchers))
+	for i, watcher := range watchers {
+		userIDs[i] = watcher.UserID
+	}
+	profiles, err := pjs.publicProfiles(userIDs)
+	if err != nil {
+		return err
+	}
+	for _, watcher := range watchers {
+		watcher.Profile = profiles[watcher.UserID]
+	}
+	return nil
+}
+
+// ============================================================================
+// REPORT METHODS
+// ============================================================================
+// Reports are cached for reportCacheTTL, so they may lag behind task changes.
+
+// reportScope checks the project, when given, belongs to the workspace
+func (pjs *ProjectService) reportScope(wsID, projectID string) (ReportScope, domain_errors.DomainError) {
+	if projectID != "" {
+		if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+			return ReportScope{}, err
+		}
+	}
+	return ReportScope{WorkspaceID: wsID, ProjectID: projectID}, nil
+}
+
+func checkReportRange(from, to time.Time) domain_errors.DomainError {
+	if !to.After(from) {
+		return domain_errors.NewValidationErrorWithValue("to", to, "TO MUST BE AFTER FROM")
+	}
+	if to.Sub(from) > maxReportRange {
+		return domain_errors.NewValidationError("from", "REPORTS COVER AT MOST A YEAR")
+	}
+	return nil
+}
+
+// Counts the tasks of a project, or of the workspace when projectID is empty,
+// by status and priority
+func (pjs *ProjectService) TaskSummary(wsID, projectID string) (*TaskSummary, domain_errors.DomainError) {
+	scope, err := pjs.reportScope(wsID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	return cachedReport(pjs.reports, "summary:"+wsID+":"+projectID, now, func() (*TaskSummary, domain_errors.DomainError) {
+		return pjs.reportRepo.TaskSummary(scope, now)
+	})
+}
+
+// Counts the tasks closed each week between from and to
+func (pjs *ProjectService) Throughput(wsID, projectID string, from, to time.Time) (*Throughput, domain_errors.DomainError) {
+	scope, err := pjs.reportScope(wsID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReportRange(from, to); err != nil {
+		return nil, err
+	}
+	from, to = from.UTC(), to.UTC()
+	now := time.Now().UTC()
+	key := "throughput:" + wsID + ":" + projectID + ":" + from.Format(time.RFC3339) + ":" + to.Format(time.RFC3339)
+	return cachedReport(pjs.reports, key, now, func() (*Throughput, domain_errors.DomainError) {
+		weeks, err := pjs.reportRepo.Throughput(scope, from, to)
+		if err != nil {
+			return nil, err
+		}
+		return &Throughput{WorkspaceID: wsID, ProjectID: projectID, From: from, To: to, Weeks: weeks, GeneratedAt: now}, nil
+	})
+}
+
+// Measures how long the tasks closed between from and to took to close
+func (pjs *ProjectService) CycleTime(wsID, projectID string, from, to time.Time) (*CycleTime, domain_errors.DomainError) {
+	scope, err := pjs.reportScope(wsID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReportRange(from, to); err != nil {
+		return nil, err
+	}
+	from, to = from.UTC(), to.UTC()
+	now := time.Now().UTC()
+	key := "cycle_time:" + wsID + ":" + projectID + ":" + from.Format(time.RFC3339) + ":" + to.Format(time.RFC3339)
+	return cachedReport(pjs.reports, key, now, func() (*CycleTime, domain_errors.DomainError) {
+		cycle, err := pjs.reportRepo.CycleTime(scope, from, to)
+		if err != nil {
+			return nil, err
+		}
+		cycle.GeneratedAt = now
+		return cycle, nil
+	})
+}
+
+// Sums the unfinished work assigned to each user
+func (pjs *ProjectService) Workload(wsID, projectID string) (*Workload, domain_errors.DomainError) {
+	scope, err := pjs.reportScope(wsID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	return cachedReport(pjs.reports, "workload:"+wsID+":"+projectID, now, func() (*Workload, domain_errors.DomainError) {
+		return pjs.reportRepo.Workload(scope, now)
+	})
+}
+
+// ============================================================================
+// CUSTOM FIELD METHODS
+// ============================================================================
+
+// checkProjectInWorkspace makes sure the project belongs to the workspace in the URL
+func (pjs *ProjectService) checkProjectInWorkspace(wsID, projectID string) domain_errors.DomainError {
+	if err := uuid.Validate(projectID); err != nil {
+		return domain_errors.NewValidationErrorWithValue("project_id", projectID, "PROJECT ID IS NOT A VALID UUID")
+	}
+	project, err := pjs.projectRepo.GetByID(projectID)
+	if err != nil {
+		return err
+	}
+	if project.WorkspaceID != wsID {
+		return domain_errors.NewNotFoundError("Project", projectID)
+	}
+	return nil
+}
+
+// Defines a custom field for the project's tasks
+func (pjs *ProjectService) CreateCustomField(wsID, projectID string, input *CreateCustomFieldInput) (*CustomField, domain_errors.DomainError) {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkProjectWritable(projectID); err != nil {
+		return nil, err
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	fields, err := pjs.fieldRepo.ListByProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) >= maxCustomFields {
+		return nil, domain_errors.NewInvalidOperationError("custom field creation", "PROJECT ALREADY HAS 50 CUSTOM FIELDS")
+	}
+	field := &CustomField{
+		ID:        uuid.NewString(),
+		ProjectID: projectID,
+		Key:       input.Key,
+		Name:      input.Name,
+		Type:      input.Type,
+		Options:   input.Options,
+		Required:  input.Required,
+		CreatedAt: time.Now().UTC(),
+	}
+	return pjs.fieldRepo.Create(field)
+}
+
+// Lists the project's custom fields in display order
+func (pjs *ProjectService) ListCustomFields(wsID, projectID string) ([]*CustomField, domain_errors.DomainError) {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return nil, err
+	}
+	return pjs.fieldRepo.ListByProject(projectID)
+}
+
+// Renames, reorders or changes the options of a custom field. Making a field
+// required applies to tasks created or updated afterwards.
+func (pjs *ProjectService) UpdateCustomField(wsID, projectID, id string, input *UpdateCustomFieldInput) (*CustomField, domain_errors.DomainError) {
+	field, err := pjs.getCustomField(wsID, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.checkProjectWritable(projectID); err != nil {
+		return nil, err
+	}
+	if err := input.Validate(field); err != nil {
+		return nil, err
+	}
+	return pjs.fieldRepo.Update(field, input)
+}
+
+// Deletes a custom field and its values on every task
+func (pjs *ProjectService) DeleteCustomField(wsID, projectID, id string) domain_errors.DomainError {
+	field, err := pjs.getCustomField(wsID, projectID, id)
+	if err != nil {
+		return err
+	}
+	if err := pjs.checkProjectWritable(projectID); err != nil {
+		return err
+	}
+	return pjs.fieldRepo.Delete(field)
+}
+
+func (pjs *ProjectService) getCustomField(wsID, projectID, id string) (*CustomField, domain_errors.DomainError) {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return nil, err
+	}
+	if err := uuid.Validate(id); err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("field_id", id, "FIELD ID IS NOT A VALID UUID")
+	}
+	return pjs.fieldRepo.GetByID(projectID, id)
+}
+
+// ============================================================================
+// MILESTONE METHODS
+// ============================================================================
+
+// Creates a milestone or sprint in the project
+func (pjs *ProjectService) CreateMilestone(wsID, projectID, creator string, input *CreateMilestoneInput) (*Milestone, domain_errors.DomainError) {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkProjectWritable(projectID); err != nil {
+		return nil, err
+	}
+	if input.Kind == "" {
+		input.Kind = MilestoneKindMilestone
+	}
+	if input.Kind != MilestoneKindMilestone && input.Kind != MilestoneKindSprint {
+		return nil, domain_errors.NewValidationErrorWithValue("kind", input.Kind, "KIND MUST BE milestone OR sprint")
+	}
+	milestone := &Milestone{
+		ID:          uuid.NewString(),
+		ProjectID:   projectID,
+		Name:        strings.TrimSpace(input.Name),
+		Kind:        input.Kind,
+		Description: input.Description,
+		CreatedBy:   creator,
+		CreatedAt:   time.Now().UTC(),
+	}
+	start, err := parseDate("start_date", input.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseDate("end_date", input.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	milestone.StartDate, milestone.EndDate = start, end
+	if err := validateMilestone(milestone); err != nil {
+		return nil, err
+	}
+	return pjs.milestoneRepo.Create(milestone)
+}
+
+func validateMilestone(milestone *Milestone) domain_errors.DomainError {
+	if milestone.Name == "" {
+		return domain_errors.NewValidationError("name", "NAME CANNOT BE EMPTY")
+	}
+	if milestone.EndDate.Before(milestone.StartDate) {
+		return domain_errors.NewValidationError("end_date", "END DATE CANNOT BE BEFORE START DATE")
+	}
+	return nil
+}
+
+// Lists the project's milestones by start date
+func (pjs *ProjectService) ListMilestones(wsID, projectID string) ([]*Milestone, domain_errors.DomainError) {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return nil, err
+	}
+	return pjs.milestoneRepo.ListByProject(projectID)
+}
+
+func (pjs *ProjectService) GetMilestone(wsID, projectID, id string) (*Milestone, domain_errors.DomainError) {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return nil, err
+	}
+	if err := uuid.Validate(id); err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("milestone_id", id, "MILESTONE ID IS NOT A VALID UUID")
+	}
+	return pjs.milestoneRepo.GetByID(projectID, id)
+}
+
+// Renames a milestone or moves its dates
+func (pjs *ProjectService) UpdateMilestone(wsID, projectID, id string, input *UpdateMilestoneInput) (*Milestone, domain_errors.DomainError) {
+	milestone, err := pjs.GetMilestone(wsID, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.checkProjectWritable(projectID); err != nil {
+		return nil, err
+	}
+	if input.Name != nil {
+		milestone.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		milestone.Description = *input.Description
+	}
+	if input.StartDate != nil {
+		if milestone.StartDate, err = parseDate("start_date", *input.StartDate); err != nil {
+			return nil, err
+		}
+	}
+	if input.EndDate != nil {
+		if milestone.EndDate, err = parseDate("end_date", *input.EndDate); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateMilestone(milestone); err != nil {
+		return nil, err
+	}
+	return pjs.milestoneRepo.Update(milestone)
+}
+
+// Deletes a milestone. Its tasks stay in the project without a milestone.
+func (pjs *ProjectService) DeleteMilestone(wsID, projectID, id string) domain_errors.DomainError {
+	if _, err := pjs.GetMilestone(wsID, projectID, id); err != nil {
+		return err
+	}
+	if err := pjs.checkProjectWritable(projectID); err != nil {
+		return err
+	}
+	return pjs.milestoneRepo.Delete(projectID, id)
+}
+
+// Moves a task into one of its project's milestones, or out of any when milestoneID is nil
+func (pjs *ProjectService) SetTaskMilestone(wsID, taskID, actor string, milestoneID *string) (*Task, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return nil, err
+	}
+	if milestoneID != nil {
+		if err := uuid.Validate(*milestoneID); err != nil {
+			return nil, domain_errors.NewValidationErrorWithValue("milestone_id", *milestoneID, "MILESTONE ID IS NOT A VALID UUID")
+		}
+		task, err := pjs.projectRepo.GetTaskByID(taskID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pjs.milestoneRepo.GetByID(task.ProjectID, *milestoneID); err != nil {
+			return nil, err
+		}
+	}
+	return pjs.projectRepo.SetTaskMilestone(taskID, milestoneID, actor, time.Now().UTC())
+}
+
+// Lists what happened to a task, most recent first
+func (pjs *ProjectService) ListTaskActivity(wsID, taskID string) ([]*TaskEvent, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	return pjs.projectRepo.ListTaskActivity(taskID)
+}
+
+// Computes the milestone's daily burndown and burnup from the activity of its tasks
+func (pjs *ProjectService) GetBurndown(wsID, projectID, id string) (*Burndown, domain_errors.DomainError) {
+	milestone, err := pjs.GetMilestone(wsID, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := pjs.milestoneRepo.ListEvents(id)
+	if err != nil {
+		return nil, err
+	}
+	estimates, err := pjs.milestoneRepo.TaskEstimates(id)
+	if err != nil {
+		return nil, err
+	}
+	return computeBurndown(milestone, events, estimates, time.Now().UTC()), nil
+}
+
+// computeBurndown replays the events, oldest first, to find the milestone's scope
+// and finished work at the end of each day from its start until its end or now
+func computeBurndown(milestone *Milestone, events []*TaskEvent, estimates map[string]int, now time.Time) *Burndown {
+	burndown := &Burndown{
+		MilestoneID:  milestone.ID,
+		StartDate:    milestone.StartDate,
+		EndDate:      milestone.EndDate,
+		Points:       []*BurndownPoint{},
+		ScopeChanges: []*ScopeChange{},
+	}
+
+	byTask := map[string][]*TaskEvent{}
+	for _, event := range events {
+		byTask[event.TaskID] = append(byTask[event.TaskID], event)
+		if event.Kind != TaskEventMilestoneChanged || event.CreatedAt.Before(milestone.StartDate) {
+			continue
+		}
+		change := ""
+		switch {
+		case event.To != nil && *event.To == milestone.ID:
+			change = "added"
+		case event.From != nil && *event.From == milestone.ID:
+			change = "removed"
+		}
+		if change != "" {
+			burndown.ScopeChanges = append(burndown.ScopeChanges, &ScopeChange{
+				TaskID:  event.TaskID,
+				Change:  change,
+				ActorID: event.ActorID,
+				At:      event.CreatedAt,
+			})
+		}
+	}
+
+	totalDays := int(milestone.EndDate.Sub(milestone.StartDate)/(24*time.Hour)) + 1
+	for day := 0; day < totalDays; day++ {
+		date := milestone.StartDate.AddDate(0, 0, day)
+		if date.After(now) {
+			break
+		}
+		endOfDay := date.AddDate(0, 0, 1)
+		point := &BurndownPoint{Date: date}
+		for taskID, taskEvents := range byTask {
+			// the task's state is the snapshot of its last event of the day
+			var last *TaskEvent
+			for _, event := range taskEvents {
+				if !event.CreatedAt.Before(endOfDay) {
+					break
+				}
+				last = event
+			}
+			if last == nil || last.MilestoneID == nil || *last.MilestoneID != milestone.ID {
+				continue
+			}
+			point.ScopeTasks++
+			point.ScopeMinutes += estimates[taskID]
+			if last.Status == TaskStatusClosed {
+				point.DoneTasks++
+				point.DoneMinutes += estimates[taskID]
+			}
+		}
+		point.RemainingTasks = point.ScopeTasks - point.DoneTasks
+		point.RemainingMinutes = point.ScopeMinutes - point.DoneMinutes
+		burndown.Points = append(burndown.Points, point)
+	}
+
+	if len(burndown.Points) > 0 {
+		initial := float64(burndown.Points[0].ScopeTasks)
+		for day, point := range burndown.Points {
+			if totalDays > 1 {
+				point.IdealRemainingTasks = initial * float64(totalDays-1-day) / float64(totalDays-1)
+			}
+		}
+	}
+	return burndown
+}
+
+// ============================================================================
+// TIME TRACKING METHODS
+// ============================================================================
+
+// maxTimesheetRange bounds the period a timesheet covers
+const maxTimesheetRange = 366 * 24 * time.Hour
+
+// Returns the estimates and logged time of a task and its subtasks
+func (pjs *ProjectService) GetTimeRollup(wsID, taskID string) (*TimeRollup, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	rollups, err := pjs.projectRepo.GetTimeRollups([]string{taskID})
+	if err != nil {
+		return nil, err
+	}
+	rollup, ok := rollups[taskID]
+	if !ok {
+		return nil, domain_errors.NewNotFoundError("task", taskID)
+	}
+	return rollup, nil
+}
+
+// Logs time the user spent on a task
+func (pjs *ProjectService) LogWork(wsID, taskID, userID string, input *CreateWorkLogInput) (*WorkLog, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return nil, err
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	log := &WorkLog{
+		ID:          uuid.NewString(),
+		TaskID:      taskID,
+		UserID:      userID,
+		StartedAt:   input.StartedAt.UTC(),
+		Minutes:     input.Minutes,
+		Description: strings.TrimSpace(input.Description),
+		CreatedAt:   time.Now().UTC(),
+	}
+	return pjs.timeRepo.CreateWorkLog(log)
+}
+
+// Lists the time logged on a task, most recent first
+func (pjs *ProjectService) ListWorkLogs(wsID, taskID string) ([]*WorkLog, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	return pjs.timeRepo.ListWorkLogs(taskID)
+}
+
+// Removes a work log entry. Only its author may remove it; the remaining
+// estimate is left as it is.
+func (pjs *ProjectService) DeleteWorkLog(wsID, taskID, id, requester string) domain_errors.DomainError {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return err
+	}
+	if err := uuid.Validate(id); err != nil {
+		return domain_errors.NewValidationErrorWithValue("worklog_id", id, "WORK LOG ID IS NOT A VALID UUID")
+	}
+	log, err := pjs.timeRepo.GetWorkLog(taskID, id)
+	if err != nil {
+		return err
+	}
+	if log.UserID != requester {
+		return domain_errors.NewForbiddenError("work log", "delete")
+	}
+	return pjs.timeRepo.DeleteWorkLog(taskID, id)
+}
+
+// Starts the user's timer on a task
+func (pjs *ProjectService) StartTimer(wsID, taskID, userID, description string) (*WorkTimer, domain_errors.DomainError) {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return nil, err
+	}
+	timer := &WorkTimer{
+		UserID:      userID,
+		TaskID:      taskID,
+		StartedAt:   time.Now().UTC(),
+		Description: strings.TrimSpace(description),
+	}
+	return pjs.timeRepo.StartTimer(timer)
+}
+
+// Returns the user's running timer if it is on a task of the workspace
+func (pjs *ProjectService) GetTimer(wsID, userID string) (*WorkTimer, domain_errors.DomainError) {
+	timer, err := pjs.timeRepo.GetTimer(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.checkTaskInWorkspace(wsID, timer.TaskID); err != nil {
+		return nil, domain_errors.NewNotFoundError("work timer", userID)
+	}
+	return timer, nil
+}
+
+// Stops the user's timer and logs the elapsed time, rounded up to the minute.
+// A timer left running for more than a day logs one day.
+func (pjs *ProjectService) StopTimer(wsID, userID string) (*WorkLog, domain_errors.DomainError) {
+	timer, err := pjs.GetTimer(wsID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := pjs.checkTasksWritable(timer.TaskID); err != nil {
+		return nil, err
+	}
+	return pjs.timeRepo.StopTimer(userID, func(timer *WorkTimer) *WorkLog {
+		now := time.Now().UTC()
+		minutes := int((now.Sub(timer.StartedAt) + time.Minute - 1) / time.Minute)
+		minutes = max(1, min(minutes, maxWorkLogMinutes))
+		return &WorkLog{
+			ID:          uuid.NewString(),
+			TaskID:      timer.TaskID,
+			UserID:      userID,
+			StartedAt:   timer.StartedAt,
+			Minutes:     minutes,
+			Description: timer.Description,
+			CreatedAt:   now,
+		}
+	})
+}
+
+// Discards the user's timer without logging time
+func (pjs *ProjectService) DiscardTimer(wsID, userID string) domain_errors.DomainError {
+	if _, err := pjs.GetTimer(wsID, userID); err != nil {
+		return err
+	}
+	return pjs.timeRepo.DiscardTimer(userID)
+}
+
+// Reports the time logged on a project by user and week between from and to
+func (pjs *ProjectService) Timesheet(wsID, projectID string, from, to time.Time) (*Timesheet, domain_errors.DomainError) {
+	if err := pjs.checkProjectInWorkspace(wsID, projectID); err != nil {
+		return nil, err
+	}
+	if !to.After(from) {
+		return nil, domain_errors.NewValidationErrorWithValue("to", to, "TO MUST BE AFTER FROM")
+	}
+	if to.Sub(from) > maxTimesheetRange {
+		return nil, domain_errors.NewValidationError("from", "TIMESHEETS COVER AT MOST A YEAR")
+	}
+	entries, err := pjs.timeRepo.Timesheet(projectID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := &Timesheet{ProjectID: projectID, From: from.UTC(), To: to.UTC(), Users: []*TimesheetUser{}}
+	byUser := map[string]*TimesheetUser{}
+	for _, entry := range entries {
+		user, ok := byUser[entry.UserID]
+		if !ok {
+			user = &TimesheetUser{UserID: entry.UserID}
+			byUser[entry.UserID] = user
+			sheet.Users = append(sheet.Users, user)
+		}
+		user.Weeks = append(user.Weeks, entry)
+		user.TotalMinutes += entry.Minutes
+		sheet.TotalMinutes += entry.Minutes
+	}
+	return sheet, nil
+}
+
+// ============================================================================
+// LABEL METHODS
+// ============================================================================
+
+// labelColorPattern accepts colours written as #RRGGBB
+var labelColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// normalizeLabel trims the name and upper-cases the colour, rejecting invalid values
+func normalizeLabel(name, color *string) domain_errors.DomainError {
+	if name != nil {
+		*name = strings.Join(strings.Fields(*name), " ")
+		if *name == "" {
+			return domain_errors.NewValidationErrorWithValue("name", *name, "LABEL NAME IS REQUIRED")
+		}
+		if len([]rune(*name)) > 50 {
+			return domain_errors.NewValidationErrorWithValue("name", *name, "LABEL NAME MUST BE AT MOST 50 CHARACTERS")
+		}
+	}
+	if color != nil {
+		if !labelColorPattern.MatchString(*color) {
+			return domain_errors.NewValidationErrorWithValue("color", *color, "LABEL COLOR MUST BE A HEX COLOR LIKE #1A2B3C")
+		}
+		*color = strings.ToUpper(*color)
+	}
+	return nil
+}
+
+// Creates a label in the workspace. Names are unique within a workspace, ignoring case.
+func (pjs *ProjectService) CreateLabel(wsID, creator string, input *CreateLabelInput) (*Label, domain_errors.DomainError) {
+	if input.Color == "" {
+		input.Color = DefaultLabelColor
+	}
+	if err := normalizeLabel(&input.Name, &input.Color); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkWorkspaceWritable(wsID); err != nil {
+		return nil, err
+	}
+	label := &Label{
+		ID:          uuid.NewString(),
+		WorkspaceID: wsID,
+		Name:        input.Name,
+		Color:       input.Color,
+		CreatedBy:   creator,
+		CreatedAt:   time.Now().UTC(),
+	}
+	return pjs.labelRepo.Create(label)
+}
+
+// Lists the workspace's labels with the number of tasks carrying each
+func (pjs *ProjectService) ListLabels(wsID string) ([]*Label, domain_errors.DomainError) {
+	return pjs.labelRepo.ListByWorkspace(wsID)
+}
+
+// Renames or recolours a label. The change shows on every task carrying it.
+func (pjs *ProjectService) UpdateLabel(wsID, id string, input *UpdateLabelInput) (*Label, domain_errors.DomainError) {
+	if err := uuid.Validate(id); err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("id", id, "LABEL ID IS NOT A VALID UUID")
+	}
+	if err := normalizeLabel(input.Name, input.Color); err != nil {
+		return nil, err
+	}
+	if err := pjs.checkWorkspaceWritable(wsID); err != nil {
+		return nil, err
+	}
+	return pjs.labelRepo.Update(wsID, id, input)
+}
+
+// Deletes a label, removing it from every task
+func (pjs *ProjectService) DeleteLabel(wsID, id string) domain_errors.DomainError {
+	if err := uuid.Validate(id); err != nil {
+		return domain_errors.NewValidationErrorWithValue("id", id, "LABEL ID IS NOT A VALID UUID")
+	}
+	if err := pjs.checkWorkspaceWritable(wsID); err != nil {
+		return err
+	}
+	return pjs.labelRepo.Delete(wsID, id)
+}
+
+// Merges a label into another: its tasks get the target label and it is deleted
+func (pjs *ProjectService) MergeLabel(wsID, sourceID, targetID string) (*Label, domain_errors.DomainError) {
+	if err := uuid.Validate(sourceID); err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("id", sourceID, "LABEL ID IS NOT A VALID UUID")
+	}
+	if err := uuid.Validate(targetID); err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("into", targetID, "LABEL ID IS NOT A VALID UUID")
+	}
+	if sourceID == targetID {
+		return nil, domain_errors.NewInvalidOperationError("label merge", "LABEL CANNOT BE MERGED INTO ITSELF")
+	}
+	if err := pjs.checkWorkspaceWritable(wsID); err != nil {
+		return nil, err
+	}
+	return pjs.labelRepo.Merge(wsID, sourceID, targetID)
+}
+
+// Puts workspace labels on a task, keeping the labels it already has
+func (pjs *ProjectService) AddTaskLabels(wsID, taskID string, labelIDs []string) (*Task, domain_errors.DomainError) {
+	if err := pjs.checkTaskLabels(wsID, taskID, labelIDs); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for _, labelID := range labelIDs {
+		if err := pjs.labelRepo.AddToTask(taskID, labelID, now); err != nil {
+			return nil, err
+		}
+	}
+	return pjs.GetTaskByID(taskID)
+}
+
+// Replaces the labels of a task
+func (pjs *ProjectService) SetTaskLabels(wsID, taskID string, labelIDs []string) (*Task, domain_errors.DomainError) {
+	if err := pjs.checkTaskLabels(wsID, taskID, labelIDs); err != nil {
+		return nil, err
+	}
+	if labelIDs == nil {
+		labelIDs = []string{}
+	}
+	if err := pjs.labelRepo.SetTaskLabels(taskID, labelIDs, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return pjs.GetTaskByID(taskID)
+}
+
+// Takes a label off a task
+func (pjs *ProjectService) RemoveTaskLabel(wsID, taskID, labelID string) domain_errors.DomainError {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return err
+	}
+	if err := uuid.Validate(labelID); err != nil {
+		return domain_errors.NewValidationErrorWithValue("label_id", labelID, "LABEL ID IS NOT A VALID UUID")
+	}
+	return pjs.labelRepo.RemoveFromTask(taskID, labelID)
+}
+
+// checkTaskLabels makes sure the task and every label belong to the workspace
+func (pjs *ProjectService) checkTaskLabels(wsID, taskID string, labelIDs []string) domain_errors.DomainError {
+	if err := pjs.checkTaskInWorkspace(wsID, taskID); err != nil {
+		return err
+	}
+	if err := pjs.checkTasksWritable(taskID); err != nil {
+		return err
+	}
+	unique := map[string]bool{}
+	for _, labelID := range labelIDs {
+		if err := uuid.Validate(labelID); err != nil {
+			return domain_errors.NewValidationErrorWithValue("label_ids", labelID, "LABEL ID IS NOT A VALID UUID")
+		}
+		unique[labelID] = true
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+	labels, err := pjs.labelRepo.ListByIDs(wsID, labelIDs)
+	if err != nil {
+		return err
+	}
+	if len(labels) != len(unique) {
+		found := map[string]bool{}
+		for _, label := range labels {
+			found[label.ID] = true
+		}
+		for labelID := range unique {
+			if !found[labelID] {
+				return domain_errors.NewNotFoundError("label", labelID)
+			}
+		}
+	}
+	return nil
+}
+
+// attachLabels fills in the labels of the tasks with a single query
+func (pjs *ProjectService) attachLabels(tasks []*Task) domain_errors.DomainError {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	labels, err := pjs.labelRepo.ListByTasks(ids)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		task.Labels = labels[task.ID]
+	}
+	return nil
+}
+
+// sanitizeFilename keeps the base name of an uploaded file without control
+// characters, shortened to fit the name column
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" {
+		return ""
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[len(runes)-255:])
+	}
+	return name
+}
@@ -0,0 +1,123 @@
+package project
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+// maxTemplateTasks bounds the tasks a template can hold
+const maxTemplateTasks = 1000
+
+// TaskTemplate is a tree of tasks saved from a task or a whole project, which can
+// be instantiated into any project of its workspace
+type TaskTemplate struct {
+	ID          string          `json:"id"`
+	WorkspaceID string          `json:"workspace_id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Tasks       []*TemplateTask `json:"tasks"`
+	CreatedBy   string          `json:"created_by"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// TemplateTask is a task of a template. Due dates are kept as a number of days
+// after the day the source task or project was created, and are placed after the
+// start date the template is instantiated with.
+type TemplateTask struct {
+	Name                    string            `json:"name"`
+	Description             string            `json:"description"`
+	Priority                TaskPriority      `json:"priority"`
+	DueOffsetDays           *int              `json:"due_offset_days,omitempty"`
+	OriginalEstimateMinutes *int              `json:"original_estimate_minutes,omitempty"`
+	CustomFields            CustomFieldValues `json:"custom_fields,omitempty"`
+	LabelIDs                []string          `json:"label_ids,omitempty"`
+	Subtasks                []*TemplateTask   `json:"subtasks,omitempty"`
+}
+
+type CreateTemplateInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// TaskID saves the tree of a task, ProjectID every task of a project
+	TaskID    string `json:"task_id"`
+	ProjectID string `json:"project_id"`
+}
+
+func (input *CreateTemplateInput) Validate() domain_errors.DomainError {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return domain_errors.NewValidationError("name", "NAME CANNOT BE EMPTY")
+	}
+	if len(input.Name) > 255 {
+		return domain_errors.NewValidationError("name", "NAME CANNOT BE LONGER THAN 255 CHARACTERS")
+	}
+	if (input.TaskID == "") == (input.ProjectID == "") {
+		return domain_errors.NewValidationError("task_id", "EITHER A TASK ID OR A PROJECT ID IS REQUIRED")
+	}
+	if input.TaskID != "" && uuid.Validate(input.TaskID) != nil {
+		return domain_errors.NewValidationErrorWithValue("task_id", input.TaskID, "TASK ID IS NOT A VALID UUID")
+	}
+	return nil
+}
+
+type InstantiateTemplateInput struct {
+	ProjectID string `json:"project_id"`
+	// ParentID puts the template's tasks under an existing task of the project
+	ParentID string `json:"parent_id"`
+	// StartDate anchors the due dates, written as 2006-01-02. Defaults to today.
+	StartDate string `json:"start_date"`
+}
+
+// CloneProjectInput names the copy of a project. The description defaults to the source's.
+type CloneProjectInput struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+}
+
+// templateTasks copies the trees without their state, keeping due dates as
+// offsets from the day of anchor
+func templateTasks(trees []*TaskTree, anchor time.Time) []*TemplateTask {
+	tasks := make([]*TemplateTask, 0, len(trees))
+	for _, tree := range trees {
+		task := &TemplateTask{
+			Name:                    tree.Name,
+			Description:             tree.Description,
+			Priority:                tree.Priority,
+			OriginalEstimateMinutes: tree.OriginalEstimateMinutes,
+			CustomFields:            tree.CustomFields,
+			Subtasks:                templateTasks(tree.Subtasks, anchor),
+		}
+		if tree.DueDate != nil {
+			offset := dayOffset(anchor, *tree.DueDate)
+			task.DueOffsetDays = &offset
+		}
+		for _, label := range tree.Labels {
+			task.LabelIDs = append(task.LabelIDs, label.ID)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// dayOffset counts the calendar days from from to to
+func dayOffset(from, to time.Time) int {
+	from, to = from.UTC(), to.UTC()
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours() / 24)
+}
+
+// countTemplateTasks returns how many tasks the trees hold and how many levels
+// of subtasks are below their roots
+func countTemplateTasks(tasks []*TemplateTask) (count, height int) {
+	for _, task := range tasks {
+		subtasks, subtaskHeight := countTemplateTasks(task.Subtasks)
+		count += 1 + subtasks
+		if len(task.Subtasks) > 0 && subtaskHeight+1 > height {
+			height = subtaskHeight + 1
+		}
+	}
+	return count, height
+}
@@ -0,0 +1,90 @@
+package project
+
+import (
+	"database/sql"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/internal/shared"
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+const (
+	// DefaultTrashRetention is how long deleted projects and tasks stay in the
+	// trash unless TRASH_RETENTION_DAYS sets another number of days
+	DefaultTrashRetention = 30 * 24 * time.Hour
+	// trashPurgeBatch is the most projects or tasks purged per transaction
+	trashPurgeBatch = 100
+)
+
+func trashRetentionFromEnv() time.Duration {
+	if days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS")); err == nil && days > 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return DefaultTrashRetention
+}
+
+type TrashItemKind string
+
+const (
+	TrashItemProject TrashItemKind = "project"
+	TrashItemTask    TrashItemKind = "task"
+)
+
+// TrashItem is a deleted project, or a deleted task with the subtasks deleted
+// along with it. Restoring the item restores all of them.
+type TrashItem struct {
+	Kind      TrashItemKind `json:"kind"`
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	ProjectID string        `json:"project_id"`
+	DeletedAt time.Time     `json:"deleted_at"`
+	DeletedBy string        `json:"deleted_by"`
+	// PurgeAt is when the item is deleted for good
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// TrashPurger permanently deletes the projects and tasks that have been in the
+// trash for longer than the retention period
+type TrashPurger struct {
+	repo      TrashRepository
+	retention time.Duration
+
+	*shared.Periodic
+}
+
+func NewTrashPurger(db *sql.DB, interval time.Duration) *TrashPurger {
+	p := &TrashPurger{
+		repo:      NewPostgresTrashRepository(db),
+		retention: trashRetentionFromEnv(),
+	}
+	p.Periodic = shared.NewPeriodic("FAILED_TO_PURGE_TRASH", interval, func(now time.Time) error {
+		_, err := p.Purge(now)
+		return err
+	})
+	return p
+}
+
+// Purge deletes the projects and tasks deleted before the retention period and
+// returns how many. Tasks go with their subtasks, which are not counted.
+func (p *TrashPurger) Purge(now time.Time) (int, error) {
+	before := now.Add(-p.retention)
+	total := 0
+	for _, purge := range []func(before time.Time, limit int) (int, domain_errors.DomainError){
+		p.repo.PurgeProjects,
+		p.repo.PurgeTasks,
+	} {
+		for {
+			purged, err := purge(before, trashPurgeBatch)
+			if err != nil {
+				return total, err
+			}
+			total += purged
+			if purged < trashPurgeBatch {
+				break
+			}
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,17 @@
+package project
+
+import (
+	"time"
+
+	"github.com/ishola-faazele/taskflow/internal/user"
+)
+
+// Watcher is a user told about the activity of a task, or of every task of a
+// project. Creating a task or project, being assigned to a task and commenting
+// on it watch it.
+type Watcher struct {
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Profile is the watcher's public profile, resolved when responding
+	Profile *user.PublicProfile `json:"profile,omitempty"`
+}
@@ -0,0 +1,24 @@
+package session
+
+import "time"
+
+// Session is a logged in device. Its ID identifies the refresh-token family:
+// every refresh token issued for the device carries it, and only the most
+// recently issued token (RefreshTokenHash) may be exchanged.
+type Session struct {
+	ID               string     `json:"id"`
+	UserID           string     `json:"user_id"`
+	UserAgent        string     `json:"user_agent"`
+	IPAddress        string     `json:"ip_address"`
+	RefreshTokenHash string     `json:"-"`
+	CreatedAt        time.Time  `json:"created_at"`
+	LastUsedAt       time.Time  `json:"last_used_at"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	Current          bool       `json:"current"`
+}
+
+// IsActive reports whether the session can still be used at the given time
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(now)
+}
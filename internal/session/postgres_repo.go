@@ -0,0 +1,173 @@
+package session
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+// PostgresSessionRepository handles session persistence
+type PostgresSessionRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresSessionRepository creates a new session repository
+func NewPostgresSessionRepository(db *sql.DB) *PostgresSessionRepository {
+	return &PostgresSessionRepository{db: db}
+}
+
+func (r *PostgresSessionRepository) Create(session *Session) (*Session, domain_errors.DomainError) {
+	query := `
+		INSERT INTO session (id, user_id, user_agent, ip_address, refresh_token_hash, created_at, last_used_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, user_id, user_agent, ip_address, refresh_token_hash, created_at, last_used_at, expires_at, revoked_at
+	`
+
+	row := r.db.QueryRow(
+		query,
+		session.ID,
+		session.UserID,
+		session.UserAgent,
+		session.IPAddress,
+		session.RefreshTokenHash,
+		session.CreatedAt,
+		session.LastUsedAt,
+		session.ExpiresAt,
+	)
+
+	result := &Session{}
+	if err := scanSession(row, result); err != nil {
+		return nil, domain_errors.NewDatabaseError("SESSION_CREATION", err)
+	}
+	return result, nil
+}
+
+func (r *PostgresSessionRepository) GetByID(id string) (*Session, domain_errors.DomainError) {
+	query := `
+		SELECT id, user_id, user_agent, ip_address, refresh_token_hash, created_at, last_used_at, expires_at, revoked_at
+		FROM session
+		WHERE id = $1
+	`
+
+	result := &Session{}
+	if err := scanSession(r.db.QueryRow(query, id), result); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("SESSION", id)
+		}
+		return nil, domain_errors.NewDatabaseError("SESSION_QUERY", err)
+	}
+	return result, nil
+}
+
+func (r *PostgresSessionRepository) Rotate(id, oldHash, newHash string, usedAt, expiresAt time.Time) (bool, domain_errors.DomainError) {
+	query := `
+		UPDATE session
+		SET refresh_token_hash = $3, last_used_at = $4, expires_at = $5
+		WHERE id = $1 AND refresh_token_hash = $2 AND revoked_at IS NULL AND expires_at > $4
+	`
+
+	result, err := r.db.Exec(query, id, oldHash, newHash, usedAt, expiresAt)
+	if err != nil {
+		return false, domain_errors.NewDatabaseError("SESSION_ROTATION", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, domain_errors.NewDatabaseError("SESSION_ROTATION", err)
+	}
+	return rows == 1, nil
+}
+
+func (r *PostgresSessionRepository) Revoke(id string) domain_errors.DomainError {
+	query := `
+		UPDATE session
+		SET revoked_at = COALESCE(revoked_at, $2)
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(query, id, time.Now().UTC())
+	if err != nil {
+		return domain_errors.NewDatabaseError("SESSION_REVOCATION", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError("SESSION_REVOCATION", err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError("SESSION", id)
+	}
+	return nil
+}
+
+func (r *PostgresSessionRepository) RevokeAllForUser(userID, exceptID string) domain_errors.DomainError {
+	query := `
+		UPDATE session
+		SET revoked_at = $3
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.Exec(query, userID, exceptID, time.Now().UTC()); err != nil {
+		return domain_errors.NewDatabaseError("SESSION_REVOCATION", err)
+	}
+	return nil
+}
+
+func (r *PostgresSessionRepository) ListActiveByUser(userID string) ([]*Session, domain_errors.DomainError) {
+	query := `
+		SELECT id, user_id, user_agent, ip_address, refresh_token_hash, created_at, last_used_at, expires_at, revoked_at
+		FROM session
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_used_at DESC
+	`
+
+	rows, err := r.db.Query(query, userID, time.Now().UTC())
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("SESSION_LIST_QUERY", err)
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+	for rows.Next() {
+		session := &Session{}
+		if err := scanSession(rows, session); err != nil {
+			return nil, domain_errors.NewDatabaseError("SESSION_LIST_SCAN", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("SESSION_LIST_ITERATION", err)
+	}
+	return sessions, nil
+}
+
+func (r *PostgresSessionRepository) IsActive(id string) (bool, domain_errors.DomainError) {
+	query := `
+		SELECT COUNT(1)
+		FROM session
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > $2
+	`
+
+	var count int
+	if err := r.db.QueryRow(query, id, time.Now().UTC()).Scan(&count); err != nil {
+		return false, domain_errors.NewDatabaseError("SESSION_VALIDATION_QUERY", err)
+	}
+	return count > 0, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSession(row rowScanner, session *Session) error {
+	return row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.RefreshTokenHash,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+	)
+}
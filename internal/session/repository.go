@@ -0,0 +1,19 @@
+package session
+
+import (
+	"time"
+
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
+
+type SessionRepository interface {
+	Create(session *Session) (*Session, domain_errors.DomainError)
+	GetByID(id string) (*Session, domain_errors.DomainError)
+	// Rotate swaps the session's current refresh token hash for a new one. It
+	// returns false when oldHash is no longer the current token of an active session.
+	Rotate(id, oldHash, newHash string, usedAt, expiresAt time.Time) (bool, domain_errors.DomainError)
+	Revoke(id string) domain_errors.DomainError
+	RevokeAllForUser(userID, exceptID string) domain_errors.DomainError
+	ListActiveByUser(userID string) ([]*Session, domain_errors.DomainError)
+	IsActive(id string) (bool, domain_errors.DomainError)
+}
@@ -3,9 +3,12 @@ package shared
 import (
 	"database/sql"
 	"log"
+	"time"
 
 	amqp_utils "github.com/ishola-faazele/taskflow/internal/utils/amqp"
+	"github.com/ishola-faazele/taskflow/internal/utils/blobstore"
 	utils_db "github.com/ishola-faazele/taskflow/internal/utils/db"
+	"github.com/ishola-faazele/taskflow/internal/utils/jwt"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -14,6 +17,8 @@ import (
 type AppState struct {
 	DB       *sql.DB
 	AmqpConn *amqp.Connection
+	KeyRing  *jwt.KeyRing
+	Blobs    blobstore.Store
 }
 
 func NewAppState() *AppState {
@@ -28,12 +33,27 @@ func NewAppState() *AppState {
 	if err := migrationMgr.EnsureTablesExist(); err != nil {
 		log.Fatalln("FAILED_TO_INITIALIZE_TABLES:", err)
 	}
+	// load the JWT signing keys shared by every instance and keep them rotated
+	keyRing := jwt.NewKeyRing(jwt.NewPostgresKeyStore(db.DB), jwt.KeyRingConfigFromEnv())
+	if err := keyRing.Rotate(time.Now().UTC()); err != nil {
+		log.Fatalln("FAILED_TO_LOAD_SIGNING_KEYS:", err)
+	}
+	keyRing.StartRotation()
+	jwt.SetDefaultKeyRing(keyRing)
+	// storage for uploaded files such as avatars
+	blobs, err := blobstore.New(blobstore.ConfigFromEnv())
+	if err != nil {
+		log.Fatalln("FAILED_TO_INITIALIZE_BLOB_STORE:", err)
+	}
 	return &AppState{
 		DB:       db.DB,
 		AmqpConn: conn,
+		KeyRing:  keyRing,
+		Blobs:    blobs,
 	}
 }
 func (as *AppState) Clean() {
+	as.KeyRing.Stop()
 	as.DB.Close()
 	as.AmqpConn.Close()
 }
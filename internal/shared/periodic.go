@@ -0,0 +1,65 @@
+package shared
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Periodic runs a background job every interval until it is stopped. Jobs embed
+// it and only supply their work.
+type Periodic struct {
+	// label is logged with the error of a run that failed
+	label    string
+	interval time.Duration
+	run      func(now time.Time) error
+
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+// NewPeriodic creates a runner calling run with the UTC time of each tick
+func NewPeriodic(label string, interval time.Duration, run func(now time.Time) error) *Periodic {
+	return &Periodic{
+		label:    label,
+		interval: interval,
+		run:      run,
+	}
+}
+
+// Start runs the job in the background every interval until Stop is called
+func (p *Periodic) Start() {
+	p.mu.Lock()
+	if p.stop != nil {
+		p.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	p.stop = stop
+	p.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				if err := p.run(now.UTC()); err != nil {
+					log.Println(p.label+":", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends the background runs
+func (p *Periodic) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
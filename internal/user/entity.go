@@ -1,34 +1,105 @@
 package user
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ishola-faazele/taskflow/pkg/utils"
 )
 
 type Auth struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                string     `json:"id"`
+	Email             string     `json:"email"`
+	PasswordHash      string     `json:"-"`
+	PasswordUpdatedAt *time.Time `json:"password_updated_at,omitempty"`
+	// TOTPSecret is sealed with the server's TOTP key
+	TOTPSecret        string     `json:"-"`
+	TOTPEnabled       bool       `json:"totp_enabled"`
+	// TOTPLastStep is the period of the last accepted code, codes from it or
+	// earlier periods are refused
+	TOTPLastStep      int64      `json:"-"`
+	MagicLinkEnabled  bool       `json:"magic_link_enabled"`
+	HasPassword       bool       `json:"has_password"`
+	CreatedAt         time.Time  `json:"created_at"`
 }
 
 func CreateNewAuth(email string) *Auth {
 	return &Auth{
-		ID:        uuid.NewString(),
-		Email:     email,
-		CreatedAt: time.Now().UTC(),
+		ID:               uuid.NewString(),
+		Email:            utils.NormalizeEmail(email),
+		MagicLinkEnabled: true,
+		CreatedAt:        time.Now().UTC(),
 	}
 }
 
+// LoginResult is the outcome of a successful first factor. When MFARequired is
+// set the tokens are empty and MFAToken must be exchanged with a TOTP or recovery code.
+type LoginResult struct {
+	AccessToken  string
+	RefreshToken string
+	MFARequired  bool
+	MFAToken     string
+}
+
+// TOTPEnrollment is returned when a user starts setting up an authenticator app
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// Profile defaults for accounts that have not chosen otherwise
+const (
+	DefaultTimezone = "UTC"
+	DefaultLocale   = "en"
+)
+
 type UserProfile struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Title    string `json:"title"`
+	Bio      string `json:"bio"`
+	Timezone string `json:"timezone"`
+	Locale   string `json:"locale"`
+	// AvatarURL is empty when the user has not uploaded an avatar
+	AvatarURL       string     `json:"avatar_url,omitempty"`
+	AvatarKey       string     `json:"-"`
+	AvatarUpdatedAt *time.Time `json:"avatar_updated_at,omitempty"`
+}
+
+// Location returns the user's time zone, for example to schedule due-date
+// reminders in their local time
+func (p *UserProfile) Location() *time.Location {
+	if loc, err := time.LoadLocation(p.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// ProfileUpdate changes the fields that are set and leaves nil fields untouched
+type ProfileUpdate struct {
+	Name     *string `json:"name"`
+	Title    *string `json:"title"`
+	Bio      *string `json:"bio"`
+	Timezone *string `json:"timezone"`
+	Locale   *string `json:"locale"`
+}
+
+// avatarURL is where an avatar is served; the version busts caches when it changes
+func avatarURL(userID string, updatedAt *time.Time) string {
+	if updatedAt == nil {
+		return ""
+	}
+	return fmt.Sprintf("/api/user/profile/%s/avatar?v=%d", userID, updatedAt.Unix())
 }
 
 type PublicProfile struct {
-	ID    string `json:"id"` 
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	ID        string `json:"id"` 
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Title     string `json:"title"`
+	Timezone  string `json:"timezone"`
+	AvatarURL string `json:"avatar_url,omitempty"`
 }
 
 type InvalidToken struct {
@@ -37,3 +108,182 @@ type InvalidToken struct {
 	InvalidatedAt time.Time `json:"invalidated_at"`
 	ExpiresAt     time.Time `json:"expires_at"`
 }
+
+// UsedToken records the redemption of a single-use token by its JTI
+type UsedToken struct {
+	JTI       string    `json:"jti"`
+	UserID    string    `json:"user_id"`
+	Purpose   string    `json:"purpose"`
+	UsedAt    time.Time `json:"used_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AttemptKind names the authentication action an attempt is counted against
+type AttemptKind string
+
+const (
+	AttemptMagicLink     AttemptKind = "magic_link"
+	AttemptPasswordLogin AttemptKind = "password_login"
+	AttemptPasswordReset AttemptKind = "password_reset"
+	AttemptMFA           AttemptKind = "mfa"
+)
+
+// RateLimit caps how many attempts of a kind are allowed per email and per IP address within Window
+type RateLimit struct {
+	PerEmail int
+	PerIP    int
+	Window   time.Duration
+}
+
+// DefaultMagicLinkRateLimit returns the limits applied to magic link requests
+func DefaultMagicLinkRateLimit() RateLimit {
+	return RateLimit{
+		PerEmail: 5,
+		PerIP:    20,
+		Window:   15 * time.Minute,
+	}
+}
+
+// DefaultPasswordLoginRateLimit returns the limits applied to password logins
+func DefaultPasswordLoginRateLimit() RateLimit {
+	return RateLimit{
+		PerEmail: 10,
+		PerIP:    50,
+		Window:   15 * time.Minute,
+	}
+}
+
+// DefaultMFARateLimit returns the limits applied to second factor attempts
+func DefaultMFARateLimit() RateLimit {
+	return RateLimit{
+		PerEmail: 5,
+		PerIP:    50,
+		Window:   5 * time.Minute,
+	}
+}
+
+// GhostUserID is the account that authored content is reassigned to when its
+// author deletes their account, so projects and tasks keep a valid creator
+const GhostUserID = "00000000-0000-0000-0000-000000000000"
+
+// WorkspaceAction is what happens to a workspace owned by a deleted account
+type WorkspaceAction string
+
+const (
+	WorkspaceActionTransfer WorkspaceAction = "transfer"
+	WorkspaceActionDelete   WorkspaceAction = "delete"
+)
+
+// WorkspaceDecision chooses the fate of one owned workspace during account deletion
+type WorkspaceDecision struct {
+	WorkspaceID string          `json:"workspace_id"`
+	Action      WorkspaceAction `json:"action"`
+	NewOwnerID  string          `json:"new_owner_id,omitempty"`
+}
+
+// OwnedWorkspace is a workspace the user owns, with how many other members it has
+type OwnedWorkspace struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	OtherMembers int    `json:"other_members"`
+}
+
+// DataExport is everything stored about a user, returned by the self-service export
+type DataExport struct {
+	ExportedAt    time.Time               `json:"exported_at"`
+	Account       *Auth                   `json:"account"`
+	Profile       *UserProfile            `json:"profile"`
+	Sessions      []*ExportedSession      `json:"sessions"`
+	Memberships   []*ExportedMembership   `json:"memberships"`
+	Invitations   []*ExportedInvitation   `json:"invitations"`
+	Projects      []*ExportedProject      `json:"projects"`
+	Tasks         []*ExportedTask         `json:"tasks"`
+	WorkLogs      []*ExportedWorkLog      `json:"work_logs"`
+	Comments      []*ExportedComment      `json:"comments"`
+	Notifications []*ExportedNotification `json:"notifications"`
+	Watching      []*ExportedWatch        `json:"watching"`
+}
+
+type ExportedSession struct {
+	ID         string     `json:"id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type ExportedMembership struct {
+	WorkspaceID   string    `json:"workspace_id"`
+	WorkspaceName string    `json:"workspace_name"`
+	Role          string    `json:"role"`
+	JoinedAt      time.Time `json:"joined_at"`
+}
+
+type ExportedInvitation struct {
+	ID           string    `json:"id"`
+	WorkspaceID  string    `json:"workspace_id"`
+	InviterID    string    `json:"inviter_id"`
+	InviteeEmail string    `json:"invitee_email"`
+	Role         string    `json:"role"`
+	IsValid      bool      `json:"is_valid"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ExportedProject struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspace_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ExportedTask struct {
+	ID          string     `json:"id"`
+	ProjectID   string     `json:"project_id"`
+	ParentID    *string    `json:"parent_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	Priority    string     `json:"priority"`
+	DueDate     *time.Time `json:"due_date"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type ExportedWorkLog struct {
+	ID          string    `json:"id"`
+	TaskID      string    `json:"task_id"`
+	StartedAt   time.Time `json:"started_at"`
+	Minutes     int       `json:"minutes"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ExportedComment struct {
+	ID        string    `json:"id"`
+	TaskID    string    `json:"task_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ExportedNotification is a notification the user received
+type ExportedNotification struct {
+	ID          string     `json:"id"`
+	WorkspaceID string     `json:"workspace_id"`
+	Kind        string     `json:"kind"`
+	TaskID      string     `json:"task_id"`
+	CommentID   *string    `json:"comment_id,omitempty"`
+	ActorID     string     `json:"actor_id"`
+	Detail      string     `json:"detail,omitempty"`
+	ReadAt      *time.Time `json:"read_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ExportedWatch is a task or project the user watches; Kind is "task" or "project"
+type ExportedWatch struct {
+	Kind      string    `json:"kind"`
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -3,11 +3,19 @@ package user
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	domain_middleware "github.com/ishola-faazele/taskflow/internal/middleware"
+	"github.com/ishola-faazele/taskflow/internal/oidc"
+	"github.com/ishola-faazele/taskflow/internal/session"
+	"github.com/ishola-faazele/taskflow/internal/utils/blobstore"
 	amqp "github.com/rabbitmq/amqp091-go"
 
+	"github.com/ishola-faazele/taskflow/pkg/utils"
 	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
 )
 
@@ -16,10 +24,12 @@ type UserHandler struct {
 	responder *domain_errors.APIResponder
 }
 
-func NewUserHandler(db *sql.DB, conn *amqp.Connection) *UserHandler {
+func NewUserHandler(db *sql.DB, blobs blobstore.Store, conn *amqp.Connection) *UserHandler {
 	postgresAuthRepo := NewPostgresAuthRepository(db)
 	postgresProfileRepo := NewPostgresUserProfileRepository(db)
-	service := NewUserService(postgresAuthRepo, postgresProfileRepo, conn)
+	postgresSessionRepo := session.NewPostgresSessionRepository(db)
+	postgresOIDCStateRepo := oidc.NewPostgresStateRepository(db)
+	service := NewUserService(postgresAuthRepo, postgresProfileRepo, postgresSessionRepo, postgresOIDCStateRepo, oidc.NewRegistryFromEnv(), blobs, conn)
 	responder := domain_errors.NewAPIResponder()
 
 	return &UserHandler{
@@ -32,11 +42,58 @@ type MagicLinkRequestDTO struct {
 	Email string `json:"email"`
 }
 
-type UserProfileDTO struct {
-	Name string `json:"name"`
-}
+// UserProfileDTO updates the fields present in the request body
+type UserProfileDTO = ProfileUpdate
 type VerifyTokenResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken string `json:"access_token,omitempty"`
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+type PasswordLoginDTO struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type MFALoginDTO struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type SetPasswordDTO struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type RemovePasswordDTO struct {
+	CurrentPassword string `json:"current_password"`
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type LoginMethodsDTO struct {
+	MagicLinkEnabled bool `json:"magic_link_enabled"`
+}
+
+type TOTPCodeDTO struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type DeleteAccountDTO struct {
+	Email        string              `json:"email"`
+	Password     string              `json:"password"`
+	Code         string              `json:"code"`
+	RecoveryCode string              `json:"recovery_code"`
+	Workspaces   []WorkspaceDecision `json:"workspaces"`
 }
 
 // Creates a magic link which the user has to verify to log in
@@ -48,12 +105,13 @@ func (h *UserHandler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// send magic link
-	if err := h.service.GetMagicLink(req.Email); err != nil {
+	if err := h.service.GetMagicLink(req.Email, utils.ClientIP(r)); err != nil {
 		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_SEND_MAGIC_LINK", err)
 		return
 	}
 
-	h.responder.NoContent(w)
+	// respond the same way whether or not the email belongs to an account
+	h.responder.Success(w, r, http.StatusAccepted, "IF_THE_ADDRESS_IS_VALID_A_MAGIC_LINK_HAS_BEEN_SENT", nil)
 }
 
 // verifies token embedded in the magic link
@@ -61,22 +119,36 @@ func (h UserHandler) VerifyToken(w http.ResponseWriter, r *http.Request) {
 	// get token from query param
 	token := r.URL.Query().Get("token")
 	// verify token and return access and refresh tokens
-	access, refresh, verifyErr := h.service.VerifyToken(token)
+	result, verifyErr := h.service.VerifyToken(token, r.UserAgent(), utils.ClientIP(r))
 	if verifyErr != nil {
 		h.responder.Error(w, r, http.StatusBadRequest, "ERROR_VERIFYING_TOKEN", verifyErr)
 		return
 	}
+	h.writeLoginResult(w, r, result, "TOKEN_SUCCESSFULLY_VERIFIED")
+}
+
+// writeLoginResult returns the access token and sets the refresh token cookie, or
+// asks for the second factor when the user has TOTP enabled
+func (h UserHandler) writeLoginResult(w http.ResponseWriter, r *http.Request, result *LoginResult, message string) {
+	if result.MFARequired {
+		data := VerifyTokenResponse{
+			MFARequired: true,
+			MFAToken:    result.MFAToken,
+		}
+		h.responder.Success(w, r, http.StatusOK, "SECOND_FACTOR_REQUIRED", data)
+		return
+	}
 	// encode access token
 	data := VerifyTokenResponse{
-		AccessToken: access,
+		AccessToken: result.AccessToken,
 	}
 	// embed refresh token in http cookie
 	http.SetCookie(w, &http.Cookie{
 		Name:     "refresh_token",
-		Value:    refresh,
+		Value:    result.RefreshToken,
 		HttpOnly: true,
 	})
-	h.responder.Success(w, r, http.StatusOK, "TOKEN_SUCCESSFULLY_VERIFIED", data)
+	h.responder.Success(w, r, http.StatusOK, message, data)
 }
 
 // Returns new access and refresh tokens while invalidating the old refresh token
@@ -168,7 +240,7 @@ func (h UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedProfile, err := h.service.UpdateProfile(userID, profile.Name)
+	updatedProfile, err := h.service.UpdateProfile(userID, profile)
 	if err != nil {
 		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_UPDATE_PROFILE", err)
 		return
@@ -186,3 +258,450 @@ func (h UserHandler) GetPublicProfile(w http.ResponseWriter, r *http.Request) {
 	}
 	h.responder.Success(w, r, http.StatusOK, "PUBLIC_PROFILE_RETRIEVED_SUCCESSFULLY", publicProfile)
 }
+
+// A route for users to list the devices they are signed in on
+func (h UserHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	currentSessionID, _ := ctx.Value(domain_middleware.SessionIDKey).(string)
+	sessions, err := h.service.ListSessions(userID, currentSessionID)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_LIST_SESSIONS", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "SESSIONS_RETRIEVED_SUCCESSFULLY", sessions)
+}
+
+// A route for users to sign a device out
+func (h UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	if err := h.service.RevokeSession(userID, r.PathValue("id")); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_REVOKE_SESSION", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// A route for users to sign out every device except the one making the request
+func (h UserHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	currentSessionID, _ := ctx.Value(domain_middleware.SessionIDKey).(string)
+	if err := h.service.RevokeOtherSessions(userID, currentSessionID); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_REVOKE_SESSIONS", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// A route for users to sign out of the current device
+func (h UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	currentSessionID, _ := ctx.Value(domain_middleware.SessionIDKey).(string)
+	if err := h.service.RevokeSession(userID, currentSessionID); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_LOGOUT", err)
+		return
+	}
+	// clear the refresh token cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+	h.responder.NoContent(w)
+}
+
+// Logs a user in with email and password
+func (h UserHandler) LoginWithPassword(w http.ResponseWriter, r *http.Request) {
+	var req PasswordLoginDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_REQUEST_BODY", err)
+		return
+	}
+	result, err := h.service.LoginWithPassword(req.Email, req.Password, r.UserAgent(), utils.ClientIP(r))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusUnauthorized, "FAILED_TO_LOGIN", err)
+		return
+	}
+	h.writeLoginResult(w, r, result, "LOGIN_SUCCESSFUL")
+}
+
+// Completes a login that requires a second factor
+func (h UserHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req MFALoginDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_REQUEST_BODY", err)
+		return
+	}
+	result, err := h.service.VerifyMFA(req.MFAToken, req.Code, req.RecoveryCode, r.UserAgent(), utils.ClientIP(r))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusUnauthorized, "FAILED_TO_VERIFY_SECOND_FACTOR", err)
+		return
+	}
+	h.writeLoginResult(w, r, result, "LOGIN_SUCCESSFUL")
+}
+
+// Emails a password reset link
+func (h UserHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req MagicLinkRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_REQUEST_BODY", err)
+		return
+	}
+	if err := h.service.RequestPasswordReset(req.Email, utils.ClientIP(r)); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_SEND_PASSWORD_RESET_LINK", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusAccepted, "IF_THE_ADDRESS_IS_VALID_A_RESET_LINK_HAS_BEEN_SENT", nil)
+}
+
+// Sets a new password using the token from a reset link
+func (h UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_REQUEST_BODY", err)
+		return
+	}
+	if err := h.service.ResetPassword(req.Token, req.Password); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "FAILED_TO_RESET_PASSWORD", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// A route for users to set or change their password
+func (h UserHandler) SetPassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	var req SetPasswordDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_REQUEST_BODY", err)
+		return
+	}
+	sessionID, _ := r.Context().Value(domain_middleware.SessionIDKey).(string)
+	if err := h.service.SetPassword(userID, sessionID, req.CurrentPassword, req.NewPassword); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_SET_PASSWORD", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// A route for users to remove their password
+func (h UserHandler) RemovePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	var req RemovePasswordDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_REQUEST_BODY", err)
+		return
+	}
+	if err := h.service.RemovePassword(userID, req.CurrentPassword); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_REMOVE_PASSWORD", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// A route for users to choose which login methods are allowed
+func (h UserHandler) SetLoginMethods(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	var req LoginMethodsDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_REQUEST_BODY", err)
+		return
+	}
+	if err := h.service.SetMagicLinkEnabled(userID, req.MagicLinkEnabled); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_UPDATE_LOGIN_METHODS", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// A route to start TOTP enrolment
+func (h UserHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	enrollment, err := h.service.EnrollTOTP(userID)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_ENROLL_TOTP", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "TOTP_ENROLMENT_STARTED", enrollment)
+}
+
+// A route to confirm TOTP enrolment with a first code
+func (h UserHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	var req TOTPCodeDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_REQUEST_BODY", err)
+		return
+	}
+	codes, err := h.service.ConfirmTOTP(userID, req.Code)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_CONFIRM_TOTP", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "TOTP_ENABLED", RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// A route to turn TOTP off
+func (h UserHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	var req TOTPCodeDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_REQUEST_BODY", err)
+		return
+	}
+	if err := h.service.DisableTOTP(userID, req.Code, req.RecoveryCode); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_DISABLE_TOTP", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// A route to replace the user's recovery codes
+func (h UserHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	var req TOTPCodeDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_REQUEST_BODY", err)
+		return
+	}
+	codes, err := h.service.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_REGENERATE_RECOVERY_CODES", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "RECOVERY_CODES_REGENERATED", RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// maxAvatarRequestSize bounds the multipart request carrying an avatar upload
+const maxAvatarRequestSize = 6 << 20
+
+// A route to upload a new avatar as the "avatar" field of a multipart form
+func (h UserHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarRequestSize)
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_AVATAR_UPLOAD", err)
+		return
+	}
+	defer file.Close()
+
+	profile, uploadErr := h.service.UploadAvatar(r.Context(), userID, file)
+	if uploadErr != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_UPLOAD_AVATAR", uploadErr)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "AVATAR_UPLOADED_SUCCESSFULLY", profile)
+}
+
+// A route to remove the user's avatar
+func (h UserHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	if err := h.service.DeleteAvatar(r.Context(), userID); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_DELETE_AVATAR", err)
+		return
+	}
+	h.responder.NoContent(w)
+}
+
+// A route serving a user's avatar image
+func (h UserHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	reader, object, err := h.service.GetAvatar(r.Context(), id)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_GET_AVATAR", err)
+		return
+	}
+	defer reader.Close()
+	w.Header().Set("Content-Type", object.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(object.Size, 10))
+	// avatar URLs carry a version, so a stored avatar never changes under its URL
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, reader)
+}
+
+// A route for users to download everything stored about them as a JSON file
+func (h UserHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	export, err := h.service.ExportData(userID)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_EXPORT_DATA", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="taskflow-export-%s.json"`, userID))
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(export)
+}
+
+// A route listing the workspaces that must be transferred or deleted before account deletion
+func (h UserHandler) ListOwnedWorkspaces(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	workspaces, err := h.service.ListOwnedWorkspaces(userID)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_LIST_OWNED_WORKSPACES", err)
+		return
+	}
+	h.responder.Success(w, r, http.StatusOK, "OWNED_WORKSPACES_RETRIEVED", workspaces)
+}
+
+// A route for users to delete their account
+func (h UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED: USER_ID_NOT_FOUND_IN_CONTEXT", nil)
+		return
+	}
+	var req DeleteAccountDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.responder.Error(w, r, http.StatusBadRequest, "INVALID_REQUEST_BODY", err)
+		return
+	}
+	if err := h.service.DeleteAccount(userID, req.Email, req.Password, req.Code, req.RecoveryCode, req.Workspaces); err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "FAILED_TO_DELETE_ACCOUNT", err)
+		return
+	}
+	// clear the refresh token cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+	h.responder.NoContent(w)
+}
+
+type OIDCProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+// Lists the single sign-on providers users can log in with
+func (h UserHandler) ListOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	data := OIDCProvidersResponse{Providers: h.service.ListOIDCProviders()}
+	h.responder.Success(w, r, http.StatusOK, "OIDC_PROVIDERS_RETRIEVED", data)
+}
+
+// oidcStateCookie holds the state of the login the browser started. SameSite=Lax
+// still sends it on the provider's top-level redirect back to the callback.
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/user/oidc/"
+)
+
+// Redirects the user to the provider to sign in
+func (h UserHandler) BeginOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	authURL, state, err := h.service.BeginOIDCLogin(r.Context(), provider)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusBadGateway, "FAILED_TO_START_OIDC_LOGIN", err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     oidcStateCookiePath,
+		MaxAge:   int(oidc.DefaultStateDuration.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Handles the provider redirecting back after the user signed in
+func (h UserHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	query := r.URL.Query()
+	browserState := ""
+	if cookie, err := r.Cookie(oidcStateCookie); err == nil {
+		browserState = cookie.Value
+	}
+	// the state is single use whatever the outcome
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Path:     oidcStateCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	if providerErr := query.Get("error"); providerErr != "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "OIDC_PROVIDER_ERROR", domain_errors.NewUnauthorizedError(providerErr))
+		return
+	}
+	result, err := h.service.CompleteOIDCLogin(r.Context(), provider, query.Get("code"), query.Get("state"), browserState, r.UserAgent(), utils.ClientIP(r))
+	if err != nil {
+		h.responder.Error(w, r, http.StatusUnauthorized, "FAILED_TO_COMPLETE_OIDC_LOGIN", err)
+		return
+	}
+	h.writeLoginResult(w, r, result, "LOGIN_SUCCESSFUL")
+}
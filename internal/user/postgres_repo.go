@@ -3,7 +3,11 @@ package user
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/ishola-faazele/taskflow/pkg/utils"
 	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
 )
 
@@ -29,6 +33,28 @@ func NewPostgresUserProfileRepository(db *sql.DB) *PostgresUserProfileRepository
 
 // Auth Repository Implementation
 
+const authColumns = `id, email, COALESCE(password_hash, ''), password_updated_at, COALESCE(totp_secret, ''), totp_enabled, totp_last_step, magic_link_enabled, created_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAuth(row rowScanner, auth *Auth) error {
+	err := row.Scan(
+		&auth.ID,
+		&auth.Email,
+		&auth.PasswordHash,
+		&auth.PasswordUpdatedAt,
+		&auth.TOTPSecret,
+		&auth.TOTPEnabled,
+		&auth.TOTPLastStep,
+		&auth.MagicLinkEnabled,
+		&auth.CreatedAt,
+	)
+	auth.HasPassword = auth.PasswordHash != ""
+	return err
+}
+
 func (r *PostgresAuthRepository) Create(auth *Auth) (*Auth, domain_errors.DomainError) {
 	// Start a transaction to ensure both auth and profile are created atomically
 	tx, err := r.db.Begin()
@@ -41,15 +67,14 @@ func (r *PostgresAuthRepository) Create(auth *Auth) (*Auth, domain_errors.Domain
 
 	// Insert auth record
 	authQuery := `
-		INSERT INTO auth (id, email, created_at)
-		VALUES ($1, $2, $3)
-		RETURNING id, email, created_at
-	`
+		INSERT INTO auth (id, email, magic_link_enabled, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + authColumns
 
-	row := tx.QueryRow(authQuery, auth.ID, auth.Email, auth.CreatedAt)
+	row := tx.QueryRow(authQuery, auth.ID, utils.NormalizeEmail(auth.Email), auth.MagicLinkEnabled, auth.CreatedAt)
 
 	result := &Auth{}
-	err = row.Scan(&result.ID, &result.Email, &result.CreatedAt)
+	err = scanAuth(row, result)
 	if err != nil {
 		return nil, domain_errors.NewDatabaseError("AUTH_CREATION", err)
 	}
@@ -74,16 +99,12 @@ func (r *PostgresAuthRepository) Create(auth *Auth) (*Auth, domain_errors.Domain
 }
 
 func (r *PostgresAuthRepository) GetByID(id string) (*Auth, domain_errors.DomainError) {
-	query := `
-		SELECT id, email, created_at
-		FROM auth
-		WHERE id = $1
-	`
+	query := `SELECT ` + authColumns + ` FROM auth WHERE id = $1`
 
 	row := r.db.QueryRow(query, id)
 
 	auth := &Auth{}
-	err := row.Scan(&auth.ID, &auth.Email, &auth.CreatedAt)
+	err := scanAuth(row, auth)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain_errors.NewNotFoundError("AUTH", id)
@@ -94,17 +115,16 @@ func (r *PostgresAuthRepository) GetByID(id string) (*Auth, domain_errors.Domain
 	return auth, nil
 }
 
+// GetByEmail ignores case, so an address typed differently finds the same account.
+// Accounts created before emails were normalized may differ only in case; the
+// oldest of them is returned.
 func (r *PostgresAuthRepository) GetByEmail(email string) (*Auth, domain_errors.DomainError) {
-	query := `
-		SELECT id, email, created_at
-		FROM auth
-		WHERE email = $1
-	`
+	query := `SELECT ` + authColumns + ` FROM auth WHERE LOWER(email) = $1 ORDER BY created_at LIMIT 1`
 
-	row := r.db.QueryRow(query, email)
+	row := r.db.QueryRow(query, utils.NormalizeEmail(email))
 
 	auth := &Auth{}
-	err := row.Scan(&auth.ID, &auth.Email, &auth.CreatedAt)
+	err := scanAuth(row, auth)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -148,11 +168,563 @@ func (r *PostgresAuthRepository) InvalidateToken(token *InvalidToken) domain_err
 	return nil
 }
 
+func (r *PostgresAuthRepository) MarkTokenUsed(token *UsedToken) (bool, domain_errors.DomainError) {
+	query := `
+		INSERT INTO used_token (jti, user_id, purpose, used_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	result, err := r.db.Exec(query, token.JTI, token.UserID, token.Purpose, token.UsedAt, token.ExpiresAt)
+	if err != nil {
+		return false, domain_errors.NewDatabaseError("TOKEN_REDEMPTION", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, domain_errors.NewDatabaseError("TOKEN_REDEMPTION", err)
+	}
+	return rows == 1, nil
+}
+
+func (r *PostgresAuthRepository) RecordAttempt(kind AttemptKind, email, ipAddress string, at time.Time) domain_errors.DomainError {
+	query := `
+		INSERT INTO auth_attempt (kind, email, ip_address, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := r.db.Exec(query, kind, strings.ToLower(email), ipAddress, at); err != nil {
+		return domain_errors.NewDatabaseError("AUTH_ATTEMPT_RECORD", err)
+	}
+	return nil
+}
+
+func (r *PostgresAuthRepository) CountAttempts(kind AttemptKind, email, ipAddress string, since time.Time) (int, int, domain_errors.DomainError) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE email = $2),
+			COUNT(*) FILTER (WHERE ip_address = $3)
+		FROM auth_attempt
+		WHERE kind = $1 AND created_at >= $4 AND (email = $2 OR ip_address = $3)
+	`
+
+	var byEmail, byIP int
+	if err := r.db.QueryRow(query, kind, strings.ToLower(email), ipAddress, since).Scan(&byEmail, &byIP); err != nil {
+		return 0, 0, domain_errors.NewDatabaseError("AUTH_ATTEMPT_COUNT", err)
+	}
+	return byEmail, byIP, nil
+}
+
+func (r *PostgresAuthRepository) DeleteAttemptsBefore(before time.Time) (int, domain_errors.DomainError) {
+	result, err := r.db.Exec(`DELETE FROM auth_attempt WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("AUTH_ATTEMPT_CLEANUP", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("AUTH_ATTEMPT_CLEANUP", err)
+	}
+	return int(rows), nil
+}
+
+func (r *PostgresAuthRepository) DeleteExpiredUsedTokens(now time.Time) (int, domain_errors.DomainError) {
+	result, err := r.db.Exec(`DELETE FROM used_token WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("USED_TOKEN_CLEANUP", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, domain_errors.NewDatabaseError("USED_TOKEN_CLEANUP", err)
+	}
+	return int(rows), nil
+}
+
+func (r *PostgresAuthRepository) SetPassword(userID, hash string, at time.Time) domain_errors.DomainError {
+	query := `
+		UPDATE auth
+		SET password_hash = NULLIF($2, ''), password_updated_at = $3
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(query, userID, hash, at)
+	if err != nil {
+		return domain_errors.NewDatabaseError("PASSWORD_UPDATE", err)
+	}
+	return expectOneRow(result, "AUTH", userID, "PASSWORD_UPDATE")
+}
+
+func (r *PostgresAuthRepository) SetTOTP(userID, secret string, enabled bool) domain_errors.DomainError {
+	query := `
+		UPDATE auth
+		SET totp_secret = NULLIF($2, ''), totp_enabled = $3,
+			totp_last_step = CASE WHEN $3 THEN totp_last_step ELSE 0 END
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(query, userID, secret, enabled)
+	if err != nil {
+		return domain_errors.NewDatabaseError("TOTP_UPDATE", err)
+	}
+	return expectOneRow(result, "AUTH", userID, "TOTP_UPDATE")
+}
+
+func (r *PostgresAuthRepository) UseTOTPStep(userID string, step int64) (bool, domain_errors.DomainError) {
+	query := `UPDATE auth SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2`
+
+	result, err := r.db.Exec(query, userID, step)
+	if err != nil {
+		return false, domain_errors.NewDatabaseError("TOTP_STEP_UPDATE", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, domain_errors.NewDatabaseError("TOTP_STEP_UPDATE", err)
+	}
+	return rows == 1, nil
+}
+
+func (r *PostgresAuthRepository) SetMagicLinkEnabled(userID string, enabled bool) domain_errors.DomainError {
+	query := `UPDATE auth SET magic_link_enabled = $2 WHERE id = $1`
+
+	result, err := r.db.Exec(query, userID, enabled)
+	if err != nil {
+		return domain_errors.NewDatabaseError("LOGIN_METHOD_UPDATE", err)
+	}
+	return expectOneRow(result, "AUTH", userID, "LOGIN_METHOD_UPDATE")
+}
+
+func (r *PostgresAuthRepository) ReplaceRecoveryCodes(userID string, codeHashes []string) domain_errors.DomainError {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return domain_errors.NewDatabaseError("START_OF_RECOVERY_CODE_TRANSACTION", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.Exec(`DELETE FROM recovery_code WHERE user_id = $1`, userID); err != nil {
+		return domain_errors.NewDatabaseError("RECOVERY_CODE_DELETION", err)
+	}
+	now := time.Now().UTC()
+	for _, codeHash := range codeHashes {
+		query := `
+			INSERT INTO recovery_code (id, user_id, code_hash, created_at)
+			VALUES ($1, $2, $3, $4)
+		`
+		if _, err := tx.Exec(query, uuid.NewString(), userID, codeHash, now); err != nil {
+			return domain_errors.NewDatabaseError("RECOVERY_CODE_CREATION", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain_errors.NewDatabaseError("COMMIT_OF_RECOVERY_CODE_TRANSACTION", err)
+	}
+	return nil
+}
+
+func (r *PostgresAuthRepository) UseRecoveryCode(userID, codeHash string) (bool, domain_errors.DomainError) {
+	query := `
+		UPDATE recovery_code
+		SET used_at = $3
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, codeHash, time.Now().UTC())
+	if err != nil {
+		return false, domain_errors.NewDatabaseError("RECOVERY_CODE_REDEMPTION", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, domain_errors.NewDatabaseError("RECOVERY_CODE_REDEMPTION", err)
+	}
+	return rows == 1, nil
+}
+
+// Account lifecycle
+
+// queryEach runs query and calls scan for every row
+func queryEach(db *sql.DB, operation, query string, args []any, scan func(rowScanner) error) domain_errors.DomainError {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return domain_errors.NewDatabaseError(operation, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return domain_errors.NewDatabaseError(operation, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return domain_errors.NewDatabaseError(operation, err)
+	}
+	return nil
+}
+
+func (r *PostgresAuthRepository) ExportData(userID string) (*DataExport, domain_errors.DomainError) {
+	account, err := r.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	export := &DataExport{
+		ExportedAt:    time.Now().UTC(),
+		Account:       account,
+		Profile:       &UserProfile{ID: userID},
+		Sessions:      []*ExportedSession{},
+		Memberships:   []*ExportedMembership{},
+		Invitations:   []*ExportedInvitation{},
+		Projects:      []*ExportedProject{},
+		Tasks:         []*ExportedTask{},
+		WorkLogs:      []*ExportedWorkLog{},
+		Comments:      []*ExportedComment{},
+		Notifications: []*ExportedNotification{},
+		Watching:      []*ExportedWatch{},
+	}
+
+	profileQuery := `SELECT ` + profileColumns + ` FROM user_profile WHERE id = $1`
+	if err := scanProfile(r.db.QueryRow(profileQuery, userID), export.Profile); err != nil && err != sql.ErrNoRows {
+		return nil, domain_errors.NewDatabaseError("EXPORT_PROFILE", err)
+	}
+
+	sessionQuery := `
+		SELECT id, user_agent, ip_address, created_at, last_used_at, revoked_at
+		FROM session
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+	err = queryEach(r.db, "EXPORT_SESSIONS", sessionQuery, []any{userID}, func(row rowScanner) error {
+		s := &ExportedSession{}
+		if err := row.Scan(&s.ID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.RevokedAt); err != nil {
+			return err
+		}
+		export.Sessions = append(export.Sessions, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	membershipQuery := `
+		SELECT m.workspace_id, w.name, m.role, m.created_at
+		FROM membership m
+		JOIN workspace w ON w.id = m.workspace_id
+		WHERE m.user_id = $1
+		ORDER BY m.created_at
+	`
+	err = queryEach(r.db, "EXPORT_MEMBERSHIPS", membershipQuery, []any{userID}, func(row rowScanner) error {
+		m := &ExportedMembership{}
+		if err := row.Scan(&m.WorkspaceID, &m.WorkspaceName, &m.Role, &m.JoinedAt); err != nil {
+			return err
+		}
+		export.Memberships = append(export.Memberships, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// invitations the user sent or received
+	invitationQuery := `
+		SELECT id, workspace_id, inviter_id, invitee_email, role, COALESCE(is_valid, FALSE), created_at
+		FROM invitation
+		WHERE inviter_id = $1 OR invitee_id = $1 OR invitee_email = $2
+		ORDER BY created_at
+	`
+	err = queryEach(r.db, "EXPORT_INVITATIONS", invitationQuery, []any{userID, account.Email}, func(row rowScanner) error {
+		i := &ExportedInvitation{}
+		if err := row.Scan(&i.ID, &i.WorkspaceID, &i.InviterID, &i.InviteeEmail, &i.Role, &i.IsValid, &i.CreatedAt); err != nil {
+			return err
+		}
+		export.Invitations = append(export.Invitations, i)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	projectQuery := `
+		SELECT id, workspace_id, name, COALESCE(description, ''), created_at
+		FROM project
+		WHERE creator = $1
+		ORDER BY created_at
+	`
+	err = queryEach(r.db, "EXPORT_PROJECTS", projectQuery, []any{userID}, func(row rowScanner) error {
+		p := &ExportedProject{}
+		if err := row.Scan(&p.ID, &p.WorkspaceID, &p.Name, &p.Description, &p.CreatedAt); err != nil {
+			return err
+		}
+		export.Projects = append(export.Projects, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	taskQuery := `
+		SELECT id, COALESCE(project_id, ''), parent_id, name, COALESCE(description, ''), status, priority, due_date, created_at, updated_at
+		FROM task
+		WHERE creator = $1
+		ORDER BY created_at
+	`
+	err = queryEach(r.db, "EXPORT_TASKS", taskQuery, []any{userID}, func(row rowScanner) error {
+		t := &ExportedTask{}
+		if err := row.Scan(&t.ID, &t.ProjectID, &t.ParentID, &t.Name, &t.Description, &t.Status, &t.Priority, &t.DueDate, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return err
+		}
+		export.Tasks = append(export.Tasks, t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	workLogQuery := `
+		SELECT id, task_id, started_at, minutes, description, created_at
+		FROM work_log
+		WHERE user_id = $1
+		ORDER BY started_at
+	`
+	err = queryEach(r.db, "EXPORT_WORK_LOGS", workLogQuery, []any{userID}, func(row rowScanner) error {
+		l := &ExportedWorkLog{}
+		if err := row.Scan(&l.ID, &l.TaskID, &l.StartedAt, &l.Minutes, &l.Description, &l.CreatedAt); err != nil {
+			return err
+		}
+		export.WorkLogs = append(export.WorkLogs, l)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	commentQuery := `
+		SELECT id, task_id, content, created_at, updated_at
+		FROM task_comment
+		WHERE author = $1
+		ORDER BY created_at
+	`
+	err = queryEach(r.db, "EXPORT_COMMENTS", commentQuery, []any{userID}, func(row rowScanner) error {
+		c := &ExportedComment{}
+		if err := row.Scan(&c.ID, &c.TaskID, &c.Content, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return err
+		}
+		export.Comments = append(export.Comments, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notificationQuery := `
+		SELECT id, workspace_id, kind, task_id, comment_id, actor_id, detail, read_at, created_at
+		FROM notification
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+	err = queryEach(r.db, "EXPORT_NOTIFICATIONS", notificationQuery, []any{userID}, func(row rowScanner) error {
+		n := &ExportedNotification{}
+		if err := row.Scan(&n.ID, &n.WorkspaceID, &n.Kind, &n.TaskID, &n.CommentID, &n.ActorID, &n.Detail, &n.ReadAt, &n.CreatedAt); err != nil {
+			return err
+		}
+		export.Notifications = append(export.Notifications, n)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	watchQuery := `
+		SELECT 'task', task_id, created_at FROM task_watcher WHERE user_id = $1
+		UNION ALL
+		SELECT 'project', project_id, created_at FROM project_watcher WHERE user_id = $1
+		ORDER BY created_at
+	`
+	err = queryEach(r.db, "EXPORT_WATCHES", watchQuery, []any{userID}, func(row rowScanner) error {
+		w := &ExportedWatch{}
+		if err := row.Scan(&w.Kind, &w.ID, &w.CreatedAt); err != nil {
+			return err
+		}
+		export.Watching = append(export.Watching, w)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (r *PostgresAuthRepository) ListOwnedWorkspaces(userID string) ([]*OwnedWorkspace, domain_errors.DomainError) {
+	query := `
+		SELECT w.id, w.name,
+			(SELECT COUNT(*) FROM membership m WHERE m.workspace_id = w.id AND m.user_id <> w.owner_id)
+		FROM workspace w
+		WHERE w.owner_id = $1
+		ORDER BY w.created_at
+	`
+
+	workspaces := []*OwnedWorkspace{}
+	err := queryEach(r.db, "OWNED_WORKSPACE_QUERY", query, []any{userID}, func(row rowScanner) error {
+		w := &OwnedWorkspace{}
+		if err := row.Scan(&w.ID, &w.Name, &w.OtherMembers); err != nil {
+			return err
+		}
+		workspaces = append(workspaces, w)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+func (r *PostgresAuthRepository) DeleteAccount(userID string, decisions []WorkspaceDecision) domain_errors.DomainError {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return domain_errors.NewDatabaseError("START_OF_ACCOUNT_DELETION_TRANSACTION", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var email string
+	if err := tx.QueryRow(`SELECT email FROM auth WHERE id = $1 FOR UPDATE`, userID).Scan(&email); err != nil {
+		if err == sql.ErrNoRows {
+			return domain_errors.NewNotFoundError("AUTH", userID)
+		}
+		return domain_errors.NewDatabaseError("ACCOUNT_DELETION", err)
+	}
+
+	// the ghost user cannot sign in: its address is undeliverable and magic links are off
+	ghostQueries := []string{
+		`INSERT INTO auth (id, email, magic_link_enabled, created_at)
+		VALUES ('` + GhostUserID + `', 'deleted-user@taskflow.invalid', FALSE, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO NOTHING`,
+		`INSERT INTO user_profile (id, name) VALUES ('` + GhostUserID + `', 'Deleted user')
+		ON CONFLICT (id) DO NOTHING`,
+	}
+	for _, query := range ghostQueries {
+		if _, err := tx.Exec(query); err != nil {
+			return domain_errors.NewDatabaseError("GHOST_USER_CREATION", err)
+		}
+	}
+
+	for _, decision := range decisions {
+		switch decision.Action {
+		case WorkspaceActionTransfer:
+			// the new owner must be a human member of the workspace
+			promote := `
+				UPDATE membership
+				SET role = 'owner'
+				WHERE workspace_id = $1 AND user_id = $2 AND user_id <> $3
+					AND NOT EXISTS (SELECT 1 FROM service_account sa WHERE sa.id = $2)
+			`
+			result, err := tx.Exec(promote, decision.WorkspaceID, decision.NewOwnerID, userID)
+			if err != nil {
+				return domain_errors.NewDatabaseError("WORKSPACE_TRANSFER", err)
+			}
+			if err := expectOneRow(result, "WORKSPACE_MEMBER", decision.NewOwnerID, "WORKSPACE_TRANSFER"); err != nil {
+				return err
+			}
+			result, err = tx.Exec(`UPDATE workspace SET owner_id = $2 WHERE id = $1 AND owner_id = $3`, decision.WorkspaceID, decision.NewOwnerID, userID)
+			if err != nil {
+				return domain_errors.NewDatabaseError("WORKSPACE_TRANSFER", err)
+			}
+			if err := expectOneRow(result, "WORKSPACE", decision.WorkspaceID, "WORKSPACE_TRANSFER"); err != nil {
+				return err
+			}
+		case WorkspaceActionDelete:
+			result, err := tx.Exec(`DELETE FROM workspace WHERE id = $1 AND owner_id = $2`, decision.WorkspaceID, userID)
+			if err != nil {
+				return domain_errors.NewDatabaseError("WORKSPACE_DELETION", err)
+			}
+			if err := expectOneRow(result, "WORKSPACE", decision.WorkspaceID, "WORKSPACE_DELETION"); err != nil {
+				return err
+			}
+		}
+	}
+
+	// anonymize authored content and remove what only identifies the user
+	statements := []struct {
+		operation string
+		query     string
+		args      []any
+	}{
+		{"PROJECT_ANONYMIZATION", `UPDATE project SET creator = $2 WHERE creator = $1`, []any{userID, GhostUserID}},
+		{"TASK_ANONYMIZATION", `UPDATE task SET creator = $2 WHERE creator = $1`, []any{userID, GhostUserID}},
+		{"PROJECT_DELETER_ANONYMIZATION", `UPDATE project SET deleted_by = $2 WHERE deleted_by = $1`, []any{userID, GhostUserID}},
+		{"TASK_DELETER_ANONYMIZATION", `UPDATE task SET deleted_by = $2 WHERE deleted_by = $1`, []any{userID, GhostUserID}},
+		{"ATTACHMENT_ANONYMIZATION", `UPDATE task_attachment SET uploaded_by = $2 WHERE uploaded_by = $1`, []any{userID, GhostUserID}},
+		{"LABEL_ANONYMIZATION", `UPDATE label SET created_by = $2 WHERE created_by = $1`, []any{userID, GhostUserID}},
+		{"WORK_LOG_ANONYMIZATION", `UPDATE work_log SET user_id = $2 WHERE user_id = $1`, []any{userID, GhostUserID}},
+		{"MILESTONE_ANONYMIZATION", `UPDATE milestone SET created_by = $2 WHERE created_by = $1`, []any{userID, GhostUserID}},
+		{"TASK_ACTIVITY_ANONYMIZATION", `UPDATE task_activity SET actor_id = $2 WHERE actor_id = $1`, []any{userID, GhostUserID}},
+		{"TASK_ASSIGNMENT_ANONYMIZATION", `UPDATE task_assignment SET assigner = $2 WHERE assigner = $1`, []any{userID, GhostUserID}},
+		{"TASK_TEMPLATE_ANONYMIZATION", `UPDATE task_template SET created_by = $2 WHERE created_by = $1`, []any{userID, GhostUserID}},
+		{"TASK_COMMENT_ANONYMIZATION", `UPDATE task_comment SET author = $2 WHERE author = $1`, []any{userID, GhostUserID}},
+		{"TASK_MENTION_ANONYMIZATION", `UPDATE task_mention SET author_id = $2 WHERE author_id = $1`, []any{userID, GhostUserID}},
+		{"NOTIFICATION_ANONYMIZATION", `UPDATE notification SET actor_id = $2 WHERE actor_id = $1`, []any{userID, GhostUserID}},
+		{"SERVICE_ACCOUNT_ANONYMIZATION", `UPDATE service_account SET created_by = $2 WHERE created_by = $1`, []any{userID, GhostUserID}},
+		{"API_TOKEN_ANONYMIZATION", `UPDATE api_token SET created_by = $2 WHERE created_by = $1`, []any{userID, GhostUserID}},
+		{"INVITATION_DELETION", `DELETE FROM invitation WHERE invitee_email = $1`, []any{email}},
+		{"AUTH_ATTEMPT_DELETION", `DELETE FROM auth_attempt WHERE email = $1`, []any{email}},
+		{"USED_TOKEN_DELETION", `DELETE FROM used_token WHERE user_id = $1`, []any{userID}},
+		{"INVALID_TOKEN_DELETION", `DELETE FROM invalid_token WHERE user_id = $1`, []any{userID}},
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement.query, statement.args...); err != nil {
+			return domain_errors.NewDatabaseError(statement.operation, err)
+		}
+	}
+
+	// remaining rows (profile, sessions, memberships, tokens, ...) cascade
+	result, err := tx.Exec(`DELETE FROM auth WHERE id = $1`, userID)
+	if err != nil {
+		return domain_errors.NewDatabaseError("ACCOUNT_DELETION", err)
+	}
+	if err := expectOneRow(result, "AUTH", userID, "ACCOUNT_DELETION"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain_errors.NewDatabaseError("COMMIT_OF_ACCOUNT_DELETION_TRANSACTION", err)
+	}
+	return nil
+}
+
+// expectOneRow turns an update that matched nothing into a not found error
+func expectOneRow(result sql.Result, resource, id, operation string) domain_errors.DomainError {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return domain_errors.NewDatabaseError(operation, err)
+	}
+	if rows == 0 {
+		return domain_errors.NewNotFoundError(resource, id)
+	}
+	return nil
+}
+
 // UserProfile Repository Implementation
 
+const profileColumns = `id, name, title, bio, timezone, locale, COALESCE(avatar_key, ''), avatar_updated_at`
+
+func scanProfile(row rowScanner, profile *UserProfile) error {
+	err := row.Scan(
+		&profile.ID,
+		&profile.Name,
+		&profile.Title,
+		&profile.Bio,
+		&profile.Timezone,
+		&profile.Locale,
+		&profile.AvatarKey,
+		&profile.AvatarUpdatedAt,
+	)
+	profile.AvatarURL = ""
+	if profile.AvatarKey != "" {
+		profile.AvatarURL = avatarURL(profile.ID, profile.AvatarUpdatedAt)
+	}
+	return err
+}
+
 func (r *PostgresUserProfileRepository) GetProfile(id string) (*UserProfile, domain_errors.DomainError) {
 	query := `
-		SELECT id, name
+		SELECT ` + profileColumns + `
 		FROM user_profile
 		WHERE id = $1
 	`
@@ -160,7 +732,7 @@ func (r *PostgresUserProfileRepository) GetProfile(id string) (*UserProfile, dom
 	row := r.db.QueryRow(query, id)
 
 	profile := &UserProfile{}
-	err := row.Scan(&profile.ID, &profile.Name)
+	err := scanProfile(row, profile)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain_errors.NewNotFoundError("USER_PROFILE", id)
@@ -171,18 +743,21 @@ func (r *PostgresUserProfileRepository) GetProfile(id string) (*UserProfile, dom
 	return profile, nil
 }
 
-func (r *PostgresUserProfileRepository) UpdateProfile(userID, name string) (*UserProfile, domain_errors.DomainError) {
+func (r *PostgresUserProfileRepository) UpdateProfile(userID string, update ProfileUpdate) (*UserProfile, domain_errors.DomainError) {
 	query := `
 		UPDATE user_profile
-		SET name = $2
+		SET name = COALESCE($2, name),
+			title = COALESCE($3, title),
+			bio = COALESCE($4, bio),
+			timezone = COALESCE($5, timezone),
+			locale = COALESCE($6, locale)
 		WHERE id = $1
-		RETURNING id, name
-	`
+		RETURNING ` + profileColumns
 
-	row := r.db.QueryRow(query, userID, name)
+	row := r.db.QueryRow(query, userID, update.Name, update.Title, update.Bio, update.Timezone, update.Locale)
 
 	result := &UserProfile{}
-	err := row.Scan(&result.ID, &result.Name)
+	err := scanProfile(row, result)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain_errors.NewNotFoundError("USER_PROFILE", userID)
@@ -192,9 +767,33 @@ func (r *PostgresUserProfileRepository) UpdateProfile(userID, name string) (*Use
 
 	return result, nil
 }
+
+func (r *PostgresUserProfileRepository) SetAvatar(userID, key string, at time.Time) (string, domain_errors.DomainError) {
+	query := `
+		WITH previous AS (
+			SELECT id, avatar_key FROM user_profile WHERE id = $1 FOR UPDATE
+		)
+		UPDATE user_profile up
+		SET avatar_key = NULLIF($2, ''),
+			avatar_updated_at = CASE WHEN $2 = '' THEN NULL ELSE $3::timestamp END
+		FROM previous
+		WHERE up.id = previous.id
+		RETURNING COALESCE(previous.avatar_key, '')
+	`
+
+	var previousKey string
+	if err := r.db.QueryRow(query, userID, key, at).Scan(&previousKey); err != nil {
+		if err == sql.ErrNoRows {
+			return "", domain_errors.NewNotFoundError("USER_PROFILE", userID)
+		}
+		return "", domain_errors.NewDatabaseError("AVATAR_UPDATE", err)
+	}
+	return previousKey, nil
+}
+
 func (r *PostgresUserProfileRepository) GetPublicProfile(id string) (*PublicProfile, domain_errors.DomainError) {
 	query := `
-		SELECT up.id, up.name, a.email
+		SELECT up.id, up.name, a.email, up.title, up.timezone, up.avatar_key IS NOT NULL, up.avatar_updated_at
 		FROM user_profile up
 		JOIN auth a ON up.id = a.id
 		WHERE up.id = $1
@@ -203,7 +802,12 @@ func (r *PostgresUserProfileRepository) GetPublicProfile(id string) (*PublicProf
 	row := r.db.QueryRow(query, id)
 
 	publicProfile := &PublicProfile{}
-	err := row.Scan(&publicProfile.ID, &publicProfile.Name, &publicProfile.Email)
+	var hasAvatar bool
+	var avatarUpdatedAt *time.Time
+	err := row.Scan(&publicProfile.ID, &publicProfile.Name, &publicProfile.Email, &publicProfile.Title, &publicProfile.Timezone, &hasAvatar, &avatarUpdatedAt)
+	if hasAvatar {
+		publicProfile.AvatarURL = avatarURL(publicProfile.ID, avatarUpdatedAt)
+	}
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain_errors.NewNotFoundError("PUBLIC_PROFILE", id)
@@ -0,0 +1,52 @@
+package user
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/internal/oidc"
+	"github.com/ishola-faazele/taskflow/internal/shared"
+)
+
+// authAttemptRetention keeps attempts well past the longest rate limit window
+const authAttemptRetention = 24 * time.Hour
+
+// AuthPruner deletes the authentication bookkeeping that can no longer matter:
+// attempts older than every rate limit window, redeemed single-use tokens that
+// have expired, since an expired token is rejected before it is looked up, and
+// single sign-on logins that were abandoned at the provider
+type AuthPruner struct {
+	repo       AuthRepository
+	oidcStates oidc.StateRepository
+
+	*shared.Periodic
+}
+
+func NewAuthPruner(db *sql.DB, interval time.Duration) *AuthPruner {
+	p := &AuthPruner{
+		repo:       NewPostgresAuthRepository(db),
+		oidcStates: oidc.NewPostgresStateRepository(db),
+	}
+	p.Periodic = shared.NewPeriodic("FAILED_TO_PRUNE_AUTH_RECORDS", interval, func(now time.Time) error {
+		_, err := p.Prune(now)
+		return err
+	})
+	return p
+}
+
+// Prune deletes the stale rows and returns how many
+func (p *AuthPruner) Prune(now time.Time) (int, error) {
+	attempts, err := p.repo.DeleteAttemptsBefore(now.Add(-authAttemptRetention))
+	if err != nil {
+		return 0, err
+	}
+	tokens, err := p.repo.DeleteExpiredUsedTokens(now)
+	if err != nil {
+		return attempts, err
+	}
+	states, err := p.oidcStates.DeleteExpired(now)
+	if err != nil {
+		return attempts + tokens, err
+	}
+	return attempts + tokens + states, nil
+}
@@ -1,6 +1,10 @@
 package user
 
-import "github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+import (
+	"time"
+
+	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
+)
 
 type AuthRepository interface {
 	Create(auth *Auth) (*Auth, domain_errors.DomainError)
@@ -8,10 +12,41 @@ type AuthRepository interface {
 	GetByEmail(email string) (*Auth, domain_errors.DomainError)
 	IsTokenValid(token_hash string) (bool, domain_errors.DomainError)
 	InvalidateToken(token_hash *InvalidToken) domain_errors.DomainError
+	// MarkTokenUsed records a single-use token, returning false if it was already used
+	MarkTokenUsed(token *UsedToken) (bool, domain_errors.DomainError)
+	RecordAttempt(kind AttemptKind, email, ipAddress string, at time.Time) domain_errors.DomainError
+	CountAttempts(kind AttemptKind, email, ipAddress string, since time.Time) (byEmail int, byIP int, err domain_errors.DomainError)
+	// DeleteAttemptsBefore and DeleteExpiredUsedTokens prune rows that can no
+	// longer affect a decision and return how many were deleted
+	DeleteAttemptsBefore(before time.Time) (int, domain_errors.DomainError)
+	DeleteExpiredUsedTokens(now time.Time) (int, domain_errors.DomainError)
+
+	// Credentials
+	// SetPassword stores a password hash, an empty hash removes the password
+	SetPassword(userID, hash string, at time.Time) domain_errors.DomainError
+	// SetTOTP stores a sealed secret; disabling also forgets the last accepted period
+	SetTOTP(userID, secret string, enabled bool) domain_errors.DomainError
+	// UseTOTPStep records the period of an accepted code, returning false if a code
+	// from that period or a later one was already accepted
+	UseTOTPStep(userID string, step int64) (bool, domain_errors.DomainError)
+	SetMagicLinkEnabled(userID string, enabled bool) domain_errors.DomainError
+	ReplaceRecoveryCodes(userID string, codeHashes []string) domain_errors.DomainError
+	// UseRecoveryCode consumes a recovery code, returning false if it is unknown or already used
+	UseRecoveryCode(userID, codeHash string) (bool, domain_errors.DomainError)
+
+	// Account lifecycle
+	ExportData(userID string) (*DataExport, domain_errors.DomainError)
+	ListOwnedWorkspaces(userID string) ([]*OwnedWorkspace, domain_errors.DomainError)
+	// DeleteAccount applies the workspace decisions, reassigns authored content to
+	// the ghost user and deletes the auth record in a single transaction
+	DeleteAccount(userID string, decisions []WorkspaceDecision) domain_errors.DomainError
 }
 
 type UserProfileRepository interface {
 	GetProfile(id string) (*UserProfile, domain_errors.DomainError)
-	UpdateProfile(userID, name string) (*UserProfile, domain_errors.DomainError)
+	UpdateProfile(userID string, update ProfileUpdate) (*UserProfile, domain_errors.DomainError)
+	// SetAvatar stores the blob key of the user's avatar, or clears it when key is
+	// empty, and returns the key it replaced
+	SetAvatar(userID, key string, at time.Time) (string, domain_errors.DomainError)
 	GetPublicProfile(id string) (*PublicProfile, domain_errors.DomainError)
 }
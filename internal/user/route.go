@@ -7,21 +7,58 @@ import (
 )
 
 func RegisterRoutes(r chi.Router, as *shared.AppState) {
-	dm := domain_middleware.NewDomainMiddleware()
-	handler := NewUserHandler(as.DB, as.AmqpConn)
+	dm := domain_middleware.NewDomainMiddleware(as.DB)
+	handler := NewUserHandler(as.DB, as.Blobs, as.AmqpConn)
 
 	// Public routes (no authentication required)
 	r.Post("/magic-link", handler.RequestMagicLink)
 	r.Get("/verify", handler.VerifyToken)
 	r.Get("/refresh-token", handler.RefreshToken)
+	r.Post("/login", handler.LoginWithPassword)
+	r.Post("/login/mfa", handler.VerifyMFA)
+	r.Post("/password/forgot", handler.RequestPasswordReset)
+	r.Post("/password/reset", handler.ResetPassword)
+	r.Get("/oidc", handler.ListOIDCProviders)
+	r.Get("/oidc/{provider}/login", handler.BeginOIDCLogin)
+	r.Get("/oidc/{provider}/callback", handler.OIDCCallback)
 
 	// Protected routes (require authentication)
 	r.Group(func(r chi.Router) {
 		r.Use(dm.Authenticate)
+		r.Use(dm.RequireResourceScope("profile"))
 
 		r.Get("/auth", handler.GetByID)
 		r.Get("/profile", handler.GetProfile)
 		r.Put("/profile", handler.UpdateProfile)
 		r.Get("/profile/{id}", handler.GetPublicProfile)
+		r.Put("/profile/avatar", handler.UploadAvatar)
+		r.Delete("/profile/avatar", handler.DeleteAvatar)
+		r.Get("/profile/{id}/avatar", handler.GetAvatar)
+	})
+
+	// Session and credential management (not available to API tokens)
+	r.Group(func(r chi.Router) {
+		r.Use(dm.Authenticate)
+		r.Use(dm.RequireSession)
+
+		// Session management
+		r.Get("/sessions", handler.ListSessions)
+		r.Delete("/sessions", handler.RevokeOtherSessions)
+		r.Delete("/sessions/{id}", handler.RevokeSession)
+		r.Post("/logout", handler.Logout)
+
+		// Credentials
+		r.Put("/password", handler.SetPassword)
+		r.Delete("/password", handler.RemovePassword)
+		r.Put("/login-methods", handler.SetLoginMethods)
+		r.Post("/totp/enroll", handler.EnrollTOTP)
+		r.Post("/totp/confirm", handler.ConfirmTOTP)
+		r.Delete("/totp", handler.DisableTOTP)
+		r.Post("/totp/recovery-codes", handler.RegenerateRecoveryCodes)
+
+		// Account data and deletion
+		r.Get("/account/export", handler.ExportData)
+		r.Get("/account/owned-workspaces", handler.ListOwnedWorkspaces)
+		r.Delete("/account", handler.DeleteAccount)
 	})
 }
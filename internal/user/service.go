@@ -1,38 +1,112 @@
 package user
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/ishola-faazele/taskflow/internal/apitoken"
+	"github.com/ishola-faazele/taskflow/internal/oidc"
+	"github.com/ishola-faazele/taskflow/internal/session"
 	amqp_utils "github.com/ishola-faazele/taskflow/internal/utils/amqp"
+	"github.com/ishola-faazele/taskflow/internal/utils/blobstore"
+	"github.com/ishola-faazele/taskflow/internal/utils/imaging"
 	"github.com/ishola-faazele/taskflow/internal/utils/jwt"
 	"github.com/ishola-faazele/taskflow/pkg/utils"
 	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"golang.org/x/text/language"
 )
 
 type UserService struct {
-	authRepo    AuthRepository
-	profileRepo UserProfileRepository
-	conn        *amqp.Connection
-	jwtUtil     *jwt.JWTUtils
+	authRepo      AuthRepository
+	profileRepo   UserProfileRepository
+	sessionRepo   session.SessionRepository
+	oidcStateRepo oidc.StateRepository
+	oidcProviders *oidc.Registry
+	conn          *amqp.Connection
+	jwtUtil       *jwt.JWTUtils
+	magicLimit    RateLimit
+	loginLimit    RateLimit
+	mfaLimit      RateLimit
+	blobs         blobstore.Store
+	// totpKey seals TOTP secrets at rest, TOTP cannot be enrolled without it
+	totpKey []byte
 }
 
-func NewUserService(authRepo AuthRepository, profileRepo UserProfileRepository, conn *amqp.Connection) *UserService {
+func NewUserService(authRepo AuthRepository, profileRepo UserProfileRepository, sessionRepo session.SessionRepository, oidcStateRepo oidc.StateRepository, oidcProviders *oidc.Registry, blobs blobstore.Store, conn *amqp.Connection) *UserService {
 	jwtUtil := jwt.NewJWTUtils(jwt.DefaultTokenConfig())
 	return &UserService{
-		authRepo:    authRepo,
-		profileRepo: profileRepo,
-		jwtUtil:     jwtUtil,
-		conn:        conn,
+		authRepo:      authRepo,
+		profileRepo:   profileRepo,
+		sessionRepo:   sessionRepo,
+		oidcStateRepo: oidcStateRepo,
+		oidcProviders: oidcProviders,
+		jwtUtil:       jwtUtil,
+		conn:          conn,
+		magicLimit:    DefaultMagicLinkRateLimit(),
+		loginLimit:    DefaultPasswordLoginRateLimit(),
+		mfaLimit:      DefaultMFARateLimit(),
+		blobs:         blobs,
+		totpKey:       totpKeyFromEnv(),
 	}
 }
 
-// Creates a magic link which the user has to verify to log in
-func (us *UserService) GetMagicLink(email string) domain_errors.DomainError {
+// totpKeyFromEnv reads the base64 encoded 32 byte key TOTP secrets are sealed with
+func totpKeyFromEnv() []byte {
+	value := os.Getenv("TOTP_ENCRYPTION_KEY")
+	if value == "" {
+		return nil
+	}
+	key, err := utils.ParseSecretKey(value)
+	if err != nil {
+		log.Println("INVALID_TOTP_ENCRYPTION_KEY:", err)
+		return nil
+	}
+	return key
+}
+
+// Records an authentication attempt and enforces the limit for its kind. Going over
+// the per-IP limit is an error; going over the per-email limit is reported through
+// the returned bool so callers can drop the request without revealing anything.
+func (us *UserService) checkRateLimit(kind AttemptKind, limit RateLimit, email, ipAddress string) (bool, domain_errors.DomainError) {
+	now := time.Now().UTC()
+	byEmail, byIP, err := us.authRepo.CountAttempts(kind, email, ipAddress, now.Add(-limit.Window))
+	if err != nil {
+		return false, err
+	}
+	if byIP >= limit.PerIP {
+		return false, domain_errors.NewRateLimitError(string(kind)+"_PER_IP", limit.Window)
+	}
+	if err := us.authRepo.RecordAttempt(kind, email, ipAddress, now); err != nil {
+		return false, err
+	}
+	return byEmail < limit.PerEmail, nil
+}
+
+// Creates a magic link which the user has to verify to log in. The outcome is the
+// same whether or not the address belongs to an account.
+func (us *UserService) GetMagicLink(email, ipAddress string) domain_errors.DomainError {
 	if !utils.IsValidEmail(email) {
 		return domain_errors.NewValidationErrorWithValue("email", email, "INVALID_EMAIL_FORMAT")
 	}
+	allowed, err := us.checkRateLimit(AttemptMagicLink, us.magicLimit, email, ipAddress)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		// silently drop the request so the address cannot be flooded
+		return nil
+	}
 	// check if user is in db
 	user, err := us.authRepo.GetByEmail(email)
 	if err != nil {
@@ -45,6 +119,10 @@ func (us *UserService) GetMagicLink(email string) domain_errors.DomainError {
 			return err
 		}
 	}
+	if !user.MagicLinkEnabled {
+		// the user only signs in with a password, answer as if the link was sent
+		return nil
+	}
 
 	// create token using auth as claim
 	authToken, token_err := us.jwtUtil.GenerateAuthToken(user.ID, email)
@@ -52,7 +130,7 @@ func (us *UserService) GetMagicLink(email string) domain_errors.DomainError {
 		return domain_errors.NewInternalError("FAILED_TO_GENERATE_TOKEN", token_err)
 	}
 	// send email with magic link
-	emailMsg, msgErr := amqp_utils.NewMagicLinkMessage(email, authToken, "/api/user/verify?token=")
+	emailMsg, msgErr := amqp_utils.NewMagicLinkMessage(email, authToken, "/api/user/verify?token=", us.recipientLocale(user.ID))
 	if msgErr != nil {
 		return domain_errors.NewInternalError("FAILED_TO_CREATE_EMAIL_MESSAGE", msgErr)
 	}
@@ -68,27 +146,97 @@ func (us *UserService) GetMagicLink(email string) domain_errors.DomainError {
 	return nil
 }
 
-// verifies token embedded in the magic link
-func (us *UserService) VerifyToken(token string) (string, string, domain_errors.DomainError) {
+// verifies token embedded in the magic link and starts a session for the device
+func (us *UserService) VerifyToken(token, userAgent, ipAddress string) (*LoginResult, domain_errors.DomainError) {
 	// check if token is signed and valid
 	claims, parseErr := us.jwtUtil.ParseUserToken(token)
 	if parseErr != nil {
-		return "", "", domain_errors.NewInternalError("ERROR_PARSING_TOKEN", parseErr)
+		return nil, domain_errors.NewInternalError("ERROR_PARSING_TOKEN", parseErr)
 	}
 	// check if token is of valid purpose
 	if claims.Purpose != jwt.PurposeAuth {
-		return "", "", domain_errors.NewUnauthorizedError("INVALID_TOKEN_PURPOSE")
+		return nil, domain_errors.NewUnauthorizedError("INVALID_TOKEN_PURPOSE")
+	}
+	auth, err := us.authRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !auth.MagicLinkEnabled {
+		return nil, domain_errors.NewUnauthorizedError("MAGIC_LINK_LOGIN_DISABLED")
+	}
+	// magic links can only be redeemed once
+	if err := us.redeemToken(claims); err != nil {
+		return nil, err
 	}
 
-	// issue new tokens for access and refresh
-	access, refresh, tokenErr := us.jwtUtil.GenerateTokenPair(claims.UserID, claims.Email)
+	return us.completeLogin(auth, userAgent, ipAddress)
+}
+
+// completeLogin finishes a login whose first factor has been verified, either by
+// starting a session or, when the user has TOTP enabled, by asking for the second factor
+func (us *UserService) completeLogin(auth *Auth, userAgent, ipAddress string) (*LoginResult, domain_errors.DomainError) {
+	if auth.TOTPEnabled {
+		mfaToken, tokenErr := us.jwtUtil.GenerateMFAToken(auth.ID, auth.Email)
+		if tokenErr != nil {
+			return nil, domain_errors.NewInternalError("FAILED_TO_GENERATE_MFA_TOKEN", tokenErr)
+		}
+		return &LoginResult{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+	access, refresh, err := us.startSession(auth.ID, auth.Email, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+// redeemToken marks a single-use token as used, failing if it was redeemed before
+func (us *UserService) redeemToken(claims *jwt.UserClaims) domain_errors.DomainError {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return domain_errors.NewUnauthorizedError("TOKEN_IS_NOT_SINGLE_USE")
+	}
+	used := &UsedToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		Purpose:   string(claims.Purpose),
+		UsedAt:    time.Now().UTC(),
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	fresh, err := us.authRepo.MarkTokenUsed(used)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return domain_errors.NewUnauthorizedError("TOKEN_ALREADY_USED")
+	}
+	return nil
+}
+
+// startSession creates a new refresh-token family for a device and issues its first token pair
+func (us *UserService) startSession(userID, email, userAgent, ipAddress string) (string, string, domain_errors.DomainError) {
+	now := time.Now().UTC()
+	sessionID := uuid.NewString()
+	access, refresh, tokenErr := us.jwtUtil.GenerateTokenPair(userID, email, sessionID)
 	if tokenErr != nil {
 		return "", "", domain_errors.NewInternalError("FAILED_TO_GENERATE_ACCESS_AND_REFRESH_TOKENS", tokenErr)
 	}
+	newSession := &session.Session{
+		ID:               sessionID,
+		UserID:           userID,
+		UserAgent:        userAgent,
+		IPAddress:        ipAddress,
+		RefreshTokenHash: utils.HashToken(refresh),
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(us.jwtUtil.Config.RefreshTokenDuration),
+	}
+	if _, err := us.sessionRepo.Create(newSession); err != nil {
+		return "", "", err
+	}
 	return access, refresh, nil
 }
 
-// Returns new access and refresh tokens while invalidating the old refresh token
+// Returns new access and refresh tokens while invalidating the old refresh token.
+// Presenting a refresh token that has already been exchanged revokes its whole session.
 func (us *UserService) RefreshToken(refreshToken string) (string, string, domain_errors.DomainError) {
 	// validate refresh token
 	claims, parseErr := us.jwtUtil.ParseUserToken(refreshToken)
@@ -98,29 +246,688 @@ func (us *UserService) RefreshToken(refreshToken string) (string, string, domain
 	if claims.Purpose != jwt.PurposeRefresh {
 		return "", "", domain_errors.NewUnauthorizedError("INVALID_REFRESH_TOKEN_PURPOSE")
 	}
-	// check if token_hash has not been invalidated
-	token_hash := utils.HashToken(refreshToken)
-	if valid, err := us.authRepo.IsTokenValid(token_hash); !valid || err != nil {
-		return "", "", domain_errors.NewUnauthorizedError("TOKEN_IS_INVALID_OR_EXPIRED")
+	if claims.SessionID == "" {
+		return "", "", domain_errors.NewUnauthorizedError("REFRESH_TOKEN_NOT_BOUND_TO_SESSION")
 	}
-	// invalidate token before creating a newone
-	invalidToken := &InvalidToken{
-		TokenHash:     token_hash,
-		UserID:        claims.UserID,
-		ExpiresAt:     claims.ExpiresAt.Time,
-		InvalidatedAt: time.Now().UTC(),
+	current, err := us.sessionRepo.GetByID(claims.SessionID)
+	if err != nil {
+		if domain_errors.IsNotFound(err) {
+			return "", "", domain_errors.NewUnauthorizedError("SESSION_NOT_FOUND")
+		}
+		return "", "", err
 	}
-	if err := us.authRepo.InvalidateToken(invalidToken); err != nil {
-		return "", "", domain_errors.NewInternalError("ERROR_INVALIDATING_REFRESH_TOKEN", err)
+	now := time.Now().UTC()
+	if current.UserID != claims.UserID || !current.IsActive(now) {
+		return "", "", domain_errors.NewUnauthorizedError("SESSION_REVOKED_OR_EXPIRED")
 	}
-	// create new access and refresh tokens
-	access, refresh, token_err := us.jwtUtil.GenerateTokenPair(claims.UserID, claims.Email)
+
+	// create new access and refresh tokens in the same family
+	access, refresh, token_err := us.jwtUtil.GenerateTokenPair(claims.UserID, claims.Email, current.ID)
 	if token_err != nil {
 		return "", "", domain_errors.NewInternalError("FAILED_TO_GENERATE_ACCESS_AND_REFRESH TOKENS", token_err)
 	}
+	expiresAt := now.Add(us.jwtUtil.Config.RefreshTokenDuration)
+	rotated, err := us.sessionRepo.Rotate(current.ID, utils.HashToken(refreshToken), utils.HashToken(refresh), now, expiresAt)
+	if err != nil {
+		return "", "", err
+	}
+	if !rotated {
+		// the token was already exchanged, so it has leaked: end the whole family
+		if err := us.sessionRepo.Revoke(current.ID); err != nil {
+			return "", "", err
+		}
+		return "", "", domain_errors.NewUnauthorizedError("REFRESH_TOKEN_REUSE_DETECTED")
+	}
 	return access, refresh, nil
 }
 
+// Lists the user's active sessions, flagging the one making the request
+func (us *UserService) ListSessions(userID, currentSessionID string) ([]*session.Session, domain_errors.DomainError) {
+	sessions, err := us.sessionRepo.ListActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		s.Current = s.ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// Revokes one of the user's sessions
+func (us *UserService) RevokeSession(userID, sessionID string) domain_errors.DomainError {
+	if err := uuid.Validate(sessionID); err != nil {
+		return domain_errors.NewValidationErrorWithValue("session_id", sessionID, "SESSION_ID_IS_NOT_A_VALID_UUID")
+	}
+	target, err := us.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		return err
+	}
+	if target.UserID != userID {
+		return domain_errors.NewNotFoundError("SESSION", sessionID)
+	}
+	return us.sessionRepo.Revoke(sessionID)
+}
+
+// Revokes every session of the user except the current one
+func (us *UserService) RevokeOtherSessions(userID, currentSessionID string) domain_errors.DomainError {
+	return us.sessionRepo.RevokeAllForUser(userID, currentSessionID)
+}
+
+// ============================================================================
+// PASSWORD AND TWO-FACTOR METHODS
+// ============================================================================
+
+const (
+	minPasswordLength  = 10
+	maxPasswordLength  = 256
+	recoveryCodeCount  = 10
+	totpIssuer         = "TaskFlow"
+	passwordResetRoute = "/api/user/password/reset?token="
+	// recentLoginWindow is how soon after logging in a user without a password may
+	// set one, since there is no current password to confirm
+	recentLoginWindow = 10 * time.Minute
+)
+
+// dummyPasswordHash is verified against when the account has no password so
+// failed logins take the same time whether or not the account exists
+var dummyPasswordHash, _ = utils.HashPassword("taskflow-dummy-password")
+
+// Lists the names of the configured single sign-on providers
+func (us *UserService) ListOIDCProviders() []string {
+	return us.oidcProviders.Names()
+}
+
+// Starts a single sign-on login and returns the provider URL to redirect the user to
+// along with the state. The nonce and PKCE verifier are kept server side until the
+// callback; the state must also be kept by the browser so the callback can only
+// complete the login in the browser that started it.
+func (us *UserService) BeginOIDCLogin(ctx context.Context, providerName string) (string, string, domain_errors.DomainError) {
+	provider, ok := us.oidcProviders.Get(providerName)
+	if !ok {
+		return "", "", domain_errors.NewNotFoundError("OIDC_PROVIDER", providerName)
+	}
+	state, stateErr := oidc.RandomToken(32)
+	if stateErr != nil {
+		return "", "", domain_errors.NewInternalError("FAILED_TO_GENERATE_OIDC_STATE", stateErr)
+	}
+	nonce, nonceErr := oidc.RandomToken(32)
+	if nonceErr != nil {
+		return "", "", domain_errors.NewInternalError("FAILED_TO_GENERATE_OIDC_NONCE", nonceErr)
+	}
+	verifier, verifierErr := oidc.NewCodeVerifier()
+	if verifierErr != nil {
+		return "", "", domain_errors.NewInternalError("FAILED_TO_GENERATE_PKCE_VERIFIER", verifierErr)
+	}
+	authURL, urlErr := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if urlErr != nil {
+		return "", "", domain_errors.NewInternalError("OIDC_PROVIDER_UNAVAILABLE", urlErr)
+	}
+
+	now := time.Now().UTC()
+	loginState := &oidc.LoginState{
+		State:        state,
+		Provider:     provider.Name(),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(oidc.DefaultStateDuration),
+	}
+	if err := us.oidcStateRepo.Save(loginState); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// Completes a single sign-on login from the provider's callback. The verified email
+// in the ID token is mapped onto an existing account, or a new one is created the
+// same way the first magic link does. browserState is the state the browser kept
+// when the login began; a callback carrying another state was started elsewhere,
+// such as a link crafted to sign the user into someone else's account.
+func (us *UserService) CompleteOIDCLogin(ctx context.Context, providerName, code, state, browserState, userAgent, ipAddress string) (*LoginResult, domain_errors.DomainError) {
+	if code == "" || state == "" {
+		return nil, domain_errors.NewValidationError("code", "CODE_AND_STATE_ARE_REQUIRED")
+	}
+	if subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return nil, domain_errors.NewUnauthorizedError("OIDC_STATE_NOT_STARTED_BY_THIS_BROWSER")
+	}
+	loginState, err := us.oidcStateRepo.Consume(state, time.Now().UTC())
+	if err != nil {
+		if domain_errors.IsNotFound(err) {
+			return nil, domain_errors.NewUnauthorizedError("INVALID_OR_EXPIRED_OIDC_STATE")
+		}
+		return nil, err
+	}
+	if loginState.Provider != providerName {
+		return nil, domain_errors.NewUnauthorizedError("OIDC_STATE_PROVIDER_MISMATCH")
+	}
+	provider, ok := us.oidcProviders.Get(providerName)
+	if !ok {
+		return nil, domain_errors.NewNotFoundError("OIDC_PROVIDER", providerName)
+	}
+
+	token, exchangeErr := provider.Exchange(ctx, code, loginState.CodeVerifier)
+	if exchangeErr != nil {
+		return nil, domain_errors.NewUnauthorizedError("OIDC_CODE_EXCHANGE_FAILED")
+	}
+	identity, verifyErr := provider.VerifyIDToken(ctx, token.IDToken, loginState.Nonce)
+	if verifyErr != nil {
+		return nil, domain_errors.NewUnauthorizedError("INVALID_ID_TOKEN")
+	}
+	if !identity.EmailVerified || !utils.IsValidEmail(identity.Email) {
+		return nil, domain_errors.NewUnauthorizedError("OIDC_EMAIL_NOT_VERIFIED")
+	}
+
+	auth, err := us.authRepo.GetByEmail(identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if auth == nil {
+		if auth, err = us.authRepo.Create(CreateNewAuth(identity.Email)); err != nil {
+			return nil, err
+		}
+	}
+	return us.completeLogin(auth, userAgent, ipAddress)
+}
+
+func validatePassword(password string) domain_errors.DomainError {
+	if len(password) < minPasswordLength {
+		return domain_errors.NewValidationError("password", fmt.Sprintf("PASSWORD_MUST_BE_AT_LEAST_%d_CHARACTERS", minPasswordLength))
+	}
+	if len(password) > maxPasswordLength {
+		return domain_errors.NewValidationError("password", fmt.Sprintf("PASSWORD_MUST_BE_AT_MOST_%d_CHARACTERS", maxPasswordLength))
+	}
+	return nil
+}
+
+// Logs a user in with their email and password
+func (us *UserService) LoginWithPassword(email, password, userAgent, ipAddress string) (*LoginResult, domain_errors.DomainError) {
+	if !utils.IsValidEmail(email) {
+		return nil, domain_errors.NewValidationErrorWithValue("email", email, "INVALID_EMAIL_FORMAT")
+	}
+	allowed, err := us.checkRateLimit(AttemptPasswordLogin, us.loginLimit, email, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, domain_errors.NewRateLimitError(string(AttemptPasswordLogin)+"_PER_EMAIL", us.loginLimit.Window)
+	}
+	auth, err := us.authRepo.GetByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if auth == nil || !auth.HasPassword {
+		_, _ = utils.VerifyPassword(password, dummyPasswordHash)
+		return nil, domain_errors.NewUnauthorizedError("INVALID_CREDENTIALS")
+	}
+	ok, verifyErr := utils.VerifyPassword(password, auth.PasswordHash)
+	if verifyErr != nil {
+		return nil, domain_errors.NewInternalError("PASSWORD_VERIFICATION", verifyErr)
+	}
+	if !ok {
+		return nil, domain_errors.NewUnauthorizedError("INVALID_CREDENTIALS")
+	}
+	return us.completeLogin(auth, userAgent, ipAddress)
+}
+
+// Completes a login with a TOTP code or a recovery code
+func (us *UserService) VerifyMFA(mfaToken, code, recoveryCode, userAgent, ipAddress string) (*LoginResult, domain_errors.DomainError) {
+	claims, parseErr := us.jwtUtil.ParseUserToken(mfaToken)
+	if parseErr != nil {
+		return nil, domain_errors.NewUnauthorizedError("INVALID_OR_EXPIRED_MFA_TOKEN")
+	}
+	if claims.Purpose != jwt.PurposeMFA {
+		return nil, domain_errors.NewUnauthorizedError("INVALID_TOKEN_PURPOSE")
+	}
+	allowed, err := us.checkRateLimit(AttemptMFA, us.mfaLimit, claims.Email, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, domain_errors.NewRateLimitError(string(AttemptMFA)+"_PER_EMAIL", us.mfaLimit.Window)
+	}
+	auth, err := us.authRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := us.checkSecondFactor(auth, code, recoveryCode); err != nil {
+		return nil, err
+	}
+	if err := us.redeemToken(claims); err != nil {
+		return nil, err
+	}
+	access, refresh, err := us.startSession(auth.ID, auth.Email, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery code
+func (us *UserService) checkSecondFactor(auth *Auth, code, recoveryCode string) domain_errors.DomainError {
+	if !auth.TOTPEnabled {
+		return domain_errors.NewInvalidOperationError("verify_second_factor", "TOTP_IS_NOT_ENABLED")
+	}
+	if code != "" {
+		return us.checkTOTP(auth, code)
+	}
+	if recoveryCode != "" {
+		used, err := us.authRepo.UseRecoveryCode(auth.ID, utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode)))
+		if err != nil {
+			return err
+		}
+		if used {
+			return nil
+		}
+		return domain_errors.NewUnauthorizedError("INVALID_RECOVERY_CODE")
+	}
+	return domain_errors.NewValidationError("code", "TOTP_CODE_OR_RECOVERY_CODE_REQUIRED")
+}
+
+// checkTOTP accepts a code for the user's secret and records its period, so the
+// same code cannot be used again while it is still current
+func (us *UserService) checkTOTP(auth *Auth, code string) domain_errors.DomainError {
+	secret, err := us.openTOTPSecret(auth)
+	if err != nil {
+		return err
+	}
+	step, ok := utils.ValidateTOTP(secret, code, time.Now(), auth.TOTPLastStep)
+	if !ok {
+		return domain_errors.NewUnauthorizedError("INVALID_TOTP_CODE")
+	}
+	fresh, err := us.authRepo.UseTOTPStep(auth.ID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return domain_errors.NewUnauthorizedError("INVALID_TOTP_CODE")
+	}
+	if !utils.IsSealedSecret(auth.TOTPSecret) && auth.TOTPEnabled {
+		// seal secrets stored before encryption the first time they are used
+		if sealed, err := us.sealTOTPSecret(auth.ID, secret); err == nil {
+			if err := us.authRepo.SetTOTP(auth.ID, sealed, true); err != nil {
+				log.Println("FAILED_TO_SEAL_TOTP_SECRET:", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (us *UserService) sealTOTPSecret(userID, secret string) (string, domain_errors.DomainError) {
+	if us.totpKey == nil {
+		return "", domain_errors.NewInternalError("TOTP_ENCRYPTION_KEY_NOT_CONFIGURED", nil)
+	}
+	sealed, err := utils.SealSecret(us.totpKey, secret, userID)
+	if err != nil {
+		return "", domain_errors.NewInternalError("TOTP_SECRET_SEALING", err)
+	}
+	return sealed, nil
+}
+
+// openTOTPSecret returns the user's secret in the clear. Secrets stored before
+// encryption was introduced are returned as they are.
+func (us *UserService) openTOTPSecret(auth *Auth) (string, domain_errors.DomainError) {
+	if !utils.IsSealedSecret(auth.TOTPSecret) {
+		return auth.TOTPSecret, nil
+	}
+	if us.totpKey == nil {
+		return "", domain_errors.NewInternalError("TOTP_ENCRYPTION_KEY_NOT_CONFIGURED", nil)
+	}
+	secret, err := utils.OpenSecret(us.totpKey, auth.TOTPSecret, auth.ID)
+	if err != nil {
+		return "", domain_errors.NewInternalError("TOTP_SECRET_OPENING", err)
+	}
+	return secret, nil
+}
+
+// Sets or changes a user's password. Changing an existing password requires the current
+// one; setting a first password requires the session to have logged in recently, so a
+// stolen access token cannot be turned into a lasting way in.
+func (us *UserService) SetPassword(userID, sessionID, currentPassword, newPassword string) domain_errors.DomainError {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+	auth, err := us.authRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if auth.HasPassword {
+		ok, verifyErr := utils.VerifyPassword(currentPassword, auth.PasswordHash)
+		if verifyErr != nil {
+			return domain_errors.NewInternalError("PASSWORD_VERIFICATION", verifyErr)
+		}
+		if !ok {
+			return domain_errors.NewUnauthorizedError("CURRENT_PASSWORD_IS_INCORRECT")
+		}
+	} else if err := us.checkRecentLogin(userID, sessionID); err != nil {
+		return err
+	}
+	hash, hashErr := utils.HashPassword(newPassword)
+	if hashErr != nil {
+		return domain_errors.NewInternalError("PASSWORD_HASHING", hashErr)
+	}
+	return us.authRepo.SetPassword(userID, hash, time.Now().UTC())
+}
+
+// checkRecentLogin makes sure the request comes from a session of the user that
+// logged in within recentLoginWindow. Refreshing tokens keeps the session, so
+// only logging in again starts the window over.
+func (us *UserService) checkRecentLogin(userID, sessionID string) domain_errors.DomainError {
+	if sessionID == "" {
+		return domain_errors.NewUnauthorizedError("RECENT_LOGIN_REQUIRED")
+	}
+	current, err := us.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		if domain_errors.IsNotFound(err) {
+			return domain_errors.NewUnauthorizedError("RECENT_LOGIN_REQUIRED")
+		}
+		return err
+	}
+	now := time.Now().UTC()
+	if current.UserID != userID || !current.IsActive(now) || now.Sub(current.CreatedAt) > recentLoginWindow {
+		return domain_errors.NewUnauthorizedError("RECENT_LOGIN_REQUIRED")
+	}
+	return nil
+}
+
+// Removes a user's password, leaving magic links as their way in
+func (us *UserService) RemovePassword(userID, currentPassword string) domain_errors.DomainError {
+	auth, err := us.authRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !auth.HasPassword {
+		return domain_errors.NewInvalidOperationError("remove_password", "NO_PASSWORD_SET")
+	}
+	if !auth.MagicLinkEnabled {
+		return domain_errors.NewInvalidOperationError("remove_password", "ENABLE_MAGIC_LINKS_BEFORE_REMOVING_PASSWORD")
+	}
+	ok, verifyErr := utils.VerifyPassword(currentPassword, auth.PasswordHash)
+	if verifyErr != nil {
+		return domain_errors.NewInternalError("PASSWORD_VERIFICATION", verifyErr)
+	}
+	if !ok {
+		return domain_errors.NewUnauthorizedError("CURRENT_PASSWORD_IS_INCORRECT")
+	}
+	return us.authRepo.SetPassword(userID, "", time.Now().UTC())
+}
+
+// Chooses whether the user can sign in with magic links
+func (us *UserService) SetMagicLinkEnabled(userID string, enabled bool) domain_errors.DomainError {
+	auth, err := us.authRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !enabled && !auth.HasPassword {
+		return domain_errors.NewInvalidOperationError("disable_magic_link", "SET_A_PASSWORD_BEFORE_DISABLING_MAGIC_LINKS")
+	}
+	return us.authRepo.SetMagicLinkEnabled(userID, enabled)
+}
+
+// Emails a password reset link. The outcome is the same whether or not the address belongs to an account.
+func (us *UserService) RequestPasswordReset(email, ipAddress string) domain_errors.DomainError {
+	if !utils.IsValidEmail(email) {
+		return domain_errors.NewValidationErrorWithValue("email", email, "INVALID_EMAIL_FORMAT")
+	}
+	allowed, err := us.checkRateLimit(AttemptPasswordReset, us.magicLimit, email, ipAddress)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return nil
+	}
+	auth, err := us.authRepo.GetByEmail(email)
+	if err != nil {
+		return err
+	}
+	if auth == nil {
+		return nil
+	}
+	token, tokenErr := us.jwtUtil.GeneratePasswordResetToken(auth.ID, auth.Email)
+	if tokenErr != nil {
+		return domain_errors.NewInternalError("FAILED_TO_GENERATE_TOKEN", tokenErr)
+	}
+	emailMsg, msgErr := amqp_utils.NewPasswordResetMessage(auth.Email, token, passwordResetRoute, us.recipientLocale(auth.ID))
+	if msgErr != nil {
+		return domain_errors.NewInternalError("FAILED_TO_CREATE_EMAIL_MESSAGE", msgErr)
+	}
+	ch, chErr := us.conn.Channel()
+	if chErr != nil {
+		return domain_errors.NewInternalError("FAILED_TO_CREATE_CHANNEL", chErr)
+	}
+	if err := amqp_utils.PublishEmailMessage(ch, emailMsg); err != nil {
+		return domain_errors.NewInternalError("FAILED_TO_PUBLISH_EMAIL_MESSAGE", err)
+	}
+	return nil
+}
+
+// Sets a new password from a reset link and signs out every existing session
+func (us *UserService) ResetPassword(token, newPassword string) domain_errors.DomainError {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+	claims, parseErr := us.jwtUtil.ParseUserToken(token)
+	if parseErr != nil {
+		return domain_errors.NewUnauthorizedError("INVALID_OR_EXPIRED_RESET_TOKEN")
+	}
+	if claims.Purpose != jwt.PurposePasswordReset {
+		return domain_errors.NewUnauthorizedError("INVALID_TOKEN_PURPOSE")
+	}
+	if err := us.redeemToken(claims); err != nil {
+		return err
+	}
+	hash, hashErr := utils.HashPassword(newPassword)
+	if hashErr != nil {
+		return domain_errors.NewInternalError("PASSWORD_HASHING", hashErr)
+	}
+	if err := us.authRepo.SetPassword(claims.UserID, hash, time.Now().UTC()); err != nil {
+		return err
+	}
+	return us.sessionRepo.RevokeAllForUser(claims.UserID, "")
+}
+
+// Starts TOTP enrolment by generating a secret. TOTP stays disabled until a code is confirmed.
+func (us *UserService) EnrollTOTP(userID string) (*TOTPEnrollment, domain_errors.DomainError) {
+	auth, err := us.authRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if auth.TOTPEnabled {
+		return nil, domain_errors.NewInvalidOperationError("enroll_totp", "TOTP_ALREADY_ENABLED")
+	}
+	secret, secretErr := utils.GenerateTOTPSecret()
+	if secretErr != nil {
+		return nil, domain_errors.NewInternalError("TOTP_SECRET_GENERATION", secretErr)
+	}
+	sealed, err := us.sealTOTPSecret(userID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := us.authRepo.SetTOTP(userID, sealed, false); err != nil {
+		return nil, err
+	}
+	return &TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(secret, totpIssuer, auth.Email),
+	}, nil
+}
+
+// Enables TOTP once the user proves their authenticator works, returning fresh recovery codes
+func (us *UserService) ConfirmTOTP(userID, code string) ([]string, domain_errors.DomainError) {
+	auth, err := us.authRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if auth.TOTPEnabled {
+		return nil, domain_errors.NewInvalidOperationError("confirm_totp", "TOTP_ALREADY_ENABLED")
+	}
+	if auth.TOTPSecret == "" {
+		return nil, domain_errors.NewInvalidOperationError("confirm_totp", "TOTP_ENROLMENT_NOT_STARTED")
+	}
+	if err := us.checkTOTP(auth, code); err != nil {
+		return nil, err
+	}
+	sealed := auth.TOTPSecret
+	if !utils.IsSealedSecret(sealed) {
+		// an enrolment started before secrets were sealed
+		if sealed, err = us.sealTOTPSecret(userID, sealed); err != nil {
+			return nil, err
+		}
+	}
+	if err := us.authRepo.SetTOTP(userID, sealed, true); err != nil {
+		return nil, err
+	}
+	return us.issueRecoveryCodes(userID)
+}
+
+// Disables TOTP after checking a TOTP or recovery code
+func (us *UserService) DisableTOTP(userID, code, recoveryCode string) domain_errors.DomainError {
+	auth, err := us.authRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := us.checkSecondFactor(auth, code, recoveryCode); err != nil {
+		return err
+	}
+	if err := us.authRepo.SetTOTP(userID, "", false); err != nil {
+		return err
+	}
+	return us.authRepo.ReplaceRecoveryCodes(userID, nil)
+}
+
+// Replaces the user's recovery codes after checking a TOTP code
+func (us *UserService) RegenerateRecoveryCodes(userID, code string) ([]string, domain_errors.DomainError) {
+	auth, err := us.authRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := us.checkSecondFactor(auth, code, ""); err != nil {
+		return nil, err
+	}
+	return us.issueRecoveryCodes(userID)
+}
+
+func (us *UserService) issueRecoveryCodes(userID string) ([]string, domain_errors.DomainError) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, codeErr := utils.GenerateRecoveryCode()
+		if codeErr != nil {
+			return nil, domain_errors.NewInternalError("RECOVERY_CODE_GENERATION", codeErr)
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	}
+	if err := us.authRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ACCOUNT DATA AND DELETION
+
+// Collects everything stored about the user for the self-service export
+func (us *UserService) ExportData(userID string) (*DataExport, domain_errors.DomainError) {
+	return us.authRepo.ExportData(userID)
+}
+
+// Lists the workspaces the user owns, which must be transferred or deleted with the account
+func (us *UserService) ListOwnedWorkspaces(userID string) ([]*OwnedWorkspace, domain_errors.DomainError) {
+	return us.authRepo.ListOwnedWorkspaces(userID)
+}
+
+// Deletes the user's account. The user confirms with their email, their password if
+// they have one and a second factor if TOTP is enabled. Every owned workspace with
+// other members needs a decision; workspaces without other members are deleted.
+// Projects and tasks the user created are kept and credited to the ghost user.
+func (us *UserService) DeleteAccount(userID, confirmEmail, password, code, recoveryCode string, decisions []WorkspaceDecision) domain_errors.DomainError {
+	if userID == GhostUserID {
+		return domain_errors.NewForbiddenError("AUTH", "delete")
+	}
+	auth, err := us.authRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if auth.Email == apitoken.ServiceAccountEmail(auth.ID) {
+		return domain_errors.NewInvalidOperationError("delete_account", "SERVICE_ACCOUNTS_ARE_DISABLED_BY_THEIR_WORKSPACE_OWNER")
+	}
+	if !strings.EqualFold(strings.TrimSpace(confirmEmail), auth.Email) {
+		return domain_errors.NewValidationError("email", "EMAIL_DOES_NOT_MATCH_ACCOUNT")
+	}
+	if auth.HasPassword {
+		ok, verifyErr := utils.VerifyPassword(password, auth.PasswordHash)
+		if verifyErr != nil {
+			return domain_errors.NewInternalError("PASSWORD_VERIFICATION", verifyErr)
+		}
+		if !ok {
+			return domain_errors.NewUnauthorizedError("CURRENT_PASSWORD_IS_INCORRECT")
+		}
+	}
+	if auth.TOTPEnabled {
+		if err := us.checkSecondFactor(auth, code, recoveryCode); err != nil {
+			return err
+		}
+	}
+
+	owned, err := us.authRepo.ListOwnedWorkspaces(userID)
+	if err != nil {
+		return err
+	}
+	resolved, err := resolveWorkspaceDecisions(userID, owned, decisions)
+	if err != nil {
+		return err
+	}
+	profile, err := us.profileRepo.GetProfile(userID)
+	if err != nil {
+		return err
+	}
+	// sessions, tokens and memberships go with the auth record
+	if err := us.authRepo.DeleteAccount(userID, resolved); err != nil {
+		return err
+	}
+	if profile.AvatarKey != "" {
+		us.deleteBlob(context.Background(), profile.AvatarKey)
+	}
+	return nil
+}
+
+// resolveWorkspaceDecisions checks the requested decisions against the owned
+// workspaces and fills in deletion for workspaces nobody else belongs to
+func resolveWorkspaceDecisions(userID string, owned []*OwnedWorkspace, decisions []WorkspaceDecision) ([]WorkspaceDecision, domain_errors.DomainError) {
+	requested := make(map[string]WorkspaceDecision, len(decisions))
+	for _, decision := range decisions {
+		if _, duplicate := requested[decision.WorkspaceID]; duplicate {
+			return nil, domain_errors.NewValidationErrorWithValue("workspaces", decision.WorkspaceID, "DUPLICATE_WORKSPACE_DECISION")
+		}
+		switch decision.Action {
+		case WorkspaceActionTransfer:
+			if err := uuid.Validate(decision.NewOwnerID); err != nil || decision.NewOwnerID == userID {
+				return nil, domain_errors.NewValidationErrorWithValue("new_owner_id", decision.NewOwnerID, "NEW_OWNER_MUST_BE_ANOTHER_MEMBER")
+			}
+		case WorkspaceActionDelete:
+		default:
+			return nil, domain_errors.NewValidationErrorWithValue("action", decision.Action, "ACTION_MUST_BE_TRANSFER_OR_DELETE")
+		}
+		requested[decision.WorkspaceID] = decision
+	}
+
+	resolved := make([]WorkspaceDecision, 0, len(owned))
+	for _, ws := range owned {
+		decision, ok := requested[ws.ID]
+		if !ok {
+			if ws.OtherMembers > 0 {
+				return nil, domain_errors.NewValidationErrorWithValue("workspaces", ws.ID, "WORKSPACE_WITH_MEMBERS_NEEDS_A_DECISION")
+			}
+			decision = WorkspaceDecision{WorkspaceID: ws.ID, Action: WorkspaceActionDelete}
+		}
+		delete(requested, ws.ID)
+		resolved = append(resolved, decision)
+	}
+	for id := range requested {
+		return nil, domain_errors.NewValidationErrorWithValue("workspaces", id, "WORKSPACE_NOT_OWNED_BY_USER")
+	}
+	return resolved, nil
+}
+
 // Gets a user's own auth data
 func (us *UserService) GetByID(id string) (*Auth, domain_errors.DomainError) {
 	return us.authRepo.GetByID(id)
@@ -136,9 +943,148 @@ func (us *UserService) GetProfile(id string) (*UserProfile, domain_errors.Domain
 	return us.profileRepo.GetProfile(id)
 }
 
-// updates's user prpofile
-func (us UserService) UpdateProfile(userID, name string) (*UserProfile, domain_errors.DomainError) {
-	return us.profileRepo.UpdateProfile(userID, name)
+// recipientLocale is the locale emails to the user are written in
+func (us *UserService) recipientLocale(userID string) string {
+	profile, err := us.profileRepo.GetProfile(userID)
+	if err != nil {
+		return DefaultLocale
+	}
+	return profile.Locale
+}
+
+// Profile field limits
+const (
+	maxNameLength  = 255
+	maxTitleLength = 100
+	maxBioLength   = 2000
+)
+
+// updates's user prpofile, validating and normalizing the fields being changed
+func (us UserService) UpdateProfile(userID string, update ProfileUpdate) (*UserProfile, domain_errors.DomainError) {
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if utf8.RuneCountInString(name) > maxNameLength {
+			return nil, domain_errors.NewValidationError("name", "NAME_TOO_LONG")
+		}
+		update.Name = &name
+	}
+	if update.Title != nil {
+		title := strings.TrimSpace(*update.Title)
+		if utf8.RuneCountInString(title) > maxTitleLength {
+			return nil, domain_errors.NewValidationError("title", "TITLE_TOO_LONG")
+		}
+		update.Title = &title
+	}
+	if update.Bio != nil {
+		bio := strings.TrimSpace(*update.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			return nil, domain_errors.NewValidationError("bio", "BIO_TOO_LONG")
+		}
+		update.Bio = &bio
+	}
+	if update.Timezone != nil {
+		timezone := strings.TrimSpace(*update.Timezone)
+		// "Local" would mean the server's zone, not the user's
+		if _, err := time.LoadLocation(timezone); err != nil || timezone == "" || timezone == "Local" {
+			return nil, domain_errors.NewValidationErrorWithValue("timezone", timezone, "UNKNOWN_IANA_TIMEZONE")
+		}
+		update.Timezone = &timezone
+	}
+	if update.Locale != nil {
+		tag, err := language.Parse(strings.TrimSpace(*update.Locale))
+		if err != nil || tag == language.Und {
+			return nil, domain_errors.NewValidationErrorWithValue("locale", *update.Locale, "INVALID_BCP47_LOCALE")
+		}
+		locale := tag.String()
+		update.Locale = &locale
+	}
+	return us.profileRepo.UpdateProfile(userID, update)
+}
+
+// avatarSize is the width and height of stored avatars in pixels
+const avatarSize = 256
+
+// Uploads are decoded and re-encoded, so only pixel data ever reaches storage
+var avatarLimits = imaging.Limits{
+	MaxBytes:     5 << 20,
+	MaxDimension: 4096,
+	Formats:      []string{"png", "jpeg", "gif"},
+}
+
+// Replaces the user's avatar with a square PNG thumbnail of the uploaded image
+func (us *UserService) UploadAvatar(ctx context.Context, userID string, r io.Reader) (*UserProfile, domain_errors.DomainError) {
+	img, _, decodeErr := imaging.Decode(r, avatarLimits)
+	if decodeErr != nil {
+		switch decodeErr {
+		case imaging.ErrTooLarge:
+			return nil, domain_errors.NewValidationError("avatar", "IMAGE_TOO_LARGE")
+		case imaging.ErrUnsupportedFormat:
+			return nil, domain_errors.NewValidationError("avatar", "IMAGE_MUST_BE_PNG_JPEG_OR_GIF")
+		default:
+			return nil, domain_errors.NewValidationError("avatar", "FAILED_TO_READ_IMAGE")
+		}
+	}
+	encoded, encodeErr := imaging.EncodePNG(imaging.SquareThumbnail(img, avatarSize))
+	if encodeErr != nil {
+		return nil, domain_errors.NewInternalError("AVATAR_ENCODING", encodeErr)
+	}
+
+	key := fmt.Sprintf("avatars/%s/%s.png", userID, uuid.NewString())
+	if _, err := us.blobs.Put(ctx, key, "image/png", bytes.NewReader(encoded)); err != nil {
+		return nil, domain_errors.NewInternalError("AVATAR_STORAGE", err)
+	}
+	previousKey, err := us.profileRepo.SetAvatar(userID, key, time.Now().UTC())
+	if err != nil {
+		us.deleteBlob(ctx, key)
+		return nil, err
+	}
+	if previousKey != "" {
+		us.deleteBlob(ctx, previousKey)
+	}
+	return us.profileRepo.GetProfile(userID)
+}
+
+// Removes the user's avatar
+func (us *UserService) DeleteAvatar(ctx context.Context, userID string) domain_errors.DomainError {
+	previousKey, err := us.profileRepo.SetAvatar(userID, "", time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if previousKey == "" {
+		return domain_errors.NewNotFoundError("AVATAR", userID)
+	}
+	us.deleteBlob(ctx, previousKey)
+	return nil
+}
+
+// Opens a user's avatar for reading. The caller closes the reader.
+func (us *UserService) GetAvatar(ctx context.Context, userID string) (io.ReadCloser, *blobstore.Object, domain_errors.DomainError) {
+	if err := uuid.Validate(userID); err != nil {
+		return nil, nil, domain_errors.NewValidationErrorWithValue("id", userID, "ID IS NOT A VALID UUID")
+	}
+	profile, err := us.profileRepo.GetProfile(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if profile.AvatarKey == "" {
+		return nil, nil, domain_errors.NewNotFoundError("AVATAR", userID)
+	}
+	reader, object, getErr := us.blobs.Get(ctx, profile.AvatarKey)
+	if getErr != nil {
+		if errors.Is(getErr, blobstore.ErrNotFound) {
+			return nil, nil, domain_errors.NewNotFoundError("AVATAR", userID)
+		}
+		return nil, nil, domain_errors.NewInternalError("AVATAR_STORAGE", getErr)
+	}
+	return reader, object, nil
+}
+
+// deleteBlob removes a blob that is no longer referenced. Failures only leave an
+// orphaned file behind, so they are logged rather than returned.
+func (us *UserService) deleteBlob(ctx context.Context, key string) {
+	if err := us.blobs.Delete(ctx, key); err != nil {
+		log.Println("FAILED_TO_DELETE_BLOB:", key, err)
+	}
 }
 
 func (us UserService) GetPublicProfile(id string) (*PublicProfile, domain_errors.DomainError) {
@@ -33,11 +33,12 @@ func PublishEmailMessage(ch *amqp.Channel, msg *EmailMessage) error {
 }
 
 // Helper methods to create messages
-func NewMagicLinkMessage(toEmail, token, verifyURL string) (*EmailMessage, error) {
+func NewMagicLinkMessage(toEmail, token, verifyURL, locale string) (*EmailMessage, error) {
 	payload := MagicLinkPayload{
 		ToEmail:   toEmail,
 		Token:     token,
 		VerifyURL: verifyURL,
+		Locale:    locale,
 	}
 
 	payloadBytes, err := json.Marshal(payload)
@@ -51,13 +52,14 @@ func NewMagicLinkMessage(toEmail, token, verifyURL string) (*EmailMessage, error
 	}, nil
 }
 
-func NewInvitationMessage(toEmail, workspaceName, role, token, invitationURL string) (*EmailMessage, error) {
+func NewInvitationMessage(toEmail, workspaceName, role, token, invitationURL, locale string) (*EmailMessage, error) {
 	payload := InvitationPayload{
 		ToEmail:       toEmail,
 		WorkspaceName: workspaceName,
 		Role:          role,
 		Token:         token,
 		InvitationURL: invitationURL,
+		Locale:        locale,
 	}
 
 	payloadBytes, err := json.Marshal(payload)
@@ -71,11 +73,12 @@ func NewInvitationMessage(toEmail, workspaceName, role, token, invitationURL str
 	}, nil
 }
 
-func NewPasswordResetMessage(toEmail, token, resetURL string) (*EmailMessage, error) {
+func NewPasswordResetMessage(toEmail, token, resetURL, locale string) (*EmailMessage, error) {
 	payload := PasswordResetPayload{
 		ToEmail:  toEmail,
 		Token:    token,
 		ResetURL: resetURL,
+		Locale:   locale,
 	}
 
 	payloadBytes, err := json.Marshal(payload)
@@ -89,6 +92,68 @@ func NewPasswordResetMessage(toEmail, token, resetURL string) (*EmailMessage, er
 	}, nil
 }
 
+func NewMentionMessage(toEmail, actorName, taskName, taskURL, locale string) (*EmailMessage, error) {
+	payload := MentionPayload{
+		ToEmail:   toEmail,
+		ActorName: actorName,
+		TaskName:  taskName,
+		TaskURL:   taskURL,
+		Locale:    locale,
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal mention payload: %w", err)
+	}
+
+	return &EmailMessage{
+		Type:    MessageTypeMention,
+		Payload: payloadBytes,
+	}, nil
+}
+
+// NewTaskActivityMessage builds a task activity email of one of the task message types
+func NewTaskActivityMessage(messageType MessageType, toEmail, actorName, taskName, detail, taskURL, locale string) (*EmailMessage, error) {
+	payload := TaskActivityPayload{
+		ToEmail:   toEmail,
+		ActorName: actorName,
+		TaskName:  taskName,
+		Detail:    detail,
+		TaskURL:   taskURL,
+		Locale:    locale,
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal task activity payload: %w", err)
+	}
+
+	return &EmailMessage{
+		Type:    messageType,
+		Payload: payloadBytes,
+	}, nil
+}
+
+func NewTaskDueMessage(toEmail, taskName, dueAt, taskURL, locale string) (*EmailMessage, error) {
+	payload := TaskDuePayload{
+		ToEmail:  toEmail,
+		TaskName: taskName,
+		DueAt:    dueAt,
+		TaskURL:  taskURL,
+		Locale:   locale,
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal task due payload: %w", err)
+	}
+
+	return &EmailMessage{
+		Type:    MessageTypeTaskDue,
+		Payload: payloadBytes,
+	}, nil
+}
+
 func NewCustomEmailMessage(toEmail string, template EmailTemplate) (*EmailMessage, error) {
 	payload := CustomEmailPayload{
 		ToEmail:  toEmail,
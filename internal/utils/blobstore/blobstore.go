@@ -0,0 +1,87 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("blobstore: object not found")
+	ErrInvalidKey = errors.New("blobstore: invalid object key")
+)
+
+// Object describes a stored blob
+type Object struct {
+	Key         string
+	ContentType string
+	Size        int64
+	ModifiedAt  time.Time
+}
+
+// Store keeps binary objects such as avatars under slash separated keys.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Put stores the content of r under key, replacing any existing object
+	Put(ctx context.Context, key, contentType string, r io.Reader) (*Object, error)
+	// Get opens the object for reading. The caller closes the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// Delete removes the object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Config selects and configures a Store backend
+type Config struct {
+	// Backend is the name of the store implementation, "local" (default) or "s3"
+	Backend string
+	// LocalDir is the root directory of the local backend
+	LocalDir string
+	// S3 configures the s3 backend
+	S3 S3Config
+}
+
+// ConfigFromEnv reads BLOBSTORE_BACKEND, BLOBSTORE_LOCAL_DIR and, for the s3
+// backend, BLOBSTORE_S3_ENDPOINT, _REGION, _BUCKET, _ACCESS_KEY, _SECRET_KEY and _PATH_STYLE
+func ConfigFromEnv() Config {
+	config := Config{
+		Backend:  "local",
+		LocalDir: "data/blobs",
+		S3:       s3ConfigFromEnv(),
+	}
+	if backend := os.Getenv("BLOBSTORE_BACKEND"); backend != "" {
+		config.Backend = backend
+	}
+	if dir := os.Getenv("BLOBSTORE_LOCAL_DIR"); dir != "" {
+		config.LocalDir = dir
+	}
+	return config
+}
+
+// New creates the store selected by config
+func New(config Config) (Store, error) {
+	switch config.Backend {
+	case "local":
+		return NewLocalStore(config.LocalDir)
+	case "s3":
+		return NewS3Store(config.S3)
+	default:
+		return nil, fmt.Errorf("blobstore: unknown backend %q", config.Backend)
+	}
+}
+
+// validateKey rejects keys that could escape the store's namespace
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
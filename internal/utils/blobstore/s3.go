@@ -0,0 +1,200 @@
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config points an S3Store at a bucket of S3 or an S3-compatible server such as MinIO
+type S3Config struct {
+	// Endpoint is the base URL of the service, e.g. https://s3.eu-west-1.amazonaws.com
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses objects as endpoint/bucket/key instead of bucket.endpoint/key,
+	// which most self-hosted servers require
+	PathStyle bool
+}
+
+// S3Store keeps objects in an S3 bucket using plain signed HTTP requests
+type S3Store struct {
+	config S3Config
+	base   *url.URL
+	signer SigV4Signer
+	client *http.Client
+}
+
+// NewS3Store creates a store for the configured bucket
+func NewS3Store(config S3Config) (*S3Store, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("blobstore: s3 endpoint and bucket are required")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	base, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return &S3Store{
+		config: config,
+		base:   base,
+		signer: SigV4Signer{
+			AccessKey: config.AccessKey,
+			SecretKey: config.SecretKey,
+			Region:    config.Region,
+			Service:   "s3",
+		},
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (s *S3Store) objectURL(key string) (*url.URL, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	u := *s.base
+	if s.config.PathStyle {
+		u.Path = u.Path + "/" + s.config.Bucket + "/" + key
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = u.Path + "/" + key
+	}
+	u.RawPath = ""
+	return &u, nil
+}
+
+func (s *S3Store) do(ctx context.Context, method, key, contentType string, body io.Reader, size int64) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.signer.Sign(req, UnsignedPayload, time.Now())
+	return s.client.Do(req)
+}
+
+func (s *S3Store) Put(ctx context.Context, key, contentType string, r io.Reader) (*Object, error) {
+	// S3 needs the length up front; spool readers that cannot tell it
+	body, size, cleanup, err := sizedReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	resp, err := s.do(ctx, http.MethodPut, key, contentType, body, size)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3Error(resp)
+	}
+	return &Object{Key: key, ContentType: contentType, Size: size, ModifiedAt: time.Now().UTC()}, nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, "", nil, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, nil, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, nil, s3Error(resp)
+	}
+	object := &Object{
+		Key:         key,
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		object.ModifiedAt = modified.UTC()
+	}
+	return resp.Body, object, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, "", nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func s3Error(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("blobstore: s3 responded %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
+
+// sizedReader returns r with its remaining length, copying it to a temporary
+// file when r cannot be measured
+func sizedReader(r io.Reader) (io.Reader, int64, func(), error) {
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		current, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			end, err := seeker.Seek(0, io.SeekEnd)
+			if err == nil {
+				if _, err := seeker.Seek(current, io.SeekStart); err == nil {
+					return seeker, end - current, func() {}, nil
+				}
+			}
+		}
+	}
+	tmp, err := os.CreateTemp("", "blobstore-*")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	size, err := io.Copy(tmp, r)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, err
+	}
+	return tmp, size, cleanup, nil
+}
+
+// s3ConfigFromEnv reads the BLOBSTORE_S3_* variables
+func s3ConfigFromEnv() S3Config {
+	pathStyle, _ := strconv.ParseBool(os.Getenv("BLOBSTORE_S3_PATH_STYLE"))
+	return S3Config{
+		Endpoint:  os.Getenv("BLOBSTORE_S3_ENDPOINT"),
+		Region:    os.Getenv("BLOBSTORE_S3_REGION"),
+		Bucket:    os.Getenv("BLOBSTORE_S3_BUCKET"),
+		AccessKey: os.Getenv("BLOBSTORE_S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("BLOBSTORE_S3_SECRET_KEY"),
+		PathStyle: pathStyle,
+	}
+}
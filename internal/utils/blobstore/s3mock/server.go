@@ -0,0 +1,183 @@
+// Package s3mock is a minimal in-memory stand-in for S3 and MinIO, for tests and
+// local development. It serves path-style PUT, GET, HEAD and DELETE of objects
+// and checks AWS Signature Version 4 on every request.
+package s3mock
+
+import (
+	"crypto/subtle"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/internal/utils/blobstore"
+)
+
+// maxClockSkew is how far a request's signing time may be from the server's clock
+const maxClockSkew = 15 * time.Minute
+
+type object struct {
+	data        []byte
+	contentType string
+	modifiedAt  time.Time
+}
+
+// Server holds buckets of objects in memory
+type Server struct {
+	accessKey string
+	secretKey string
+	region    string
+
+	mu      sync.Mutex
+	buckets map[string]map[string]object
+
+	httpServer *httptest.Server
+}
+
+// New creates a server accepting requests signed with the given credentials
+func New(accessKey, secretKey, region string) *Server {
+	return &Server{
+		accessKey: accessKey,
+		secretKey: secretKey,
+		region:    region,
+		buckets:   map[string]map[string]object{},
+	}
+}
+
+// Start serves on a random local port until Close is called
+func (s *Server) Start() {
+	s.httpServer = httptest.NewServer(s.Handler())
+}
+
+// Close stops a server started with Start
+func (s *Server) Close() {
+	if s.httpServer != nil {
+		s.httpServer.Close()
+	}
+}
+
+// URL is the endpoint of a started server
+func (s *Server) URL() string {
+	return s.httpServer.URL
+}
+
+// CreateBucket adds an empty bucket
+func (s *Server) CreateBucket(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.buckets[name]; !exists {
+		s.buckets[name] = map[string]object{}
+	}
+}
+
+// Keys lists the objects of a bucket
+func (s *Server) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.buckets[bucket]))
+	for key := range s.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// S3Config returns the configuration of a blobstore.S3Store using this server
+func (s *Server) S3Config(bucket string) blobstore.S3Config {
+	return blobstore.S3Config{
+		Endpoint:  s.URL(),
+		Region:    s.region,
+		Bucket:    bucket,
+		AccessKey: s.accessKey,
+		SecretKey: s.secretKey,
+		PathStyle: true,
+	}
+}
+
+// Handler returns the server's HTTP handler
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	s.mu.Lock()
+	objects, ok := s.buckets[bucket]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if key == "" {
+		writeError(w, http.StatusNotImplemented, "NotImplemented")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		s.mu.Lock()
+		objects[key] = object{data: data, contentType: r.Header.Get("Content-Type"), modifiedAt: time.Now().UTC()}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		s.mu.Lock()
+		obj, found := objects[key]
+		s.mu.Unlock()
+		if !found {
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modifiedAt.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj.data)
+		}
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(objects, key)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// authorized verifies the request's Signature Version 4 Authorization header
+func (s *Server) authorized(r *http.Request) bool {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	fields := map[string]string{}
+	for _, part := range strings.Split(auth, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		fields[name] = value
+	}
+	credential := strings.Split(fields["Credential"], "/")
+	if len(credential) != 5 || credential[0] != s.accessKey || credential[2] != s.region || credential[3] != "s3" {
+		return false
+	}
+	at, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil || time.Since(at).Abs() > maxClockSkew {
+		return false
+	}
+	signer := blobstore.SigV4Signer{AccessKey: s.accessKey, SecretKey: s.secretKey, Region: s.region, Service: "s3"}
+	expected := signer.Signature(r, r.Header.Get("X-Amz-Content-Sha256"), at, strings.Split(fields["SignedHeaders"], ";"))
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(fields["Signature"])) == 1
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, "<Error><Code>"+code+"</Code></Error>")
+}
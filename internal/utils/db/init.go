@@ -13,6 +13,9 @@ type TableDefinition struct {
 	CreateSQL    string
 	Indices      []string
 	Dependencies []string // Tables this table depends on (for foreign keys)
+	// Alterations bring tables created by older versions up to date. They run on
+	// every startup, so each statement must be idempotent (e.g. ADD COLUMN IF NOT EXISTS).
+	Alterations []string
 }
 
 // MigrationManager handles database schema migrations
@@ -35,6 +38,7 @@ func NewMigrationManager(db *sql.DB) *MigrationManager {
 	mgr.registerUserTables()
 	mgr.registerWorkspaceTables()
 	mgr.registerProjectTables()
+	mgr.registerServiceAccountTables()
 	return mgr
 }
 
@@ -48,7 +52,8 @@ func (m *MigrationManager) registerWorkspaceTables() {
 				id VARCHAR(255) PRIMARY KEY,
 				name VARCHAR(255) NOT NULL,
 				owner_id VARCHAR(255) NOT NULL,
-				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				archived_at TIMESTAMP
 			)
 		`,
 		Indices: []string{
@@ -56,6 +61,9 @@ func (m *MigrationManager) registerWorkspaceTables() {
 			`CREATE INDEX IF NOT EXISTS idx_workspace_name ON workspace(name)`,
 		},
 		Dependencies: []string{},
+		Alterations: []string{
+			`ALTER TABLE workspace ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP`,
+		},
 	})
 
 	// Membership table
@@ -125,6 +133,36 @@ func (m *MigrationManager) registerWorkspaceTables() {
 
 }
 
+// registerServiceAccountTables registers tables for non-human workspace members
+func (m *MigrationManager) registerServiceAccountTables() {
+	m.RegisterTable(TableDefinition{
+		Name: "service_account",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS service_account (
+				id VARCHAR(255) PRIMARY KEY,
+				workspace_id VARCHAR(255) NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				role VARCHAR(50) NOT NULL,
+				created_by VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				disabled_at TIMESTAMP,
+				CONSTRAINT fk_service_account_auth
+					FOREIGN KEY (id)
+					REFERENCES auth(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_service_account_workspace
+					FOREIGN KEY (workspace_id)
+					REFERENCES workspace(id)
+					ON DELETE CASCADE
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_service_account_workspace_id ON service_account(workspace_id)`,
+		},
+		Dependencies: []string{"auth", "workspace"},
+	})
+}
+
 // registerUserTables registers user-related tables
 func (m *MigrationManager) registerUserTables() {
 	// Auth table
@@ -134,13 +172,52 @@ func (m *MigrationManager) registerUserTables() {
 			CREATE TABLE IF NOT EXISTS auth (
 				id VARCHAR(255) PRIMARY KEY,
 				email VARCHAR(255) NOT NULL UNIQUE,
+				password_hash TEXT,
+				password_updated_at TIMESTAMP,
+				totp_secret TEXT,
+				totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+				totp_last_step BIGINT NOT NULL DEFAULT 0,
+				magic_link_enabled BOOLEAN NOT NULL DEFAULT TRUE,
 				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 			)
 		`,
 		Indices: []string{
 			`CREATE INDEX IF NOT EXISTS idx_auth_email ON auth(email)`,
+			`CREATE INDEX IF NOT EXISTS idx_auth_email_lower ON auth(LOWER(email))`,
 		},
 		Dependencies: []string{},
+		Alterations: []string{
+			`ALTER TABLE auth ADD COLUMN IF NOT EXISTS password_hash TEXT`,
+			`ALTER TABLE auth ADD COLUMN IF NOT EXISTS password_updated_at TIMESTAMP`,
+			`ALTER TABLE auth ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64)`,
+			`ALTER TABLE auth ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE`,
+			// sealed secrets are longer than the base32 secrets stored before them
+			`ALTER TABLE auth ALTER COLUMN totp_secret TYPE TEXT`,
+			`ALTER TABLE auth ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE auth ADD COLUMN IF NOT EXISTS magic_link_enabled BOOLEAN NOT NULL DEFAULT TRUE`,
+		},
+	})
+
+	// Recovery code table, single-use fallbacks for TOTP
+	m.RegisterTable(TableDefinition{
+		Name: "recovery_code",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS recovery_code (
+				id VARCHAR(255) PRIMARY KEY,
+				user_id VARCHAR(255) NOT NULL,
+				code_hash VARCHAR(64) NOT NULL,
+				used_at TIMESTAMP,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				CONSTRAINT fk_recovery_code_user
+					FOREIGN KEY (user_id)
+					REFERENCES auth(id)
+					ON DELETE CASCADE
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_recovery_code_user_id ON recovery_code(user_id)`,
+		},
+		Dependencies: []string{"auth"},
 	})
 
 	// User Profile table
@@ -150,6 +227,12 @@ func (m *MigrationManager) registerUserTables() {
 			CREATE TABLE IF NOT EXISTS user_profile (
 				id VARCHAR(255) PRIMARY KEY,
 				name VARCHAR(255) NOT NULL DEFAULT '',
+				title VARCHAR(100) NOT NULL DEFAULT '',
+				bio TEXT NOT NULL DEFAULT '',
+				timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+				locale VARCHAR(35) NOT NULL DEFAULT 'en',
+				avatar_key VARCHAR(512),
+				avatar_updated_at TIMESTAMP,
 				CONSTRAINT fk_user_profile_auth
 					FOREIGN KEY (id)
 					REFERENCES auth(id)
@@ -158,6 +241,14 @@ func (m *MigrationManager) registerUserTables() {
 		`,
 		Indices:      []string{},
 		Dependencies: []string{"auth"},
+		Alterations: []string{
+			`ALTER TABLE user_profile ADD COLUMN IF NOT EXISTS title VARCHAR(100) NOT NULL DEFAULT ''`,
+			`ALTER TABLE user_profile ADD COLUMN IF NOT EXISTS bio TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE user_profile ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC'`,
+			`ALTER TABLE user_profile ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT 'en'`,
+			`ALTER TABLE user_profile ADD COLUMN IF NOT EXISTS avatar_key VARCHAR(512)`,
+			`ALTER TABLE user_profile ADD COLUMN IF NOT EXISTS avatar_updated_at TIMESTAMP`,
+		},
 	})
 	m.RegisterTable(TableDefinition{
 		Name: "invalid_token",
@@ -174,6 +265,137 @@ func (m *MigrationManager) registerUserTables() {
 		},
 		Dependencies: []string{},
 	})
+	// Used token table, records the JTI of single-use tokens once redeemed
+	m.RegisterTable(TableDefinition{
+		Name: "used_token",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS used_token (
+				jti VARCHAR(255) PRIMARY KEY,
+				user_id VARCHAR(255) NOT NULL,
+				purpose VARCHAR(50) NOT NULL,
+				used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMP NOT NULL
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_used_token_expires_at ON used_token(expires_at)`,
+		},
+		Dependencies: []string{},
+	})
+	// Auth attempt table, used to rate limit authentication requests
+	m.RegisterTable(TableDefinition{
+		Name: "auth_attempt",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS auth_attempt (
+				id BIGSERIAL PRIMARY KEY,
+				kind VARCHAR(50) NOT NULL,
+				email VARCHAR(255) NOT NULL,
+				ip_address VARCHAR(64) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_auth_attempt_email ON auth_attempt(kind, email, created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_auth_attempt_ip ON auth_attempt(kind, ip_address, created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_auth_attempt_created_at ON auth_attempt(created_at)`,
+		},
+		Dependencies: []string{},
+	})
+	// Session table, one row per refresh-token family
+	m.RegisterTable(TableDefinition{
+		Name: "session",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS session (
+				id VARCHAR(255) PRIMARY KEY,
+				user_id VARCHAR(255) NOT NULL,
+				user_agent TEXT NOT NULL DEFAULT '',
+				ip_address VARCHAR(64) NOT NULL DEFAULT '',
+				refresh_token_hash VARCHAR(64) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMP NOT NULL,
+				revoked_at TIMESTAMP,
+				CONSTRAINT fk_session_user
+					FOREIGN KEY (user_id)
+					REFERENCES auth(id)
+					ON DELETE CASCADE
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_session_user_id ON session(user_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_session_expires_at ON session(expires_at)`,
+		},
+		Dependencies: []string{"auth"},
+	})
+
+	// OIDC login state table (pending authorization requests)
+	m.RegisterTable(TableDefinition{
+		Name: "oidc_login_state",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS oidc_login_state (
+				state VARCHAR(255) PRIMARY KEY,
+				provider VARCHAR(100) NOT NULL,
+				code_verifier VARCHAR(255) NOT NULL,
+				nonce VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMP NOT NULL
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_oidc_login_state_expires_at ON oidc_login_state(expires_at)`,
+		},
+		Dependencies: []string{},
+	})
+
+	// Signing key table (JWT key ring)
+	m.RegisterTable(TableDefinition{
+		Name: "signing_key",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS signing_key (
+				id VARCHAR(255) PRIMARY KEY,
+				algorithm VARCHAR(20) NOT NULL,
+				private_key TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				activates_at TIMESTAMP NOT NULL,
+				retires_at TIMESTAMP NOT NULL,
+				expires_at TIMESTAMP NOT NULL
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_signing_key_expires_at ON signing_key(expires_at)`,
+		},
+		Dependencies: []string{},
+	})
+
+	// API token table (personal access tokens and service account tokens)
+	m.RegisterTable(TableDefinition{
+		Name: "api_token",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS api_token (
+				id VARCHAR(255) PRIMARY KEY,
+				kind VARCHAR(50) NOT NULL,
+				user_id VARCHAR(255) NOT NULL,
+				created_by VARCHAR(255) NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				prefix VARCHAR(32) NOT NULL,
+				token_hash VARCHAR(64) NOT NULL UNIQUE,
+				scopes TEXT NOT NULL DEFAULT '',
+				expires_at TIMESTAMP,
+				last_used_at TIMESTAMP,
+				last_used_ip VARCHAR(64) NOT NULL DEFAULT '',
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				revoked_at TIMESTAMP,
+				CONSTRAINT fk_api_token_user
+					FOREIGN KEY (user_id)
+					REFERENCES auth(id)
+					ON DELETE CASCADE
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_api_token_user_id ON api_token(user_id)`,
+		},
+		Dependencies: []string{"auth"},
+	})
 }
 func (m *MigrationManager) registerProjectTables() {
 	// Project table
@@ -187,6 +409,10 @@ func (m *MigrationManager) registerProjectTables() {
 				workspace_id VARCHAR(255) NOT NULL,
 				creator VARCHAR(255) NOT NULL,
 				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				deleted_at TIMESTAMP,
+				deleted_by VARCHAR(255),
+				archived_at TIMESTAMP,
+				version INTEGER NOT NULL DEFAULT 1,
 				CONSTRAINT fk_project_workspace
 					FOREIGN KEY (workspace_id)
 					REFERENCES workspace(id)
@@ -194,15 +420,23 @@ func (m *MigrationManager) registerProjectTables() {
 				CONSTRAINT fk_project_creator
 					FOREIGN KEY (creator)
 					REFERENCES auth(id)
-					ON DELETE SET NULL
+					ON DELETE RESTRICT
 			)
 		`,
 		Indices: []string{
 			`CREATE INDEX IF NOT EXISTS idx_project_workspace_id ON project(workspace_id)`,
 			`CREATE INDEX IF NOT EXISTS idx_project_creator ON project(creator)`,
 			`CREATE INDEX IF NOT EXISTS idx_project_name ON project(name)`,
+			`CREATE INDEX IF NOT EXISTS idx_project_deleted_at ON project(deleted_at) WHERE deleted_at IS NOT NULL`,
 		},
 		Dependencies: []string{"workspace", "auth"},
+		Alterations: []string{
+			restrictCreatorOnDelete("project", "fk_project_creator"),
+			`ALTER TABLE project ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+			`ALTER TABLE project ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(255)`,
+			`ALTER TABLE project ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP`,
+			`ALTER TABLE project ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+		},
 	})
 	// Task table
 	m.RegisterTable(TableDefinition{
@@ -220,6 +454,13 @@ func (m *MigrationManager) registerProjectTables() {
 				due_date TIMESTAMP,
 				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 				updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				custom_fields JSONB NOT NULL DEFAULT '{}',
+				original_estimate_minutes INTEGER,
+				remaining_estimate_minutes INTEGER,
+				milestone_id VARCHAR(255),
+				deleted_at TIMESTAMP,
+				deleted_by VARCHAR(255),
+				version INTEGER NOT NULL DEFAULT 1,
 				CONSTRAINT fk_task_parent
 					FOREIGN KEY (parent_id)
 					REFERENCES task(id)
@@ -231,7 +472,7 @@ func (m *MigrationManager) registerProjectTables() {
 				CONSTRAINT fk_task_creator
 					FOREIGN KEY (creator)
 					REFERENCES auth(id)
-					ON DELETE SET NULL
+					ON DELETE RESTRICT
 			)
 		`,
 		Indices: []string{
@@ -241,9 +482,549 @@ func (m *MigrationManager) registerProjectTables() {
 			`CREATE INDEX IF NOT EXISTS idx_task_priority ON task(priority)`,
 			`CREATE INDEX IF NOT EXISTS idx_task_due_date ON task(due_date)`,
 			`CREATE INDEX IF NOT EXISTS idx_task_creator ON task(creator)`,
+			// equality filters on custom fields; each field also gets a typed
+			// expression index when it is defined
+			`CREATE INDEX IF NOT EXISTS idx_task_custom_fields ON task USING GIN (custom_fields jsonb_path_ops)`,
+			// the trash and the purge job look up deleted tasks only
+			`CREATE INDEX IF NOT EXISTS idx_task_deleted_at ON task(deleted_at) WHERE deleted_at IS NOT NULL`,
 		},
 		Dependencies: []string{"project"},
+		Alterations: []string{
+			restrictCreatorOnDelete("task", "fk_task_creator"),
+			`ALTER TABLE task ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}'`,
+			`ALTER TABLE task ADD COLUMN IF NOT EXISTS original_estimate_minutes INTEGER`,
+			`ALTER TABLE task ADD COLUMN IF NOT EXISTS remaining_estimate_minutes INTEGER`,
+			`ALTER TABLE task ADD COLUMN IF NOT EXISTS milestone_id VARCHAR(255)`,
+			`ALTER TABLE task ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+			`ALTER TABLE task ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(255)`,
+			`ALTER TABLE task ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+		},
+	})
+	// Milestone table, milestones and sprints of a project
+	m.RegisterTable(TableDefinition{
+		Name: "milestone",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS milestone (
+				id VARCHAR(255) PRIMARY KEY,
+				project_id VARCHAR(255) NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				kind VARCHAR(20) NOT NULL,
+				description TEXT NOT NULL DEFAULT '',
+				start_date DATE NOT NULL,
+				end_date DATE NOT NULL,
+				created_by VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				CONSTRAINT fk_milestone_project
+					FOREIGN KEY (project_id)
+					REFERENCES project(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_milestone_creator
+					FOREIGN KEY (created_by)
+					REFERENCES auth(id)
+					ON DELETE RESTRICT
+			)
+		`,
+		// the task column predates this table, so its constraint and index are
+		// created here, where they run on fresh and existing databases alike
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_milestone_project_id ON milestone(project_id)`,
+			`DO $$
+			BEGIN
+				IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_task_milestone') THEN
+					ALTER TABLE task ADD CONSTRAINT fk_task_milestone
+						FOREIGN KEY (milestone_id) REFERENCES milestone(id) ON DELETE SET NULL;
+				END IF;
+			END $$`,
+			`CREATE INDEX IF NOT EXISTS idx_task_milestone_id ON task(milestone_id)`,
+		},
+		Dependencies: []string{"project", "task", "auth"},
+	})
+	// Task assignment table. Assignments of a deleted account go with it.
+	m.RegisterTable(TableDefinition{
+		Name: "task_assignment",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS task_assignment (
+				id VARCHAR(255) PRIMARY KEY,
+				task_id VARCHAR(255) NOT NULL,
+				assigner VARCHAR(255) NOT NULL,
+				assignee VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				CONSTRAINT fk_task_assignment_task
+					FOREIGN KEY (task_id)
+					REFERENCES task(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_task_assignment_assigner
+					FOREIGN KEY (assigner)
+					REFERENCES auth(id)
+					ON DELETE RESTRICT,
+				CONSTRAINT fk_task_assignment_assignee
+					FOREIGN KEY (assignee)
+					REFERENCES auth(id)
+					ON DELETE CASCADE
+			)
+		`,
+		Indices: []string{
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_task_assignment_task_assignee ON task_assignment(task_id, assignee)`,
+			`CREATE INDEX IF NOT EXISTS idx_task_assignment_assignee ON task_assignment(assignee)`,
+		},
+		Dependencies: []string{"task", "auth"},
+	})
+	// Task activity table, the history of task changes. Rows outlive the
+	// milestones they mention so burndowns can be replayed.
+	m.RegisterTable(TableDefinition{
+		Name: "task_activity",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS task_activity (
+				id VARCHAR(255) PRIMARY KEY,
+				task_id VARCHAR(255) NOT NULL,
+				actor_id VARCHAR(255) NOT NULL,
+				kind VARCHAR(50) NOT NULL,
+				from_value TEXT,
+				to_value TEXT,
+				status VARCHAR(50) NOT NULL,
+				milestone_id VARCHAR(255),
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				CONSTRAINT fk_task_activity_task
+					FOREIGN KEY (task_id)
+					REFERENCES task(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_task_activity_actor
+					FOREIGN KEY (actor_id)
+					REFERENCES auth(id)
+					ON DELETE RESTRICT
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_task_activity_task_id ON task_activity(task_id, created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_task_activity_milestone_id ON task_activity(milestone_id)`,
+		},
+		Dependencies: []string{"task", "auth"},
+	})
+	// Work log table, time users spent on tasks
+	m.RegisterTable(TableDefinition{
+		Name: "work_log",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS work_log (
+				id VARCHAR(255) PRIMARY KEY,
+				task_id VARCHAR(255) NOT NULL,
+				user_id VARCHAR(255) NOT NULL,
+				started_at TIMESTAMP NOT NULL,
+				minutes INTEGER NOT NULL CHECK (minutes > 0),
+				description TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				CONSTRAINT fk_work_log_task
+					FOREIGN KEY (task_id)
+					REFERENCES task(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_work_log_user
+					FOREIGN KEY (user_id)
+					REFERENCES auth(id)
+					ON DELETE RESTRICT
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_work_log_task_id ON work_log(task_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_work_log_user_started_at ON work_log(user_id, started_at)`,
+		},
+		Dependencies: []string{"task", "auth"},
 	})
+	// Work timer table, at most one running timer per user
+	m.RegisterTable(TableDefinition{
+		Name: "work_timer",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS work_timer (
+				user_id VARCHAR(255) PRIMARY KEY,
+				task_id VARCHAR(255) NOT NULL,
+				started_at TIMESTAMP NOT NULL,
+				description TEXT NOT NULL DEFAULT '',
+				CONSTRAINT fk_work_timer_user
+					FOREIGN KEY (user_id)
+					REFERENCES auth(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_work_timer_task
+					FOREIGN KEY (task_id)
+					REFERENCES task(id)
+					ON DELETE CASCADE
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_work_timer_task_id ON work_timer(task_id)`,
+		},
+		Dependencies: []string{"task", "auth"},
+	})
+	// Custom field table, the fields a project defines for its tasks
+	m.RegisterTable(TableDefinition{
+		Name: "custom_field",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS custom_field (
+				id VARCHAR(255) PRIMARY KEY,
+				project_id VARCHAR(255) NOT NULL,
+				key VARCHAR(40) NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				type VARCHAR(20) NOT NULL,
+				options JSONB NOT NULL DEFAULT '[]',
+				required BOOLEAN NOT NULL DEFAULT FALSE,
+				position INTEGER NOT NULL DEFAULT 0,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				indexed BOOLEAN NOT NULL DEFAULT FALSE,
+				CONSTRAINT fk_custom_field_project
+					FOREIGN KEY (project_id)
+					REFERENCES project(id)
+					ON DELETE CASCADE
+			)
+		`,
+		Indices: []string{
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_custom_field_project_key ON custom_field(project_id, key)`,
+			// the fields whose task index is still to be built
+			`CREATE INDEX IF NOT EXISTS idx_custom_field_unindexed ON custom_field(created_at) WHERE NOT indexed`,
+		},
+		Dependencies: []string{"project"},
+		Alterations: []string{
+			// fields created before the flag existed were indexed along with them
+			`ALTER TABLE custom_field ADD COLUMN IF NOT EXISTS indexed BOOLEAN NOT NULL DEFAULT TRUE`,
+			`ALTER TABLE custom_field ALTER COLUMN indexed SET DEFAULT FALSE`,
+		},
+	})
+	// Blob table, one row per stored file content shared by its attachments
+	m.RegisterTable(TableDefinition{
+		Name: "blob",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS blob (
+				key VARCHAR(512) PRIMARY KEY,
+				size BIGINT NOT NULL,
+				content_type VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				last_referenced_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				stored BOOLEAN NOT NULL DEFAULT FALSE
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_blob_last_referenced_at ON blob(last_referenced_at)`,
+		},
+		Dependencies: []string{},
+		Alterations: []string{
+			// blobs recorded before the flag existed were stored before being recorded
+			`ALTER TABLE blob ADD COLUMN IF NOT EXISTS stored BOOLEAN NOT NULL DEFAULT TRUE`,
+			`ALTER TABLE blob ALTER COLUMN stored SET DEFAULT FALSE`,
+		},
+	})
+	// Task attachment table
+	m.RegisterTable(TableDefinition{
+		Name: "task_attachment",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS task_attachment (
+				id VARCHAR(255) PRIMARY KEY,
+				task_id VARCHAR(255) NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				content_type VARCHAR(255) NOT NULL,
+				size BIGINT NOT NULL,
+				sha256 VARCHAR(64) NOT NULL,
+				blob_key VARCHAR(512) NOT NULL,
+				uploaded_by VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				CONSTRAINT fk_task_attachment_task
+					FOREIGN KEY (task_id)
+					REFERENCES task(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_task_attachment_blob
+					FOREIGN KEY (blob_key)
+					REFERENCES blob(key)
+					ON DELETE RESTRICT,
+				CONSTRAINT fk_task_attachment_uploader
+					FOREIGN KEY (uploaded_by)
+					REFERENCES auth(id)
+					ON DELETE RESTRICT
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_task_attachment_task_id ON task_attachment(task_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_task_attachment_blob_key ON task_attachment(blob_key)`,
+		},
+		Dependencies: []string{"task", "blob", "auth"},
+	})
+	// Label table
+	m.RegisterTable(TableDefinition{
+		Name: "label",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS label (
+				id VARCHAR(255) PRIMARY KEY,
+				workspace_id VARCHAR(255) NOT NULL,
+				name VARCHAR(50) NOT NULL,
+				color VARCHAR(7) NOT NULL,
+				created_by VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				CONSTRAINT fk_label_workspace
+					FOREIGN KEY (workspace_id)
+					REFERENCES workspace(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_label_creator
+					FOREIGN KEY (created_by)
+					REFERENCES auth(id)
+					ON DELETE RESTRICT
+			)
+		`,
+		Indices: []string{
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_label_workspace_name ON label(workspace_id, LOWER(name))`,
+		},
+		Dependencies: []string{"workspace", "auth"},
+	})
+	// Task label table
+	m.RegisterTable(TableDefinition{
+		Name: "task_label",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS task_label (
+				task_id VARCHAR(255) NOT NULL,
+				label_id VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (task_id, label_id),
+				CONSTRAINT fk_task_label_task
+					FOREIGN KEY (task_id)
+					REFERENCES task(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_task_label_label
+					FOREIGN KEY (label_id)
+					REFERENCES label(id)
+					ON DELETE CASCADE
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_task_label_label_id ON task_label(label_id)`,
+		},
+		Dependencies: []string{"task", "label"},
+	})
+	// Task template table. Tasks are stored as a JSON tree, since templates are
+	// only ever read and written whole.
+	m.RegisterTable(TableDefinition{
+		Name: "task_template",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS task_template (
+				id VARCHAR(255) PRIMARY KEY,
+				workspace_id VARCHAR(255) NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				description TEXT NOT NULL DEFAULT '',
+				tasks JSONB NOT NULL DEFAULT '[]'::jsonb,
+				created_by VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				CONSTRAINT fk_task_template_workspace
+					FOREIGN KEY (workspace_id)
+					REFERENCES workspace(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_task_template_creator
+					FOREIGN KEY (created_by)
+					REFERENCES auth(id)
+					ON DELETE RESTRICT
+			)
+		`,
+		Indices: []string{
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_task_template_workspace_name ON task_template(workspace_id, LOWER(name))`,
+		},
+		Dependencies: []string{"workspace", "auth"},
+	})
+	// Task comment table
+	m.RegisterTable(TableDefinition{
+		Name: "task_comment",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS task_comment (
+				id VARCHAR(255) PRIMARY KEY,
+				task_id VARCHAR(255) NOT NULL,
+				author VARCHAR(255) NOT NULL,
+				content TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				CONSTRAINT fk_task_comment_task
+					FOREIGN KEY (task_id)
+					REFERENCES task(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_task_comment_author
+					FOREIGN KEY (author)
+					REFERENCES auth(id)
+					ON DELETE RESTRICT
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_task_comment_task_id ON task_comment(task_id, created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_task_comment_author ON task_comment(author)`,
+		},
+		Dependencies: []string{"task", "auth"},
+	})
+	// Task mention table, the members mentioned in a task's description or in
+	// one of its comments. Mentions of a deleted account go with it.
+	m.RegisterTable(TableDefinition{
+		Name: "task_mention",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS task_mention (
+				id VARCHAR(255) PRIMARY KEY,
+				task_id VARCHAR(255) NOT NULL,
+				comment_id VARCHAR(255),
+				user_id VARCHAR(255) NOT NULL,
+				author_id VARCHAR(255) NOT NULL,
+				handle VARCHAR(320) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				CONSTRAINT fk_task_mention_task
+					FOREIGN KEY (task_id)
+					REFERENCES task(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_task_mention_comment
+					FOREIGN KEY (comment_id)
+					REFERENCES task_comment(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_task_mention_user
+					FOREIGN KEY (user_id)
+					REFERENCES auth(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_task_mention_author
+					FOREIGN KEY (author_id)
+					REFERENCES auth(id)
+					ON DELETE RESTRICT
+			)
+		`,
+		Indices: []string{
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_task_mention_source_user ON task_mention(task_id, COALESCE(comment_id, ''), user_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_task_mention_comment_id ON task_mention(comment_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_task_mention_user_id ON task_mention(user_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_task_mention_author_id ON task_mention(author_id)`,
+		},
+		Dependencies: []string{"task", "task_comment", "auth"},
+	})
+	// Notification table, one row per event a user is told about in a workspace.
+	// Notifications of a deleted account go with it.
+	m.RegisterTable(TableDefinition{
+		Name: "notification",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS notification (
+				id VARCHAR(255) PRIMARY KEY,
+				user_id VARCHAR(255) NOT NULL,
+				workspace_id VARCHAR(255) NOT NULL,
+				kind VARCHAR(50) NOT NULL,
+				task_id VARCHAR(255) NOT NULL,
+				comment_id VARCHAR(255),
+				actor_id VARCHAR(255) NOT NULL,
+				detail TEXT NOT NULL DEFAULT '',
+				read_at TIMESTAMP,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				CONSTRAINT fk_notification_user
+					FOREIGN KEY (user_id)
+					REFERENCES auth(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_notification_workspace
+					FOREIGN KEY (workspace_id)
+					REFERENCES workspace(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_notification_task
+					FOREIGN KEY (task_id)
+					REFERENCES task(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_notification_comment
+					FOREIGN KEY (comment_id)
+					REFERENCES task_comment(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_notification_actor
+					FOREIGN KEY (actor_id)
+					REFERENCES auth(id)
+					ON DELETE RESTRICT
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_notification_user_workspace ON notification(user_id, workspace_id, created_at DESC)`,
+			`CREATE INDEX IF NOT EXISTS idx_notification_unread ON notification(user_id, workspace_id) WHERE read_at IS NULL`,
+			`CREATE INDEX IF NOT EXISTS idx_notification_actor_id ON notification(actor_id)`,
+		},
+		Alterations: []string{
+			`ALTER TABLE notification ADD COLUMN IF NOT EXISTS detail TEXT NOT NULL DEFAULT ''`,
+		},
+		Dependencies: []string{"auth", "workspace", "task", "task_comment"},
+	})
+	// Task watcher table, the users told about a task's activity. Watches of a
+	// deleted account go with it.
+	m.RegisterTable(TableDefinition{
+		Name: "task_watcher",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS task_watcher (
+				task_id VARCHAR(255) NOT NULL,
+				user_id VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (task_id, user_id),
+				CONSTRAINT fk_task_watcher_task
+					FOREIGN KEY (task_id)
+					REFERENCES task(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_task_watcher_user
+					FOREIGN KEY (user_id)
+					REFERENCES auth(id)
+					ON DELETE CASCADE
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_task_watcher_user_id ON task_watcher(user_id)`,
+		},
+		Dependencies: []string{"task", "auth"},
+	})
+	// Project watcher table, the users told about the activity of every task of a project
+	m.RegisterTable(TableDefinition{
+		Name: "project_watcher",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS project_watcher (
+				project_id VARCHAR(255) NOT NULL,
+				user_id VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (project_id, user_id),
+				CONSTRAINT fk_project_watcher_project
+					FOREIGN KEY (project_id)
+					REFERENCES project(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_project_watcher_user
+					FOREIGN KEY (user_id)
+					REFERENCES auth(id)
+					ON DELETE CASCADE
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_project_watcher_user_id ON project_watcher(user_id)`,
+		},
+		Dependencies: []string{"project", "auth"},
+	})
+	// Task due reminder table, the due date each assignee was last reminded of.
+	// Moving the due date makes the assignee due another reminder.
+	m.RegisterTable(TableDefinition{
+		Name: "task_due_reminder",
+		CreateSQL: `
+			CREATE TABLE IF NOT EXISTS task_due_reminder (
+				task_id VARCHAR(255) NOT NULL,
+				user_id VARCHAR(255) NOT NULL,
+				due_date TIMESTAMP NOT NULL,
+				sent_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (task_id, user_id),
+				CONSTRAINT fk_task_due_reminder_task
+					FOREIGN KEY (task_id)
+					REFERENCES task(id)
+					ON DELETE CASCADE,
+				CONSTRAINT fk_task_due_reminder_user
+					FOREIGN KEY (user_id)
+					REFERENCES auth(id)
+					ON DELETE CASCADE
+			)
+		`,
+		Indices: []string{
+			`CREATE INDEX IF NOT EXISTS idx_task_due_reminder_user_id ON task_due_reminder(user_id)`,
+		},
+		Dependencies: []string{"task", "auth"},
+	})
+}
+
+// restrictCreatorOnDelete replaces the ON DELETE SET NULL creator constraint of
+// older schemas, which could never succeed on the NOT NULL column. Accounts are
+// deleted by first reassigning their content to the ghost user.
+func restrictCreatorOnDelete(table, constraint string) string {
+	return fmt.Sprintf(`
+		DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '%[2]s' AND confdeltype = 'n') THEN
+				ALTER TABLE %[1]s DROP CONSTRAINT %[2]s;
+				ALTER TABLE %[1]s ADD CONSTRAINT %[2]s
+					FOREIGN KEY (creator) REFERENCES auth(id) ON DELETE RESTRICT;
+			END IF;
+		END $$
+	`, table, constraint)
 }
 
 // RegisterTable adds a new table definition to the migration manager
@@ -290,6 +1071,21 @@ func (m *MigrationManager) createTable(table TableDefinition) error {
 	return nil
 }
 
+// migrateTable applies a table's alterations and creates any indices added since it was created
+func (m *MigrationManager) migrateTable(table TableDefinition) error {
+	for _, alterSQL := range table.Alterations {
+		if _, err := m.db.Exec(alterSQL); err != nil {
+			return fmt.Errorf("failed to alter table %s: %w", table.Name, err)
+		}
+	}
+	for _, indexSQL := range table.Indices {
+		if _, err := m.db.Exec(indexSQL); err != nil {
+			return fmt.Errorf("failed to create index for table %s: %w", table.Name, err)
+		}
+	}
+	return nil
+}
+
 // sortTablesByDependencies returns tables sorted by their dependencies
 // Tables without dependencies come first, then tables that depend on them
 func (m *MigrationManager) sortTablesByDependencies() []TableDefinition {
@@ -357,6 +1153,9 @@ func (m *MigrationManager) EnsureTablesExist() error {
 			}
 		} else {
 			m.logger.Info(fmt.Sprintf("Table '%s' already exists", table.Name))
+			if err := m.migrateTable(table); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -0,0 +1,123 @@
+// Package imaging decodes untrusted uploads and produces resized copies of them
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"  // register the GIF decoder
+	_ "image/jpeg" // register the JPEG decoder
+	"image/png"
+	"io"
+)
+
+var (
+	ErrTooLarge          = errors.New("imaging: image is too large")
+	ErrUnsupportedFormat = errors.New("imaging: unsupported image format")
+)
+
+// Limits bound what Decode accepts
+type Limits struct {
+	// MaxBytes is the largest encoded size
+	MaxBytes int64
+	// MaxDimension is the largest width or height, which bounds the memory a
+	// small but highly compressed image can claim once decoded
+	MaxDimension int
+	// Formats lists the accepted formats as named by image.Decode ("png", "jpeg", "gif")
+	Formats []string
+}
+
+// Decode reads an image from r, checking its size, format and dimensions before
+// decoding the pixels
+func Decode(r io.Reader, limits Limits) (image.Image, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limits.MaxBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > limits.MaxBytes {
+		return nil, "", ErrTooLarge
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedFormat
+	}
+	if !accepts(limits.Formats, format) {
+		return nil, "", ErrUnsupportedFormat
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > limits.MaxDimension || config.Height > limits.MaxDimension {
+		return nil, "", ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedFormat
+	}
+	return img, format, nil
+}
+
+func accepts(formats []string, format string) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// SquareThumbnail crops the centre square of img and scales it to size x size
+func SquareThumbnail(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+	src := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(src, src.Bounds(), img, crop.Min, draw.Src)
+	return resize(src, size, size)
+}
+
+// resize scales src to width x height, averaging every source pixel a destination
+// pixel covers. Enlarging falls back to the nearest source pixel.
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := max((y+1)*srcH/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := max((x+1)*srcW/width, x0+1)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					b += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// EncodePNG encodes img as a PNG
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,65 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+)
+
+// JSONWebKey is the public half of a ring key as published in the JWKS
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet is the document served at /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWK encodes the key's public half
+func (k *SigningKey) JWK() JSONWebKey {
+	jwk := JSONWebKey{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	switch public := k.PublicKey().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// JWKS returns the public keys of every unexpired key in the ring
+func (k *KeyRing) JWKS() JSONWebKeySet {
+	keys := k.VerificationKeys()
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keys))}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, key.JWK())
+	}
+	return set
+}
+
+// ServeJWKS serves the key set so other services can verify tokens without a
+// shared secret. New keys are published ahead of use, so a short cache is safe.
+func (k *KeyRing) ServeJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(k.JWKS()); err != nil {
+		http.Error(w, "Failed to write response", http.StatusInternalServerError)
+	}
+}
@@ -8,10 +8,13 @@ import (
 	"github.com/google/uuid"
 )
 
+// JWTUtils signs tokens with the current key of its key ring. SecretKey is only
+// used to keep verifying HS256 tokens issued before asymmetric signing.
 type JWTUtils struct {
 	SecretKey string
 	Issuer    string
 	Config    TokenConfig
+	Keys      *KeyRing
 }
 
 type TokenPurpose string
@@ -21,14 +24,20 @@ const (
 	PurposeAuth       TokenPurpose = "auth"
 	PurposeRefresh    TokenPurpose = "refresh"
 	PurposeInvitation TokenPurpose = "invitation"
+	// PurposePasswordReset tokens are emailed to let a user set a new password
+	PurposePasswordReset TokenPurpose = "password_reset"
+	// PurposeMFA tokens prove the first factor passed while the second is pending
+	PurposeMFA TokenPurpose = "mfa"
 )
 
 // TokenConfig holds expiration durations for different token purposes
 type TokenConfig struct {
-	AccessTokenDuration     time.Duration
-	AuthTokenDuration       time.Duration
-	RefreshTokenDuration    time.Duration
-	InvitationTokenDuration time.Duration
+	AccessTokenDuration        time.Duration
+	AuthTokenDuration          time.Duration
+	RefreshTokenDuration       time.Duration
+	InvitationTokenDuration    time.Duration
+	PasswordResetTokenDuration time.Duration
+	MFATokenDuration           time.Duration
 }
 
 // BaseClaims contains common fields for all token types
@@ -52,6 +61,10 @@ func (j *JWTUtils) newBaseRegisteredClaims(purpose TokenPurpose, subject string)
 		expiresAt = now.Add(j.Config.RefreshTokenDuration)
 	case PurposeInvitation:
 		expiresAt = now.Add(j.Config.InvitationTokenDuration)
+	case PurposePasswordReset:
+		expiresAt = now.Add(j.Config.PasswordResetTokenDuration)
+	case PurposeMFA:
+		expiresAt = now.Add(j.Config.MFATokenDuration)
 	default:
 		expiresAt = now.Add(j.Config.AccessTokenDuration)
 	}
@@ -67,10 +80,12 @@ func (j *JWTUtils) newBaseRegisteredClaims(purpose TokenPurpose, subject string)
 	}
 }
 
-// UserClaims for authentication tokens
+// UserClaims for authentication tokens. SessionID is set on access and refresh
+// tokens and names the session (refresh-token family) they were issued for.
 type UserClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid,omitempty"`
 	BaseClaims
 }
 
@@ -88,14 +103,17 @@ type InvitationClaims struct {
 // DefaultTokenConfig returns sensible defaults for token durations
 func DefaultTokenConfig() TokenConfig {
 	return TokenConfig{
-		AccessTokenDuration:     15 * 4 * 24 * 365 * time.Minute, // Short-lived for security
-		AuthTokenDuration:       15 * time.Minute,                // Medium-lived for authentication flows
-		RefreshTokenDuration:    7 * 24 * time.Hour,              // Long-lived (7 days)
-		InvitationTokenDuration: 24 * time.Hour,                  // Short-lived for invitation links
+		AccessTokenDuration:        15 * time.Minute,   // Short-lived for security
+		AuthTokenDuration:          15 * time.Minute,   // Medium-lived for authentication flows
+		RefreshTokenDuration:       7 * 24 * time.Hour, // Long-lived (7 days)
+		InvitationTokenDuration:    24 * time.Hour,     // Short-lived for invitation links
+		PasswordResetTokenDuration: time.Hour,          // Matches the expiry stated in the reset email
+		MFATokenDuration:           5 * time.Minute,    // Time allowed to enter the second factor
 	}
 }
 
-// NewJWTUtils creates a new JWTUtils with the provided secret key and issuer
+// NewJWTUtils creates a new JWTUtils using the default key ring and the issuer
+// and legacy secret from the environment
 func NewJWTUtils(config TokenConfig) *JWTUtils {
 	secretKey := os.Getenv("JWT_SECRET_KEY")
 	issuer := os.Getenv("JWT_ISSUER")
@@ -106,27 +124,48 @@ func NewJWTUtils(config TokenConfig) *JWTUtils {
 		SecretKey: secretKey,
 		Issuer:    issuer,
 		Config:    config,
+		Keys:      DefaultKeyRing(),
 	}
 }
 
-// GenerateToken generates a token with any claims type
+// GenerateToken signs a token with any claims type using the ring's current key
 func (j *JWTUtils) GenerateToken(claims jwt.Claims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(j.SecretKey))
+	key, err := j.Keys.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.ID
+	signedToken, err := token.SignedString(key.PrivateKey)
 	if err != nil {
 		return "", err
 	}
 	return signedToken, nil
 }
 
-// ParseToken parses a token with specific claims type
-func ParseToken[T jwt.Claims](tokenStr string, secretKey string, claimsPtr T) (T, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, claimsPtr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+// keyFunc picks the verification key named by the token's kid header. Tokens
+// without a kid are legacy HS256 tokens and are accepted only while a secret is set.
+func (j *JWTUtils) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || j.SecretKey == "" {
 			return nil, jwt.ErrSignatureInvalid
 		}
-		return []byte(secretKey), nil
-	})
+		return []byte(j.SecretKey), nil
+	}
+	key, err := j.Keys.VerificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.Method().Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	return key.PublicKey(), nil
+}
+
+// ParseToken parses a token with specific claims type
+func ParseToken[T jwt.Claims](tokenStr string, keyFunc jwt.Keyfunc, claimsPtr T) (T, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, claimsPtr, keyFunc)
 	if err != nil {
 		var zero T
 		return zero, err
@@ -141,12 +180,12 @@ func ParseToken[T jwt.Claims](tokenStr string, secretKey string, claimsPtr T) (T
 
 // ParseUserToken parses a user authentication token
 func (j *JWTUtils) ParseUserToken(tokenStr string) (*UserClaims, error) {
-	return ParseToken(tokenStr, j.SecretKey, &UserClaims{})
+	return ParseToken(tokenStr, j.keyFunc, &UserClaims{})
 }
 
 // ParseInvitationToken parses an invitation token
 func (j *JWTUtils) ParseInvitationToken(tokenStr string) (*InvitationClaims, error) {
-	return ParseToken(tokenStr, j.SecretKey, &InvitationClaims{})
+	return ParseToken(tokenStr, j.keyFunc, &InvitationClaims{})
 }
 
 // NewUserClaims creates user claims with purpose-based expiration
@@ -161,6 +200,13 @@ func (j *JWTUtils) NewUserClaims(userID, email string, purpose TokenPurpose) *Us
 	}
 }
 
+// NewSessionClaims creates user claims bound to a session
+func (j *JWTUtils) NewSessionClaims(userID, email, sessionID string, purpose TokenPurpose) *UserClaims {
+	claims := j.NewUserClaims(userID, email, purpose)
+	claims.SessionID = sessionID
+	return claims
+}
+
 // NewInvitationClaims creates invitation claims
 func (j *JWTUtils) NewInvitationClaims(invitationID, workspaceID, inviterID, inviteeEmail, inviteeID, role string) *InvitationClaims {
 	return &InvitationClaims{
@@ -211,8 +257,8 @@ func (b *BaseClaims) TimeUntilExpiry() time.Duration {
 // Helper methods for JWTUtils to simplify token generation
 
 // GenerateAccessToken creates an access token
-func (j *JWTUtils) GenerateAccessToken(userID, email string) (string, error) {
-	claims := j.NewUserClaims(userID, email, PurposeAccess)
+func (j *JWTUtils) GenerateAccessToken(userID, email, sessionID string) (string, error) {
+	claims := j.NewSessionClaims(userID, email, sessionID, PurposeAccess)
 	return j.GenerateToken(claims)
 }
 
@@ -222,20 +268,32 @@ func (j *JWTUtils) GenerateAuthToken(userID, email string) (string, error) {
 	return j.GenerateToken(claims)
 }
 
+// GeneratePasswordResetToken creates a password reset token
+func (j *JWTUtils) GeneratePasswordResetToken(userID, email string) (string, error) {
+	claims := j.NewUserClaims(userID, email, PurposePasswordReset)
+	return j.GenerateToken(claims)
+}
+
+// GenerateMFAToken creates a token for completing a login with a second factor
+func (j *JWTUtils) GenerateMFAToken(userID, email string) (string, error) {
+	claims := j.NewUserClaims(userID, email, PurposeMFA)
+	return j.GenerateToken(claims)
+}
+
 // GenerateRefreshToken creates a refresh token
-func (j *JWTUtils) GenerateRefreshToken(userID, email string) (string, error) {
-	claims := j.NewUserClaims(userID, email, PurposeRefresh)
+func (j *JWTUtils) GenerateRefreshToken(userID, email, sessionID string) (string, error) {
+	claims := j.NewSessionClaims(userID, email, sessionID, PurposeRefresh)
 	return j.GenerateToken(claims)
 }
 
-// GenerateTokenPair generates both access and refresh tokens
-func (j *JWTUtils) GenerateTokenPair(userID, email string) (accessToken, refreshToken string, err error) {
-	accessToken, err = j.GenerateAccessToken(userID, email)
+// GenerateTokenPair generates both access and refresh tokens for a session
+func (j *JWTUtils) GenerateTokenPair(userID, email, sessionID string) (accessToken, refreshToken string, err error) {
+	accessToken, err = j.GenerateAccessToken(userID, email, sessionID)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken, err = j.GenerateRefreshToken(userID, email)
+	refreshToken, err = j.GenerateRefreshToken(userID, email, sessionID)
 	if err != nil {
 		return "", "", err
 	}
@@ -0,0 +1,393 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Supported asymmetric signing algorithms
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownKeyID         = errors.New("jwt: token signed with an unknown key")
+	ErrUnsupportedAlgorithm = errors.New("jwt: unsupported signing algorithm")
+)
+
+// SigningKey is one key of the ring. A key signs new tokens between ActivatesAt
+// and RetiresAt and verifies tokens until ExpiresAt, which is long enough for
+// every token it signed to expire on its own.
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	PrivateKey  crypto.Signer
+	CreatedAt   time.Time
+	ActivatesAt time.Time
+	RetiresAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// PublicKey returns the key used to verify the key's signatures
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// Method returns the signing method matching the key's algorithm
+func (k *SigningKey) Method() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// canSign reports whether the key is the one to sign with at now
+func (k *SigningKey) canSign(now time.Time) bool {
+	return !now.Before(k.ActivatesAt) && now.Before(k.RetiresAt)
+}
+
+// GenerateSigningKey creates a new key pair for the algorithm
+func GenerateSigningKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
+
+// KeyStore persists the keys of a ring so every API instance signs and verifies
+// with the same keys
+type KeyStore interface {
+	ListKeys() ([]*SigningKey, error)
+	SaveKey(key *SigningKey) error
+	DeleteExpiredKeys(now time.Time) error
+}
+
+// KeyRingConfig controls key generation and rotation
+type KeyRingConfig struct {
+	Algorithm string
+	// RotationInterval is how long each key signs new tokens
+	RotationInterval time.Duration
+	// PrePublish is how long before taking over a new key is listed in the JWKS,
+	// so verifiers that cache the key set see it before the first token it signs
+	PrePublish time.Duration
+	// VerifyFor is how long a retired key keeps verifying; it must cover the
+	// longest token lifetime
+	VerifyFor time.Duration
+	// CheckInterval is how often StartRotation reloads the store and rotates
+	CheckInterval time.Duration
+}
+
+// DefaultKeyRingConfig returns sensible defaults for key rotation
+func DefaultKeyRingConfig() KeyRingConfig {
+	return KeyRingConfig{
+		Algorithm:        AlgorithmRS256,
+		RotationInterval: 30 * 24 * time.Hour, // Monthly rotation
+		PrePublish:       24 * time.Hour,      // Published a day before use
+		VerifyFor:        8 * 24 * time.Hour,  // Outlives refresh tokens (7 days)
+		CheckInterval:    time.Hour,
+	}
+}
+
+// KeyRingConfigFromEnv reads JWT_SIGNING_ALGORITHM (RS256 or EdDSA) and
+// JWT_KEY_ROTATION_INTERVAL (a Go duration) over the defaults
+func KeyRingConfigFromEnv() KeyRingConfig {
+	config := DefaultKeyRingConfig()
+	if algorithm := os.Getenv("JWT_SIGNING_ALGORITHM"); algorithm != "" {
+		config.Algorithm = algorithm
+	}
+	if interval, err := time.ParseDuration(os.Getenv("JWT_KEY_ROTATION_INTERVAL")); err == nil && interval > 0 {
+		config.RotationInterval = interval
+	}
+	return config
+}
+
+// reloadThrottle limits store reloads triggered by tokens with unknown key ids
+const reloadThrottle = 10 * time.Second
+
+// KeyRing holds the signing and verification keys
+type KeyRing struct {
+	store  KeyStore
+	config KeyRingConfig
+
+	rotateMu   sync.Mutex
+	mu         sync.RWMutex
+	keys       []*SigningKey
+	lastReload time.Time
+	stop       chan struct{}
+}
+
+// NewKeyRing creates a ring backed by store. Call Rotate before first use to load
+// the keys and make sure one can sign.
+func NewKeyRing(store KeyStore, config KeyRingConfig) *KeyRing {
+	return &KeyRing{
+		store:  store,
+		config: config,
+	}
+}
+
+// Reload replaces the cached keys with the store's
+func (k *KeyRing) Reload() error {
+	keys, err := k.store.ListKeys()
+	if err != nil {
+		return err
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].ActivatesAt.Equal(keys[j].ActivatesAt) {
+			return keys[i].CreatedAt.After(keys[j].CreatedAt)
+		}
+		return keys[i].ActivatesAt.After(keys[j].ActivatesAt)
+	})
+	k.mu.Lock()
+	k.keys = keys
+	k.lastReload = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+// Rotate reloads the keys, drops expired ones and creates a key when none can
+// sign, or a successor when the current key is about to retire
+func (k *KeyRing) Rotate(now time.Time) error {
+	k.rotateMu.Lock()
+	defer k.rotateMu.Unlock()
+	if err := k.store.DeleteExpiredKeys(now); err != nil {
+		return err
+	}
+	if err := k.Reload(); err != nil {
+		return err
+	}
+
+	current := k.signingKey(now)
+	switch {
+	case current == nil:
+		return k.addKey(now, now)
+	case !k.hasSuccessor(current) && !now.Before(current.RetiresAt.Add(-k.config.PrePublish)):
+		return k.addKey(now, current.RetiresAt)
+	}
+	return nil
+}
+
+func (k *KeyRing) addKey(now, activatesAt time.Time) error {
+	private, err := GenerateSigningKey(k.config.Algorithm)
+	if err != nil {
+		return err
+	}
+	retiresAt := activatesAt.Add(k.config.RotationInterval)
+	key := &SigningKey{
+		ID:          uuid.NewString(),
+		Algorithm:   k.config.Algorithm,
+		PrivateKey:  private,
+		CreatedAt:   now,
+		ActivatesAt: activatesAt,
+		RetiresAt:   retiresAt,
+		ExpiresAt:   retiresAt.Add(k.config.VerifyFor),
+	}
+	if err := k.store.SaveKey(key); err != nil {
+		return err
+	}
+	return k.Reload()
+}
+
+func (k *KeyRing) hasSuccessor(current *SigningKey) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.ActivatesAt.After(current.ActivatesAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// signingKey returns the newest key allowed to sign at now
+func (k *KeyRing) signingKey(now time.Time) *SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.canSign(now) {
+			return key
+		}
+	}
+	return nil
+}
+
+// SigningKey returns the key to sign new tokens with, rotating first if needed
+func (k *KeyRing) SigningKey() (*SigningKey, error) {
+	now := time.Now().UTC()
+	if key := k.signingKey(now); key != nil {
+		return key, nil
+	}
+	if err := k.Rotate(now); err != nil {
+		return nil, err
+	}
+	if key := k.signingKey(now); key != nil {
+		return key, nil
+	}
+	return nil, errors.New("jwt: no signing key available")
+}
+
+// VerificationKey returns the unexpired key with the given id. An unknown id
+// triggers a throttled reload so keys created by other instances are picked up.
+func (k *KeyRing) VerificationKey(kid string) (*SigningKey, error) {
+	if key := k.lookup(kid); key != nil {
+		return key, nil
+	}
+	k.mu.RLock()
+	stale := time.Since(k.lastReload) > reloadThrottle
+	k.mu.RUnlock()
+	if stale {
+		if err := k.Reload(); err != nil {
+			return nil, err
+		}
+		if key := k.lookup(kid); key != nil {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKeyID
+}
+
+func (k *KeyRing) lookup(kid string) *SigningKey {
+	now := time.Now().UTC()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.ID == kid && now.Before(key.ExpiresAt) {
+			return key
+		}
+	}
+	return nil
+}
+
+// VerificationKeys returns every unexpired key, including ones not yet signing
+func (k *KeyRing) VerificationKeys() []*SigningKey {
+	now := time.Now().UTC()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keys := make([]*SigningKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		if now.Before(key.ExpiresAt) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// StartRotation rotates in the background every CheckInterval until Stop is called
+func (k *KeyRing) StartRotation() {
+	k.mu.Lock()
+	if k.stop != nil {
+		k.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	k.stop = stop
+	k.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(k.config.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				if err := k.Rotate(now.UTC()); err != nil {
+					log.Println("FAILED_TO_ROTATE_SIGNING_KEYS:", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends background rotation
+func (k *KeyRing) Stop() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.stop != nil {
+		close(k.stop)
+		k.stop = nil
+	}
+}
+
+var (
+	defaultKeyRing   *KeyRing
+	defaultKeyRingMu sync.Mutex
+)
+
+// SetDefaultKeyRing sets the ring used by NewJWTUtils. The API sets a database
+// backed ring at startup.
+func SetDefaultKeyRing(ring *KeyRing) {
+	defaultKeyRingMu.Lock()
+	defer defaultKeyRingMu.Unlock()
+	defaultKeyRing = ring
+}
+
+// DefaultKeyRing returns the ring used by NewJWTUtils. Without SetDefaultKeyRing it
+// is an in-memory ring whose keys do not survive a restart.
+func DefaultKeyRing() *KeyRing {
+	defaultKeyRingMu.Lock()
+	defer defaultKeyRingMu.Unlock()
+	if defaultKeyRing == nil {
+		defaultKeyRing = NewKeyRing(NewMemoryKeyStore(), KeyRingConfigFromEnv())
+	}
+	return defaultKeyRing
+}
+
+// MemoryKeyStore keeps keys in process memory
+type MemoryKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]*SigningKey
+}
+
+// NewMemoryKeyStore creates an empty in-memory key store
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: map[string]*SigningKey{}}
+}
+
+func (s *MemoryKeyStore) ListKeys() ([]*SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]*SigningKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (s *MemoryKeyStore) SaveKey(key *SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.keys[key.ID]; exists {
+		return fmt.Errorf("jwt: key %s already exists", key.ID)
+	}
+	s.keys[key.ID] = key
+	return nil
+}
+
+func (s *MemoryKeyStore) DeleteExpiredKeys(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, key := range s.keys {
+		if !now.Before(key.ExpiresAt) {
+			delete(s.keys, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ishola-faazele/taskflow/pkg/utils"
+)
+
+// ErrSealKeyNotConfigured is returned when a private key would have to be stored
+// in the clear
+var ErrSealKeyNotConfigured = errors.New("jwt: SIGNING_KEY_ENCRYPTION_KEY is not configured")
+
+// PostgresKeyStore keeps the key ring in the signing_key table. Private keys are
+// stored as PKCS #8 PEM sealed with SIGNING_KEY_ENCRYPTION_KEY, so the table or a
+// backup of it is not enough to sign tokens.
+type PostgresKeyStore struct {
+	db      *sql.DB
+	sealKey []byte
+}
+
+// NewPostgresKeyStore creates a new key store
+func NewPostgresKeyStore(db *sql.DB) *PostgresKeyStore {
+	return &PostgresKeyStore{db: db, sealKey: sealKeyFromEnv()}
+}
+
+// sealKeyFromEnv reads the base64 encoded 32 byte key private keys are sealed with
+func sealKeyFromEnv() []byte {
+	value := os.Getenv("SIGNING_KEY_ENCRYPTION_KEY")
+	if value == "" {
+		return nil
+	}
+	key, err := utils.ParseSecretKey(value)
+	if err != nil {
+		log.Println("INVALID_SIGNING_KEY_ENCRYPTION_KEY:", err)
+		return nil
+	}
+	return key
+}
+
+func (s *PostgresKeyStore) ListKeys() ([]*SigningKey, error) {
+	query := `
+		SELECT id, algorithm, private_key, created_at, activates_at, retires_at, expires_at
+		FROM signing_key
+		WHERE expires_at > $1
+	`
+
+	rows, err := s.db.Query(query, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*SigningKey
+	// unsealed holds the PEM of keys stored before private keys were sealed
+	unsealed := map[*SigningKey]string{}
+	for rows.Next() {
+		key := &SigningKey{}
+		var stored string
+		err := rows.Scan(&key.ID, &key.Algorithm, &stored, &key.CreatedAt, &key.ActivatesAt, &key.RetiresAt, &key.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		privatePEM := stored
+		if utils.IsSealedSecret(stored) {
+			if s.sealKey == nil {
+				return nil, ErrSealKeyNotConfigured
+			}
+			if privatePEM, err = utils.OpenSecret(s.sealKey, stored, key.ID); err != nil {
+				return nil, err
+			}
+		} else {
+			unsealed[key] = stored
+		}
+		if key.PrivateKey, err = decodePrivateKey(privatePEM); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// seal the keys stored in the clear the first time they are loaded
+	if s.sealKey != nil {
+		for key, privatePEM := range unsealed {
+			if err := s.sealStoredKey(key.ID, privatePEM); err != nil {
+				log.Println("FAILED_TO_SEAL_SIGNING_KEY:", key.ID, err)
+			}
+		}
+	}
+	return keys, nil
+}
+
+func (s *PostgresKeyStore) SaveKey(key *SigningKey) error {
+	sealed, err := s.sealPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO signing_key (id, algorithm, private_key, created_at, activates_at, retires_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err = s.db.Exec(query, key.ID, key.Algorithm, sealed, key.CreatedAt, key.ActivatesAt, key.RetiresAt, key.ExpiresAt)
+	return err
+}
+
+// sealPrivateKey encodes the key as PEM and seals it to the key's id, so a sealed
+// key copied to another row does not open
+func (s *PostgresKeyStore) sealPrivateKey(key *SigningKey) (string, error) {
+	if s.sealKey == nil {
+		return "", ErrSealKeyNotConfigured
+	}
+	privatePEM, err := encodePrivateKey(key.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	return utils.SealSecret(s.sealKey, privatePEM, key.ID)
+}
+
+// sealStoredKey replaces a key stored in the clear with its sealed form, unless
+// another instance sealed it first
+func (s *PostgresKeyStore) sealStoredKey(id, privatePEM string) error {
+	sealed, err := utils.SealSecret(s.sealKey, privatePEM, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`UPDATE signing_key SET private_key = $1 WHERE id = $2 AND private_key = $3`, sealed, id, privatePEM)
+	return err
+}
+
+func (s *PostgresKeyStore) DeleteExpiredKeys(now time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM signing_key WHERE expires_at <= $1`, now)
+	return err
+}
+
+func encodePrivateKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func decodePrivateKey(encoded string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("jwt: invalid private key PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	return signer, nil
+}
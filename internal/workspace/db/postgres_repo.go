@@ -3,6 +3,7 @@ package workspace
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	. "github.com/ishola-faazele/taskflow/internal/workspace/entity"
 	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
@@ -53,13 +54,13 @@ func (r *PostgresWorkspaceRepository) Create(ws *Workspace) (*Workspace, domain_
 	workspaceQuery := `
 		INSERT INTO workspace (id, name, owner_id, created_at)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, name, owner_id, created_at
+		RETURNING id, name, owner_id, created_at, archived_at
 	`
 
 	row := tx.QueryRow(workspaceQuery, ws.ID, ws.Name, ws.OwnerID, ws.CreatedAt)
 
 	result := &Workspace{}
-	err = row.Scan(&result.ID, &result.Name, &result.OwnerID, &result.CreatedAt)
+	err = row.Scan(&result.ID, &result.Name, &result.OwnerID, &result.CreatedAt, &result.ArchivedAt)
 	if err != nil {
 		return nil, domain_errors.NewDatabaseError("workspace creation - insert workspace", err)
 	}
@@ -86,7 +87,7 @@ func (r *PostgresWorkspaceRepository) Create(ws *Workspace) (*Workspace, domain_
 
 func (r *PostgresWorkspaceRepository) GetByID(id string) (*Workspace, domain_errors.DomainError) {
 	query := `
-		SELECT id, name, owner_id, created_at
+		SELECT id, name, owner_id, created_at, archived_at
 		FROM workspace
 		WHERE id = $1
 	`
@@ -94,7 +95,7 @@ func (r *PostgresWorkspaceRepository) GetByID(id string) (*Workspace, domain_err
 	row := r.db.QueryRow(query, id)
 
 	workspace := &Workspace{}
-	err := row.Scan(&workspace.ID, &workspace.Name, &workspace.OwnerID, &workspace.CreatedAt)
+	err := row.Scan(&workspace.ID, &workspace.Name, &workspace.OwnerID, &workspace.CreatedAt, &workspace.ArchivedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain_errors.NewNotFoundError("workspace", id)
@@ -110,13 +111,13 @@ func (r *PostgresWorkspaceRepository) Update(ws *Workspace) (*Workspace, domain_
 		UPDATE workspace
 		SET name = $2
 		WHERE id = $1
-		RETURNING id, name, owner_id, created_at
+		RETURNING id, name, owner_id, created_at, archived_at
 	`
 
 	row := r.db.QueryRow(query, ws.ID, ws.Name)
 
 	result := &Workspace{}
-	err := row.Scan(&result.ID, &result.Name, &result.OwnerID, &result.CreatedAt)
+	err := row.Scan(&result.ID, &result.Name, &result.OwnerID, &result.CreatedAt, &result.ArchivedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain_errors.NewNotFoundError("workspace", ws.ID)
@@ -147,15 +148,15 @@ func (r *PostgresWorkspaceRepository) Delete(id string) domain_errors.DomainErro
 	return nil
 }
 
-func (r *PostgresWorkspaceRepository) ListByOwner(ownerID string) ([]*Workspace, domain_errors.DomainError) {
+func (r *PostgresWorkspaceRepository) ListByOwner(ownerID string, includeArchived bool) ([]*Workspace, domain_errors.DomainError) {
 	query := `
-		SELECT id, name, owner_id, created_at
+		SELECT id, name, owner_id, created_at, archived_at
 		FROM workspace
-		WHERE owner_id = $1
+		WHERE owner_id = $1 AND ($2 OR archived_at IS NULL)
 		ORDER BY name
 	`
 
-	rows, err := r.db.Query(query, ownerID)
+	rows, err := r.db.Query(query, ownerID, includeArchived)
 	if err != nil {
 		return nil, domain_errors.NewDatabaseError("workspace query", err)
 	}
@@ -164,7 +165,7 @@ func (r *PostgresWorkspaceRepository) ListByOwner(ownerID string) ([]*Workspace,
 	var workspaces []*Workspace
 	for rows.Next() {
 		workspace := &Workspace{}
-		err := rows.Scan(&workspace.ID, &workspace.Name, &workspace.OwnerID, &workspace.CreatedAt)
+		err := rows.Scan(&workspace.ID, &workspace.Name, &workspace.OwnerID, &workspace.CreatedAt, &workspace.ArchivedAt)
 		if err != nil {
 			return nil, domain_errors.NewDatabaseError("workspace query", err)
 		}
@@ -178,6 +179,70 @@ func (r *PostgresWorkspaceRepository) ListByOwner(ownerID string) ([]*Workspace,
 	return workspaces, nil
 }
 
+func (r *PostgresWorkspaceRepository) ListByMember(userID string, includeArchived bool) ([]*WorkspaceSummary, domain_errors.DomainError) {
+	query := `
+		SELECT w.id, w.name, w.owner_id, w.created_at, w.archived_at, m.role,
+			(SELECT COUNT(*) FROM membership mc WHERE mc.workspace_id = w.id) AS member_count,
+			(SELECT COUNT(*) FROM project p WHERE p.workspace_id = w.id AND p.deleted_at IS NULL) AS project_count
+		FROM membership m
+		JOIN workspace w ON w.id = m.workspace_id
+		WHERE m.user_id = $1 AND ($2 OR w.archived_at IS NULL)
+		ORDER BY w.name
+	`
+
+	rows, err := r.db.Query(query, userID, includeArchived)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("workspace membership query", err)
+	}
+	defer rows.Close()
+
+	var workspaces []*WorkspaceSummary
+	for rows.Next() {
+		summary := &WorkspaceSummary{}
+		err := rows.Scan(
+			&summary.ID,
+			&summary.Name,
+			&summary.OwnerID,
+			&summary.CreatedAt,
+			&summary.ArchivedAt,
+			&summary.Role,
+			&summary.MemberCount,
+			&summary.ProjectCount,
+		)
+		if err != nil {
+			return nil, domain_errors.NewDatabaseError("workspace membership scan", err)
+		}
+		workspaces = append(workspaces, summary)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("workspace membership iteration", err)
+	}
+
+	return workspaces, nil
+}
+
+// SetArchived archives the workspace at archivedAt, or unarchives it when archivedAt is nil
+func (r *PostgresWorkspaceRepository) SetArchived(id string, archivedAt *time.Time) (*Workspace, domain_errors.DomainError) {
+	query := `
+		UPDATE workspace
+		SET archived_at = $2
+		WHERE id = $1
+		RETURNING id, name, owner_id, created_at, archived_at
+	`
+
+	result := &Workspace{}
+	err := r.db.QueryRow(query, id, archivedAt).Scan(&result.ID, &result.Name, &result.OwnerID, &result.CreatedAt, &result.ArchivedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain_errors.NewNotFoundError("workspace", id)
+		}
+		return nil, domain_errors.NewDatabaseError("workspace archive", err)
+	}
+
+	return result, nil
+}
+
 // MembershipRepository implementation
 
 func (r *PostgresMembershipRepository) Add(membership *Membership) (*Membership, error) {
@@ -268,6 +333,27 @@ func (r *PostgresMembershipRepository) IsMember(userID, workspaceID string) (boo
 	return count > 0, nil
 }
 
+// GetRole returns the user's role in the workspace, or an empty role when they
+// are not a member
+func (r *PostgresMembershipRepository) GetRole(userID, workspaceID string) (Role, error) {
+	query := `
+		SELECT role
+		FROM membership
+		WHERE user_id = $1 AND workspace_id = $2
+	`
+
+	var role Role
+	err := r.db.QueryRow(query, userID, workspaceID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", domain_errors.NewDatabaseError("membership role query", err)
+	}
+
+	return role, nil
+}
+
 // InvitationRepository implementation
 
 func (r *PostgresInvitationRepository) Create(invitation *Invitation) (*Invitation, domain_errors.DomainError) {
@@ -356,3 +442,67 @@ func (r *PostgresInvitationRepository) ListInvitationToWorkspace(workspace_id st
 	}
 	return results, nil
 }
+
+// ListPendingByEmail returns valid invitations sent to email since the given time
+// whose invitee has not already joined the workspace
+func (r *PostgresInvitationRepository) ListPendingByEmail(email string, since time.Time) ([]*Invitation, domain_errors.DomainError) {
+	query := `
+		SELECT i.id, COALESCE(i.invitee_id, ''), i.invitee_email, i.inviter_id, i.workspace_id, i.role, i.is_valid, i.created_at
+		FROM invitation i
+		WHERE LOWER(i.invitee_email) = LOWER($1)
+			AND i.is_valid = TRUE
+			AND i.created_at >= $2
+			AND NOT EXISTS (
+				SELECT 1
+				FROM membership m
+				JOIN auth a ON a.id = m.user_id
+				WHERE m.workspace_id = i.workspace_id AND LOWER(a.email) = LOWER(i.invitee_email)
+			)
+		ORDER BY i.created_at DESC
+	`
+
+	rows, err := r.db.Query(query, email, since)
+	if err != nil {
+		return nil, domain_errors.NewDatabaseError("FAILED GETTING PENDING INVITATIONS", err)
+	}
+	defer rows.Close()
+
+	var results []*Invitation
+	for rows.Next() {
+		invitation := &Invitation{}
+		err := rows.Scan(&invitation.ID, &invitation.InviteeID, &invitation.InviteeEmail, &invitation.InviterID, &invitation.WorkspaceID, &invitation.Role, &invitation.IsValid, &invitation.CreatedAt)
+		if err != nil {
+			return nil, domain_errors.NewDatabaseError("FAILED GETTING PENDING INVITATIONS", err)
+		}
+		results = append(results, invitation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, domain_errors.NewDatabaseError("FAILED GETTING PENDING INVITATIONS", err)
+	}
+	return results, nil
+}
+
+// RecipientLocale returns the locale of the account registered to email, or the
+// inviter's locale when nobody has signed up with it yet
+func (r *PostgresInvitationRepository) RecipientLocale(email, inviterID string) (string, domain_errors.DomainError) {
+	query := `
+		SELECT COALESCE(
+			(
+				SELECT up.locale
+				FROM auth a
+				JOIN user_profile up ON up.id = a.id
+				WHERE LOWER(a.email) = LOWER($1)
+				ORDER BY a.created_at
+				LIMIT 1
+			),
+			(SELECT locale FROM user_profile WHERE id = $2),
+			''
+		)
+	`
+
+	var locale string
+	if err := r.db.QueryRow(query, email, inviterID).Scan(&locale); err != nil {
+		return "", domain_errors.NewDatabaseError("FAILED GETTING INVITATION LOCALE", err)
+	}
+	return locale, nil
+}
@@ -7,6 +7,16 @@ type Workspace struct {
 	Name      string    `json:"name"`
 	OwnerID   string    `json:"owner_id"`
 	CreatedAt time.Time `json:"created_at"`
+	// ArchivedAt is set while the workspace is archived and read-only
+	ArchivedAt *time.Time `json:"archived_at"`
+}
+
+// WorkspaceSummary is a workspace as seen by one of its members
+type WorkspaceSummary struct {
+	Workspace
+	Role         Role `json:"role"`
+	MemberCount  int  `json:"member_count"`
+	ProjectCount int  `json:"project_count"`
 }
 
 type Invitation struct {
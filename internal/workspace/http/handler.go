@@ -126,6 +126,42 @@ func (h *WorkspaceHandler) DeleteWorkspace(w http.ResponseWriter, r *http.Reques
 	h.responder.NoContent(w)
 }
 
+// ArchiveWorkspace handles making a workspace read-only
+func (h *WorkspaceHandler) ArchiveWorkspace(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	requester, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || requester == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+
+	workspace, err := h.service.ArchiveWorkspace(id, requester)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to archive workspace", err)
+		return
+	}
+
+	h.responder.Success(w, r, http.StatusOK, "Workspace archived successfully", workspace)
+}
+
+// UnarchiveWorkspace handles making an archived workspace writable again
+func (h *WorkspaceHandler) UnarchiveWorkspace(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	requester, ok := r.Context().Value(domain_middleware.UserIDKey).(string)
+	if !ok || requester == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+
+	workspace, err := h.service.UnarchiveWorkspace(id, requester)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to unarchive workspace", err)
+		return
+	}
+
+	h.responder.Success(w, r, http.StatusOK, "Workspace unarchived successfully", workspace)
+}
+
 // ListWorkspaces handles listing workspaces by owner
 func (h *WorkspaceHandler) ListWorkspaces(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -135,7 +171,27 @@ func (h *WorkspaceHandler) ListWorkspaces(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	workspaces, err := h.service.ListWorkspacesByOwner(ownerID)
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	workspaces, err := h.service.ListWorkspacesByOwner(ownerID, includeArchived)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to list workspaces", err)
+		return
+	}
+
+	h.responder.Success(w, r, http.StatusOK, "Workspaces retrieved successfully", workspaces)
+}
+
+// ListJoinedWorkspaces handles listing every workspace the requester is a member of
+func (h *WorkspaceHandler) ListJoinedWorkspaces(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(domain_middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User ID not found in context", nil)
+		return
+	}
+
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	workspaces, err := h.service.ListWorkspacesByMember(userID, includeArchived)
 	if err != nil {
 		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to list workspaces", err)
 		return
@@ -218,6 +274,24 @@ func (h *WorkspaceHandler) ListWorkspaceInvitations(w http.ResponseWriter, r *ht
 	h.responder.Success(w, r, http.StatusOK, "Invitations retrieved successfully", invitations)
 }
 
+// ListPendingInvitations handles listing the invitations sent to the requester's email
+func (h *WorkspaceHandler) ListPendingInvitations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	email, ok := ctx.Value(domain_middleware.UserEmailKey).(string)
+	if !ok || email == "" {
+		h.responder.Error(w, r, http.StatusUnauthorized, "Unauthorized: User email not found in context", nil)
+		return
+	}
+
+	invitations, err := h.service.ListPendingInvitations(email)
+	if err != nil {
+		h.responder.Error(w, r, http.StatusInternalServerError, "Failed to list pending invitations", err)
+		return
+	}
+
+	h.responder.Success(w, r, http.StatusOK, "Pending invitations retrieved successfully", invitations)
+}
+
 // / MEMBERSHIP HANDLERS
 type RemoveMembershipRequest struct {
 	UserID      string `json:"user_id"`
@@ -7,25 +7,30 @@ import (
 )
 
 func RegisterRoutes(r chi.Router, as *shared.AppState) {
-	dm := domain_middleware.NewDomainMiddleware()
+	dm := domain_middleware.NewDomainMiddleware(as.DB)
 	handler := NewWorkspaceHandler(as.DB, as.AmqpConn)
 	r.Use(dm.Authenticate)
+	r.Use(dm.RequireResourceScope("workspaces"))
 
 	// Workspace routes
 	r.Post("/", handler.CreateWorkspace)
 	r.Get("/mine", handler.ListWorkspaces)
+	r.Get("/joined", handler.ListJoinedWorkspaces)
 	r.Put("/{id}", handler.UpdateWorkspace)
 	r.Get("/{id}", handler.GetWorkspace)
 	r.Delete("/{id}", handler.DeleteWorkspace)
+	r.Post("/{id}/archive", handler.ArchiveWorkspace)
+	r.Post("/{id}/unarchive", handler.UnarchiveWorkspace)
 
 	// Invitation routes
 	r.Post("/invitation", handler.CreateInvitation)
 	r.Get("/invitation", handler.ListWorkspaceInvitations)
+	r.Get("/invitation/pending", handler.ListPendingInvitations)
 	r.Get("/invitation/{id}", handler.GetInvitation)
 	r.Delete("/invitation/{id}", handler.DeleteInvitation)
 
 	// Membership routes
-	r.Get("/membership/add", handler.AddMembership)
+	r.With(dm.RequireSession).Get("/membership/add", handler.AddMembership)
 	r.Get("/membership", handler.ListWorkspaceMembers)
 	r.Post("/membership/remove", handler.RemoveMembership)
 }
@@ -1,6 +1,8 @@
 package workspace
 
 import (
+	"time"
+
 	. "github.com/ishola-faazele/taskflow/internal/workspace/entity"
 	"github.com/ishola-faazele/taskflow/pkg/utils/domain_errors"
 )
@@ -10,7 +12,9 @@ type WorkspaceRepository interface {
 	GetByID(id string) (*Workspace, domain_errors.DomainError)
 	Update(ws *Workspace) (*Workspace, domain_errors.DomainError)
 	Delete(id string) domain_errors.DomainError
-	ListByOwner(ownerID string) ([]*Workspace, domain_errors.DomainError)
+	ListByOwner(ownerID string, includeArchived bool) ([]*Workspace, domain_errors.DomainError)
+	ListByMember(userID string, includeArchived bool) ([]*WorkspaceSummary, domain_errors.DomainError)
+	SetArchived(id string, archivedAt *time.Time) (*Workspace, domain_errors.DomainError)
 }
 
 type InvitationRepository interface {
@@ -18,6 +22,9 @@ type InvitationRepository interface {
 	GetByID(id string) (*Invitation, domain_errors.DomainError)
 	DeleteInvitation(id string) domain_errors.DomainError
 	ListInvitationToWorkspace(ws_id string) ([]*Invitation, domain_errors.DomainError)
+	ListPendingByEmail(email string, since time.Time) ([]*Invitation, domain_errors.DomainError)
+	// RecipientLocale returns the locale an invitation to email is written in
+	RecipientLocale(email, inviterID string) (string, domain_errors.DomainError)
 }
 
 type MembershipRepository interface {
@@ -25,4 +32,5 @@ type MembershipRepository interface {
 	Remove(userID, workspaceID string) error
 	ListByWorkspace(workspaceID string) ([]*Membership, error)
 	IsMember(userID, workspaceID string) (bool, error)
+	GetRole(userID, workspaceID string) (Role, error)
 }
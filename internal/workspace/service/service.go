@@ -75,6 +75,9 @@ func (s *WorkspaceService) UpdateWorkspace(id, name, requester string) (*Workspa
 	if requester != workspace.OwnerID {
 		return nil, domain_errors.NewUnauthorizedError("REQUESTER IS NOT THE OWNER OF WORKSPACE")
 	}
+	if workspace.ArchivedAt != nil {
+		return nil, domain_errors.NewInvalidOperationError("update workspace", "THE WORKSPACE IS ARCHIVED")
+	}
 	workspace.Name = name
 
 	return s.WorkspaceRepo.Update(workspace)
@@ -95,13 +98,59 @@ func (s *WorkspaceService) DeleteWorkspace(id, requester string) domain_errors.D
 	return s.WorkspaceRepo.Delete(id)
 }
 
-func (s *WorkspaceService) ListWorkspacesByOwner(ownerID string) ([]*Workspace, domain_errors.DomainError) {
+func (s *WorkspaceService) ListWorkspacesByOwner(ownerID string, includeArchived bool) ([]*Workspace, domain_errors.DomainError) {
 	log.Println("USER ID: ", ownerID)
 	if err := uuid.Validate(ownerID); err != nil {
 		return nil, domain_errors.NewValidationErrorWithValue("ownerID", ownerID, "OWNER ID IS NOT A VALID UUID")
 	}
-	return s.WorkspaceRepo.ListByOwner(ownerID)
+	return s.WorkspaceRepo.ListByOwner(ownerID, includeArchived)
+
+}
 
+// ListWorkspacesByMember returns every workspace the user belongs to along with
+// their role and the workspace's member and project counts. Archived workspaces
+// are left out unless includeArchived is set.
+func (s *WorkspaceService) ListWorkspacesByMember(userID string, includeArchived bool) ([]*WorkspaceSummary, domain_errors.DomainError) {
+	if err := uuid.Validate(userID); err != nil {
+		return nil, domain_errors.NewValidationErrorWithValue("user_id", userID, "USER ID IS NOT A VALID UUID")
+	}
+	return s.WorkspaceRepo.ListByMember(userID, includeArchived)
+}
+
+// ArchiveWorkspace makes the workspace and everything in it read-only until an
+// admin or the owner unarchives it
+func (s *WorkspaceService) ArchiveWorkspace(id, requester string) (*Workspace, domain_errors.DomainError) {
+	if err := s.checkCanArchive(id, requester); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	return s.WorkspaceRepo.SetArchived(id, &now)
+}
+
+// UnarchiveWorkspace makes an archived workspace writable again
+func (s *WorkspaceService) UnarchiveWorkspace(id, requester string) (*Workspace, domain_errors.DomainError) {
+	if err := s.checkCanArchive(id, requester); err != nil {
+		return nil, err
+	}
+	return s.WorkspaceRepo.SetArchived(id, nil)
+}
+
+// checkCanArchive allows only the workspace's admins and owner to archive it
+func (s *WorkspaceService) checkCanArchive(id, requester string) domain_errors.DomainError {
+	if err := uuid.Validate(id); err != nil {
+		return domain_errors.NewValidationErrorWithValue("workspace_id", id, "WORKSPACE ID IS NOT A VALID UUID")
+	}
+	if err := uuid.Validate(requester); err != nil {
+		return domain_errors.NewValidationErrorWithValue("requester_id", requester, "REQUESTER_ID IS NOT A VALID UUID")
+	}
+	role, err := s.MembershipRepo.GetRole(requester, id)
+	if err != nil {
+		return domain_errors.NewDatabaseError("membership role query", err)
+	}
+	if role != RoleAdmin && role != RoleOwner {
+		return domain_errors.NewUnauthorizedError("ONLY WORKSPACE ADMINS CAN ARCHIVE OR UNARCHIVE IT")
+	}
+	return nil
 }
 
 // INVITATION FUNCTIONS
@@ -140,8 +189,14 @@ func (s *WorkspaceService) CreateInvitation(invitee, inviter, ws, email string,
 	if errToken != nil {
 		return nil, domain_errors.NewInternalError("FAILED GENERATING INVITATION TOKEN", errToken)
 	}
+	// write the invitation in the invitee's language, or the inviter's when the
+	// invitee has no account yet
+	locale, localeErr := s.InvitationRepo.RecipientLocale(email, inviter)
+	if localeErr != nil {
+		log.Println("FAILED_TO_RESOLVE_INVITATION_LOCALE:", localeErr)
+	}
 	// Publish Invitation to Queue to be sent to user
-	emailMsg, errEmail := amqp_utils.NewInvitationMessage(email, inv.WorkspaceID, string(role), token, "/api/workspace/accept?token=")
+	emailMsg, errEmail := amqp_utils.NewInvitationMessage(email, inv.WorkspaceID, string(role), token, "/api/workspace/accept?token=", locale)
 	if errEmail != nil {
 		return nil, domain_errors.NewInternalError("FAILED_CREATING_INVITATION_EMAIL", errEmail)
 	}
@@ -198,6 +253,16 @@ func (s *WorkspaceService) ListWorkspaceInvitations(ws_id, requester string) ([]
 	return s.InvitationRepo.ListInvitationToWorkspace(ws_id)
 }
 
+// ListPendingInvitations returns the invitations addressed to email that have not
+// expired and have not been accepted yet
+func (s *WorkspaceService) ListPendingInvitations(email string) ([]*Invitation, domain_errors.DomainError) {
+	if !utils.IsValidEmail(email) {
+		return nil, domain_errors.NewValidationErrorWithValue("email", email, "INVALID EMAIL FORMAT")
+	}
+	since := time.Now().UTC().Add(-s.jwtUtil.Config.InvitationTokenDuration)
+	return s.InvitationRepo.ListPendingByEmail(email, since)
+}
+
 // MEMBERSHIP FUNCTIONS
 
 func (s *WorkspaceService) AddMembership(token string) (*Membership, error) {
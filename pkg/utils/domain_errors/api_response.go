@@ -2,8 +2,10 @@ package domain_errors
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/ishola-faazele/taskflow/pkg/utils/logger"
@@ -64,6 +66,10 @@ func (a *APIResponder) Error(w http.ResponseWriter, r *http.Request, statusCode
 		// Override status code with the one from domain error
 		statusCode = int(domainErr.Code())
 
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(rateLimitErr.RetryAfter.Seconds())))
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(statusCode)
 		cause := "UNKNOWN"
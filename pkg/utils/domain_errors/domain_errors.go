@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // ErrorCode represents HTTP status codes for domain errors
@@ -18,6 +19,9 @@ const (
 	ErrCodeForbidden        ErrorCode = http.StatusForbidden           // 403
 	ErrCodeInvalidOperation ErrorCode = http.StatusUnprocessableEntity // 422
 	ErrCodeInternal         ErrorCode = http.StatusInternalServerError // 500
+	ErrCodeTooManyRequests  ErrorCode = http.StatusTooManyRequests     // 429
+	// ErrCodePreconditionFailed answers a conditional request made against a stale version
+	ErrCodePreconditionFailed ErrorCode = http.StatusPreconditionFailed // 412
 )
 
 // DomainError is the base error interface for all domain errors
@@ -209,6 +213,25 @@ func NewConflictError(resource, constraint string) *ConflictError {
 	}
 }
 
+// NewVersionConflictError reports a conditional write made against a stale
+// version of a resource. current is the resource as it is now, so the client
+// can reconcile its changes and retry.
+func NewVersionConflictError(resource, id string, current any) *ConflictError {
+	return &ConflictError{
+		BaseError: &BaseError{
+			code:    ErrCodePreconditionFailed,
+			message: fmt.Sprintf("%s with ID '%s' was modified since it was read", resource, id),
+			details: map[string]interface{}{
+				"resource": resource,
+				"id":       id,
+				"current":  current,
+			},
+		},
+		Resource:   resource,
+		Constraint: "version",
+	}
+}
+
 // UnauthorizedError represents an authentication failure
 type UnauthorizedError struct {
 	*BaseError
@@ -292,6 +315,28 @@ func NewInternalError(component string, cause error) *InternalError {
 	}
 }
 
+// RateLimitError represents a caller exceeding an allowed request rate
+type RateLimitError struct {
+	*BaseError
+	Limit      string
+	RetryAfter time.Duration
+}
+
+func NewRateLimitError(limit string, retryAfter time.Duration) *RateLimitError {
+	return &RateLimitError{
+		BaseError: &BaseError{
+			code:    ErrCodeTooManyRequests,
+			message: fmt.Sprintf("rate limit exceeded: %s", limit),
+			details: map[string]interface{}{
+				"limit":               limit,
+				"retry_after_seconds": int(retryAfter.Seconds()),
+			},
+		},
+		Limit:      limit,
+		RetryAfter: retryAfter,
+	}
+}
+
 // Error checking utilities
 
 // IsNotFound checks if an error is a NotFoundError
@@ -330,6 +375,12 @@ func IsForbidden(err error) bool {
 	return errors.As(err, &forbiddenErr)
 }
 
+// IsRateLimited checks if an error is a RateLimitError
+func IsRateLimited(err error) bool {
+	var rateLimitErr *RateLimitError
+	return errors.As(err, &rateLimitErr)
+}
+
 // GetDomainError extracts a DomainError from an error chain
 func GetDomainError(err error) (DomainError, bool) {
 	var domainErr DomainError
@@ -16,6 +16,12 @@ func IsValidEmail(email string) bool {
 	return re.MatchString(email)
 }
 
+// NormalizeEmail trims and lowercases an address, the form accounts are stored and
+// looked up in
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 // EmailConfig holds SMTP configuration for Gmail
 type EmailConfig struct {
 	SMTPHost    string
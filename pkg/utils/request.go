@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+)
+
+// trustedProxies are the proxies whose X-Forwarded-For and X-Real-IP headers are
+// believed, read once from TRUSTED_PROXIES as comma separated addresses or CIDRs
+var trustedProxies = sync.OnceValue(func() []netip.Prefix {
+	return parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+})
+
+// parseTrustedProxies parses comma separated addresses and CIDRs, skipping
+// entries that are neither
+func parseTrustedProxies(value string) []netip.Prefix {
+	prefixes := []netip.Prefix{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		}
+	}
+	return prefixes
+}
+
+// ClientIP returns the address of the client that made the request. Forwarding
+// headers are only believed when the request comes from a trusted proxy, since
+// anyone else can set them to any value.
+func ClientIP(r *http.Request) string {
+	return clientIP(r, trustedProxies())
+}
+
+func clientIP(r *http.Request, proxies []netip.Prefix) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !isTrustedProxy(peer, proxies) {
+		return peer
+	}
+	// each proxy appends the address it got the request from, so the client is the
+	// last entry not added for one of our own proxies. An entry that is not an
+	// address was not written by a proxy, so nothing before it is believed.
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		addr, err := netip.ParseAddr(hop)
+		if err != nil {
+			return peer
+		}
+		if !isTrustedProxy(hop, proxies) {
+			return addr.Unmap().String()
+		}
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap().String()
+	}
+	return peer
+}
+
+func isTrustedProxy(address string, proxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
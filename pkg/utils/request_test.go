@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	got := parseTrustedProxies(" 10.0.0.1, 192.168.0.0/16,not-an-ip,, fd00::/8 , ::ffff:172.16.0.1, 2001:db8::1")
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.1/32"),
+		netip.MustParsePrefix("192.168.0.0/16"),
+		netip.MustParsePrefix("fd00::/8"),
+		netip.MustParsePrefix("172.16.0.1/32"),
+		netip.MustParsePrefix("2001:db8::1/128"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("prefixes = %v, want %v", got, want)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies := parseTrustedProxies("10.0.0.0/8, 192.168.1.1, fd00::/8, 2001:db8::1")
+	cases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "203.0.113.7:51234",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "untrusted peer forging X-Forwarded-For",
+			remoteAddr: "203.0.113.7:51234",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "untrusted peer forging X-Real-IP",
+			remoteAddr: "203.0.113.7:51234",
+			realIP:     "198.51.100.1",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "one trusted proxy",
+			remoteAddr: "10.0.0.2:443",
+			forwarded:  []string{"203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "chain of trusted proxies",
+			remoteAddr: "10.0.0.2:443",
+			forwarded:  []string{"203.0.113.7, 192.168.1.1, 10.1.2.3"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "chain split over several headers",
+			remoteAddr: "10.0.0.2:443",
+			forwarded:  []string{"203.0.113.7", "192.168.1.1"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "client prepending a forged hop",
+			remoteAddr: "10.0.0.2:443",
+			forwarded:  []string{"198.51.100.1, 203.0.113.7, 10.1.2.3"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "client prepending a trusted address",
+			remoteAddr: "10.0.0.2:443",
+			forwarded:  []string{"10.9.9.9, 203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "empty X-Forwarded-For",
+			remoteAddr: "10.0.0.2:443",
+			forwarded:  []string{""},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "only separators",
+			remoteAddr: "10.0.0.2:443",
+			forwarded:  []string{" , ,"},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "malformed hop",
+			remoteAddr: "10.0.0.2:443",
+			forwarded:  []string{"not-an-ip"},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "malformed hop behind the client",
+			remoteAddr: "10.0.0.2:443",
+			forwarded:  []string{"203.0.113.7, 203.0.113.8:80"},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "malformed hop before the client",
+			remoteAddr: "10.0.0.2:443",
+			forwarded:  []string{"<script>, 203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "X-Real-IP when every hop is a proxy",
+			remoteAddr: "10.0.0.2:443",
+			forwarded:  []string{"10.1.2.3"},
+			realIP:     "203.0.113.7",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "malformed X-Real-IP",
+			remoteAddr: "10.0.0.2:443",
+			realIP:     "anyone",
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "IPv6 proxy in a CIDR",
+			remoteAddr: "[fd00::5]:443",
+			forwarded:  []string{"2001:db8:ffff::7"},
+			want:       "2001:db8:ffff::7",
+		},
+		{
+			name:       "IPv6 proxy listed by address",
+			remoteAddr: "[2001:db8::1]:443",
+			forwarded:  []string{"203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "IPv6 chain",
+			remoteAddr: "[fd00::5]:443",
+			forwarded:  []string{"2001:db8:ffff::7, fd00::9, 2001:db8::1"},
+			want:       "2001:db8:ffff::7",
+		},
+		{
+			name:       "untrusted IPv6 peer",
+			remoteAddr: "[2001:db8::2]:443",
+			forwarded:  []string{"203.0.113.7"},
+			want:       "2001:db8::2",
+		},
+		{
+			name:       "IPv4-mapped proxy",
+			remoteAddr: "[::ffff:10.0.0.2]:443",
+			forwarded:  []string{"::ffff:203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "peer without a port",
+			remoteAddr: "10.0.0.2",
+			forwarded:  []string{"203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for _, value := range tc.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tc.realIP != "" {
+				r.Header.Set("X-Real-IP", tc.realIP)
+			}
+			if got := clientIP(r, proxies); got != tc.want {
+				t.Fatalf("client ip = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutTrustedProxies(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.2:443"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	if got := clientIP(r, parseTrustedProxies("")); got != "10.0.0.2" {
+		t.Fatalf("client ip = %q, want the peer", got)
+	}
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedSecretPrefix marks values produced by SealSecret, so secrets stored before
+// encryption was introduced can still be told apart and read
+const sealedSecretPrefix = "v1:"
+
+var ErrInvalidSealedSecret = errors.New("invalid sealed secret")
+
+// ParseSecretKey decodes a base64 encoded 32 byte AES-256 key
+func ParseSecretKey(value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secret key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// SealSecret encrypts plaintext with AES-256-GCM. The associated data, such as
+// the id of the row the secret belongs to, must be given again to open it, so a
+// sealed value copied to another row does not decrypt.
+func SealSecret(key []byte, plaintext, associatedData string) (string, error) {
+	aead, err := newSecretAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(associatedData))
+	return sealedSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenSecret decrypts a value produced by SealSecret
+func OpenSecret(key []byte, sealed, associatedData string) (string, error) {
+	encoded, ok := strings.CutPrefix(sealed, sealedSecretPrefix)
+	if !ok {
+		return "", ErrInvalidSealedSecret
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidSealedSecret
+	}
+	aead, err := newSecretAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < aead.NonceSize() {
+		return "", ErrInvalidSealedSecret
+	}
+	plaintext, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(associatedData))
+	if err != nil {
+		return "", ErrInvalidSealedSecret
+	}
+	return string(plaintext), nil
+}
+
+// IsSealedSecret reports whether value was produced by SealSecret
+func IsSealedSecret(value string) bool {
+	return strings.HasPrefix(value, sealedSecretPrefix)
+}
+
+func newSecretAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}